@@ -0,0 +1,5 @@
+ALTER TABLE quiz_answers DROP CONSTRAINT IF EXISTS quiz_answers_question_id_option_id_key;
+ALTER TABLE user_answers DROP CONSTRAINT IF EXISTS user_answers_session_id_question_id_option_id_key;
+DELETE FROM user_answers a USING user_answers b
+WHERE a.session_id = b.session_id AND a.question_id = b.question_id AND a.id > b.id;
+ALTER TABLE user_answers ADD CONSTRAINT user_answers_session_id_question_id_key UNIQUE (session_id, question_id);
@@ -0,0 +1,3 @@
+ALTER TABLE user_answers DROP CONSTRAINT IF EXISTS user_answers_session_id_question_id_key;
+ALTER TABLE user_answers ADD CONSTRAINT user_answers_session_id_question_id_option_id_key UNIQUE (session_id, question_id, option_id);
+ALTER TABLE quiz_answers ADD CONSTRAINT quiz_answers_question_id_option_id_key UNIQUE (question_id, option_id);
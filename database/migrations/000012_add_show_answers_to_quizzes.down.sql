@@ -0,0 +1,2 @@
+ALTER TABLE quizzes DROP COLUMN IF EXISTS answers_reveal_at;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS show_answers;
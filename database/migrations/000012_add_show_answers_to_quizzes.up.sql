@@ -0,0 +1,2 @@
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS show_answers TEXT NOT NULL DEFAULT 'after_submit';
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS answers_reveal_at TIMESTAMPTZ;
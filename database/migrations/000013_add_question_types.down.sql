@@ -0,0 +1,7 @@
+DELETE FROM user_answers WHERE option_id IS NULL;
+ALTER TABLE user_answers DROP COLUMN IF EXISTS value;
+ALTER TABLE user_answers ALTER COLUMN option_id SET NOT NULL;
+ALTER TABLE questions_options DROP COLUMN IF EXISTS match_value;
+ALTER TABLE questions_options DROP COLUMN IF EXISTS position;
+ALTER TABLE questions DROP COLUMN IF EXISTS answer_key;
+ALTER TABLE questions DROP COLUMN IF EXISTS type;
@@ -0,0 +1,6 @@
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'single_choice';
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS answer_key JSONB;
+ALTER TABLE questions_options ADD COLUMN IF NOT EXISTS position INTEGER;
+ALTER TABLE questions_options ADD COLUMN IF NOT EXISTS match_value TEXT;
+ALTER TABLE user_answers ALTER COLUMN option_id DROP NOT NULL;
+ALTER TABLE user_answers ADD COLUMN IF NOT EXISTS value TEXT;
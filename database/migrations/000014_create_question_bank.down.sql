@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS user_quiz_session_questions;
+
+ALTER TABLE quizzes DROP COLUMN IF EXISTS shuffle;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS bank_grade_level;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS bank_subject_id;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS draw_count;
+
+DELETE FROM questions WHERE quiz_id IS NULL;
+DROP INDEX IF EXISTS questions_bank_idx;
+ALTER TABLE questions DROP CONSTRAINT IF EXISTS questions_quiz_or_bank;
+ALTER TABLE questions DROP COLUMN IF EXISTS grade_level;
+ALTER TABLE questions DROP COLUMN IF EXISTS subject_id;
+ALTER TABLE questions ALTER COLUMN quiz_id SET NOT NULL;
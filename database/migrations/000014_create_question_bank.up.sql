@@ -0,0 +1,19 @@
+ALTER TABLE questions ALTER COLUMN quiz_id DROP NOT NULL;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS subject_id UUID REFERENCES subjects(id) ON DELETE CASCADE;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS grade_level INTEGER;
+ALTER TABLE questions ADD CONSTRAINT questions_quiz_or_bank CHECK ((quiz_id IS NULL) <> (subject_id IS NULL));
+CREATE INDEX IF NOT EXISTS questions_bank_idx ON questions (subject_id, grade_level) WHERE quiz_id IS NULL;
+
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS draw_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS bank_subject_id UUID REFERENCES subjects(id) ON DELETE SET NULL;
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS bank_grade_level INTEGER;
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS shuffle BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS user_quiz_session_questions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+  session_id UUID NOT NULL REFERENCES user_quiz_sessions(id) ON DELETE CASCADE,
+  question_id UUID NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+  position INTEGER NOT NULL,
+  option_order JSONB NOT NULL DEFAULT '[]',
+  UNIQUE (session_id, question_id)
+);
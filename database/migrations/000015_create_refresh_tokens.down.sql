@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token TEXT;
+
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS user_recovery_codes;
+
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS mfa_verified;
+
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_challenge_attempts;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_challenge_id;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
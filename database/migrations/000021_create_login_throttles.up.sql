@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS login_throttles (
+  scope TEXT NOT NULL,
+  key TEXT NOT NULL,
+  failures INTEGER NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  locked_until TIMESTAMPTZ,
+  PRIMARY KEY (scope, key)
+);
+
+CREATE TABLE IF NOT EXISTS login_lockout_events (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+  user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  event TEXT NOT NULL,
+  scope TEXT NOT NULL,
+  key TEXT NOT NULL,
+  failures INTEGER NOT NULL DEFAULT 0,
+  locked_until TIMESTAMPTZ,
+  ip_address TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS login_lockout_events_user_idx ON login_lockout_events (user_id, created_at);
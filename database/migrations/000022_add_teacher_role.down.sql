@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS teacher_subjects;
+
+UPDATE users SET role = 'user' WHERE role NOT IN ('admin', 'user');
+CREATE TYPE user_role AS ENUM ('admin', 'user');
+ALTER TABLE users ALTER COLUMN role DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN role TYPE user_role USING role::user_role;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
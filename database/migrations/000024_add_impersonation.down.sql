@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS impersonation_requests;
+
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS impersonation_reason;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS impersonator_id;
@@ -0,0 +1,17 @@
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS impersonator_id UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS impersonation_reason TEXT NOT NULL DEFAULT '';
+
+-- kept when the users or the session are deleted, it is the audit trail of support staff
+CREATE TABLE IF NOT EXISTS impersonation_requests (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+  session_id UUID REFERENCES user_sessions(id) ON DELETE SET NULL,
+  impersonator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  method TEXT NOT NULL,
+  path TEXT NOT NULL,
+  status INT NOT NULL,
+  ip_address TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS impersonation_requests_session_idx ON impersonation_requests (session_id);
+CREATE INDEX IF NOT EXISTS impersonation_requests_impersonator_idx ON impersonation_requests (impersonator_id, created_at);
@@ -0,0 +1,12 @@
+-- Rows in the trash would come back without the column, they are purged instead
+DELETE FROM courses WHERE deleted_at IS NOT NULL;
+DELETE FROM subjects WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS users_email_active_idx;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+DROP INDEX IF EXISTS courses_deleted_at_idx;
+DROP INDEX IF EXISTS subjects_deleted_at_idx;
+DROP INDEX IF EXISTS users_deleted_at_idx;
+ALTER TABLE courses DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE subjects DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
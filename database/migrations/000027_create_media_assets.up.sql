@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS media_assets (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+  hash TEXT NOT NULL UNIQUE,
+  owner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  category TEXT NOT NULL,
+  file_name TEXT NOT NULL,
+  url TEXT NOT NULL,
+  content_type TEXT NOT NULL,
+  size BIGINT NOT NULL,
+  width INT NOT NULL DEFAULT 0,
+  height INT NOT NULL DEFAULT 0,
+  reference_count INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS media_assets_url_idx ON media_assets (url);
+CREATE INDEX IF NOT EXISTS media_assets_created_idx ON media_assets (created_at);
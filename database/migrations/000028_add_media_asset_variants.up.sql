@@ -0,0 +1 @@
+ALTER TABLE media_assets ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,178 @@
+// Package analytics computes the item analysis of a quiz from its graded attempts.
+package analytics
+
+import (
+	"fp-designpattern/internal/scoring"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Question describes a question that may appear in attempts, Options lists the choices in display order.
+type Question struct {
+	ID      uuid.UUID
+	Type    string
+	Options []Option
+}
+
+type Option struct {
+	ID   uuid.UUID
+	Text string
+}
+
+// Attempt is a submitted session with its score (0-100) and the graded questions it was given.
+type Attempt struct {
+	Score     int
+	Duration  time.Duration
+	Questions []scoring.QuestionResult
+}
+
+type Report struct {
+	Attempts          int
+	AverageScore      float64
+	AverageDuration   time.Duration
+	ScoreDistribution []Bucket
+	Questions         []QuestionStats
+}
+
+// Bucket counts the attempts scoring From to To, both inclusive.
+type Bucket struct {
+	From  int
+	To    int
+	Count int
+}
+
+type QuestionStats struct {
+	QuestionID uuid.UUID
+	Type       string
+	// Presented is how many attempts were given the question, Answered how many of them answered it.
+	Presented int
+	Answered  int
+	// PValue is the share of attempts that answered the question fully correct.
+	PValue float64
+	// Discrimination is the point-biserial correlation between answering correctly and the attempt score,
+	// nil when it is undefined because everyone or no one answered correctly or all scores are equal.
+	Discrimination *float64
+	Options        []OptionStats
+}
+
+type OptionStats struct {
+	OptionID uuid.UUID
+	Text     string
+	Picked   int
+	// Share is Picked divided by the attempts the question was presented in.
+	Share float64
+}
+
+// Analyze builds the report, questions appear in the order given and only count the attempts they were part of.
+func Analyze(questions []Question, attempts []Attempt) *Report {
+	report := &Report{
+		Attempts:          len(attempts),
+		ScoreDistribution: make([]Bucket, 10),
+		Questions:         make([]QuestionStats, len(questions)),
+	}
+	for i := range report.ScoreDistribution {
+		report.ScoreDistribution[i] = Bucket{From: i * 10, To: i*10 + 9}
+	}
+	report.ScoreDistribution[9].To = 100
+
+	index := make(map[uuid.UUID]int, len(questions))
+	picks := make([]map[uuid.UUID]int, len(questions))
+	// correct and scores hold per question whether each attempt got it right and the attempt score
+	correct := make([][]bool, len(questions))
+	scores := make([][]float64, len(questions))
+	for i, question := range questions {
+		index[question.ID] = i
+		picks[i] = make(map[uuid.UUID]int)
+		report.Questions[i] = QuestionStats{QuestionID: question.ID, Type: question.Type}
+	}
+
+	var totalScore float64
+	var totalDuration time.Duration
+	for _, attempt := range attempts {
+		totalScore += float64(attempt.Score)
+		totalDuration += attempt.Duration
+		bucket := attempt.Score / 10
+		bucket = max(0, min(bucket, 9))
+		report.ScoreDistribution[bucket].Count++
+
+		for _, result := range attempt.Questions {
+			i, ok := index[result.QuestionID]
+			if !ok {
+				continue
+			}
+			stats := &report.Questions[i]
+			stats.Presented++
+			if result.Status != scoring.StatusUnanswered {
+				stats.Answered++
+			}
+			for _, optionID := range result.Selected {
+				picks[i][optionID]++
+			}
+			correct[i] = append(correct[i], result.Status == scoring.StatusCorrect)
+			scores[i] = append(scores[i], float64(attempt.Score))
+		}
+	}
+	if len(attempts) > 0 {
+		report.AverageScore = totalScore / float64(len(attempts))
+		report.AverageDuration = totalDuration / time.Duration(len(attempts))
+	}
+
+	for i, question := range questions {
+		stats := &report.Questions[i]
+		if stats.Presented > 0 {
+			right := 0
+			for _, isCorrect := range correct[i] {
+				if isCorrect {
+					right++
+				}
+			}
+			stats.PValue = float64(right) / float64(stats.Presented)
+		}
+		stats.Discrimination = pointBiserial(correct[i], scores[i])
+		for _, option := range question.Options {
+			optionStats := OptionStats{OptionID: option.ID, Text: option.Text, Picked: picks[i][option.ID]}
+			if stats.Presented > 0 {
+				optionStats.Share = float64(optionStats.Picked) / float64(stats.Presented)
+			}
+			stats.Options = append(stats.Options, optionStats)
+		}
+	}
+	return report
+}
+
+// pointBiserial is (M1 - M0) / s * sqrt(p * q) with s the population standard deviation of the scores.
+func pointBiserial(correct []bool, scores []float64) *float64 {
+	n := float64(len(scores))
+	if n < 2 {
+		return nil
+	}
+	var sum, sumRight float64
+	right := 0
+	for i, score := range scores {
+		sum += score
+		if correct[i] {
+			sumRight += score
+			right++
+		}
+	}
+	if right == 0 || right == len(scores) {
+		return nil
+	}
+	mean := sum / n
+	var variance float64
+	for _, score := range scores {
+		variance += (score - mean) * (score - mean)
+	}
+	deviation := math.Sqrt(variance / n)
+	if deviation == 0 {
+		return nil
+	}
+
+	p := float64(right) / n
+	meanRight := sumRight / float64(right)
+	meanWrong := (sum - sumRight) / float64(len(scores)-right)
+	r := (meanRight - meanWrong) / deviation * math.Sqrt(p*(1-p))
+	return &r
+}
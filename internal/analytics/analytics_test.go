@@ -0,0 +1,158 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/scoring"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPointBiserial(t *testing.T) {
+	tests := []struct {
+		name    string
+		correct []bool
+		scores  []float64
+		// want is nil for an undefined correlation
+		want *float64
+	}{
+		// Mean 60, deviation sqrt(1000), M1 90, M0 30 and p 0.5
+		{"strong", []bool{true, true, false, false}, []float64{100, 80, 40, 20}, ptr(60 / math.Sqrt(1000) * 0.5)},
+		{"inverted", []bool{false, false, true, true}, []float64{100, 80, 40, 20}, ptr(-60 / math.Sqrt(1000) * 0.5)},
+		// Mean 50, deviation sqrt(500), M1 30, M0 70 and p 0.5
+		{"weak negative", []bool{true, false, true, false}, []float64{20, 80, 40, 60}, ptr(-40 / math.Sqrt(500) * 0.5)},
+		// Mean 55, M1 70, M0 40, deviation 15 and p 0.5
+		{"one of each", []bool{true, false}, []float64{70, 40}, ptr(1.0)},
+		{"everyone correct", []bool{true, true, true}, []float64{10, 50, 90}, nil},
+		{"no one correct", []bool{false, false}, []float64{10, 90}, nil},
+		{"equal scores", []bool{true, false, true}, []float64{50, 50, 50}, nil},
+		{"single attempt", []bool{true}, []float64{100}, nil},
+		{"no attempts", nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		got := pointBiserial(tt.correct, tt.scores)
+		switch {
+		case tt.want == nil && got != nil:
+			t.Errorf("%s: got %v, want undefined", tt.name, *got)
+		case tt.want != nil && got == nil:
+			t.Errorf("%s: got undefined, want %v", tt.name, *tt.want)
+		case tt.want != nil && math.Abs(*got-*tt.want) > 1e-9:
+			t.Errorf("%s: got %v, want %v", tt.name, *got, *tt.want)
+		}
+	}
+}
+
+func ptr(value float64) *float64 {
+	return &value
+}
+
+func TestAnalyze(t *testing.T) {
+	q1, q2, unknown := uuid.New(), uuid.New(), uuid.New()
+	a, b := uuid.New(), uuid.New()
+	questions := []Question{
+		{ID: q1, Type: entity.QuestionTypeSingleChoice, Options: []Option{{ID: a, Text: "A"}, {ID: b, Text: "B"}}},
+		{ID: q2, Type: entity.QuestionTypeNumeric},
+	}
+	result := func(questionID uuid.UUID, status string, selected ...uuid.UUID) scoring.QuestionResult {
+		return scoring.QuestionResult{QuestionID: questionID, Status: status, Selected: selected}
+	}
+	attempts := []Attempt{
+		{Score: 100, Duration: 4 * time.Minute, Questions: []scoring.QuestionResult{
+			result(q1, scoring.StatusCorrect, a), result(q2, scoring.StatusCorrect), result(unknown, scoring.StatusCorrect),
+		}},
+		{Score: 80, Duration: 2 * time.Minute, Questions: []scoring.QuestionResult{
+			result(q1, scoring.StatusCorrect, a), result(q2, scoring.StatusUnanswered),
+		}},
+		{Score: 40, Duration: 6 * time.Minute, Questions: []scoring.QuestionResult{
+			result(q1, scoring.StatusIncorrect, b),
+		}},
+		{Score: 20, Duration: 0, Questions: []scoring.QuestionResult{
+			result(q1, scoring.StatusUnanswered), result(q2, scoring.StatusIncorrect),
+		}},
+	}
+
+	report := Analyze(questions, attempts)
+	if report.Attempts != 4 || report.AverageScore != 60 || report.AverageDuration != 3*time.Minute {
+		t.Fatalf("summary = %d attempts, %v average, %v duration, want 4, 60, 3m", report.Attempts, report.AverageScore, report.AverageDuration)
+	}
+	wantBuckets := map[int]int{2: 1, 4: 1, 8: 1, 9: 1}
+	for i, bucket := range report.ScoreDistribution {
+		if bucket.Count != wantBuckets[i] {
+			t.Errorf("bucket %d-%d = %d, want %d", bucket.From, bucket.To, bucket.Count, wantBuckets[i])
+		}
+	}
+	if last := report.ScoreDistribution[9]; last.From != 90 || last.To != 100 {
+		t.Errorf("last bucket = %d-%d, want 90-100", last.From, last.To)
+	}
+
+	first := report.Questions[0]
+	if first.Presented != 4 || first.Answered != 3 || first.PValue != 0.5 {
+		t.Errorf("q1 = presented %d, answered %d, p %v, want 4, 3, 0.5", first.Presented, first.Answered, first.PValue)
+	}
+	if want := 60 / math.Sqrt(1000) * 0.5; first.Discrimination == nil || math.Abs(*first.Discrimination-want) > 1e-9 {
+		t.Errorf("q1 discrimination = %v, want %v", first.Discrimination, want)
+	}
+	if len(first.Options) != 2 || first.Options[0].Picked != 2 || first.Options[0].Share != 0.5 || first.Options[1].Picked != 1 || first.Options[1].Share != 0.25 {
+		t.Errorf("q1 options = %+v, want A picked 2 (0.5) and B 1 (0.25)", first.Options)
+	}
+
+	// Only the three attempts given the question count: scores 100, 80 and 20 with the first right
+	second := report.Questions[1]
+	if second.Presented != 3 || second.Answered != 2 || math.Abs(second.PValue-1.0/3) > 1e-9 {
+		t.Errorf("q2 = presented %d, answered %d, p %v, want 3, 2, 1/3", second.Presented, second.Answered, second.PValue)
+	}
+	mean := (100.0 + 80 + 20) / 3
+	deviation := math.Sqrt(((100-mean)*(100-mean) + (80-mean)*(80-mean) + (20-mean)*(20-mean)) / 3)
+	if want := (100 - 50) / deviation * math.Sqrt(2.0/9); second.Discrimination == nil || math.Abs(*second.Discrimination-want) > 1e-9 {
+		t.Errorf("q2 discrimination = %v, want %v", second.Discrimination, want)
+	}
+}
+
+func TestAnalyzeWithoutAttempts(t *testing.T) {
+	report := Analyze([]Question{{ID: uuid.New(), Type: entity.QuestionTypeShortAnswer}}, nil)
+	if report.AverageScore != 0 || report.Questions[0].PValue != 0 || report.Questions[0].Discrimination != nil {
+		t.Fatalf("report = %+v, want zeros and no discrimination", report)
+	}
+}
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	questionID := uuid.New()
+	report := Analyze([]Question{{ID: questionID, Type: entity.QuestionTypeSingleChoice, Options: []Option{
+		{ID: uuid.New(), Text: "=HYPERLINK(\"http://evil.example\")"},
+		{ID: uuid.New(), Text: "+1"},
+		{ID: uuid.New(), Text: "-1"},
+		{ID: uuid.New(), Text: "@SUM(A1)"},
+		{ID: uuid.New(), Text: "plain = text"},
+	}}}, nil)
+	buf := new(bytes.Buffer)
+	if err := WriteCSV(buf, report); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := csv.NewReader(buf)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var options []string
+	for _, record := range records {
+		if len(record) == 11 && record[1] == questionID.String() {
+			options = append(options, record[8])
+		}
+	}
+	want := []string{"'=HYPERLINK(\"http://evil.example\")", "'+1", "'-1", "'@SUM(A1)", "plain = text"}
+	if len(options) != len(want) {
+		t.Fatalf("options = %q, want %q", options, want)
+	}
+	for i := range want {
+		if options[i] != want[i] {
+			t.Errorf("option %d = %q, want %q", i, options[i], want[i])
+		}
+	}
+}
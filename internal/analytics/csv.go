@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteCSV writes the report as three blank line separated tables: the summary, one row per question
+// option (non-choice questions get a single row without option columns) and the score distribution.
+func WriteCSV(w io.Writer, report *Report) error {
+	writer := csv.NewWriter(w)
+	records := [][]string{
+		{"metric", "value"},
+		{"attempts", strconv.Itoa(report.Attempts)},
+		{"average_score", formatFloat(report.AverageScore)},
+		{"average_duration_seconds", formatFloat(report.AverageDuration.Seconds())},
+		{},
+		{"question_number", "question_id", "question_type", "presented", "answered", "p_value", "discrimination", "option_id", "option", "picked", "picked_share"},
+	}
+	for i, question := range report.Questions {
+		discrimination := ""
+		if question.Discrimination != nil {
+			discrimination = formatFloat(*question.Discrimination)
+		}
+		row := []string{
+			strconv.Itoa(i + 1),
+			question.QuestionID.String(),
+			question.Type,
+			strconv.Itoa(question.Presented),
+			strconv.Itoa(question.Answered),
+			formatFloat(question.PValue),
+			discrimination,
+		}
+		if len(question.Options) == 0 {
+			records = append(records, append(row, "", "", "", ""))
+			continue
+		}
+		for _, option := range question.Options {
+			records = append(records, append(append([]string{}, row...),
+				option.OptionID.String(),
+				cell(option.Text),
+				strconv.Itoa(option.Picked),
+				formatFloat(option.Share),
+			))
+		}
+	}
+	records = append(records, []string{}, []string{"score_from", "score_to", "attempts"})
+	for _, bucket := range report.ScoreDistribution {
+		records = append(records, []string{strconv.Itoa(bucket.From), strconv.Itoa(bucket.To), strconv.Itoa(bucket.Count)})
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// cell keeps spreadsheets from running text that starts like a formula.
+func cell(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 4, 64)
+}
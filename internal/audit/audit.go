@@ -0,0 +1,108 @@
+// Package audit names the administrative changes kept in the audit trail and works out what a
+// change did to the resource.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const (
+	ActionCreate         = "create"
+	ActionUpdate         = "update"
+	ActionDelete         = "delete"
+	ActionRestore        = "restore"
+	ActionImport         = "import"
+	ActionSetRole        = "set_role"
+	ActionImpersonate    = "impersonate"
+	ActionRevokeSessions = "revoke_sessions"
+	ActionRevokeAPIKeys  = "revoke_api_keys"
+	ActionResetTwoFactor = "reset_two_factor"
+	ActionUnlock         = "unlock"
+)
+
+const (
+	ResourceUser           = "user"
+	ResourceSubject        = "subject"
+	ResourceTeacherSubject = "teacher_subject"
+	ResourceCourse         = "course"
+	ResourceUserCourse     = "user_course"
+	ResourceQuiz           = "quiz"
+	ResourceQuestion       = "question"
+	ResourceOption         = "option"
+)
+
+// Snapshot is the JSON of a resource as it is now, taken before a change is made to it.
+func Snapshot(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Redacted is the value recorded for a secret field that changed, such as a password.
+const Redacted = "changed"
+
+// Redact adds the secret fields to the JSON object of a snapshot with Redacted as their value, so
+// the trail shows they changed without what they changed to. Anything but an object is returned as it is.
+func Redact(snapshot json.RawMessage, fields ...string) json.RawMessage {
+	var object map[string]json.RawMessage
+	if json.Unmarshal(snapshot, &object) != nil || object == nil {
+		return snapshot
+	}
+	for _, field := range fields {
+		object[field] = json.RawMessage(`"` + Redacted + `"`)
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return snapshot
+	}
+	return data
+}
+
+// Diff keeps the fields of two JSON objects that differ. A nil side, a resource that was created or
+// deleted, is kept whole against the other. Values that are not objects are compared as a whole.
+func Diff(before json.RawMessage, after json.RawMessage) (json.RawMessage, json.RawMessage, error) {
+	if len(before) == 0 || len(after) == 0 {
+		return before, after, nil
+	}
+	var beforeFields, afterFields map[string]json.RawMessage
+	if json.Unmarshal(before, &beforeFields) != nil || json.Unmarshal(after, &afterFields) != nil {
+		if bytes.Equal(before, after) {
+			return nil, nil, nil
+		}
+		return before, after, nil
+	}
+
+	changedBefore := make(map[string]json.RawMessage)
+	changedAfter := make(map[string]json.RawMessage)
+	for field, value := range beforeFields {
+		if other, ok := afterFields[field]; !ok || !equal(value, other) {
+			changedBefore[field] = value
+		}
+	}
+	for field, value := range afterFields {
+		if other, ok := beforeFields[field]; !ok || !equal(value, other) {
+			changedAfter[field] = value
+		}
+	}
+	beforeDiff, err := json.Marshal(changedBefore)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterDiff, err := json.Marshal(changedAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return beforeDiff, afterDiff, nil
+}
+
+// equal compares JSON values by their compacted form, so formatting does not count as a change.
+func equal(a json.RawMessage, b json.RawMessage) bool {
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
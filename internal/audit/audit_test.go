@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name       string
+		before     string
+		after      string
+		wantBefore string
+		wantAfter  string
+	}{
+		{"changed field", `{"name":"a","grade":1}`, `{"name":"b","grade":1}`, `{"name":"a"}`, `{"name":"b"}`},
+		{"nothing changed", `{"name":"a","grade":1}`, `{"grade":1,"name":"a"}`, `{}`, `{}`},
+		{"formatting only", `{"tags": [1, 2], "meta": {"a": 1}}`, `{"tags":[1,2],"meta":{"a":1}}`, `{}`, `{}`},
+		{"nested change", `{"meta":{"a":1,"b":2}}`, `{"meta":{"a":1,"b":3}}`, `{"meta":{"a":1,"b":2}}`, `{"meta":{"a":1,"b":3}}`},
+		{"field added", `{"name":"a"}`, `{"name":"a","email":"a@example.com"}`, `{}`, `{"email":"a@example.com"}`},
+		{"field removed", `{"name":"a","email":"a@example.com"}`, `{"name":"a"}`, `{"email":"a@example.com"}`, `{}`},
+		{"null and missing differ", `{"name":null}`, `{}`, `{"name":null}`, `{}`},
+		{"number and string differ", `{"grade":1}`, `{"grade":"1"}`, `{"grade":1}`, `{"grade":"1"}`},
+		{"created", ``, `{"name":"a"}`, ``, `{"name":"a"}`},
+		{"deleted", `{"name":"a"}`, ``, `{"name":"a"}`, ``},
+		{"arrays compared whole", `[1,2]`, `[1,3]`, `[1,2]`, `[1,3]`},
+		{"equal arrays", `[1,2]`, `[1,2]`, ``, ``},
+		{"scalars", `1`, `2`, `1`, `2`},
+	}
+	for _, tt := range tests {
+		before, after, err := Diff(raw(tt.before), raw(tt.after))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !sameJSON(before, tt.wantBefore) || !sameJSON(after, tt.wantAfter) {
+			t.Errorf("%s: Diff = %s, %s, want %s, %s", tt.name, before, after, tt.wantBefore, tt.wantAfter)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{`"a"`, `"a"`, true},
+		{`{"a": 1}`, `{"a":1}`, true},
+		{"[1,\n 2]", `[1,2]`, true},
+		{`1`, `1.0`, false},
+		{`{"a":1,"b":2}`, `{"b":2,"a":1}`, false},
+		{`"a"`, `"b"`, false},
+		// Invalid JSON is compared byte for byte
+		{`{a}`, `{a}`, true},
+		{`{a}`, `{ a}`, false},
+	}
+	for _, tt := range tests {
+		if got := equal(json.RawMessage(tt.a), json.RawMessage(tt.b)); got != tt.want {
+			t.Errorf("equal(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRedactedPasswordChange(t *testing.T) {
+	before := Snapshot(map[string]any{"name": "a"})
+	after := Redact(Snapshot(map[string]any{"name": "a"}), "password")
+	changedBefore, changedAfter, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameJSON(changedBefore, `{}`) || !sameJSON(changedAfter, `{"password":"changed"}`) {
+		t.Fatalf("Diff = %s, %s, want only the redacted password", changedBefore, changedAfter)
+	}
+
+	if got := Redact(raw(`[1]`), "password"); string(got) != `[1]` {
+		t.Errorf("Redact of an array = %s", got)
+	}
+	if got := Redact(nil, "password"); got != nil {
+		t.Errorf("Redact of nothing = %s", got)
+	}
+}
+
+func raw(value string) json.RawMessage {
+	if value == "" {
+		return nil
+	}
+	return json.RawMessage(value)
+}
+
+// sameJSON compares JSON ignoring formatting and key order, the empty string standing for nothing.
+func sameJSON(got json.RawMessage, want string) bool {
+	if want == "" {
+		return len(got) == 0
+	}
+	var a, b any
+	if json.Unmarshal(got, &a) != nil || json.Unmarshal([]byte(want), &b) != nil {
+		return false
+	}
+	return Snapshot(a) != nil && string(Snapshot(a)) == string(Snapshot(b))
+}
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"context"
 	"fp-designpattern/internal/delivery/http"
 	"fp-designpattern/internal/delivery/http/middleware"
 	"fp-designpattern/internal/delivery/http/route"
+	"fp-designpattern/internal/mailer"
+	"fp-designpattern/internal/rbac"
 	"fp-designpattern/internal/repository"
+	"fp-designpattern/internal/scoring"
 	"fp-designpattern/internal/usecase"
+	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/gofiber/fiber/v2"
@@ -26,32 +31,119 @@ func Bootstrap(config *BootstrapConfig) {
 	//setup repositories
 	userRepository := repository.NewUserRepository(config.Log)
 	subjectRepository := repository.NewSubjectRepository(config.Log)
-	fileRepository := repository.NewLocalFileRepository(
-		"./public/images",
-		"/images",
-	)
 	courseRepository := repository.NewCourseRepository(config.Log)
 	userCourseRepository := repository.NewUserCourseRepository(config.Log)
+	quizRepository := repository.NewQuizRepository(config.Log)
+	questionRepository := repository.NewQuestionRepository(config.Log)
+	questionOptionRepository := repository.NewQuestionOptionRepository(config.Log)
+	userQuizSessionRepository := repository.NewUserQuizSessionRepository(config.Log)
+	userAnswerRepository := repository.NewUserAnswerRepository(config.Log)
+	quizAnswerRepository := repository.NewQuizAnswerRepository(config.Log)
+	userQuizSessionQuestionRepository := repository.NewUserQuizSessionQuestionRepository(config.Log)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(config.Log)
+	userSessionRepository := repository.NewUserSessionRepository(config.Log)
+	passwordResetTokenRepository := repository.NewPasswordResetTokenRepository(config.Log)
+	userRecoveryCodeRepository := repository.NewUserRecoveryCodeRepository(config.Log)
+	userIdentityRepository := repository.NewUserIdentityRepository(config.Log)
+	loginThrottleRepository := repository.NewLoginThrottleRepository(config.Log)
+	loginLockoutEventRepository := repository.NewLoginLockoutEventRepository(config.Log)
+	teacherSubjectRepository := repository.NewTeacherSubjectRepository(config.Log)
+	apiKeyRepository := repository.NewAPIKeyRepository(config.Log)
+	impersonationRequestRepository := repository.NewImpersonationRequestRepository(config.Log)
+	auditEventRepository := repository.NewAuditEventRepository(config.Log)
+	mediaAssetRepository := repository.NewMediaAssetRepository(config.Log)
+	//setup file storage
+	fileStorage := NewFileStorage(config.Config, config.Log)
+	uploadPolicies := NewUploadPolicies(config.Config)
+	//setup tokens
+	tokenManager := NewTokenManager(config.Config, config.Log)
+	//setup mailer
+	config.Config.SetDefault("mail.queue_size", 100)
+	mailQueue := mailer.NewQueue(NewMailer(config.Config, config.Log), config.Log, config.Config.GetInt("mail.queue_size"))
+	//setup permissions
+	policy := NewPolicy(config.Config, config.Log)
+	//setup identity providers
+	identityProviders := NewIdentityRegistry(config.Config, config.Log, policy)
+	//setup login lockout
+	loginLimiter := NewLoginLimiter(config.Config)
+	subjectAccess := usecase.NewSubjectAccess(config.Log, policy, teacherSubjectRepository, courseRepository)
+	//setup audit trail
+	auditor := usecase.NewAuditor(config.Log, auditEventRepository)
+	config.Config.SetDefault("auth.password_reset_ttl", time.Hour)
+	//setup scoring
+	config.Config.SetDefault("quiz.partial_credit", true)
+	scorer := scoring.NewScorer(config.Config.GetBool("quiz.partial_credit"))
 	//setup use cases
-	userUseCase := usecase.NewUserUseCase(config.DB, config.Log, config.Validate, userRepository)
-	subjectUseCase := usecase.NewSubjectUsecase(config.DB, config.Log, config.Validate, subjectRepository)
-	courseUseCase := usecase.NewCourseUsecase(config.DB, config.Log, config.Validate, courseRepository, subjectRepository, fileRepository)
-	userCourseUseCase := usecase.NewUserCourseUsecase(config.DB, config.Log, config.Validate, courseRepository, userRepository, userCourseRepository)
+	userUseCase := usecase.NewUserUseCase(config.DB, config.Log, config.Validate, userRepository, refreshTokenRepository, userSessionRepository, userRecoveryCodeRepository, userIdentityRepository, apiKeyRepository, passwordResetTokenRepository, impersonationRequestRepository, mediaAssetRepository, fileStorage,
+		loginThrottleRepository, loginLockoutEventRepository, loginLimiter, tokenManager, identityProviders, policy, auditor, mailQueue, config.Config.GetString("auth.verify_email_url"))
+	passwordResetUseCase := usecase.NewPasswordResetUsecase(config.DB, config.Log, config.Validate, userRepository, passwordResetTokenRepository, userSessionRepository,
+		apiKeyRepository, mailQueue, config.Config.GetDuration("auth.password_reset_ttl"), config.Config.GetString("auth.password_reset_url"))
+	subjectUseCase := usecase.NewSubjectUsecase(config.DB, config.Log, config.Validate, subjectRepository, courseRepository, auditor)
+	courseUseCase := usecase.NewCourseUsecase(config.DB, config.Log, config.Validate, courseRepository, subjectRepository, fileStorage, mediaAssetRepository, subjectAccess, auditor)
+	userCourseUseCase := usecase.NewUserCourseUsecase(config.DB, config.Log, config.Validate, courseRepository, userRepository, userCourseRepository, fileStorage, mediaAssetRepository, subjectAccess, auditor)
+	quizUseCase := usecase.NewQuizUsecase(config.DB, config.Log, config.Validate, quizRepository, questionRepository, questionOptionRepository, quizAnswerRepository, courseRepository, subjectRepository, subjectAccess, auditor)
+	teacherSubjectUseCase := usecase.NewTeacherSubjectUsecase(config.DB, config.Log, config.Validate, teacherSubjectRepository, subjectRepository, userRepository, policy, auditor)
+	auditUseCase := usecase.NewAuditUsecase(config.DB, config.Log, config.Validate, auditEventRepository)
+	config.Config.SetDefault("trash.retention", 30*24*time.Hour)
+	// A retention of zero or less would purge whatever is put in the trash at the next tick
+	if retention := config.Config.GetDuration("trash.retention"); retention <= 0 {
+		config.Log.Fatalf("Invalid trash.retention %s, it has to be positive", retention)
+	}
+	trashUseCase := usecase.NewTrashUsecase(config.DB, config.Log, userRepository, subjectRepository, courseRepository, mediaAssetRepository, config.Config.GetDuration("trash.retention"))
+	uploadUseCase := usecase.NewUploadUsecase(config.DB, config.Log, config.Validate, fileStorage, mediaAssetRepository, uploadPolicies)
+	quizSessionUseCase := usecase.NewQuizSessionUsecase(config.DB, config.Log, config.Validate, quizRepository, questionRepository, questionOptionRepository, userCourseRepository, userQuizSessionRepository, userAnswerRepository, quizAnswerRepository, userQuizSessionQuestionRepository, scorer, subjectAccess)
 	//setup controllers
-	userController := http.NewUserController(userUseCase, courseUseCase, config.Log)
+	userController := http.NewUserController(userUseCase, courseUseCase, passwordResetUseCase, uploadUseCase, config.Log)
 	subjectController := http.NewSubjectController(subjectUseCase, config.Log)
-	courseController := http.NewCourseController(courseUseCase, config.Log)
+	courseController := http.NewCourseController(courseUseCase, uploadUseCase, config.Log)
 	userCourseController := http.NewUserCourseController(userCourseUseCase, config.Log)
+	quizController := http.NewQuizController(quizUseCase, config.Log)
+	quizSessionController := http.NewQuizSessionController(quizSessionUseCase, config.Log)
+	teacherSubjectController := http.NewTeacherSubjectController(teacherSubjectUseCase, config.Log)
+	auditController := http.NewAuditController(auditUseCase, config.Log)
+	mediaController := http.NewMediaController(uploadUseCase, config.Log)
 	//setup middleware
 	authMiddleware := middleware.NewAuth(userUseCase)
+	impersonationAuditMiddleware := middleware.NewImpersonationAudit(userUseCase)
+	verifiedEmailMiddleware := middleware.NewPassThrough()
+	if config.Config.GetBool("auth.require_verified_email") {
+		verifiedEmailMiddleware = middleware.NewRequireVerifiedEmail(userUseCase)
+	}
+	// Admins need a second factor on the admin routes, teachers reach some of them without one
+	adminMFAMiddleware := middleware.NewPassThrough()
+	if config.Config.GetBool("auth.require_admin_2fa") {
+		adminMFAMiddleware = middleware.RequireMFA(rbac.RoleAdmin)
+	}
 	routeConfig := route.RouteConfig{
-		App:                  config.App,
-		UserController:       userController,
-		SubjectController:    subjectController,
-		CourseController:     courseController,
-		UserCourseController: userCourseController,
-		AuthMiddleware:       authMiddleware,
+		App:                          config.App,
+		UserController:               userController,
+		SubjectController:            subjectController,
+		CourseController:             courseController,
+		UserCourseController:         userCourseController,
+		QuizController:               quizController,
+		QuizSessionController:        quizSessionController,
+		TeacherSubjectController:     teacherSubjectController,
+		AuditController:              auditController,
+		MediaController:              mediaController,
+		AuthMiddleware:               authMiddleware,
+		ImpersonationAuditMiddleware: impersonationAuditMiddleware,
+		Policy:                       policy,
+		VerifiedEmailMiddleware:      verifiedEmailMiddleware,
+		AdminMFAMiddleware:           adminMFAMiddleware,
 	}
 
 	routeConfig.Setup()
+
+	//setup background workers
+	autoSubmitInterval := config.Config.GetDuration("quiz.auto_submit_interval")
+	if autoSubmitInterval <= 0 {
+		autoSubmitInterval = 30 * time.Second
+	}
+	go quizSessionUseCase.RunAutoSubmit(context.Background(), autoSubmitInterval)
+	purgeInterval := config.Config.GetDuration("trash.purge_interval")
+	if purgeInterval <= 0 {
+		purgeInterval = time.Hour
+	}
+	go trashUseCase.RunPurge(context.Background(), purgeInterval)
+	go mailQueue.Run(context.Background())
 }
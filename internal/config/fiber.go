@@ -5,16 +5,30 @@ import (
 	"github.com/spf13/viper"
 )
 
+// multipartOverhead is room in a request body for the multipart headers and fields that come with
+// an uploaded file.
+const multipartOverhead = 64 << 10
+
 func NewFiber(config *viper.Viper) *fiber.App {
 	var app = fiber.New(fiber.Config{
 		AppName:      config.GetString("app.name"),
 		ErrorHandler: NewErrorHandler(),
 		Prefork:      config.GetBool("web.prefork"),
+		BodyLimit:    NewBodyLimit(config),
 	})
 
 	return app
 }
 
+// NewBodyLimit is the size of the largest request body, web.body_limit and 4 MiB by default. Fiber
+// answers a larger body with a bare 413 before any handler sees it, so the limit is raised to fit
+// the largest file of the upload.<category>.max_bytes settings: a file over the limit of its
+// category is rejected by the upload policy instead, which tells the client what it allows.
+func NewBodyLimit(config *viper.Viper) int {
+	config.SetDefault("web.body_limit", fiber.DefaultBodyLimit)
+	return max(config.GetInt("web.body_limit"), int(NewUploadPolicies(config).MaxBytes())+multipartOverhead)
+}
+
 func NewErrorHandler() fiber.ErrorHandler {
 	return func(ctx *fiber.Ctx, err error) error {
 		code := fiber.StatusInternalServerError
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestNewBodyLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   int
+	}{
+		// The attachments are the largest uploads by default
+		{"defaults", `{}`, 20<<20 + multipartOverhead},
+		{"larger body limit", `{"web": {"body_limit": 67108864}}`, 64 << 20},
+		{"smaller body limit", `{"web": {"body_limit": 1048576}}`, 20<<20 + multipartOverhead},
+		{"larger upload", `{"upload": {"attachment": {"max_bytes": 52428800}}}`, 50<<20 + multipartOverhead},
+		{"smaller uploads", `{"upload": {"attachment": {"max_bytes": 1048576}, "course_image": {"max_bytes": 1048576}}}`, fiber.DefaultBodyLimit},
+	}
+	for _, tt := range tests {
+		if got := NewBodyLimit(readConfig(t, tt.config)); got != tt.want {
+			t.Errorf("%s: body limit %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestBodyLimitLeavesUploadsToPolicies sends a file just over the limit of its category, which the
+// upload policy has to see to explain why it is rejected.
+func TestBodyLimitLeavesUploadsToPolicies(t *testing.T) {
+	app := NewFiber(readConfig(t, `{}`))
+	app.Post("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusOK)
+	})
+	response, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", bytes.NewReader(make([]byte, 20<<20+1))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != fiber.StatusOK {
+		t.Errorf("status %d, want the body to reach the handler", response.StatusCode)
+	}
+}
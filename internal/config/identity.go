@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fp-designpattern/internal/identity"
+	"fp-designpattern/internal/rbac"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// NewIdentityRegistry reads oidc.providers, a list of {"name", "issuer", "client_id", "client_secret",
+// "redirect_url", "scopes"}, and how accounts are provisioned on first sign in. The default role
+// has to be one the policy knows.
+func NewIdentityRegistry(viper *viper.Viper, log *logrus.Logger, policy *rbac.Policy) *identity.Registry {
+	var configs []identity.Config
+	if err := viper.UnmarshalKey("oidc.providers", &configs); err != nil {
+		log.Fatalf("Failed to read oidc providers: %v", err)
+	}
+	registry, err := identity.NewRegistry(configs)
+	if err != nil {
+		log.Fatalf("Invalid oidc config: %v", err)
+	}
+
+	viper.SetDefault("oidc.auto_provision", true)
+	viper.SetDefault("oidc.default_role", rbac.RoleUser)
+	viper.SetDefault("oidc.default_grade_level", 1)
+	registry.AutoProvision = viper.GetBool("oidc.auto_provision")
+	registry.DefaultRole = viper.GetString("oidc.default_role")
+	registry.DefaultGradeLevel = viper.GetInt("oidc.default_grade_level")
+	if !policy.HasRole(registry.DefaultRole) {
+		log.Fatalf("Unknown oidc.default_role %q, it has to be a role of rbac.roles", registry.DefaultRole)
+	}
+	return registry
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fp-designpattern/internal/lockout"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// NewLoginLimiter reads auth.lockout.account and auth.lockout.ip, each with threshold, base_delay,
+// max_delay and window. An IP is shared by everyone behind a school network, so it gets more room.
+func NewLoginLimiter(viper *viper.Viper) *lockout.Limiter {
+	viper.SetDefault("auth.lockout.account.threshold", 5)
+	viper.SetDefault("auth.lockout.account.base_delay", 30*time.Second)
+	viper.SetDefault("auth.lockout.account.max_delay", time.Hour)
+	viper.SetDefault("auth.lockout.account.window", 15*time.Minute)
+	viper.SetDefault("auth.lockout.ip.threshold", 50)
+	viper.SetDefault("auth.lockout.ip.base_delay", time.Minute)
+	viper.SetDefault("auth.lockout.ip.max_delay", time.Hour)
+	viper.SetDefault("auth.lockout.ip.window", 15*time.Minute)
+
+	return &lockout.Limiter{
+		Account: lockoutPolicy(viper, "auth.lockout.account"),
+		IP:      lockoutPolicy(viper, "auth.lockout.ip"),
+	}
+}
+
+func lockoutPolicy(viper *viper.Viper, key string) lockout.Policy {
+	return lockout.Policy{
+		Threshold: viper.GetInt(key + ".threshold"),
+		BaseDelay: viper.GetDuration(key + ".base_delay"),
+		MaxDelay:  viper.GetDuration(key + ".max_delay"),
+		Window:    viper.GetDuration(key + ".window"),
+	}
+}
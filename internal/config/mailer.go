@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fp-designpattern/internal/mailer"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// NewMailer picks the mailer from mail.driver, "smtp" sends for real and "log" logs the mails for
+// development. There is no default: the logged mails hold reset tokens, so logging them has to be
+// asked for.
+func NewMailer(viper *viper.Viper, log *logrus.Logger) mailer.Mailer {
+	from := viper.GetString("mail.from")
+	switch driver := viper.GetString("mail.driver"); driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(
+			viper.GetString("mail.smtp.host"),
+			viper.GetInt("mail.smtp.port"),
+			viper.GetString("mail.smtp.username"),
+			viper.GetString("mail.smtp.password"),
+			from,
+		)
+	case "log":
+		log.Warnf("Mails are written to the log, do not use the log mail driver in production")
+		return mailer.NewLogMailer(log, viper.GetString("mail.dir"), from)
+	default:
+		log.Fatalf("Unknown mail driver %q, expected smtp or log", driver)
+		return nil
+	}
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fp-designpattern/internal/rbac"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// NewPolicy reads rbac.roles, a map of role to the permissions it is granted, "*" granting all,
+// over the default roles, and rbac.unscoped_roles, the roles not limited to their assigned subjects.
+func NewPolicy(viper *viper.Viper, log *logrus.Logger) *rbac.Policy {
+	roles := rbac.DefaultRoles()
+	var configured map[string][]string
+	if err := viper.UnmarshalKey("rbac.roles", &configured); err != nil {
+		log.Fatalf("Failed to read rbac roles: %v", err)
+	}
+	for role, permissions := range configured {
+		roles[role] = permissions
+	}
+
+	viper.SetDefault("rbac.unscoped_roles", []string{rbac.RoleAdmin})
+	return rbac.NewPolicy(roles, viper.GetStringSlice("rbac.unscoped_roles"))
+}
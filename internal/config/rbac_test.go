@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fp-designpattern/internal/rbac"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func readConfig(t *testing.T, content string) *viper.Viper {
+	t.Helper()
+	config := viper.New()
+	config.SetConfigType("json")
+	if err := config.ReadConfig(strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestNewPolicy(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	tests := []struct {
+		name       string
+		config     string
+		role       string
+		permission string
+		can        bool
+		scoped     bool
+	}{
+		{"defaults without config", `{}`, rbac.RoleTeacher, rbac.PermissionCourseCreate, true, true},
+		{"default admin", `{}`, rbac.RoleAdmin, rbac.PermissionUserManage, true, false},
+		{"configured role replaces its default", `{"rbac": {"roles": {"teacher": ["course:read"]}}}`, rbac.RoleTeacher, rbac.PermissionCourseCreate, false, true},
+		{"other roles keep their default", `{"rbac": {"roles": {"teacher": ["course:read"]}}}`, rbac.RoleAdmin, rbac.PermissionUserManage, true, false},
+		{"new role", `{"rbac": {"roles": {"auditor": ["audit:read"]}}}`, "auditor", rbac.PermissionAuditRead, true, true},
+		{"wildcard", `{"rbac": {"roles": {"auditor": ["*"]}}}`, "auditor", rbac.PermissionQuizDelete, true, true},
+		{"unscoped role", `{"rbac": {"roles": {"auditor": ["audit:read"]}, "unscoped_roles": ["auditor"]}}`, "auditor", rbac.PermissionAuditRead, true, false},
+		{"unscoped roles replace the default", `{"rbac": {"unscoped_roles": ["teacher"]}}`, rbac.RoleAdmin, rbac.PermissionUserManage, true, true},
+		{"unknown role", `{"rbac": {"roles": {"auditor": ["audit:read"]}}}`, "intern", rbac.PermissionAuditRead, false, true},
+		{"role emptied", `{"rbac": {"roles": {"teacher": []}}}`, rbac.RoleTeacher, rbac.PermissionCourseRead, false, true},
+	}
+	for _, tt := range tests {
+		policy := NewPolicy(readConfig(t, tt.config), log)
+		if got := policy.Can(tt.role, tt.permission); got != tt.can {
+			t.Errorf("%s: Can(%q, %q) = %v, want %v", tt.name, tt.role, tt.permission, got, tt.can)
+		}
+		if got := policy.Scoped(tt.role); got != tt.scoped {
+			t.Errorf("%s: Scoped(%q) = %v, want %v", tt.name, tt.role, got, tt.scoped)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"context"
+	"fp-designpattern/internal/repository"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/api/option"
+)
+
+// NewFileStorage picks where uploaded files are kept from storage.driver, "gcs" keeps them in the
+// Google Cloud Storage bucket storage.gcs.bucket, "s3" in the bucket storage.s3.bucket of an
+// S3-compatible store such as MinIO and "local", the default, on the local disk.
+func NewFileStorage(viper *viper.Viper, log *logrus.Logger) repository.FileStorage {
+	viper.SetDefault("storage.driver", "local")
+	switch driver := viper.GetString("storage.driver"); driver {
+	case "gcs":
+		bucket := viper.GetString("storage.gcs.bucket")
+		if bucket == "" {
+			log.Fatalf("storage.gcs.bucket is required by the gcs storage driver")
+		}
+		var options []option.ClientOption
+		if endpoint := viper.GetString("storage.gcs.endpoint"); endpoint != "" {
+			// An emulator such as fake-gcs-server, it takes no credentials
+			options = append(options, option.WithEndpoint(endpoint), option.WithoutAuthentication())
+		} else if credentialsFile := viper.GetString("storage.gcs.credentials_file"); credentialsFile != "" {
+			options = append(options, option.WithCredentialsFile(credentialsFile))
+		}
+		client, err := storage.NewClient(context.Background(), options...)
+		if err != nil {
+			log.Fatalf("Failed to create storage client: %v", err)
+		}
+		return repository.NewGCSFileRepository(client, bucket, viper.GetString("storage.gcs.base_url"))
+	case "s3":
+		bucket := viper.GetString("storage.s3.bucket")
+		endpoint := viper.GetString("storage.s3.endpoint")
+		if bucket == "" || endpoint == "" {
+			log.Fatalf("storage.s3.bucket and storage.s3.endpoint are required by the s3 storage driver")
+		}
+		viper.SetDefault("storage.s3.part_size", 16<<20)
+		client, err := minio.New(endpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(viper.GetString("storage.s3.access_key"), viper.GetString("storage.s3.secret_key"), ""),
+			Secure: viper.GetBool("storage.s3.use_ssl"),
+			Region: viper.GetString("storage.s3.region"),
+			// MinIO serves buckets under the path of its endpoint rather than as subdomains
+			BucketLookup: minio.BucketLookupPath,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create s3 client: %v", err)
+		}
+		// A private bucket is only read through signed URLs valid for storage.s3.presign_ttl
+		var presignTTL time.Duration
+		if viper.GetBool("storage.s3.private") {
+			viper.SetDefault("storage.s3.presign_ttl", "15m")
+			presignTTL = viper.GetDuration("storage.s3.presign_ttl")
+		}
+		return repository.NewS3FileRepository(client, bucket, viper.GetString("storage.s3.base_url"), viper.GetUint64("storage.s3.part_size"), presignTTL)
+	case "local":
+		viper.SetDefault("storage.local.path", "./public/images")
+		viper.SetDefault("storage.local.url", "/images")
+		return repository.NewLocalFileRepository(viper.GetString("storage.local.path"), viper.GetString("storage.local.url"))
+	default:
+		// A typo must not quietly keep uploads on the disk of one server
+		log.Fatalf("Unknown storage driver %q, expected local, gcs or s3", driver)
+		return nil
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fp-designpattern/internal/token"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// NewTokenManager reads the jwt config, keys is a list of {"id", "secret"} and active_key the id
+// of the key new tokens are signed with.
+func NewTokenManager(viper *viper.Viper, log *logrus.Logger) *token.Manager {
+	var keys []token.Key
+	if err := viper.UnmarshalKey("jwt.keys", &keys); err != nil {
+		log.Fatalf("Failed to read jwt keys: %v", err)
+	}
+	issuer := viper.GetString("jwt.issuer")
+	if issuer == "" {
+		issuer = viper.GetString("app.name")
+	}
+	accessTTL := viper.GetDuration("jwt.access_ttl")
+	if accessTTL <= 0 {
+		accessTTL = 15 * time.Minute
+	}
+	refreshTTL := viper.GetDuration("jwt.refresh_ttl")
+	if refreshTTL <= 0 {
+		refreshTTL = 30 * 24 * time.Hour
+	}
+
+	manager, err := token.NewManager(issuer, keys, viper.GetString("jwt.active_key"), accessTTL, refreshTTL)
+	if err != nil {
+		log.Fatalf("Invalid jwt config: %v", err)
+	}
+	if verificationTTL := viper.GetDuration("jwt.verification_ttl"); verificationTTL > 0 {
+		manager.VerificationTTL = verificationTTL
+	}
+	if apiKeyTTL := viper.GetDuration("jwt.api_key_ttl"); apiKeyTTL > 0 {
+		manager.APIKeyTTL = apiKeyTTL
+	}
+	if apiKeyMaxTTL := viper.GetDuration("jwt.api_key_max_ttl"); apiKeyMaxTTL > 0 {
+		manager.APIKeyMaxTTL = apiKeyMaxTTL
+	}
+	if impersonationTTL := viper.GetDuration("jwt.impersonation_ttl"); impersonationTTL > 0 {
+		manager.ImpersonationTTL = impersonationTTL
+	}
+	return manager
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fp-designpattern/internal/upload"
+
+	"github.com/spf13/viper"
+)
+
+// NewUploadPolicies starts from the built-in upload policies and reads the size limit of each
+// category from upload.<category>.max_bytes, e.g. upload.attachment.max_bytes, the pixel limit of
+// its images from upload.<category>.max_pixels and the widths of its image variants from
+// upload.<category>.variants, e.g. upload.course_image.variants.medium. The body limit of the app
+// grows with the largest max_bytes, see NewBodyLimit.
+func NewUploadPolicies(viper *viper.Viper) upload.Policies {
+	policies := upload.DefaultPolicies()
+	for category, policy := range policies {
+		key := "upload." + category
+		viper.SetDefault(key+".max_bytes", policy.MaxBytes)
+		policy.MaxBytes = viper.GetInt64(key + ".max_bytes")
+		viper.SetDefault(key+".max_pixels", policy.MaxPixels)
+		policy.MaxPixels = viper.GetInt64(key + ".max_pixels")
+		// A configured variant hides the built-in ones from GetStringMap, so both are looked at,
+		// a width of 0 turns a built-in variant off
+		names := make(map[string]bool)
+		for name, width := range policy.Variants {
+			viper.SetDefault(key+".variants."+name, width)
+			names[name] = true
+		}
+		for name := range viper.GetStringMap(key + ".variants") {
+			names[name] = true
+		}
+		policy.Variants = make(map[string]int)
+		for name := range names {
+			if width := viper.GetInt(key + ".variants." + name); width > 0 {
+				policy.Variants[name] = width
+			}
+		}
+		policies[category] = policy
+	}
+	return policies
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/usecase"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditController struct {
+	Log     *logrus.Logger
+	Usecase *usecase.AuditUsecase
+}
+
+func NewAuditController(usecase *usecase.AuditUsecase, logger *logrus.Logger) *AuditController {
+	return &AuditController{
+		Log:     logger,
+		Usecase: usecase,
+	}
+}
+
+func (c *AuditController) List(ctx *fiber.Ctx) error {
+	request := &model.SearchAuditEventRequest{
+		ActorID:      ctx.Query("actor_id"),
+		Action:       ctx.Query("action"),
+		ResourceType: ctx.Query("resource_type"),
+		ResourceID:   ctx.Query("resource_id"),
+		Page:         ctx.QueryInt("page"),
+		Size:         ctx.QueryInt("size"),
+	}
+	for name, field := range map[string]**time.Time{"from": &request.From, "to": &request.To} {
+		value := ctx.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.Log.WithError(err).Warnf("Invalid %s format", name)
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid "+name+" format, expected RFC 3339")
+		}
+		*field = &parsed
+	}
+
+	responses, total, err := c.Usecase.Search(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search audit events")
+		return err
+	}
+
+	paging := &model.PageMetadata{
+		Page:      request.Page,
+		Size:      request.Size,
+		TotalItem: total,
+		TotalPage: int64(math.Ceil(float64(total) / float64(request.Size))),
+	}
+
+	return ctx.JSON(model.WebResponse[[]model.AuditEventResponse]{
+		Data:   responses,
+		Paging: paging,
+	})
+}
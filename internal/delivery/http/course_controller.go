@@ -1,26 +1,27 @@
 package http
 
 import (
-	"fmt"
+	"fp-designpattern/internal/delivery/http/middleware"
 	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/upload"
 	"fp-designpattern/internal/usecase"
-	"fp-designpattern/pkg/timezone"
 	"math"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 )
 
 type CourseController struct {
-	Log     *logrus.Logger
-	Usecase *usecase.CourseUsecase
+	Log           *logrus.Logger
+	Usecase       *usecase.CourseUsecase
+	UploadUsecase *usecase.UploadUsecase
 }
 
-func NewCourseController(usecase *usecase.CourseUsecase, logger *logrus.Logger) *CourseController {
+func NewCourseController(usecase *usecase.CourseUsecase, uploadUsecase *usecase.UploadUsecase, logger *logrus.Logger) *CourseController {
 	return &CourseController{
-		Log:     logger,
-		Usecase: usecase,
+		Log:           logger,
+		Usecase:       usecase,
+		UploadUsecase: uploadUsecase,
 	}
 }
 func (c *CourseController) Create(ctx *fiber.Ctx) error {
@@ -29,6 +30,7 @@ func (c *CourseController) Create(ctx *fiber.Ctx) error {
 		c.Log.Warnf("Failed to parse request body: %v", err)
 		return fiber.ErrBadRequest
 	}
+	request.Actor = middleware.GetUser(ctx)
 	courseRepsonse, err := c.Usecase.Create(ctx.UserContext(), request)
 	if err != nil {
 		c.Log.Warnf("Failed to create subject: %v", err)
@@ -39,7 +41,8 @@ func (c *CourseController) Create(ctx *fiber.Ctx) error {
 
 func (c *CourseController) Get(ctx *fiber.Ctx) error {
 	request := &model.GetCourseRequest{
-		ID: ctx.Params("id"),
+		ID:    ctx.Params("id"),
+		Actor: middleware.GetUser(ctx),
 	}
 	courseResponse, err := c.Usecase.Get(ctx.UserContext(), request)
 	if err != nil {
@@ -57,6 +60,7 @@ func (c *CourseController) List(ctx *fiber.Ctx) error {
 		SubjectID:  ctx.Query("subject_id"),
 		Page:       ctx.QueryInt("page"),
 		Size:       ctx.QueryInt("size"),
+		Actor:      middleware.GetUser(ctx),
 	}
 
 	responses, total, err := c.Usecase.Search(ctx.UserContext(), request)
@@ -85,6 +89,7 @@ func (c *CourseController) Update(ctx *fiber.Ctx) error {
 		c.Log.Warnf("Failed to parse request body: %v", err)
 		return fiber.ErrBadRequest
 	}
+	request.Actor = middleware.GetUser(ctx)
 	courseResponse, err := c.Usecase.Update(ctx.UserContext(), request)
 	if err != nil {
 		c.Log.Warnf("Failed to update subject: %v", err)
@@ -95,7 +100,8 @@ func (c *CourseController) Update(ctx *fiber.Ctx) error {
 
 func (c *CourseController) Delete(ctx *fiber.Ctx) error {
 	request := &model.DeleteCourseRequest{
-		ID: ctx.Params("id"),
+		ID:    ctx.Params("id"),
+		Actor: middleware.GetUser(ctx),
 	}
 	courseResponse, err := c.Usecase.Delete(ctx.UserContext(), request)
 	if err != nil {
@@ -105,39 +111,61 @@ func (c *CourseController) Delete(ctx *fiber.Ctx) error {
 	return ctx.JSON(model.WebResponse[*model.CourseResponse]{Data: courseResponse})
 }
 
-func (c *CourseController) UploadFile(ctx *fiber.Ctx) error {
-	fileHeader, err := ctx.FormFile("file")
-	if err != nil {
-		c.Log.Warnf("Failed to get file: %v", err)
-		return fiber.ErrBadRequest
+func (c *CourseController) Trash(ctx *fiber.Ctx) error {
+	request := &model.SearchTrashRequest{
+		Page:  ctx.QueryInt("page"),
+		Size:  ctx.QueryInt("size"),
+		Actor: middleware.GetUser(ctx),
 	}
 
-	file, err := fileHeader.Open()
+	responses, total, err := c.Usecase.Trash(ctx.UserContext(), request)
 	if err != nil {
-		c.Log.Warnf("Failed to open file: %v", err)
-		return fiber.ErrBadRequest
+		c.Log.WithError(err).Warnf("Failed to search deleted courses")
+		return err
 	}
-	defer file.Close()
 
-	now := time.Now().In(timezone.WIB)
-	timestampPrefix := now.Format("20060102_150405")
+	paging := &model.PageMetadata{
+		Page:      request.Page,
+		Size:      request.Size,
+		TotalItem: total,
+		TotalPage: int64(math.Ceil(float64(total) / float64(request.Size))),
+	}
 
-	fileName := fmt.Sprintf("%s_%s", timestampPrefix, fileHeader.Filename)
+	return ctx.JSON(model.WebResponse[[]model.CourseListResponse]{
+		Data:   responses,
+		Paging: paging,
+	})
+}
 
-	// Upload to folder "courses/"
-	objectPath := fmt.Sprintf("courses/%s", fileName)
+func (c *CourseController) Restore(ctx *fiber.Ctx) error {
+	request := &model.RestoreCourseRequest{
+		ID:    ctx.Params("id"),
+		Actor: middleware.GetUser(ctx),
+	}
+	response, err := c.Usecase.Restore(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to restore course")
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.CourseResponse]{Data: response})
+}
 
-	url, err := c.Usecase.UploadFile(
-		ctx.UserContext(),
-		file,
-		objectPath,
-		fileHeader.Header.Get("Content-Type"),
-	)
+func (c *CourseController) UploadFile(ctx *fiber.Ctx) error {
+	asset, err := uploadFormFile(ctx, c.UploadUsecase, upload.CategoryCourseImage)
 	if err != nil {
 		c.Log.Warnf("Failed to upload file: %v", err)
-		return fiber.ErrInternalServerError
+		return uploadError(ctx, err)
 	}
 
 	// Return public URL
-	return ctx.JSON(model.WebResponse[string]{Data: url})
+	return ctx.JSON(model.WebResponse[string]{Data: asset.URL})
+}
+
+func (c *CourseController) UploadAttachment(ctx *fiber.Ctx) error {
+	asset, err := uploadFormFile(ctx, c.UploadUsecase, upload.CategoryAttachment)
+	if err != nil {
+		c.Log.Warnf("Failed to upload attachment: %v", err)
+		return uploadError(ctx, err)
+	}
+	return ctx.JSON(model.WebResponse[string]{Data: asset.URL})
 }
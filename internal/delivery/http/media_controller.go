@@ -0,0 +1,51 @@
+package http
+
+import (
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/usecase"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type MediaController struct {
+	Log     *logrus.Logger
+	Usecase *usecase.UploadUsecase
+}
+
+func NewMediaController(usecase *usecase.UploadUsecase, logger *logrus.Logger) *MediaController {
+	return &MediaController{
+		Log:     logger,
+		Usecase: usecase,
+	}
+}
+
+func (c *MediaController) List(ctx *fiber.Ctx) error {
+	request := &model.SearchMediaAssetRequest{
+		Keyword:     ctx.Query("keyword"),
+		ContentType: ctx.Query("content_type"),
+		Category:    ctx.Query("category"),
+		OwnerID:     ctx.Query("owner_id"),
+		Page:        ctx.QueryInt("page"),
+		Size:        ctx.QueryInt("size"),
+	}
+
+	responses, total, err := c.Usecase.Search(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search media assets")
+		return err
+	}
+
+	paging := &model.PageMetadata{
+		Page:      request.Page,
+		Size:      request.Size,
+		TotalItem: total,
+		TotalPage: int64(math.Ceil(float64(total) / float64(request.Size))),
+	}
+
+	return ctx.JSON(model.WebResponse[[]model.MediaAssetResponse]{
+		Data:   responses,
+		Paging: paging,
+	})
+}
@@ -1,17 +1,20 @@
 package middleware
 
 import (
+	"errors"
 	"fp-designpattern/internal/helper"
 	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
 	"fp-designpattern/internal/usecase"
+	"slices"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func NewAuth(userUsecase *usecase.UserUseCase) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
-		request := &model.VerifyUserRequest{Token: ctx.Get("Authorization", "NOT_FOUND")}
-		userUsecase.Log.Debugf("Authorization : %s", request.Token)
+		request := &model.VerifyUserRequest{Token: strings.TrimPrefix(ctx.Get("Authorization", "NOT_FOUND"), "Bearer ")}
 
 		auth, err := userUsecase.Verify(ctx.UserContext(), request)
 
@@ -19,6 +22,7 @@ func NewAuth(userUsecase *usecase.UserUseCase) fiber.Handler {
 			userUsecase.Log.Warnf("Failed to verify user: %v", err)
 			return fiber.ErrUnauthorized
 		}
+		auth.IPAddress = ctx.IP()
 		userUsecase.Log.Debugf("User: %v", auth.ID)
 		ctx.Locals("auth", auth)
 		return ctx.Next()
@@ -29,11 +33,137 @@ func GetUser(ctx *fiber.Ctx) *model.Auth {
 	return helper.GetUser(ctx)
 }
 
-func RequireRole(role string) fiber.Handler {
+// NewRequireVerifiedEmail rejects users whose email is not verified. The access token claim is trusted
+// when it says verified, otherwise the user is read again in case they verified after it was issued.
+func NewRequireVerifiedEmail(userUsecase *usecase.UserUseCase) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
-		auth := helper.GetUser(ctx) // Extract the user info
+		auth := helper.GetUser(ctx)
+		if auth.EmailVerified {
+			return ctx.Next()
+		}
+
+		verified, err := userUsecase.EmailVerified(ctx.UserContext(), &model.GetUserRequest{ID: auth.ID})
+		if err != nil {
+			userUsecase.Log.Warnf("Failed to check email verification: %v", err)
+			return err
+		}
+		if !verified {
+			return fiber.NewError(fiber.StatusForbidden, "Verify your email address to access this resource")
+		}
+		return ctx.Next()
+	}
+}
+
+// NewPassThrough stands in for an optional middleware that is switched off.
+func NewPassThrough() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		return ctx.Next()
+	}
+}
+
+// RequireMFA rejects sessions of the roles that were not signed in with a second factor, other
+// roles pass. An API key counts as signed in like the session that created it.
+func RequireMFA(roles ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		auth := helper.GetUser(ctx)
+		if auth == nil || !auth.MFA && slices.Contains(roles, auth.Role) {
+			return fiber.NewError(fiber.StatusForbidden, "Two-factor authentication is required for this resource")
+		}
+		return ctx.Next()
+	}
+}
+
+// RequireSession rejects API keys and impersonation, the account itself is only managed by its
+// owner from a signed in session.
+func RequireSession() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		auth := helper.GetUser(ctx)
+		if auth == nil || auth.APIKeyID != "" {
+			return fiber.NewError(fiber.StatusForbidden, "API keys cannot access this resource")
+		}
+		if auth.ImpersonatorID != "" {
+			return ErrImpersonating
+		}
+		return ctx.Next()
+	}
+}
+
+// RejectAPIKeys rejects API keys on routes that only make sense for a session, such as logout.
+func RejectAPIKeys() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		auth := helper.GetUser(ctx)
+		if auth == nil || auth.APIKeyID != "" {
+			return fiber.NewError(fiber.StatusForbidden, "API keys cannot access this resource")
+		}
+		return ctx.Next()
+	}
+}
+
+var ErrImpersonating = fiber.NewError(fiber.StatusForbidden, "This action is not allowed while impersonating")
+
+// RejectImpersonation rejects changes an admin impersonating a user must not make in their name.
+func RejectImpersonation() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		auth := helper.GetUser(ctx)
+		if auth != nil && auth.ImpersonatorID != "" {
+			return ErrImpersonating
+		}
+		return ctx.Next()
+	}
+}
+
+// NewImpersonationAudit records every request made while impersonating with the status it ended with.
+func NewImpersonationAudit(userUsecase *usecase.UserUseCase) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		auth := helper.GetUser(ctx)
+		if auth == nil || auth.ImpersonatorID == "" {
+			return ctx.Next()
+		}
+
+		err := ctx.Next()
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+		userUsecase.Log.Infof("User %s impersonating user %s: %s %s %d", auth.ImpersonatorID, auth.ID, ctx.Method(), ctx.OriginalURL(), status)
+		request := &model.ImpersonatedRequest{
+			SessionID:      auth.SessionID,
+			ImpersonatorID: auth.ImpersonatorID,
+			UserID:         auth.ID,
+			Method:         ctx.Method(),
+			Path:           ctx.OriginalURL(),
+			Status:         status,
+			IPAddress:      ctx.IP(),
+		}
+		if recordErr := userUsecase.RecordImpersonatedRequest(ctx.UserContext(), request); recordErr != nil {
+			userUsecase.Log.Errorf("Failed to record impersonated request: %v", recordErr)
+		}
+		return err
+	}
+}
+
+// RequireScope rejects API keys not scoped to the routes every signed in user has, sessions pass.
+func RequireScope(scope string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		auth := helper.GetUser(ctx)
+		if auth == nil || !rbac.InScopes(auth.Scopes, scope) {
+			return fiber.NewError(fiber.StatusForbidden, "You do not have access to this resource")
+		}
+		return ctx.Next()
+	}
+}
+
+// RequirePermission rejects users whose role is not granted the permission, and API keys not
+// scoped to it. Scoped roles are further limited to their subjects by the usecases.
+func RequirePermission(policy *rbac.Policy, permission string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		auth := helper.GetUser(ctx)
 
-		if auth == nil || auth.Role != role {
+		if auth == nil || !policy.Can(auth.Role, permission) || !rbac.InScopes(auth.Scopes, permission) {
 			return fiber.NewError(fiber.StatusForbidden, "You do not have access to this resource")
 		}
 
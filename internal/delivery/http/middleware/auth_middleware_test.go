@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
+	"fp-designpattern/internal/repository"
+	"fp-designpattern/internal/usecase"
+	"io"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// serve runs a request signed in as auth through the handlers and returns the status it ends with.
+func serve(t *testing.T, auth *model.Auth, method string, handlers ...fiber.Handler) int {
+	t.Helper()
+	app := fiber.New()
+	signIn := func(ctx *fiber.Ctx) error {
+		ctx.Locals("auth", auth)
+		return ctx.Next()
+	}
+	ok := func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusOK)
+	}
+	app.Add(method, "/", append(append([]fiber.Handler{signIn}, handlers...), ok)...)
+	response, err := app.Test(httptest.NewRequest(method, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode
+}
+
+func TestRequireMFA(t *testing.T) {
+	tests := []struct {
+		name string
+		auth *model.Auth
+		want int
+	}{
+		{"admin with a second factor", &model.Auth{Role: rbac.RoleAdmin, MFA: true}, fiber.StatusOK},
+		{"admin without a second factor", &model.Auth{Role: rbac.RoleAdmin}, fiber.StatusForbidden},
+		{"admin key created with a second factor", &model.Auth{Role: rbac.RoleAdmin, MFA: true, APIKeyID: "key"}, fiber.StatusOK},
+		{"admin key created without a second factor", &model.Auth{Role: rbac.RoleAdmin, APIKeyID: "key"}, fiber.StatusForbidden},
+		// Teachers reach the admin routes of their subjects whether or not they use two-factor authentication
+		{"teacher without a second factor", &model.Auth{Role: rbac.RoleTeacher}, fiber.StatusOK},
+		{"teacher key scoped to admin routes", &model.Auth{Role: rbac.RoleTeacher, APIKeyID: "key", Scopes: []string{rbac.PermissionCourseUpdate}}, fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := serve(t, tt.auth, fiber.MethodGet, RequireMFA(rbac.RoleAdmin)); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	tests := []struct {
+		name string
+		auth *model.Auth
+		want int
+	}{
+		{"session", &model.Auth{ID: "u1", SessionID: "s1"}, fiber.StatusOK},
+		{"api key", &model.Auth{ID: "u1", APIKeyID: "key", Scopes: []string{rbac.All}}, fiber.StatusForbidden},
+		// An impersonating admin cannot change the password, email or second factor of the user
+		{"impersonation", &model.Auth{ID: "u1", SessionID: "s1", ImpersonatorID: "admin"}, fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		for _, method := range []string{fiber.MethodPut, fiber.MethodPost} {
+			if got := serve(t, tt.auth, method, RequireSession()); got != tt.want {
+				t.Errorf("%s %s: status %d, want %d", tt.name, method, got, tt.want)
+			}
+		}
+	}
+}
+
+// TestImpersonationAuditRecordsEveryRequest needs the database of the usecase tests, see newTestDB there.
+func TestImpersonationAuditRecordsEveryRequest(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	users := &usecase.UserUseCase{DB: db, Log: log, Validate: validator.New(), ImpersonationRequestRepository: repository.NewImpersonationRequestRepository(log)}
+
+	var admin, user entity.User
+	for _, u := range []*entity.User{&admin, &user} {
+		*u = entity.User{ID: uuid.New(), Username: "test", Password: "-", PhoneNumber: "08123456789", GradeLevel: 10, Role: rbac.RoleUser, BirthDate: time.Date(2008, 1, 2, 0, 0, 0, 0, time.UTC)}
+		u.Email = u.ID.String() + "@example.com"
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	session := &entity.UserSession{UserID: user.ID, Device: "impersonation", LastSeenAt: time.Now(), ImpersonatorID: &admin.ID}
+	if err := db.Omit("User").Create(session).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Delete(&entity.ImpersonationRequest{}, "session_id = ?", session.ID)
+		db.Unscoped().Delete(&entity.User{}, "id IN ?", []uuid.UUID{admin.ID, user.ID})
+		if connection, err := db.DB(); err == nil {
+			connection.Close()
+		}
+	})
+
+	impersonating := &model.Auth{ID: user.ID.String(), Role: user.Role, SessionID: session.ID.String(), ImpersonatorID: admin.ID.String()}
+	tests := []struct {
+		name     string
+		auth     *model.Auth
+		handlers []fiber.Handler
+		want     int
+	}{
+		{"allowed request", impersonating, nil, fiber.StatusOK},
+		{"refused request", impersonating, []fiber.Handler{RequireSession()}, fiber.StatusForbidden},
+		{"failed request", impersonating, []fiber.Handler{func(ctx *fiber.Ctx) error { return fiber.ErrNotFound }}, fiber.StatusNotFound},
+		// The user's own requests are not recorded
+		{"own request", &model.Auth{ID: user.ID.String(), Role: user.Role, SessionID: uuid.NewString()}, nil, fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := serve(t, tt.auth, fiber.MethodGet, append([]fiber.Handler{NewImpersonationAudit(users)}, tt.handlers...)...); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	var requests []entity.ImpersonationRequest
+	if err := db.Where("user_id = ?", user.ID).Order("created_at").Find(&requests).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("recorded %d requests, want the 3 impersonated ones", len(requests))
+	}
+	for i, want := range []int{fiber.StatusOK, fiber.StatusForbidden, fiber.StatusNotFound} {
+		request := requests[i]
+		if request.Status != want || request.Method != fiber.MethodGet || request.Path != "/" || request.ImpersonatorID == nil || *request.ImpersonatorID != admin.ID {
+			t.Errorf("request %d = %+v, want status %d by the admin", i, request, want)
+		}
+	}
+}
+
+// TestRequireVerifiedEmail needs the database of the usecase tests, see newTestDB there.
+func TestRequireVerifiedEmail(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	users := &usecase.UserUseCase{DB: db, Log: log, Validate: validator.New(), UserRepository: repository.NewUserRepository(log)}
+
+	verifiedAt := time.Now()
+	var verified, unverified entity.User
+	for _, u := range []*entity.User{&verified, &unverified} {
+		*u = entity.User{ID: uuid.New(), Username: "test", Password: "-", PhoneNumber: "08123456789", GradeLevel: 10, Role: rbac.RoleUser, BirthDate: time.Date(2008, 1, 2, 0, 0, 0, 0, time.UTC)}
+		u.Email = u.ID.String() + "@example.com"
+	}
+	verified.EmailVerifiedAt = &verifiedAt
+	for _, u := range []*entity.User{&verified, &unverified} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&entity.User{}, "id IN ?", []uuid.UUID{verified.ID, unverified.ID})
+		if connection, err := db.DB(); err == nil {
+			connection.Close()
+		}
+	})
+
+	tests := []struct {
+		name string
+		auth *model.Auth
+		want int
+	}{
+		{"token issued verified", &model.Auth{ID: verified.ID.String(), EmailVerified: true}, fiber.StatusOK},
+		// The user verified after the token was issued
+		{"verified since the token", &model.Auth{ID: verified.ID.String()}, fiber.StatusOK},
+		{"unverified", &model.Auth{ID: unverified.ID.String()}, fiber.StatusForbidden},
+		{"unknown user", &model.Auth{ID: uuid.NewString()}, fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := serve(t, tt.auth, fiber.MethodGet, NewRequireVerifiedEmail(users)); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package http
+
+import (
+	"errors"
+	"fp-designpattern/internal/delivery/http/middleware"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/quizformat"
+	"fp-designpattern/internal/usecase"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type QuizController struct {
+	Log     *logrus.Logger
+	Usecase *usecase.QuizUsecase
+}
+
+func NewQuizController(usecase *usecase.QuizUsecase, logger *logrus.Logger) *QuizController {
+	return &QuizController{
+		Log:     logger,
+		Usecase: usecase,
+	}
+}
+
+func (c *QuizController) Create(ctx *fiber.Ctx) error {
+	request := new(model.QuizRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.Actor = middleware.GetUser(ctx)
+	quizResponse, err := c.Usecase.Create(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to create quiz: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizResponse]{Data: quizResponse})
+}
+
+func (c *QuizController) Get(ctx *fiber.Ctx) error {
+	request := &model.GetQuizRequest{
+		ID:    ctx.Params("id"),
+		Actor: middleware.GetUser(ctx),
+	}
+	quizResponse, err := c.Usecase.Get(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to get quiz: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizResponse]{Data: quizResponse})
+}
+
+func (c *QuizController) List(ctx *fiber.Ctx) error {
+
+	request := &model.SearchQuizRequest{
+		QuizName: ctx.Query("quiz_name"),
+		CourseID: ctx.Query("course_id"),
+		Page:     ctx.QueryInt("page"),
+		Size:     ctx.QueryInt("size"),
+		Actor:    middleware.GetUser(ctx),
+	}
+
+	responses, total, err := c.Usecase.Search(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search quiz")
+		return err
+	}
+
+	paging := &model.PageMetadata{
+		Page:      request.Page,
+		Size:      request.Size,
+		TotalItem: total,
+		TotalPage: int64(math.Ceil(float64(total) / float64(request.Size))),
+	}
+
+	return ctx.JSON(model.WebResponse[[]model.QuizListResponse]{
+		Data:   responses,
+		Paging: paging,
+	})
+}
+
+func (c *QuizController) Update(ctx *fiber.Ctx) error {
+	request := new(model.UpdateQuizRequest)
+	request.ID = ctx.Params("id")
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.Actor = middleware.GetUser(ctx)
+	quizResponse, err := c.Usecase.Update(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to update quiz: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizResponse]{Data: quizResponse})
+}
+
+func (c *QuizController) Delete(ctx *fiber.Ctx) error {
+	request := &model.DeleteQuizRequest{
+		ID:    ctx.Params("id"),
+		Actor: middleware.GetUser(ctx),
+	}
+	quizResponse, err := c.Usecase.Delete(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to delete quiz: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizResponse]{Data: quizResponse})
+}
+
+func (c *QuizController) CreateQuestion(ctx *fiber.Ctx) error {
+	request := new(model.QuestionRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.QuizID = ctx.Params("id")
+	request.Actor = middleware.GetUser(ctx)
+	questionResponse, err := c.Usecase.CreateQuestion(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to create question: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuestionResponse]{Data: questionResponse})
+}
+
+func (c *QuizController) UpdateQuestion(ctx *fiber.Ctx) error {
+	request := new(model.UpdateQuestionRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.ID = ctx.Params("questionId")
+	request.QuizID = ctx.Params("id")
+	request.Actor = middleware.GetUser(ctx)
+	questionResponse, err := c.Usecase.UpdateQuestion(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to update question: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuestionResponse]{Data: questionResponse})
+}
+
+func (c *QuizController) DeleteQuestion(ctx *fiber.Ctx) error {
+	request := &model.DeleteQuestionRequest{
+		ID:     ctx.Params("questionId"),
+		QuizID: ctx.Params("id"),
+		Actor:  middleware.GetUser(ctx),
+	}
+	questionResponse, err := c.Usecase.DeleteQuestion(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to delete question: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuestionResponse]{Data: questionResponse})
+}
+
+func (c *QuizController) CreateOption(ctx *fiber.Ctx) error {
+	request := new(model.OptionRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.QuizID = ctx.Params("id")
+	request.QuestionID = ctx.Params("questionId")
+	request.Actor = middleware.GetUser(ctx)
+	optionResponse, err := c.Usecase.CreateOption(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to create option: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.OptionResponse]{Data: optionResponse})
+}
+
+func (c *QuizController) UpdateOption(ctx *fiber.Ctx) error {
+	request := new(model.UpdateOptionRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.ID = ctx.Params("optionId")
+	request.QuizID = ctx.Params("id")
+	request.QuestionID = ctx.Params("questionId")
+	request.Actor = middleware.GetUser(ctx)
+	optionResponse, err := c.Usecase.UpdateOption(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to update option: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.OptionResponse]{Data: optionResponse})
+}
+
+func (c *QuizController) DeleteOption(ctx *fiber.Ctx) error {
+	request := &model.DeleteOptionRequest{
+		ID:         ctx.Params("optionId"),
+		QuizID:     ctx.Params("id"),
+		QuestionID: ctx.Params("questionId"),
+		Actor:      middleware.GetUser(ctx),
+	}
+	optionResponse, err := c.Usecase.DeleteOption(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to delete option: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.OptionResponse]{Data: optionResponse})
+}
+
+func (c *QuizController) SetAnswerKey(ctx *fiber.Ctx) error {
+	request := new(model.AnswerKeyRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.QuizID = ctx.Params("id")
+	request.QuestionID = ctx.Params("questionId")
+	request.Actor = middleware.GetUser(ctx)
+	questionResponse, err := c.Usecase.SetAnswerKey(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to set answer key: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuestionResponse]{Data: questionResponse})
+}
+
+func (c *QuizController) ListBank(ctx *fiber.Ctx) error {
+	request := &model.SearchQuestionBankRequest{
+		SubjectID:  ctx.Query("subject_id"),
+		GradeLevel: ctx.QueryInt("grade_level"),
+		Type:       ctx.Query("type"),
+		Page:       ctx.QueryInt("page"),
+		Size:       ctx.QueryInt("size"),
+		Actor:      middleware.GetUser(ctx),
+	}
+
+	responses, total, err := c.Usecase.SearchBank(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search question bank")
+		return err
+	}
+
+	paging := &model.PageMetadata{
+		Page:      request.Page,
+		Size:      request.Size,
+		TotalItem: total,
+		TotalPage: int64(math.Ceil(float64(total) / float64(request.Size))),
+	}
+
+	return ctx.JSON(model.WebResponse[[]model.QuestionResponse]{
+		Data:   responses,
+		Paging: paging,
+	})
+}
+
+func (c *QuizController) GetBankQuestion(ctx *fiber.Ctx) error {
+	request := &model.GetBankQuestionRequest{
+		ID:    ctx.Params("questionId"),
+		Actor: middleware.GetUser(ctx),
+	}
+	questionResponse, err := c.Usecase.GetBankQuestion(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to get bank question: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuestionResponse]{Data: questionResponse})
+}
+
+func (c *QuizController) Import(ctx *fiber.Ctx) error {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		c.Log.Warnf("Failed to get file: %v", err)
+		return fiber.ErrBadRequest
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.Log.Warnf("Failed to open file: %v", err)
+		return fiber.ErrBadRequest
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		c.Log.Warnf("Failed to read file: %v", err)
+		return fiber.ErrBadRequest
+	}
+
+	request := &model.ImportQuizRequest{
+		CourseID: ctx.FormValue("course_id"),
+		QuizName: ctx.FormValue("quiz_name"),
+		Format:   ctx.FormValue("format"),
+		Content:  content,
+		Actor:    middleware.GetUser(ctx),
+	}
+	request.TimeLimit, _ = strconv.Atoi(ctx.FormValue("time_limit"))
+	if request.Format == "" {
+		// Fall back to the file extension, Moodle exports XML and GIFT is usually plain text
+		switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+		case ".xml":
+			request.Format = quizformat.FormatMoodleXML
+		case ".gift", ".txt":
+			request.Format = quizformat.FormatGIFT
+		}
+	}
+
+	quizResponse, err := c.Usecase.Import(ctx.UserContext(), request)
+	var importErr *usecase.ImportError
+	if errors.As(err, &importErr) {
+		c.Log.Warnf("Failed to import quiz: %v", err)
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(model.WebResponse[[]model.ImportLineError]{
+			Data:   importErr.Lines,
+			Errors: "some questions could not be imported",
+		})
+	}
+	if err != nil {
+		c.Log.Warnf("Failed to import quiz: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizResponse]{Data: quizResponse})
+}
+
+func (c *QuizController) Export(ctx *fiber.Ctx) error {
+	request := &model.ExportQuizRequest{
+		ID:     ctx.Params("id"),
+		Format: ctx.Query("format", quizformat.FormatMoodleXML),
+		Actor:  middleware.GetUser(ctx),
+	}
+	exportResponse, err := c.Usecase.Export(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to export quiz: %v", err)
+		return err
+	}
+	ctx.Set(fiber.HeaderContentType, exportResponse.ContentType)
+	ctx.Attachment(exportResponse.FileName)
+	return ctx.Send(exportResponse.Content)
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"fp-designpattern/internal/delivery/http/middleware"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/usecase"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type QuizSessionController struct {
+	Log     *logrus.Logger
+	Usecase *usecase.QuizSessionUsecase
+}
+
+func NewQuizSessionController(usecase *usecase.QuizSessionUsecase, logger *logrus.Logger) *QuizSessionController {
+	return &QuizSessionController{
+		Log:     logger,
+		Usecase: usecase,
+	}
+}
+
+func (c *QuizSessionController) Start(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+	request := &model.StartQuizSessionRequest{
+		QuizID: ctx.Params("id"),
+		UserID: auth.ID,
+	}
+	sessionResponse, err := c.Usecase.Start(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to start quiz session: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizSessionResponse]{Data: sessionResponse})
+}
+
+func (c *QuizSessionController) Get(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+	request := &model.GetQuizSessionRequest{
+		ID:     ctx.Params("id"),
+		UserID: auth.ID,
+	}
+	sessionResponse, err := c.Usecase.Get(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to get quiz session: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizSessionResponse]{Data: sessionResponse})
+}
+
+func (c *QuizSessionController) SaveAnswer(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+	request := new(model.SaveAnswerRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.SessionID = ctx.Params("id")
+	request.UserID = auth.ID
+	sessionResponse, err := c.Usecase.SaveAnswer(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to save answer: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizSessionResponse]{Data: sessionResponse})
+}
+
+func (c *QuizSessionController) Submit(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+	request := &model.SubmitQuizSessionRequest{
+		ID:     ctx.Params("id"),
+		UserID: auth.ID,
+	}
+	sessionResponse, err := c.Usecase.Submit(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to submit quiz session: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizSessionResponse]{Data: sessionResponse})
+}
+
+func (c *QuizSessionController) Attempts(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+	request := &model.SearchQuizAttemptRequest{
+		QuizID: ctx.Params("id"),
+		UserID: auth.ID,
+		Page:   ctx.QueryInt("page"),
+		Size:   ctx.QueryInt("size"),
+	}
+
+	responses, total, err := c.Usecase.Attempts(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to list quiz attempts")
+		return err
+	}
+
+	paging := &model.PageMetadata{
+		Page:      request.Page,
+		Size:      request.Size,
+		TotalItem: total,
+		TotalPage: int64(math.Ceil(float64(total) / float64(request.Size))),
+	}
+
+	return ctx.JSON(model.WebResponse[[]model.QuizAttemptResponse]{
+		Data:   responses,
+		Paging: paging,
+	})
+}
+
+func (c *QuizSessionController) Review(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+	request := &model.ReviewQuizSessionRequest{
+		ID:     ctx.Params("id"),
+		UserID: auth.ID,
+	}
+	reviewResponse, err := c.Usecase.Review(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to review quiz session: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizReviewResponse]{Data: reviewResponse})
+}
+
+func (c *QuizSessionController) Analytics(ctx *fiber.Ctx) error {
+	request := &model.QuizAnalyticsRequest{
+		QuizID: ctx.Params("id"),
+		Actor:  middleware.GetUser(ctx),
+	}
+	if ctx.Query("format") == "csv" {
+		content, err := c.Usecase.AnalyticsCSV(ctx.UserContext(), request)
+		if err != nil {
+			c.Log.Warnf("Failed to export quiz analytics: %v", err)
+			return err
+		}
+		ctx.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		ctx.Attachment(request.QuizID + "_analytics.csv")
+		return ctx.Send(content)
+	}
+
+	analyticsResponse, err := c.Usecase.Analytics(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to get quiz analytics: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.QuizAnalyticsResponse]{Data: analyticsResponse})
+}
@@ -3,17 +3,35 @@ package route
 import (
 	"fp-designpattern/internal/delivery/http"
 	"fp-designpattern/internal/delivery/http/middleware"
+	"fp-designpattern/internal/rbac"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type RouteConfig struct {
-	App                  *fiber.App
-	UserController       *http.UserController
-	SubjectController    *http.SubjectController
-	CourseController     *http.CourseController
-	UserCourseController *http.UserCourseController
-	AuthMiddleware       fiber.Handler
+	App                   *fiber.App
+	UserController        *http.UserController
+	SubjectController     *http.SubjectController
+	CourseController      *http.CourseController
+	UserCourseController  *http.UserCourseController
+	QuizController        *http.QuizController
+	QuizSessionController *http.QuizSessionController
+	// TeacherSubjectController assigns subjects to the users of scoped roles.
+	TeacherSubjectController *http.TeacherSubjectController
+	// AuditController searches the audit trail of administrative changes.
+	AuditController *http.AuditController
+	// MediaController lists the media library for reusing uploaded files.
+	MediaController *http.MediaController
+	AuthMiddleware  fiber.Handler
+	// ImpersonationAuditMiddleware records the requests of admins impersonating users.
+	ImpersonationAuditMiddleware fiber.Handler
+	// Policy maps the roles to the permissions the admin routes require.
+	Policy *rbac.Policy
+	// VerifiedEmailMiddleware guards the course routes, it passes everyone unless auth.require_verified_email is set.
+	VerifiedEmailMiddleware fiber.Handler
+	// AdminMFAMiddleware guards the admin routes, it passes everyone unless auth.require_admin_2fa is set
+	// and then only stops admins signed in without a second factor.
+	AdminMFAMiddleware fiber.Handler
 }
 
 func (c *RouteConfig) Setup() {
@@ -25,7 +43,14 @@ func (c *RouteConfig) SetupGuestRoute() {
 	// users
 	c.App.Post("/api/users/register", c.UserController.Register)
 	c.App.Post("/api/users/login", c.UserController.Login)
+	c.App.Post("/api/users/login/2fa", c.UserController.LoginTwoFactor)
+	c.App.Post("/api/users/refresh", c.UserController.Refresh)
+	c.App.Post("/api/users/password/forgot", c.UserController.ForgotPassword)
+	c.App.Post("/api/users/password/reset", c.UserController.ResetPassword)
+	c.App.Post("/api/users/verify-email", c.UserController.VerifyEmail)
 	c.App.Get("/api/users/user/:id", c.UserController.Get)
+	c.App.Get("/api/auth/oidc/:provider", c.UserController.StartIdentityLogin)
+	c.App.Post("/api/auth/oidc/:provider/callback", c.UserController.IdentityLogin)
 
 	//subjects
 	c.App.Get("api/subjects", c.SubjectController.List)
@@ -34,37 +59,120 @@ func (c *RouteConfig) SetupGuestRoute() {
 
 func (c *RouteConfig) SetupAuthRoute() {
 	c.App.Use(c.AuthMiddleware)
-	//authenticated users
-	c.App.Get("/api/users/current", c.UserController.Current)
-	c.App.Post("/api/users/logout", c.UserController.Logout)
-	c.App.Put("api/users", c.UserController.Update)
+	c.App.Use(c.ImpersonationAuditMiddleware)
+	//authenticated users, API keys need the scope of a route that has no permission
+	profile := middleware.RequireScope(rbac.ScopeProfileRead)
+	c.App.Get("/api/users/current", profile, c.UserController.Current)
+	// the account itself, API keys and impersonating admins cannot manage it
+	session := middleware.RequireSession()
+	c.App.Post("/api/users/logout", middleware.RejectAPIKeys(), c.UserController.Logout)
+	c.App.Put("api/users", session, c.UserController.Update)
+	c.App.Post("/api/users/avatar", session, c.UserController.UploadAvatar)
+	c.App.Get("/api/users/sessions", session, c.UserController.Sessions)
+	c.App.Delete("/api/users/sessions/:id", session, c.UserController.RevokeSession)
+	c.App.Post("/api/users/2fa/enroll", session, c.UserController.EnrollTwoFactor)
+	c.App.Post("/api/users/2fa/confirm", session, c.UserController.ConfirmTwoFactor)
+	c.App.Post("/api/users/2fa/disable", session, c.UserController.DisableTwoFactor)
+	c.App.Get("/api/users/identities", session, c.UserController.Identities)
+	c.App.Post("/api/users/identities/:provider", session, c.UserController.StartIdentityLink)
+	c.App.Post("/api/users/identities/:provider/callback", session, c.UserController.LinkIdentity)
+	c.App.Delete("/api/users/identities/:id", session, c.UserController.UnlinkIdentity)
+	c.App.Get("/api/users/api-keys", session, c.UserController.APIKeys)
+	c.App.Post("/api/users/api-keys", session, c.UserController.CreateAPIKey)
+	c.App.Delete("/api/users/api-keys/:id", session, c.UserController.RevokeAPIKey)
+	c.App.Get("/api/users/subjects", profile, c.TeacherSubjectController.Current)
 
 	// accessable courses
-	c.App.Get("/api/courses", c.UserCourseController.ListAccessable)
-	c.App.Get("/api/courses/:id", c.UserCourseController.Get)
+	courses := middleware.RequireScope(rbac.ScopeCourseAccess)
+	c.App.Get("/api/courses", courses, c.VerifiedEmailMiddleware, c.UserCourseController.ListAccessable)
+	c.App.Get("/api/courses/:id", courses, c.VerifiedEmailMiddleware, c.UserCourseController.Get)
 
-	// Admin-only
-	adminOnly := c.App.Group("/api/admin", middleware.RequireRole("admin"))
+	// quiz sessions, an impersonating admin only looks at them
+	notImpersonating := middleware.RejectImpersonation()
+	quiz := middleware.RequireScope(rbac.ScopeQuizTake)
+	c.App.Post("/api/quizzes/:id/sessions", quiz, notImpersonating, c.QuizSessionController.Start)
+	c.App.Get("/api/quizzes/:id/attempts", quiz, c.QuizSessionController.Attempts)
+	c.App.Get("/api/quiz-sessions/:id", quiz, c.QuizSessionController.Get)
+	c.App.Put("/api/quiz-sessions/:id/answers", quiz, notImpersonating, c.QuizSessionController.SaveAnswer)
+	c.App.Post("/api/quiz-sessions/:id/submit", quiz, notImpersonating, c.QuizSessionController.Submit)
+	c.App.Get("/api/quiz-sessions/:id/review", quiz, c.QuizSessionController.Review)
+
+	// Admin area, every route requires a permission of the user's role
+	admin := c.App.Group("/api/admin", notImpersonating, c.AdminMFAMiddleware)
+	can := func(permission string) fiber.Handler {
+		return middleware.RequirePermission(c.Policy, permission)
+	}
 	// users
-	adminOnly.Get("/users", c.UserController.List)
-	adminOnly.Put("/users/:id", c.UserController.AdminUpdate)
-	adminOnly.Delete("/users/:id", c.UserController.Delete)
+	admin.Get("/users", can(rbac.PermissionUserManage), c.UserController.List)
+	admin.Get("/users/trash", can(rbac.PermissionUserManage), c.UserController.Trash)
+	admin.Post("/users/:id/restore", can(rbac.PermissionUserManage), c.UserController.Restore)
+	admin.Put("/users/:id", can(rbac.PermissionUserManage), c.UserController.AdminUpdate)
+	admin.Put("/users/:id/role", can(rbac.PermissionUserManage), c.UserController.SetRole)
+	admin.Post("/users/:id/impersonate", middleware.RequireSession(), can(rbac.PermissionUserImpersonate), c.UserController.Impersonate)
+	admin.Delete("/users/:id/sessions", can(rbac.PermissionUserManage), c.UserController.RevokeAllSessions)
+	admin.Delete("/users/:id/api-keys", can(rbac.PermissionUserManage), c.UserController.RevokeAllAPIKeys)
+	admin.Post("/users/:id/verification", can(rbac.PermissionUserManage), c.UserController.ResendVerification)
+	admin.Delete("/users/:id/2fa", can(rbac.PermissionUserManage), c.UserController.ResetTwoFactor)
+	admin.Delete("/users/:id/lockout", can(rbac.PermissionUserManage), c.UserController.UnlockLogin)
+	admin.Delete("/users/:id", can(rbac.PermissionUserManage), c.UserController.Delete)
+	// subjects assigned to teachers
+	admin.Get("/users/:id/subjects", can(rbac.PermissionTeacherSubjectManage), c.TeacherSubjectController.List)
+	admin.Post("/users/:id/subjects", can(rbac.PermissionTeacherSubjectManage), c.TeacherSubjectController.Assign)
+	admin.Delete("/users/:id/subjects/:subjectId", can(rbac.PermissionTeacherSubjectManage), c.TeacherSubjectController.Unassign)
+	// audit trail
+	admin.Get("/audit", can(rbac.PermissionAuditRead), c.AuditController.List)
 	// subjects
-	adminOnly.Post("/subjects", c.SubjectController.Create)
-	adminOnly.Put("/subjects/:id", c.SubjectController.Update)
-	adminOnly.Delete("/subjects/:id", c.SubjectController.Delete)
+	admin.Post("/subjects", can(rbac.PermissionSubjectCreate), c.SubjectController.Create)
+	admin.Put("/subjects/:id", can(rbac.PermissionSubjectUpdate), c.SubjectController.Update)
+	admin.Delete("/subjects/:id", can(rbac.PermissionSubjectDelete), c.SubjectController.Delete)
+	admin.Get("/subjects/trash", can(rbac.PermissionSubjectDelete), c.SubjectController.Trash)
+	admin.Post("/subjects/:id/restore", can(rbac.PermissionSubjectDelete), c.SubjectController.Restore)
 
 	// courses
-	adminOnly.Get("/courses", c.CourseController.List)
-	adminOnly.Get("/courses/:id", c.CourseController.Get)
-	adminOnly.Post("/courses/upload", c.CourseController.UploadFile)
-	adminOnly.Post("/courses", c.CourseController.Create)
-	adminOnly.Put("/courses/:id", c.CourseController.Update)
-	adminOnly.Delete("/courses/:id", c.CourseController.Delete)
+	admin.Get("/courses", can(rbac.PermissionCourseRead), c.CourseController.List)
+	admin.Get("/courses/trash", can(rbac.PermissionCourseDelete), c.CourseController.Trash)
+	admin.Post("/courses/:id/restore", can(rbac.PermissionCourseDelete), c.CourseController.Restore)
+	admin.Get("/courses/:id", can(rbac.PermissionCourseRead), c.CourseController.Get)
+	admin.Post("/courses/upload", can(rbac.PermissionCourseCreate), c.CourseController.UploadFile)
+	admin.Post("/courses/attachments", can(rbac.PermissionCourseCreate), c.CourseController.UploadAttachment)
+	// media library
+	admin.Get("/media", can(rbac.PermissionMediaRead), c.MediaController.List)
+	admin.Post("/courses", can(rbac.PermissionCourseCreate), c.CourseController.Create)
+	admin.Put("/courses/:id", can(rbac.PermissionCourseUpdate), c.CourseController.Update)
+	admin.Delete("/courses/:id", can(rbac.PermissionCourseDelete), c.CourseController.Delete)
 
 	// course permissions
-	adminOnly.Get("/user-courses", c.UserCourseController.List)
-	adminOnly.Post("/user-courses", c.UserCourseController.Create)
-	adminOnly.Delete("/user-courses/:id", c.UserCourseController.Delete)
+	admin.Get("/user-courses", can(rbac.PermissionUserCourseRead), c.UserCourseController.List)
+	admin.Post("/user-courses", can(rbac.PermissionUserCourseCreate), c.UserCourseController.Create)
+	admin.Delete("/user-courses/:id", can(rbac.PermissionUserCourseDelete), c.UserCourseController.Delete)
+
+	// quizzes
+	admin.Get("/quizzes", can(rbac.PermissionQuizRead), c.QuizController.List)
+	admin.Get("/quizzes/:id", can(rbac.PermissionQuizRead), c.QuizController.Get)
+	admin.Post("/quizzes", can(rbac.PermissionQuizCreate), c.QuizController.Create)
+	admin.Post("/quizzes/import", can(rbac.PermissionQuizCreate), c.QuizController.Import)
+	admin.Get("/quizzes/:id/export", can(rbac.PermissionQuizRead), c.QuizController.Export)
+	admin.Get("/quizzes/:id/analytics", can(rbac.PermissionQuizRead), c.QuizSessionController.Analytics)
+	admin.Put("/quizzes/:id", can(rbac.PermissionQuizUpdate), c.QuizController.Update)
+	admin.Delete("/quizzes/:id", can(rbac.PermissionQuizDelete), c.QuizController.Delete)
+	// quiz questions
+	admin.Post("/quizzes/:id/questions", can(rbac.PermissionQuizUpdate), c.QuizController.CreateQuestion)
+	admin.Put("/quizzes/:id/questions/:questionId", can(rbac.PermissionQuizUpdate), c.QuizController.UpdateQuestion)
+	admin.Delete("/quizzes/:id/questions/:questionId", can(rbac.PermissionQuizUpdate), c.QuizController.DeleteQuestion)
+	admin.Put("/quizzes/:id/questions/:questionId/answers", can(rbac.PermissionQuizUpdate), c.QuizController.SetAnswerKey)
+	// question options
+	admin.Post("/quizzes/:id/questions/:questionId/options", can(rbac.PermissionQuizUpdate), c.QuizController.CreateOption)
+	admin.Put("/quizzes/:id/questions/:questionId/options/:optionId", can(rbac.PermissionQuizUpdate), c.QuizController.UpdateOption)
+	admin.Delete("/quizzes/:id/questions/:questionId/options/:optionId", can(rbac.PermissionQuizUpdate), c.QuizController.DeleteOption)
+	// question bank, the question handlers address the bank when the route has no quiz id
+	admin.Get("/question-bank", can(rbac.PermissionQuestionBankRead), c.QuizController.ListBank)
+	admin.Get("/question-bank/:questionId", can(rbac.PermissionQuestionBankRead), c.QuizController.GetBankQuestion)
+	admin.Post("/question-bank", can(rbac.PermissionQuestionBankManage), c.QuizController.CreateQuestion)
+	admin.Put("/question-bank/:questionId", can(rbac.PermissionQuestionBankManage), c.QuizController.UpdateQuestion)
+	admin.Delete("/question-bank/:questionId", can(rbac.PermissionQuestionBankManage), c.QuizController.DeleteQuestion)
+	admin.Put("/question-bank/:questionId/answers", can(rbac.PermissionQuestionBankManage), c.QuizController.SetAnswerKey)
+	admin.Post("/question-bank/:questionId/options", can(rbac.PermissionQuestionBankManage), c.QuizController.CreateOption)
+	admin.Put("/question-bank/:questionId/options/:optionId", can(rbac.PermissionQuestionBankManage), c.QuizController.UpdateOption)
+	admin.Delete("/question-bank/:questionId/options/:optionId", can(rbac.PermissionQuestionBankManage), c.QuizController.DeleteOption)
 
 }
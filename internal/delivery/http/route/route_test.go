@@ -0,0 +1,104 @@
+package route
+
+import (
+	"fp-designpattern/internal/delivery/http/middleware"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestImpersonationRoutes runs impersonated requests through the routes. They must be refused
+// before they reach a controller, none is set up, and every one of them must be recorded.
+func TestImpersonationRoutes(t *testing.T) {
+	// A teacher is impersonated, whose role is granted the course routes of the admin area
+	auth := &model.Auth{ID: "u1", Role: rbac.RoleTeacher, SessionID: "s1", ImpersonatorID: "admin"}
+	recorded := 0
+	config := RouteConfig{
+		App: fiber.New(),
+		AuthMiddleware: func(ctx *fiber.Ctx) error {
+			ctx.Locals("auth", auth)
+			return ctx.Next()
+		},
+		ImpersonationAuditMiddleware: func(ctx *fiber.Ctx) error {
+			recorded++
+			return ctx.Next()
+		},
+		Policy:                  rbac.NewPolicy(rbac.DefaultRoles(), []string{rbac.RoleAdmin}),
+		VerifiedEmailMiddleware: middleware.NewPassThrough(),
+		AdminMFAMiddleware:      middleware.NewPassThrough(),
+	}
+	config.Setup()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"password or email change", fiber.MethodPut, "/api/users"},
+		{"two-factor enrollment", fiber.MethodPost, "/api/users/2fa/enroll"},
+		{"two-factor confirmation", fiber.MethodPost, "/api/users/2fa/confirm"},
+		{"two-factor removal", fiber.MethodPost, "/api/users/2fa/disable"},
+		{"api key creation", fiber.MethodPost, "/api/users/api-keys"},
+		{"admin course list", fiber.MethodGet, "/api/admin/courses"},
+		{"admin course update", fiber.MethodPut, "/api/admin/courses/c1"},
+		{"impersonating again", fiber.MethodPost, "/api/admin/users/u2/impersonate"},
+		{"quiz answer", fiber.MethodPut, "/api/quiz-sessions/q1/answers"},
+	}
+	for _, tt := range tests {
+		response, err := config.App.Test(httptest.NewRequest(tt.method, tt.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != fiber.StatusForbidden {
+			t.Errorf("%s: status %d, want 403", tt.name, response.StatusCode)
+		}
+	}
+	if recorded != len(tests) {
+		t.Errorf("recorded %d requests, want %d", recorded, len(tests))
+	}
+}
+
+// TestVerifiedEmailRoutes runs a user with an unverified email through the course routes, the
+// verified email gate must refuse them before they reach a controller.
+func TestVerifiedEmailRoutes(t *testing.T) {
+	auth := &model.Auth{ID: "u1", Role: rbac.RoleUser, SessionID: "s1"}
+	gated := 0
+	config := RouteConfig{
+		App: fiber.New(),
+		AuthMiddleware: func(ctx *fiber.Ctx) error {
+			ctx.Locals("auth", auth)
+			return ctx.Next()
+		},
+		ImpersonationAuditMiddleware: middleware.NewPassThrough(),
+		Policy:                       rbac.NewPolicy(rbac.DefaultRoles(), []string{rbac.RoleAdmin}),
+		VerifiedEmailMiddleware: func(ctx *fiber.Ctx) error {
+			gated++
+			return fiber.NewError(fiber.StatusForbidden, "Verify your email address to access this resource")
+		},
+		AdminMFAMiddleware: middleware.NewPassThrough(),
+	}
+	config.Setup()
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"course list", "/api/courses"},
+		{"course", "/api/courses/c1"},
+	}
+	for _, tt := range tests {
+		response, err := config.App.Test(httptest.NewRequest(fiber.MethodGet, tt.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != fiber.StatusForbidden {
+			t.Errorf("%s: status %d, want 403", tt.name, response.StatusCode)
+		}
+	}
+	if gated != len(tests) {
+		t.Errorf("gate checked %d requests, want %d", gated, len(tests))
+	}
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"fp-designpattern/internal/delivery/http/middleware"
 	"fp-designpattern/internal/model"
 	"fp-designpattern/internal/usecase"
 	"math"
@@ -27,6 +28,7 @@ func (c *SubjectController) Create(ctx *fiber.Ctx) error {
 		c.Log.Warnf("Failed to parse request body: %v", err)
 		return fiber.ErrBadRequest
 	}
+	request.Actor = middleware.GetUser(ctx)
 	subjectResponse, err := c.Usecase.Create(ctx.UserContext(), request)
 	if err != nil {
 		c.Log.Warnf("Failed to create subject: %v", err)
@@ -79,6 +81,7 @@ func (c *SubjectController) Update(ctx *fiber.Ctx) error {
 		c.Log.Warnf("Failed to parse request body: %v", err)
 		return fiber.ErrBadRequest
 	}
+	request.Actor = middleware.GetUser(ctx)
 	subjectResponse, err := c.Usecase.Update(ctx.UserContext(), request)
 	if err != nil {
 		c.Log.Warnf("Failed to update subject: %v", err)
@@ -89,7 +92,8 @@ func (c *SubjectController) Update(ctx *fiber.Ctx) error {
 
 func (c *SubjectController) Delete(ctx *fiber.Ctx) error {
 	request := &model.DeleteSubjectRequest{
-		ID: ctx.Params("id"),
+		ID:    ctx.Params("id"),
+		Actor: middleware.GetUser(ctx),
 	}
 	subjectResponse, err := c.Usecase.Delete(ctx.UserContext(), request)
 	if err != nil {
@@ -98,3 +102,42 @@ func (c *SubjectController) Delete(ctx *fiber.Ctx) error {
 	}
 	return ctx.JSON(model.WebResponse[*model.SubjectResponse]{Data: subjectResponse})
 }
+
+func (c *SubjectController) Trash(ctx *fiber.Ctx) error {
+	request := &model.SearchTrashRequest{
+		Page:  ctx.QueryInt("page"),
+		Size:  ctx.QueryInt("size"),
+		Actor: middleware.GetUser(ctx),
+	}
+
+	responses, total, err := c.Usecase.Trash(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search deleted subjects")
+		return err
+	}
+
+	paging := &model.PageMetadata{
+		Page:      request.Page,
+		Size:      request.Size,
+		TotalItem: total,
+		TotalPage: int64(math.Ceil(float64(total) / float64(request.Size))),
+	}
+
+	return ctx.JSON(model.WebResponse[[]model.SubjectResponse]{
+		Data:   responses,
+		Paging: paging,
+	})
+}
+
+func (c *SubjectController) Restore(ctx *fiber.Ctx) error {
+	request := &model.RestoreSubjectRequest{
+		ID:    ctx.Params("id"),
+		Actor: middleware.GetUser(ctx),
+	}
+	response, err := c.Usecase.Restore(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to restore subject")
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.SubjectResponse]{Data: response})
+}
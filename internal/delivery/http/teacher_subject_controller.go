@@ -0,0 +1,83 @@
+package http
+
+import (
+	"fp-designpattern/internal/delivery/http/middleware"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/usecase"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type TeacherSubjectController struct {
+	Log     *logrus.Logger
+	Usecase *usecase.TeacherSubjectUsecase
+}
+
+func NewTeacherSubjectController(usecase *usecase.TeacherSubjectUsecase, logger *logrus.Logger) *TeacherSubjectController {
+	return &TeacherSubjectController{
+		Log:     logger,
+		Usecase: usecase,
+	}
+}
+
+func (c *TeacherSubjectController) Assign(ctx *fiber.Ctx) error {
+	request := new(model.TeacherSubjectRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.UserID = ctx.Params("id")
+	request.Actor = middleware.GetUser(ctx)
+
+	response, err := c.Usecase.Assign(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to assign subject: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.TeacherSubjectResponse]{Data: response})
+}
+
+func (c *TeacherSubjectController) List(ctx *fiber.Ctx) error {
+	request := &model.ListTeacherSubjectRequest{
+		UserID: ctx.Params("id"),
+	}
+
+	responses, err := c.Usecase.List(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to list subjects: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[[]model.TeacherSubjectResponse]{Data: responses})
+}
+
+// Current lists the subjects assigned to the signed in user.
+func (c *TeacherSubjectController) Current(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := &model.ListTeacherSubjectRequest{
+		UserID: auth.ID,
+	}
+
+	responses, err := c.Usecase.List(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to list subjects: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[[]model.TeacherSubjectResponse]{Data: responses})
+}
+
+func (c *TeacherSubjectController) Unassign(ctx *fiber.Ctx) error {
+	request := &model.DeleteTeacherSubjectRequest{
+		UserID:    ctx.Params("id"),
+		SubjectID: ctx.Params("subjectId"),
+		Actor:     middleware.GetUser(ctx),
+	}
+
+	response, err := c.Usecase.Unassign(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to unassign subject: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"errors"
+	"fp-designpattern/internal/delivery/http/middleware"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/upload"
+	"fp-designpattern/internal/usecase"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// uploadFormFile uploads the "file" form field under the policy of category into the media library.
+func uploadFormFile(ctx *fiber.Ctx, uploadUsecase *usecase.UploadUsecase, category string) (*model.MediaAssetResponse, error) {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return nil, fiber.ErrBadRequest
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fiber.ErrBadRequest
+	}
+	defer file.Close()
+
+	return uploadUsecase.Upload(ctx.UserContext(), &model.UploadFileRequest{
+		Category: category,
+		FileName: fileHeader.Filename,
+		Size:     fileHeader.Size,
+		File:     file,
+		Actor:    middleware.GetUser(ctx),
+	})
+}
+
+// uploadError answers a rejected upload with its 413 or 415 status and what would be accepted,
+// and passes any other error on.
+func uploadError(ctx *fiber.Ctx, err error) error {
+	var rejected *upload.Error
+	if !errors.As(err, &rejected) {
+		return err
+	}
+	return ctx.Status(rejected.Status).JSON(model.WebResponse[*model.UploadErrorResponse]{
+		Data: &model.UploadErrorResponse{
+			Category:    rejected.Category,
+			MaxBytes:    rejected.MaxBytes,
+			MaxPixels:   rejected.MaxPixels,
+			ContentType: rejected.ContentType,
+			Allowed:     rejected.Allowed,
+		},
+		Errors: rejected.Message,
+	})
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"fp-designpattern/internal/delivery/http/middleware"
 	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/upload"
 	"fp-designpattern/internal/usecase"
 	"math"
 	"time"
@@ -12,16 +13,20 @@ import (
 )
 
 type UserController struct {
-	Log           *logrus.Logger
-	UserUsecase   *usecase.UserUseCase
-	CourseUsecase *usecase.CourseUsecase
+	Log                  *logrus.Logger
+	UserUsecase          *usecase.UserUseCase
+	CourseUsecase        *usecase.CourseUsecase
+	PasswordResetUsecase *usecase.PasswordResetUsecase
+	UploadUsecase        *usecase.UploadUsecase
 }
 
-func NewUserController(userUsecase *usecase.UserUseCase, courseUsecase *usecase.CourseUsecase, logger *logrus.Logger) *UserController {
+func NewUserController(userUsecase *usecase.UserUseCase, courseUsecase *usecase.CourseUsecase, passwordResetUsecase *usecase.PasswordResetUsecase, uploadUsecase *usecase.UploadUsecase, logger *logrus.Logger) *UserController {
 	return &UserController{
-		Log:           logger,
-		UserUsecase:   userUsecase,
-		CourseUsecase: courseUsecase,
+		Log:                  logger,
+		UserUsecase:          userUsecase,
+		CourseUsecase:        courseUsecase,
+		PasswordResetUsecase: passwordResetUsecase,
+		UploadUsecase:        uploadUsecase,
 	}
 }
 
@@ -51,6 +56,9 @@ func (c *UserController) Login(ctx *fiber.Ctx) error {
 		return fiber.ErrBadRequest
 	}
 
+	request.IPAddress = ctx.IP()
+	request.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+
 	response, err := c.UserUsecase.Login(ctx.UserContext(), request)
 	if err != nil {
 		c.Log.Warnf("Failed to login user: %v", err)
@@ -59,6 +67,97 @@ func (c *UserController) Login(ctx *fiber.Ctx) error {
 	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
 }
 
+func (c *UserController) LoginTwoFactor(ctx *fiber.Ctx) error {
+	request := new(model.LoginTwoFactorRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.IPAddress = ctx.IP()
+	request.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+
+	response, err := c.UserUsecase.LoginTwoFactor(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to login user with two-factor: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
+}
+
+func (c *UserController) Refresh(ctx *fiber.Ctx) error {
+	request := new(model.RefreshTokenRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.IPAddress = ctx.IP()
+
+	response, err := c.UserUsecase.Refresh(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to refresh token: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
+}
+
+func (c *UserController) VerifyEmail(ctx *fiber.Ctx) error {
+	request := new(model.VerifyEmailRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+
+	response, err := c.UserUsecase.VerifyEmail(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to verify email: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
+
+func (c *UserController) ResendVerification(ctx *fiber.Ctx) error {
+	request := &model.ResendVerificationRequest{
+		UserID: ctx.Params("id"),
+	}
+
+	response, err := c.UserUsecase.ResendVerification(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to resend verification")
+		return err
+	}
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
+
+func (c *UserController) ForgotPassword(ctx *fiber.Ctx) error {
+	request := new(model.ForgotPasswordRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+
+	response, err := c.PasswordResetUsecase.Forgot(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to request password reset: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
+
+func (c *UserController) ResetPassword(ctx *fiber.Ctx) error {
+	request := new(model.ResetPasswordRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+
+	response, err := c.PasswordResetUsecase.Reset(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to reset password: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
+
 func (c *UserController) Current(ctx *fiber.Ctx) error {
 	auth := middleware.GetUser(ctx)
 
@@ -92,7 +191,8 @@ func (c *UserController) Logout(ctx *fiber.Ctx) error {
 	auth := middleware.GetUser(ctx)
 
 	request := &model.LogoutUserRequest{
-		ID: auth.ID,
+		ID:        auth.ID,
+		SessionID: auth.SessionID,
 	}
 
 	response, err := c.UserUsecase.Logout(ctx.UserContext(), request)
@@ -104,6 +204,209 @@ func (c *UserController) Logout(ctx *fiber.Ctx) error {
 	return ctx.JSON(model.WebResponse[bool]{Data: response})
 }
 
+func (c *UserController) Sessions(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := &model.ListUserSessionRequest{
+		UserID:    auth.ID,
+		SessionID: auth.SessionID,
+	}
+
+	responses, err := c.UserUsecase.Sessions(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to list sessions")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[[]model.UserSessionResponse]{Data: responses})
+}
+
+func (c *UserController) RevokeSession(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := &model.RevokeUserSessionRequest{
+		ID:     ctx.Params("id"),
+		UserID: auth.ID,
+	}
+
+	response, err := c.UserUsecase.RevokeSession(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to revoke session")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
+
+func (c *UserController) RevokeAllSessions(ctx *fiber.Ctx) error {
+	request := &model.RevokeAllUserSessionRequest{
+		UserID: ctx.Params("id"),
+		Actor:  middleware.GetUser(ctx),
+	}
+
+	response, err := c.UserUsecase.RevokeAllSessions(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to revoke sessions")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[int64]{Data: response})
+}
+
+func (c *UserController) RevokeAllAPIKeys(ctx *fiber.Ctx) error {
+	request := &model.RevokeAllAPIKeyRequest{
+		UserID: ctx.Params("id"),
+		Actor:  middleware.GetUser(ctx),
+	}
+
+	response, err := c.UserUsecase.RevokeAllAPIKeys(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to revoke api keys")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[int64]{Data: response})
+}
+
+func (c *UserController) CreateAPIKey(ctx *fiber.Ctx) error {
+	request := new(model.CreateAPIKeyRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	auth := middleware.GetUser(ctx)
+	request.UserID = auth.ID
+	request.MFA = auth.MFA
+
+	response, err := c.UserUsecase.CreateAPIKey(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to create api key")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[*model.APIKeyResponse]{Data: response})
+}
+
+func (c *UserController) APIKeys(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := &model.ListAPIKeyRequest{
+		UserID: auth.ID,
+	}
+
+	responses, err := c.UserUsecase.APIKeys(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to list api keys")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[[]model.APIKeyResponse]{Data: responses})
+}
+
+func (c *UserController) RevokeAPIKey(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := &model.RevokeAPIKeyRequest{
+		ID:     ctx.Params("id"),
+		UserID: auth.ID,
+	}
+
+	response, err := c.UserUsecase.RevokeAPIKey(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to revoke api key")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
+
+func (c *UserController) EnrollTwoFactor(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := &model.EnrollTwoFactorRequest{
+		UserID: auth.ID,
+	}
+
+	response, err := c.UserUsecase.EnrollTwoFactor(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to enroll two-factor")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[*model.TwoFactorEnrollResponse]{Data: response})
+}
+
+func (c *UserController) ConfirmTwoFactor(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := new(model.ConfirmTwoFactorRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.UserID = auth.ID
+	request.SessionID = auth.SessionID
+
+	response, err := c.UserUsecase.ConfirmTwoFactor(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to confirm two-factor")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[*model.TwoFactorRecoveryCodesResponse]{Data: response})
+}
+
+func (c *UserController) DisableTwoFactor(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := new(model.DisableTwoFactorRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.UserID = auth.ID
+
+	response, err := c.UserUsecase.DisableTwoFactor(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to disable two-factor")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
+
+func (c *UserController) ResetTwoFactor(ctx *fiber.Ctx) error {
+	request := &model.ResetTwoFactorRequest{
+		UserID: ctx.Params("id"),
+		Actor:  middleware.GetUser(ctx),
+	}
+
+	response, err := c.UserUsecase.ResetTwoFactor(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to reset two-factor")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
+
+func (c *UserController) UnlockLogin(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := &model.UnlockUserRequest{
+		UserID: ctx.Params("id"),
+		Actor:  auth,
+	}
+
+	response, err := c.UserUsecase.UnlockLogin(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to unlock login")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
+
 func (c *UserController) List(ctx *fiber.Ctx) error {
 	var birthDate *time.Time
 	if birthDateStr := ctx.Query("birth_date"); birthDateStr != "" {
@@ -151,12 +454,13 @@ func (c *UserController) Update(ctx *fiber.Ctx) error {
 		ID: auth.ID,
 	}
 	request := new(model.UpdateUserRequest)
-	request.ID = user.ID
 	err := ctx.BodyParser(request)
 	if err != nil {
 		c.Log.Warnf("Failed to parse request body: %v", err)
 		return fiber.ErrBadRequest
 	}
+	// After parsing, so the body cannot name another user
+	request.ID = user.ID
 
 	response, err := c.UserUsecase.Update(ctx.UserContext(), request)
 	if err != nil {
@@ -167,15 +471,35 @@ func (c *UserController) Update(ctx *fiber.Ctx) error {
 	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
 }
 
+func (c *UserController) UploadAvatar(ctx *fiber.Ctx) error {
+	asset, err := uploadFormFile(ctx, c.UploadUsecase, upload.CategoryAvatar)
+	if err != nil {
+		c.Log.Warnf("Failed to upload avatar: %v", err)
+		return uploadError(ctx, err)
+	}
+
+	request := &model.UpdateUserRequest{
+		ID:        middleware.GetUser(ctx).ID,
+		AvatarUrl: asset.URL,
+	}
+	response, err := c.UserUsecase.Update(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to update avatar: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
+}
+
 func (c *UserController) AdminUpdate(ctx *fiber.Ctx) error {
 	userID := ctx.Params("id")
 	request := new(model.UpdateUserRequest)
-	request.ID = userID
 	err := ctx.BodyParser(request)
 	if err != nil {
 		c.Log.Warnf("Failed to parse request body: %v", err)
 		return fiber.ErrBadRequest
 	}
+	request.ID = userID
+	request.Actor = middleware.GetUser(ctx)
 
 	response, err := c.UserUsecase.Update(ctx.UserContext(), request)
 	if err != nil {
@@ -186,9 +510,49 @@ func (c *UserController) AdminUpdate(ctx *fiber.Ctx) error {
 	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
 }
 
+func (c *UserController) Impersonate(ctx *fiber.Ctx) error {
+	request := new(model.ImpersonateUserRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	auth := middleware.GetUser(ctx)
+	request.UserID = ctx.Params("id")
+	request.Actor = auth
+	request.IPAddress = ctx.IP()
+	request.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+
+	response, err := c.UserUsecase.Impersonate(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to impersonate user")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
+}
+
+func (c *UserController) SetRole(ctx *fiber.Ctx) error {
+	request := new(model.UpdateUserRoleRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return fiber.ErrBadRequest
+	}
+	request.ID = ctx.Params("id")
+	request.Actor = middleware.GetUser(ctx)
+
+	response, err := c.UserUsecase.SetRole(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to set role: %v", err)
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
+}
+
 func (c *UserController) Delete(ctx *fiber.Ctx) error {
 	request := &model.DeleteUserRequest{
-		ID: ctx.Params("id"),
+		ID:    ctx.Params("id"),
+		Actor: middleware.GetUser(ctx),
 	}
 	response, err := c.UserUsecase.Delete(ctx.UserContext(), request)
 	if err != nil {
@@ -198,3 +562,185 @@ func (c *UserController) Delete(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
 }
+
+func (c *UserController) Trash(ctx *fiber.Ctx) error {
+	request := &model.SearchTrashRequest{
+		Page:  ctx.QueryInt("page"),
+		Size:  ctx.QueryInt("size"),
+		Actor: middleware.GetUser(ctx),
+	}
+
+	responses, total, err := c.UserUsecase.Trash(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search deleted users")
+		return err
+	}
+
+	paging := &model.PageMetadata{
+		Page:      request.Page,
+		Size:      request.Size,
+		TotalItem: total,
+		TotalPage: int64(math.Ceil(float64(total) / float64(request.Size))),
+	}
+
+	return ctx.JSON(model.WebResponse[[]model.UserResponse]{
+		Data:   responses,
+		Paging: paging,
+	})
+}
+
+func (c *UserController) Restore(ctx *fiber.Ctx) error {
+	request := &model.RestoreUserRequest{
+		ID:    ctx.Params("id"),
+		Actor: middleware.GetUser(ctx),
+	}
+	response, err := c.UserUsecase.Restore(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to restore user")
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
+}
+
+// identityFlowCookie keeps the signed flow of an OpenID Connect login, it binds the callback to the
+// browser that started the login.
+const identityFlowCookie = "oidc_flow"
+
+func (c *UserController) setIdentityFlow(ctx *fiber.Ctx, flow string) {
+	ctx.Cookie(&fiber.Cookie{
+		Name:     identityFlowCookie,
+		Value:    flow,
+		Path:     "/api",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		Secure:   ctx.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// clearIdentityFlow expires the flow cookie. It goes out with the path it was set at, the browser
+// would keep that cookie otherwise, and with a past expiry, as a negative MaxAge alone is not sent.
+func (c *UserController) clearIdentityFlow(ctx *fiber.Ctx) {
+	ctx.Cookie(&fiber.Cookie{
+		Name:     identityFlowCookie,
+		Path:     "/api",
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+		Secure:   ctx.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func (c *UserController) identityCallbackRequest(ctx *fiber.Ctx) (*model.IdentityCallbackRequest, error) {
+	request := new(model.IdentityCallbackRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		c.Log.Warnf("Failed to parse request body: %v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	request.Provider = ctx.Params("provider")
+	request.Flow = ctx.Cookies(identityFlowCookie)
+	request.IPAddress = ctx.IP()
+	request.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+	// The flow is single use, whatever the outcome
+	c.clearIdentityFlow(ctx)
+	return request, nil
+}
+
+func (c *UserController) StartIdentityLogin(ctx *fiber.Ctx) error {
+	request := &model.StartIdentityRequest{
+		Provider: ctx.Params("provider"),
+	}
+
+	response, err := c.UserUsecase.StartIdentity(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to start identity login: %v", err)
+		return err
+	}
+	c.setIdentityFlow(ctx, response.Flow)
+
+	return ctx.JSON(model.WebResponse[*model.IdentityAuthorizationResponse]{Data: response})
+}
+
+func (c *UserController) IdentityLogin(ctx *fiber.Ctx) error {
+	request, err := c.identityCallbackRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.UserUsecase.IdentityLogin(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.Warnf("Failed to login user with identity: %v", err)
+		return err
+	}
+	return ctx.JSON(model.WebResponse[*model.UserResponse]{Data: response})
+}
+
+func (c *UserController) Identities(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := &model.ListUserIdentityRequest{
+		UserID: auth.ID,
+	}
+
+	responses, err := c.UserUsecase.Identities(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to list identities")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[[]model.UserIdentityResponse]{Data: responses})
+}
+
+func (c *UserController) StartIdentityLink(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := &model.StartIdentityRequest{
+		Provider:   ctx.Params("provider"),
+		LinkUserID: auth.ID,
+	}
+
+	response, err := c.UserUsecase.StartIdentity(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to start identity link")
+		return err
+	}
+	c.setIdentityFlow(ctx, response.Flow)
+
+	return ctx.JSON(model.WebResponse[*model.IdentityAuthorizationResponse]{Data: response})
+}
+
+func (c *UserController) LinkIdentity(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request, err := c.identityCallbackRequest(ctx)
+	if err != nil {
+		return err
+	}
+	request.UserID = auth.ID
+
+	response, err := c.UserUsecase.LinkIdentity(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to link identity")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[*model.UserIdentityResponse]{Data: response})
+}
+
+func (c *UserController) UnlinkIdentity(ctx *fiber.Ctx) error {
+	auth := middleware.GetUser(ctx)
+
+	request := &model.UnlinkUserIdentityRequest{
+		ID:     ctx.Params("id"),
+		UserID: auth.ID,
+	}
+
+	response, err := c.UserUsecase.UnlinkIdentity(ctx.UserContext(), request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to unlink identity")
+		return err
+	}
+
+	return ctx.JSON(model.WebResponse[bool]{Data: response})
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestIdentityFlowCookie checks that the flow cookie is cleared at the path it was set at, a
+// cookie of another path is a cookie of its own and the flow could be replayed.
+func TestIdentityFlowCookie(t *testing.T) {
+	c := new(UserController)
+	app := fiber.New()
+	app.Get("/api/auth/oidc/test", func(ctx *fiber.Ctx) error {
+		c.setIdentityFlow(ctx, "flow")
+		return nil
+	})
+	app.Post("/api/auth/oidc/test/callback", func(ctx *fiber.Ctx) error {
+		c.clearIdentityFlow(ctx)
+		return nil
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		want   []string
+	}{
+		{fiber.MethodGet, "/api/auth/oidc/test", []string{"oidc_flow=flow", "max-age=600", "path=/api"}},
+		{fiber.MethodPost, "/api/auth/oidc/test/callback", []string{"oidc_flow=;", "expires=Thu, 01 Jan 1970 00:00:00 GMT", "path=/api"}},
+	}
+	for _, tt := range tests {
+		response, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cookie := response.Header.Get(fiber.HeaderSetCookie)
+		for _, want := range tt.want {
+			if !strings.Contains(cookie, want) {
+				t.Errorf("%s %s set cookie %q, want %q in it", tt.method, tt.path, cookie, want)
+			}
+		}
+	}
+}
@@ -40,6 +40,7 @@ func (c *UserCourseController) Create(ctx *fiber.Ctx) error {
 		c.Log.Warnf("Failed to parse request body: %v", err)
 		return fiber.ErrBadRequest
 	}
+	request.Actor = middleware.GetUser(ctx)
 	userCourseRepsonse, err := c.Usecase.Create(ctx.UserContext(), request)
 	if err != nil {
 		c.Log.Warnf("Failed to create subject: %v", err)
@@ -55,6 +56,7 @@ func (c *UserCourseController) List(ctx *fiber.Ctx) error {
 		CourseID: ctx.Query("course_id"),
 		Page:     ctx.QueryInt("page"),
 		Size:     ctx.QueryInt("size"),
+		Actor:    middleware.GetUser(ctx),
 	}
 
 	responses, total, err := c.Usecase.Search(ctx.UserContext(), request)
@@ -111,7 +113,8 @@ func (c *UserCourseController) ListAccessable(ctx *fiber.Ctx) error {
 
 func (c *UserCourseController) Delete(ctx *fiber.Ctx) error {
 	request := &model.DeleteUserCourseRequest{
-		ID: ctx.Params("id"),
+		ID:    ctx.Params("id"),
+		Actor: middleware.GetUser(ctx),
 	}
 	userCourseResponse, err := c.Usecase.Delete(ctx.UserContext(), request)
 	if err != nil {
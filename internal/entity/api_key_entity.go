@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// APIKey is a long lived credential for scripts, it stores the hash of the key and acts as its
+// owner, limited to the scopes it was created with.
+type APIKey struct {
+	ID     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID uuid.UUID `gorm:"column:user_id;not null;type:uuid"`
+	Name   string    `gorm:"column:name;not null"`
+	// Prefix is the start of the key, shown so the owner can tell their keys apart.
+	Prefix  string                      `gorm:"column:prefix;not null"`
+	KeyHash string                      `gorm:"column:key_hash;not null"`
+	Scopes  datatypes.JSONSlice[string] `gorm:"column:scopes;type:jsonb;not null"`
+	// MFAVerified is copied from the session the key was created in.
+	MFAVerified bool       `gorm:"column:mfa_verified"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at;not null"`
+	LastUsedAt  *time.Time `gorm:"column:last_used_at"`
+	RevokedAt   *time.Time `gorm:"column:revoked_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at;"`
+	//Foreign Key
+	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
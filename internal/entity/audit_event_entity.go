@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// AuditEvent is an administrative change. Before and After hold the fields the change touched,
+// Before is empty for a created resource and After for a deleted one.
+type AuditEvent struct {
+	ID           uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ActorID      *uuid.UUID     `gorm:"column:actor_id;type:uuid"`
+	Action       string         `gorm:"column:action;not null"`
+	ResourceType string         `gorm:"column:resource_type;not null"`
+	ResourceID   string         `gorm:"column:resource_id;not null"`
+	Before       datatypes.JSON `gorm:"column:before;type:jsonb"`
+	After        datatypes.JSON `gorm:"column:after;type:jsonb"`
+	IPAddress    string         `gorm:"column:ip_address;"`
+	CreatedAt    time.Time      `gorm:"column:created_at;"`
+	//Foreign Key
+	Actor *User `gorm:"foreignKey:ActorID;references:ID;constraint:OnDelete:SET NULL"`
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type Course struct {
@@ -15,6 +16,8 @@ type Course struct {
 	CreatedAt  time.Time      `gorm:"column:created_at;default:now()"`
 	UpdatedAt  time.Time      `gorm:"column:updated_at;default:now()"`
 	SubjectID  uuid.UUID      `gorm:"column:subject_id;not null;type:uuid"`
+	// DeletedAt is set while the course is in the trash, queries leave such courses out.
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at"`
 	//Foreign Key
 	Subject Subject `gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE"`
 }
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImpersonationRequest records a request an admin made while impersonating a user.
+type ImpersonationRequest struct {
+	ID             uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	SessionID      *uuid.UUID `gorm:"column:session_id;type:uuid"`
+	ImpersonatorID *uuid.UUID `gorm:"column:impersonator_id;type:uuid"`
+	UserID         *uuid.UUID `gorm:"column:user_id;type:uuid"`
+	Method         string     `gorm:"column:method;not null"`
+	Path           string     `gorm:"column:path;not null"`
+	Status         int        `gorm:"column:status;not null"`
+	IPAddress      string     `gorm:"column:ip_address;"`
+	CreatedAt      time.Time  `gorm:"column:created_at;"`
+}
@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginLockoutEvent records a lockout starting, or an admin, the actor, lifting one.
+type LoginLockoutEvent struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID      *uuid.UUID `gorm:"column:user_id;type:uuid"`
+	ActorID     *uuid.UUID `gorm:"column:actor_id;type:uuid"`
+	Event       string     `gorm:"column:event;"`
+	Scope       string     `gorm:"column:scope;"`
+	Key         string     `gorm:"column:key;"`
+	Failures    int        `gorm:"column:failures;"`
+	LockedUntil *time.Time `gorm:"column:locked_until"`
+	IPAddress   string     `gorm:"column:ip_address;"`
+	CreatedAt   time.Time  `gorm:"column:created_at;"`
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// LoginThrottle counts the consecutive failed logins of an account, keyed by email, or of an IP address.
+type LoginThrottle struct {
+	Scope         string     `gorm:"column:scope;primaryKey"`
+	Key           string     `gorm:"column:key;primaryKey"`
+	Failures      int        `gorm:"column:failures;"`
+	LastFailureAt time.Time  `gorm:"column:last_failure_at;"`
+	LockedUntil   *time.Time `gorm:"column:locked_until"`
+}
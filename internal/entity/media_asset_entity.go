@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// MediaAsset is an uploaded file, stored once under the SHA-256 Hash of its content however often
+// it is uploaded. ReferenceCount is how many courses and avatars use its URL. Variants maps the
+// names of the scaled down copies of an image to their URLs.
+type MediaAsset struct {
+	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Hash           string         `gorm:"column:hash;not null;unique"`
+	OwnerID        *uuid.UUID     `gorm:"column:owner_id;type:uuid"`
+	Category       string         `gorm:"column:category;not null"`
+	FileName       string         `gorm:"column:file_name;not null"`
+	URL            string         `gorm:"column:url;not null"`
+	ContentType    string         `gorm:"column:content_type;not null"`
+	Size           int64          `gorm:"column:size;not null"`
+	Width          int            `gorm:"column:width;not null"`
+	Height         int            `gorm:"column:height;not null"`
+	ReferenceCount int            `gorm:"column:reference_count;not null"`
+	Variants       datatypes.JSON `gorm:"column:variants;type:jsonb;not null"`
+	CreatedAt      time.Time      `gorm:"column:created_at;default:now()"`
+	UpdatedAt      time.Time      `gorm:"column:updated_at;default:now()"`
+	//Foreign Key
+	Owner *User `gorm:"foreignKey:OwnerID;references:ID;constraint:OnDelete:SET NULL"`
+}
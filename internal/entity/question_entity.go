@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+const (
+	QuestionTypeSingleChoice   = "single_choice"
+	QuestionTypeMultipleSelect = "multiple_select"
+	QuestionTypeTrueFalse      = "true_false"
+	QuestionTypeShortAnswer    = "short_answer"
+	QuestionTypeNumeric        = "numeric"
+	QuestionTypeOrdering       = "ordering"
+	QuestionTypeMatching       = "matching"
+)
+
+type Question struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Type      string         `gorm:"column:type;not null;default:single_choice"`
+	Content   datatypes.JSON `gorm:"column:content;type:jsonb;not null"`
+	AnswerKey datatypes.JSON `gorm:"column:answer_key;type:jsonb"`
+	// Questions of the question bank have no quiz, they are tagged by subject and grade level instead
+	QuizID     *uuid.UUID `gorm:"column:quiz_id;type:uuid"`
+	SubjectID  *uuid.UUID `gorm:"column:subject_id;type:uuid"`
+	GradeLevel *int       `gorm:"column:grade_level"`
+	//Foreign Key
+	Quiz    *Quiz            `gorm:"foreignKey:QuizID;references:ID;constraint:OnDelete:CASCADE"`
+	Subject *Subject         `gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE"`
+	Options []QuestionOption `gorm:"foreignKey:QuestionID;references:ID"`
+}
+
+// IsChoice reports whether the question is answered by picking options recorded in quiz_answers.
+func (q *Question) IsChoice() bool {
+	switch q.Type {
+	case QuestionTypeSingleChoice, QuestionTypeMultipleSelect, QuestionTypeTrueFalse, "":
+		return true
+	}
+	return false
+}
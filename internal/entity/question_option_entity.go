@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+type QuestionOption struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Option     string    `gorm:"column:option;not null"`
+	Position   *int      `gorm:"column:position"`
+	MatchValue *string   `gorm:"column:match_value"`
+	QuestionID uuid.UUID `gorm:"column:question_id;not null;type:uuid"`
+	//Foreign Key
+	Question Question `gorm:"foreignKey:QuestionID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (QuestionOption) TableName() string {
+	return "questions_options"
+}
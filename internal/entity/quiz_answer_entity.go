@@ -0,0 +1,11 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+type QuizAnswer struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	QuestionID uuid.UUID `gorm:"column:question_id;not null;type:uuid"`
+	OptionID   uuid.UUID `gorm:"column:option_id;not null;type:uuid"`
+}
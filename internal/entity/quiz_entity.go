@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ShowAnswersAfterSubmit     = "after_submit"
+	ShowAnswersAfterRevealDate = "after_reveal_date"
+	ShowAnswersNever           = "never"
+)
+
+type Quiz struct {
+	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	QuizName        string     `gorm:"column:quiz_name;not null"`
+	TimeLimit       int        `gorm:"column:time_limit;not null"`
+	ShowAnswers     string     `gorm:"column:show_answers;not null;default:after_submit"`
+	AnswersRevealAt *time.Time `gorm:"column:answers_reveal_at"`
+	DrawCount       int        `gorm:"column:draw_count;not null;default:0"`
+	BankSubjectID   *uuid.UUID `gorm:"column:bank_subject_id;type:uuid"`
+	BankGradeLevel  *int       `gorm:"column:bank_grade_level"`
+	Shuffle         bool       `gorm:"column:shuffle;not null;default:false"`
+	CreatedAt       time.Time  `gorm:"column:created_at;default:now()"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at;default:now()"`
+	CourseID        uuid.UUID  `gorm:"column:course_id;not null;type:uuid"`
+	//Foreign Key
+	Course    Course     `gorm:"foreignKey:CourseID;references:ID;constraint:OnDelete:CASCADE"`
+	Questions []Question `gorm:"foreignKey:QuizID;references:ID"`
+}
+
+func (Quiz) TableName() string {
+	return "quizzes"
+}
+
+// AnswersVisible reports whether students may see the correct options of a submitted attempt.
+func (q *Quiz) AnswersVisible(now time.Time) bool {
+	switch q.ShowAnswers {
+	case ShowAnswersNever:
+		return false
+	case ShowAnswersAfterRevealDate:
+		return q.AnswersRevealAt != nil && !now.Before(*q.AnswersRevealAt)
+	default:
+		return true
+	}
+}
+
+// DrawsFromBank reports whether every session draws DrawCount questions from the question bank
+// instead of using the questions of the quiz.
+func (q *Quiz) DrawsFromBank() bool {
+	return q.DrawCount > 0 && q.BankSubjectID != nil
+}
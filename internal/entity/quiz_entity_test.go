@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnswersVisible(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	tests := []struct {
+		name            string
+		showAnswers     string
+		answersRevealAt *time.Time
+		want            bool
+	}{
+		{"after submit", ShowAnswersAfterSubmit, nil, true},
+		{"default of quizzes stored before the policy", "", nil, true},
+		{"never", ShowAnswersNever, nil, false},
+		{"never ignores the reveal date", ShowAnswersNever, &past, false},
+		{"before the reveal date", ShowAnswersAfterRevealDate, &future, false},
+		{"at the reveal date", ShowAnswersAfterRevealDate, &now, true},
+		{"after the reveal date", ShowAnswersAfterRevealDate, &past, true},
+		{"reveal date missing", ShowAnswersAfterRevealDate, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quiz := &Quiz{ShowAnswers: tt.showAnswers, AnswersRevealAt: tt.answersRevealAt}
+			if got := quiz.AnswersVisible(now); got != tt.want {
+				t.Errorf("AnswersVisible() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken stores the hash of an issued refresh token. Every refresh revokes the token and issues
+// the next one for the same session, so presenting a revoked token again means it leaked and the
+// session is revoked.
+type RefreshToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    uuid.UUID  `gorm:"column:user_id;not null;type:uuid"`
+	SessionID uuid.UUID  `gorm:"column:session_id;not null;type:uuid"`
+	TokenHash string     `gorm:"column:token_hash;not null"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;"`
+	//Foreign Key
+	User    User        `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Session UserSession `gorm:"foreignKey:SessionID;references:ID;constraint:OnDelete:CASCADE"`
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Subject struct {
@@ -11,4 +12,6 @@ type Subject struct {
 	SubjectName string    `gorm:"column:subject_name;"`
 	CreatedAt   time.Time `gorm:"column:created_at;"`
 	UpdatedAt   time.Time `gorm:"column:updated_at;"`
+	// DeletedAt is set while the subject is in the trash, queries leave such subjects out.
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at"`
 }
@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TeacherSubject assigns a subject to a user whose role is scoped, such as a teacher, who may then
+// manage its courses and quizzes.
+type TeacherSubject struct {
+	UserID    uuid.UUID `gorm:"column:user_id;type:uuid;primaryKey"`
+	SubjectID uuid.UUID `gorm:"column:subject_id;type:uuid;primaryKey"`
+	CreatedAt time.Time `gorm:"column:created_at;"`
+	//Foreign Key
+	Subject Subject `gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,13 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+type UserAnswer struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	SessionID  uuid.UUID  `gorm:"column:session_id;not null;type:uuid"`
+	QuestionID uuid.UUID  `gorm:"column:question_id;not null;type:uuid"`
+	OptionID   *uuid.UUID `gorm:"column:option_id;type:uuid"`
+	Value      *string    `gorm:"column:value"`
+}
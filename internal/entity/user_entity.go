@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type User struct {
@@ -16,7 +17,19 @@ type User struct {
 	Role        string    `gorm:"column:role;"`
 	AvatarUrl   string    `gorm:"column:avatar_url;"`
 	BirthDate   time.Time `gorm:"column:birth_date;type:date"`
-	Token       string    `gorm:"column:token"`
-	CreatedAt   time.Time `gorm:"column:created_at;"`
-	UpdatedAt   time.Time `gorm:"column:updated_at;"`
+	// EmailVerifiedAt is nil until the user opens the verification link sent to Email.
+	EmailVerifiedAt *time.Time `gorm:"column:email_verified_at"`
+	// TOTPSecret is set on enrollment, two-factor authentication is on once TOTPEnabledAt is set.
+	// TOTPLastStep is the time step of the last accepted code, so a code cannot be replayed.
+	TOTPSecret    string     `gorm:"column:totp_secret"`
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at"`
+	TOTPLastStep  int64      `gorm:"column:totp_last_step"`
+	// MFAChallengeID is the id of the challenge Login last issued, empty once it was exchanged or
+	// MFAChallengeAttempts reached the limit.
+	MFAChallengeID       string    `gorm:"column:mfa_challenge_id"`
+	MFAChallengeAttempts int       `gorm:"column:mfa_challenge_attempts"`
+	CreatedAt            time.Time `gorm:"column:created_at;"`
+	UpdatedAt            time.Time `gorm:"column:updated_at;"`
+	// DeletedAt is set while the user is in the trash, queries leave such users out.
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at"`
 }
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links the user to an account at an external OpenID Connect provider.
+type UserIdentity struct {
+	ID       uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID   uuid.UUID `gorm:"column:user_id;not null;type:uuid"`
+	Provider string    `gorm:"column:provider;not null"`
+	// Subject is the stable id the provider gives the account, Email what it reported at the last sign in.
+	Subject   string    `gorm:"column:subject;not null"`
+	Email     string    `gorm:"column:email;"`
+	CreatedAt time.Time `gorm:"column:created_at;"`
+	//Foreign Key
+	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserQuizSession struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	StartedAt     time.Time  `gorm:"column:started_at;default:now()"`
+	EndedAt       *time.Time `gorm:"column:ended_at"`
+	Submitted     bool       `gorm:"column:submitted"`
+	AutoSubmitted bool       `gorm:"column:auto_submitted"`
+	Score         *int       `gorm:"column:score"`
+	CreatedAt     time.Time  `gorm:"column:created_at;default:now()"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at;default:now()"`
+	UserID        uuid.UUID  `gorm:"column:user_id;not null;type:uuid"`
+	QuizID        uuid.UUID  `gorm:"column:quiz_id;not null;type:uuid"`
+	//Foreign Key
+	User    User         `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Quiz    Quiz         `gorm:"foreignKey:QuizID;references:ID;constraint:OnDelete:CASCADE"`
+	Answers []UserAnswer `gorm:"foreignKey:SessionID;references:ID"`
+}
+
+// Deadline is the moment the session runs out of time, quizzes.time_limit is in minutes.
+func (s *UserQuizSession) Deadline(timeLimit int) time.Time {
+	return s.StartedAt.Add(time.Duration(timeLimit) * time.Minute)
+}
@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// UserQuizSessionQuestion is one question drawn for a session, in the order and with the option
+// order the student sees, so grading and review keep using the same set.
+type UserQuizSessionQuestion struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	SessionID   uuid.UUID      `gorm:"column:session_id;not null;type:uuid"`
+	QuestionID  uuid.UUID      `gorm:"column:question_id;not null;type:uuid"`
+	Position    int            `gorm:"column:position;not null"`
+	OptionOrder datatypes.JSON `gorm:"column:option_order;type:jsonb;not null"`
+	//Foreign Key
+	Session  UserQuizSession `gorm:"foreignKey:SessionID;references:ID;constraint:OnDelete:CASCADE"`
+	Question Question        `gorm:"foreignKey:QuestionID;references:ID;constraint:OnDelete:CASCADE"`
+}
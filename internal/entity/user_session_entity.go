@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserSession is a signed in device, its refresh tokens and access tokens carry its id.
+type UserSession struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID     uuid.UUID  `gorm:"column:user_id;not null;type:uuid"`
+	Device     string     `gorm:"column:device;"`
+	IPAddress  string     `gorm:"column:ip_address;"`
+	UserAgent  string     `gorm:"column:user_agent;"`
+	CreatedAt  time.Time  `gorm:"column:created_at;"`
+	LastSeenAt time.Time  `gorm:"column:last_seen_at;"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+	// MFAVerified is set when the session was signed in with a second factor.
+	MFAVerified bool `gorm:"column:mfa_verified"`
+	// ImpersonatorID is the admin the session was issued to when it impersonates the user.
+	ImpersonatorID      *uuid.UUID `gorm:"column:impersonator_id;type:uuid"`
+	ImpersonationReason string     `gorm:"column:impersonation_reason"`
+	//Foreign Key
+	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,167 @@
+// Package identity signs users in with external OpenID Connect providers using the authorization
+// code flow with PKCE.
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var ErrUnknownProvider = errors.New("unknown identity provider")
+
+type Config struct {
+	Name         string   `mapstructure:"name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+// Claims are the claims of a verified ID token that accounts are matched on.
+type Claims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// Provider is one configured identity provider. Its discovery document is fetched on first use,
+// so a provider that is down at startup does not keep the application from starting.
+type Provider struct {
+	Config Config
+
+	mutex    sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewProvider(config Config) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"profile", "email"}
+	}
+	return &Provider{Config: config}
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, p.Config.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("discover %s: %w", p.Config.Name, err)
+	}
+	scopes := []string{oidc.ScopeOpenID}
+	for _, scope := range p.Config.Scopes {
+		if scope != oidc.ScopeOpenID {
+			scopes = append(scopes, scope)
+		}
+	}
+	p.oauth = &oauth2.Config{
+		ClientID:     p.Config.ClientID,
+		ClientSecret: p.Config.ClientSecret,
+		RedirectURL:  p.Config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.Config.ClientID})
+	return p.oauth, p.verifier, nil
+}
+
+// Flow is what has to be kept between sending the user to the provider and the callback.
+type Flow struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewFlow generates a random state, nonce and PKCE verifier.
+func NewFlow() *Flow {
+	return &Flow{
+		State:    oauth2.GenerateVerifier(),
+		Nonce:    oauth2.GenerateVerifier(),
+		Verifier: oauth2.GenerateVerifier(),
+	}
+}
+
+// AuthCodeURL is where the user is sent to sign in with the provider.
+func (p *Provider) AuthCodeURL(ctx context.Context, flow *Flow) (string, error) {
+	config, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return config.AuthCodeURL(flow.State, oidc.Nonce(flow.Nonce), oauth2.S256ChallengeOption(flow.Verifier)), nil
+}
+
+// Exchange redeems the code from the callback and verifies the ID token it returns against the flow.
+func (p *Provider) Exchange(ctx context.Context, flow *Flow, code string) (*Claims, error) {
+	config, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchange code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify id_token: %w", err)
+	}
+	if idToken.Nonce != flow.Nonce {
+		return nil, errors.New("id_token nonce does not match")
+	}
+
+	claims := new(Claims)
+	if err := idToken.Claims(claims); err != nil {
+		return nil, fmt.Errorf("read id_token claims: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("id_token has no subject")
+	}
+	return claims, nil
+}
+
+// Registry holds the configured providers by name.
+type Registry struct {
+	// AutoProvision creates an account on the first sign in with a verified email that has none,
+	// with DefaultRole and DefaultGradeLevel.
+	AutoProvision     bool
+	DefaultRole       string
+	DefaultGradeLevel int
+	providers         map[string]*Provider
+}
+
+func NewRegistry(configs []Config) (*Registry, error) {
+	registry := &Registry{providers: make(map[string]*Provider, len(configs))}
+	for _, config := range configs {
+		if config.Name == "" || config.Issuer == "" || config.ClientID == "" {
+			return nil, errors.New("identity provider needs a name, issuer and client_id")
+		}
+		if _, ok := registry.providers[config.Name]; ok {
+			return nil, fmt.Errorf("duplicate identity provider %q", config.Name)
+		}
+		registry.providers[config.Name] = NewProvider(config)
+	}
+	return registry, nil
+}
+
+func (r *Registry) Get(name string) (*Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
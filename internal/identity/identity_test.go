@@ -0,0 +1,289 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const (
+	testClientID     = "school-portal"
+	testClientSecret = "client-secret"
+	testRedirectURL  = "https://app.example/oidc/callback"
+)
+
+type mockGrant struct {
+	challenge string
+	nonce     string
+	claims    map[string]any
+}
+
+// mockProvider is an in-process OpenID provider serving discovery, keys, authorization and tokens.
+type mockProvider struct {
+	server *httptest.Server
+	signer jose.Signer
+	keys   jose.JSONWebKeySet
+
+	mutex  sync.Mutex
+	codes  map[string]mockGrant
+	claims map[string]any
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: "test-key"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockProvider{
+		signer: signer,
+		keys:   jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "test-key", Algorithm: "RS256", Use: "sig"}}},
+		codes:  make(map[string]mockGrant),
+		claims: map[string]any{"sub": "student-1", "email": "student@school.example", "email_verified": true, "name": "Student One"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", mock.discovery)
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, mock.keys)
+	})
+	mux.HandleFunc("/authorize", mock.authorize)
+	mux.HandleFunc("/token", mock.token)
+	mock.server = httptest.NewServer(mux)
+	t.Cleanup(mock.server.Close)
+	return mock
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(Config{
+		Name:         "school",
+		Issuer:       m.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+	})
+}
+
+func (m *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                m.server.URL,
+		"authorization_endpoint":                m.server.URL + "/authorize",
+		"token_endpoint":                        m.server.URL + "/token",
+		"jwks_uri":                              m.server.URL + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize signs the user in straight away and redirects back with a code bound to the PKCE challenge.
+func (m *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != testClientID || query.Get("response_type") != "code" || query.Get("redirect_uri") != testRedirectURL {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "pkce required", http.StatusBadRequest)
+		return
+	}
+
+	m.mutex.Lock()
+	code := rand.Text()
+	claims := make(map[string]any, len(m.claims))
+	for name, value := range m.claims {
+		claims[name] = value
+	}
+	m.codes[code] = mockGrant{challenge: query.Get("code_challenge"), nonce: query.Get("nonce"), claims: claims}
+	m.mutex.Unlock()
+
+	redirect, _ := url.Parse(testRedirectURL)
+	redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != testClientID || clientSecret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mutex.Lock()
+	grant, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mutex.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":   m.server.URL,
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Minute).Unix(),
+		"nonce": grant.nonce,
+	}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	payload, _ := json.Marshal(claims)
+	signed, err := m.signer.Sign(payload)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	idToken, _ := signed.CompactSerialize()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// signIn follows the authorization URL like a browser would and returns the code from the callback.
+func signIn(t *testing.T, provider *Provider, flow *Flow) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), flow)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	response, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d", response.StatusCode)
+	}
+	callback, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := callback.Query().Get("state"); got != flow.State {
+		t.Fatalf("callback state = %q, want %q", got, flow.State)
+	}
+	return callback.Query().Get("code")
+}
+
+func TestExchange(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := mock.provider()
+	flow := NewFlow()
+
+	claims, err := provider.Exchange(context.Background(), flow, signIn(t, provider, flow))
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "student-1" || claims.Email != "student@school.example" || !claims.EmailVerified || claims.Name != "Student One" {
+		t.Fatalf("claims = %+v", claims)
+	}
+}
+
+func TestExchangeUnverifiedEmail(t *testing.T) {
+	mock := newMockProvider(t)
+	mock.claims["email_verified"] = false
+	provider := mock.provider()
+	flow := NewFlow()
+
+	claims, err := provider.Exchange(context.Background(), flow, signIn(t, provider, flow))
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.EmailVerified {
+		t.Fatal("email reported as verified")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := mock.provider()
+	flow := NewFlow()
+	code := signIn(t, provider, flow)
+
+	flow.Verifier = NewFlow().Verifier
+	if _, err := provider.Exchange(context.Background(), flow, code); err == nil {
+		t.Fatal("Exchange accepted a code with the wrong PKCE verifier")
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := mock.provider()
+	flow := NewFlow()
+	code := signIn(t, provider, flow)
+
+	flow.Nonce = NewFlow().Nonce
+	if _, err := provider.Exchange(context.Background(), flow, code); err == nil {
+		t.Fatal("Exchange accepted an id_token with another nonce")
+	}
+}
+
+func TestExchangeRejectsReusedCode(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := mock.provider()
+	flow := NewFlow()
+	code := signIn(t, provider, flow)
+
+	if _, err := provider.Exchange(context.Background(), flow, code); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := provider.Exchange(context.Background(), flow, code); err == nil {
+		t.Fatal("Exchange accepted a code twice")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry, err := NewRegistry([]Config{{Name: "school", Issuer: "https://idp.example", ClientID: "app"}})
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	if _, err := registry.Get("school"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, err := registry.Get("other"); !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("Get unknown = %v, want ErrUnknownProvider", err)
+	}
+
+	if _, err := NewRegistry([]Config{{Name: "school", Issuer: "https://idp.example", ClientID: "app"}, {Name: "school", Issuer: "https://idp.example", ClientID: "app"}}); err == nil {
+		t.Fatal("NewRegistry accepted duplicate providers")
+	}
+	if _, err := NewRegistry([]Config{{Name: "school"}}); err == nil {
+		t.Fatal("NewRegistry accepted a provider without issuer")
+	}
+}
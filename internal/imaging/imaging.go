@@ -0,0 +1,104 @@
+// Package imaging strips the metadata of uploaded images and renders their size variants.
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+)
+
+// JPEGQuality is the quality re-encoded and resized JPEGs are written with.
+const JPEGQuality = 85
+
+// Orient turns an image decoded as stored into how its EXIF orientation says it is viewed. The
+// pixels are copied as bytes between RGBA images, a decoded JPEG is converted to one in one pass.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		src = image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(src, src.Rect, img, bounds.Min, draw.Src)
+	}
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < h; y++ {
+		row := src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y)
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[row+4*x:][:4])
+		}
+	}
+	return dst
+}
+
+// Resize scales an image down to width, keeping its aspect ratio. Images no wider are returned as
+// they are, with false.
+func Resize(img image.Image, width int) (image.Image, bool) {
+	bounds := img.Bounds()
+	if width <= 0 || bounds.Dx() <= width {
+		return img, false
+	}
+	height := max(1, (bounds.Dy()*width+bounds.Dx()/2)/bounds.Dx())
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst, true
+}
+
+// Encode writes an image as contentType, one of JPEG, PNG and WebP. Nothing but the pixels is
+// written, so the result carries no metadata.
+func Encode(w io.Writer, img image.Image, contentType string) error {
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: JPEGQuality})
+	case "image/png":
+		return png.Encode(w, img)
+	case "image/webp":
+		// Lossless, the encoder is pure Go and has no lossy mode
+		return nativewebp.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("cannot encode %s", contentType)
+	}
+}
+
+// Extension is the file extension images encoded as contentType are stored with.
+func Extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,176 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func testImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 40), G: uint8(y * 40), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+// exifSegment is an APP1 segment holding a big-endian TIFF structure with only an orientation tag.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestStripMetadataJPEG(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, testImage(4, 2), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := append(append([]byte{0xFF, 0xD8}, exifSegment(6)...), buf.Bytes()[2:]...)
+	if got := Orientation(data); got != 6 {
+		t.Fatalf("Orientation = %d, want 6", got)
+	}
+
+	stripped, err := StripMetadata(data, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stripped, []byte("Exif")) {
+		t.Fatal("stripped JPEG still holds EXIF")
+	}
+	if got := Orientation(stripped); got != 1 {
+		t.Fatalf("Orientation after strip = %d, want 1", got)
+	}
+	if !bytes.Equal(stripped, buf.Bytes()) {
+		t.Fatal("stripping changed more than the EXIF segment")
+	}
+}
+
+func TestStripMetadataPNG(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, testImage(4, 2)); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+
+	// A tEXt chunk right after the signature and IHDR
+	text := []byte("tEXtComment\x00taken at home")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+	ihdrEnd := 8 + 12 + 13
+	data := append(append(append([]byte{}, encoded[:ihdrEnd]...), chunk...), encoded[ihdrEnd:]...)
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	stripped, err := StripMetadata(data, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripped, encoded) {
+		t.Fatal("stripped PNG differs from the one without text")
+	}
+}
+
+func TestStripMetadataMalformed(t *testing.T) {
+	if _, err := StripMetadata([]byte("\xFF\xD8\xFF\xE1\x10"), "image/jpeg"); err == nil {
+		t.Fatal("StripMetadata of a truncated JPEG succeeded")
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := testImage(3, 2)
+	tests := []struct {
+		orientation int
+		width       int
+		height      int
+		// where the top left pixel of the stored image ends up
+		x, y int
+	}{
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+
+	for _, tt := range tests {
+		oriented := Orient(img, tt.orientation)
+		bounds := oriented.Bounds()
+		if bounds.Dx() != tt.width || bounds.Dy() != tt.height {
+			t.Fatalf("orientation %d: size = %dx%d, want %dx%d", tt.orientation, bounds.Dx(), bounds.Dy(), tt.width, tt.height)
+		}
+		if got, want := color.NRGBAModel.Convert(oriented.At(tt.x, tt.y)), img.At(0, 0); got != want {
+			t.Fatalf("orientation %d: pixel at %d,%d = %v, want %v", tt.orientation, tt.x, tt.y, got, want)
+		}
+	}
+}
+
+// TestOrientRGBA turns an RGBA image, copied without converting, that does not start at the origin.
+func TestOrientRGBA(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 5, 4))
+	draw.Draw(img, img.Rect, testImage(5, 4), image.Point{}, draw.Src)
+	sub := img.SubImage(image.Rect(1, 1, 4, 3)).(*image.RGBA)
+
+	oriented := Orient(sub, 6)
+	if bounds := oriented.Bounds(); bounds != image.Rect(0, 0, 2, 3) {
+		t.Fatalf("bounds = %v, want 2x3 at the origin", bounds)
+	}
+	// Turned clockwise, the top left of the stored image is the top right
+	if got, want := oriented.At(1, 0), sub.At(1, 1); got != want {
+		t.Fatalf("pixel at 1,0 = %v, want %v", got, want)
+	}
+	if got, want := oriented.At(0, 2), sub.At(3, 2); got != want {
+		t.Fatalf("pixel at 0,2 = %v, want %v", got, want)
+	}
+}
+
+func TestResize(t *testing.T) {
+	resized, ok := Resize(testImage(400, 300), 160)
+	if !ok {
+		t.Fatal("Resize did not scale down")
+	}
+	if bounds := resized.Bounds(); bounds.Dx() != 160 || bounds.Dy() != 120 {
+		t.Fatalf("size = %dx%d, want 160x120", bounds.Dx(), bounds.Dy())
+	}
+	if _, ok := Resize(testImage(100, 50), 160); ok {
+		t.Fatal("Resize scaled up")
+	}
+}
+
+func TestEncodeWebP(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Encode(buf, testImage(5, 3), "image/webp"); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := webp.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := decoded.Bounds(); bounds.Dx() != 5 || bounds.Dy() != 3 {
+		t.Fatalf("size = %dx%d, want 5x3", bounds.Dx(), bounds.Dy())
+	}
+}
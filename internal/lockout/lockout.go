@@ -0,0 +1,60 @@
+// Package lockout decides how long an account or IP address is locked out after failed logins.
+package lockout
+
+import "time"
+
+const (
+	ScopeAccount = "account"
+	ScopeIP      = "ip"
+
+	EventLocked   = "locked"
+	EventUnlocked = "unlocked"
+)
+
+// Policy locks out once Threshold consecutive failures are reached, for BaseDelay, doubling with
+// every further failure up to MaxDelay. Failures stop counting as consecutive after Window without
+// one, measured from the end of the lockout so a lockout longer than Window does not start over.
+type Policy struct {
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Window    time.Duration
+}
+
+// Delay is how long to lock out after the given number of consecutive failures.
+func (p Policy) Delay(failures int) time.Duration {
+	if p.Threshold <= 0 || failures < p.Threshold {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.Threshold; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Failures is the count after one more failure at now, given the count and time of the last one
+// and the end of the last lockout, nil when there was none.
+func (p Policy) Failures(failures int, lastFailureAt time.Time, lockedUntil *time.Time, now time.Time) int {
+	since := lastFailureAt
+	if lockedUntil != nil && lockedUntil.After(since) {
+		since = *lockedUntil
+	}
+	if now.Sub(since) > p.Window {
+		return 1
+	}
+	return failures + 1
+}
+
+// Limiter holds the policies of the two scopes failed logins are counted in.
+type Limiter struct {
+	Account Policy
+	IP      Policy
+}
+
+func (l *Limiter) Policy(scope string) Policy {
+	if scope == ScopeIP {
+		return l.IP
+	}
+	return l.Account
+}
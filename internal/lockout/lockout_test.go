@@ -0,0 +1,85 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+var policy = Policy{Threshold: 5, BaseDelay: 30 * time.Second, MaxDelay: time.Hour, Window: 15 * time.Minute}
+
+func TestDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		failures int
+		want     time.Duration
+	}{
+		{"no failures", policy, 0, 0},
+		{"below threshold", policy, 4, 0},
+		{"at threshold", policy, 5, 30 * time.Second},
+		{"one past", policy, 6, time.Minute},
+		{"doubling", policy, 9, 8 * time.Minute},
+		{"just under the cap", policy, 11, 32 * time.Minute},
+		{"capped", policy, 12, time.Hour},
+		{"far past the cap", policy, 1000, time.Hour},
+		{"disabled", Policy{BaseDelay: time.Minute, MaxDelay: time.Hour}, 100, 0},
+		{"base over the cap", Policy{Threshold: 1, BaseDelay: 2 * time.Hour, MaxDelay: time.Hour}, 1, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Delay(tt.failures); got != tt.want {
+			t.Errorf("%s: Delay(%d) = %s, want %s", tt.name, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestFailures(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) *time.Time {
+		value := now.Add(offset)
+		return &value
+	}
+	tests := []struct {
+		name          string
+		failures      int
+		lastFailureAt time.Time
+		lockedUntil   *time.Time
+		want          int
+	}{
+		{"first failure", 0, time.Time{}, nil, 1},
+		{"within the window", 3, now.Add(-time.Minute), nil, 4},
+		{"at the edge of the window", 3, now.Add(-15 * time.Minute), nil, 4},
+		{"after the window", 3, now.Add(-16 * time.Minute), nil, 1},
+		{"lockout ended within the window", 8, now.Add(-20 * time.Minute), at(-10 * time.Minute), 9},
+		// A lockout of 16m is longer than the window, the next failure still counts on from it
+		{"right after a lockout longer than the window", 10, now.Add(-16 * time.Minute), at(-time.Second), 11},
+		{"still locked", 10, now.Add(-time.Minute), at(time.Minute), 11},
+		{"window passed after the lockout", 10, now.Add(-time.Hour), at(-16 * time.Minute), 1},
+		{"lockout before the last failure", 6, now.Add(-16 * time.Minute), at(-30 * time.Minute), 1},
+	}
+	for _, tt := range tests {
+		if got := policy.Failures(tt.failures, tt.lastFailureAt, tt.lockedUntil, now); got != tt.want {
+			t.Errorf("%s: Failures = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestBackoffGrowsPastWindow fails as soon as the lockout runs out, which would start over at the
+// base delay if the window were measured from the last failure.
+func TestBackoffGrowsPastWindow(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	failures, lastFailureAt := 0, time.Time{}
+	var lockedUntil *time.Time
+	var delay time.Duration
+	for i := 0; i < 15; i++ {
+		failures = policy.Failures(failures, lastFailureAt, lockedUntil, now)
+		lastFailureAt = now
+		if delay = policy.Delay(failures); delay > 0 {
+			until := now.Add(delay)
+			lockedUntil = &until
+			now = until.Add(time.Second)
+		}
+	}
+	if delay != time.Hour {
+		t.Fatalf("delay after %d failures = %s, want the 1h cap", failures, delay)
+	}
+}
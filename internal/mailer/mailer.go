@@ -0,0 +1,155 @@
+// Package mailer sends the emails of the application, over SMTP or to the log and disk during development.
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// render writes the message as a plain text RFC 5322 email.
+func render(from string, message *Message, now time.Time) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + from + "\r\n")
+	builder.WriteString("To: " + message.To + "\r\n")
+	builder.WriteString("Subject: " + message.Subject + "\r\n")
+	builder.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
+
+// validHeader rejects values that would let a caller inject extra headers.
+func validHeader(values ...string) error {
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("mail header %q contains a line break", value)
+		}
+	}
+	return nil
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPMailer(host string, port int, username string, password string, from string) *SMTPMailer {
+	return &SMTPMailer{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message *Message) error {
+	if err := validHeader(message.To, message.Subject); err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	address := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	return smtp.SendMail(address, auth, m.From, []string{message.To}, render(m.From, message, time.Now()))
+}
+
+// LogMailer logs every message and, when Dir is set, also writes it there as an .eml file.
+type LogMailer struct {
+	Log  *logrus.Logger
+	Dir  string
+	From string
+}
+
+func NewLogMailer(log *logrus.Logger, dir string, from string) *LogMailer {
+	return &LogMailer{
+		Log:  log,
+		Dir:  dir,
+		From: from,
+	}
+}
+
+func (m *LogMailer) Send(ctx context.Context, message *Message) error {
+	if err := validHeader(message.To, message.Subject); err != nil {
+		return err
+	}
+	m.Log.Infof("Mail to %s : %s\n%s", message.To, message.Subject, message.Body)
+	if m.Dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("%s_%s.eml", now.Format("20060102_150405"), uuid.NewString())
+	return os.WriteFile(filepath.Join(m.Dir, name), render(m.From, message, now), 0o644)
+}
+
+// Queue hands messages to a worker that sends them with Mailer, so requests do not wait on the mail
+// server and take as long whether a mail is sent or not.
+type Queue struct {
+	Mailer   Mailer
+	Log      *logrus.Logger
+	messages chan *Message
+}
+
+func NewQueue(mailer Mailer, log *logrus.Logger, size int) *Queue {
+	return &Queue{
+		Mailer:   mailer,
+		Log:      log,
+		messages: make(chan *Message, size),
+	}
+}
+
+// Send queues the message, it fails when the queue is full rather than hold up the request.
+func (q *Queue) Send(ctx context.Context, message *Message) error {
+	if err := validHeader(message.To, message.Subject); err != nil {
+		return err
+	}
+	select {
+	case q.messages <- message:
+		return nil
+	default:
+		return errors.New("mail queue is full")
+	}
+}
+
+// Run sends the queued messages until ctx is done.
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message := <-q.messages:
+			if err := q.Mailer.Send(ctx, message); err != nil {
+				q.Log.Errorf("Failed send mail %q : %+v", message.Subject, err)
+			}
+		}
+	}
+}
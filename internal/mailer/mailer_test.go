@@ -0,0 +1,224 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRender(t *testing.T) {
+	now := time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC)
+	got := string(render("quiz@example.com", &Message{To: "a@example.com", Subject: "Hello", Body: "line one\nline two"}, now))
+	want := "From: quiz@example.com\r\n" +
+		"To: a@example.com\r\n" +
+		"Subject: Hello\r\n" +
+		"Date: Sun, 01 Jun 2025 09:30:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\n" +
+		"line one\r\nline two"
+	if got != want {
+		t.Fatalf("render =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestMailersRejectHeaderInjection(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	mailers := map[string]Mailer{
+		"smtp":  NewSMTPMailer("127.0.0.1", 1, "", "", "quiz@example.com"),
+		"log":   NewLogMailer(log, "", "quiz@example.com"),
+		"queue": NewQueue(NewLogMailer(log, "", "quiz@example.com"), log, 1),
+	}
+	messages := []*Message{
+		{To: "a@example.com\r\nBcc: b@example.com", Subject: "Hello"},
+		{To: "a@example.com", Subject: "Hello\nBcc: b@example.com"},
+	}
+	for name, mailer := range mailers {
+		for _, message := range messages {
+			if err := mailer.Send(context.Background(), message); err == nil {
+				t.Errorf("%s mailer sent %+v", name, message)
+			}
+		}
+	}
+}
+
+func TestLogMailerWritesEML(t *testing.T) {
+	log := logrus.New()
+	logged := new(bytes.Buffer)
+	log.SetOutput(logged)
+	dir := t.TempDir()
+	mailer := NewLogMailer(log, dir, "quiz@example.com")
+
+	if err := mailer.Send(context.Background(), &Message{To: "a@example.com", Subject: "Hello", Body: "token 123"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logged.String(), "token 123") {
+		t.Fatalf("log = %q, want the body", logged)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("eml files = %v, %v, want one", files, err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(content, []byte("From: quiz@example.com\r\nTo: a@example.com\r\n")) || !bytes.HasSuffix(content, []byte("\r\n\r\ntoken 123")) {
+		t.Fatalf("eml = %q", content)
+	}
+}
+
+// smtpServer accepts one mail at a time without authentication and returns what it received.
+func smtpServer(t *testing.T) (string, int, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ready")
+		var transcript strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL"), strings.HasPrefix(command, "RCPT"):
+				transcript.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if data == ".\r\n" {
+						break
+					}
+					transcript.WriteString(data)
+				}
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 bye")
+				received <- transcript.String()
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	address := listener.Addr().(*net.TCPAddr)
+	return address.IP.String(), address.Port, received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	host, port, received := smtpServer(t)
+	mailer := NewSMTPMailer(host, port, "", "", "quiz@example.com")
+	if err := mailer.Send(context.Background(), &Message{To: "a@example.com", Subject: "Hello", Body: "Hi"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case transcript := <-received:
+		for _, want := range []string{"MAIL FROM:<quiz@example.com>", "RCPT TO:<a@example.com>", "Subject: Hello\r\n", "\r\n\r\nHi"} {
+			if !strings.Contains(transcript, want) {
+				t.Errorf("transcript %q does not contain %q", transcript, want)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server received no mail")
+	}
+}
+
+func TestSMTPMailerUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	if err := NewSMTPMailer("127.0.0.1", port, "", "", "quiz@example.com").Send(context.Background(), &Message{To: "a@example.com", Subject: "Hello"}); err == nil {
+		t.Fatalf("Send to closed port %d succeeded", port)
+	}
+}
+
+// recordingMailer records what it is asked to send and fails the messages without a body.
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []*Message
+	done chan struct{}
+}
+
+func (m *recordingMailer) Send(ctx context.Context, message *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer func() { m.done <- struct{}{} }()
+	if message.Body == "" {
+		return errors.New("no body")
+	}
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	next := &recordingMailer{done: make(chan struct{}, 3)}
+	queue := NewQueue(next, log, 2)
+
+	// Send returns before anything is sent and refuses more than the queue holds
+	if err := queue.Send(context.Background(), &Message{To: "a@example.com", Subject: "First"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Send(context.Background(), &Message{To: "a@example.com", Subject: "Second", Body: "Hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Send(context.Background(), &Message{To: "a@example.com", Subject: "Third", Body: "Hi"}); err == nil {
+		t.Fatal("Send to a full queue succeeded")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		queue.Run(ctx)
+		close(stopped)
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-next.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("queued mail was not sent")
+		}
+	}
+	cancel()
+	<-stopped
+
+	// A failed mail does not stop the ones after it
+	if len(next.sent) != 1 || next.sent[0].Subject != "Second" {
+		t.Fatalf("sent = %+v, want only Second", next.sent)
+	}
+}
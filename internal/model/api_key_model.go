@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIKeyResponse struct {
+	ID     uuid.UUID `json:"id"`
+	Name   string    `json:"name"`
+	Prefix string    `json:"prefix"`
+	// Key is only returned when the key is created, it cannot be read again.
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+	// MFA is whether the session creating the key was signed in with a second factor.
+	MFA       bool       `json:"-"`
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,required,max=100"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type ListAPIKeyRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+}
+
+type RevokeAPIKeyRequest struct {
+	ID     string `json:"-" validate:"required,max=100"`
+	UserID string `json:"-" validate:"required,max=100"`
+}
+
+type RevokeAllAPIKeyRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+	Actor  *Auth  `json:"-"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditEventResponse struct {
+	ID            uuid.UUID       `json:"id"`
+	ActorID       *uuid.UUID      `json:"actor_id,omitempty"`
+	ActorUsername string          `json:"actor_username,omitempty"`
+	Action        string          `json:"action"`
+	ResourceType  string          `json:"resource_type"`
+	ResourceID    string          `json:"resource_id"`
+	Before        json.RawMessage `json:"before,omitempty"`
+	After         json.RawMessage `json:"after,omitempty"`
+	IPAddress     string          `json:"ip_address,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type SearchAuditEventRequest struct {
+	ActorID      string     `json:"actor_id,omitempty" validate:"omitempty,uuid"`
+	Action       string     `json:"action,omitempty" validate:"max=50"`
+	ResourceType string     `json:"resource_type,omitempty" validate:"max=50"`
+	ResourceID   string     `json:"resource_id,omitempty" validate:"max=100"`
+	From         *time.Time `json:"from,omitempty"`
+	To           *time.Time `json:"to,omitempty"`
+	Page         int        `json:"page,omitempty" validate:"min=1"`
+	Size         int        `json:"size,omitempty" validate:"min=1,max=100"`
+}
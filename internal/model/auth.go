@@ -3,4 +3,18 @@ package model
 type Auth struct {
 	ID   string
 	Role string
+	// SessionID is the refresh token family the access token was issued for.
+	SessionID string
+	// EmailVerified is whether the email was verified when the access token was issued.
+	EmailVerified bool
+	// MFA is whether the session was signed in with a second factor.
+	MFA bool
+	// APIKeyID is set when the request is authenticated with an API key instead of a session.
+	APIKeyID string
+	// ImpersonatorID is set when an admin is acting as the user, ID is then the impersonated user.
+	ImpersonatorID string
+	// IPAddress is where the request came from, kept with the changes it makes.
+	IPAddress string
+	// Scopes are the permissions an API key is limited to, nil for sessions.
+	Scopes []string
 }
@@ -0,0 +1,18 @@
+package converter
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+)
+
+func APIKeyToResponse(key *entity.APIKey) *model.APIKeyResponse {
+	return &model.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
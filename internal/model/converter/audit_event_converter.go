@@ -0,0 +1,25 @@
+package converter
+
+import (
+	"encoding/json"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+)
+
+func AuditEventToResponse(event *entity.AuditEvent) *model.AuditEventResponse {
+	response := &model.AuditEventResponse{
+		ID:           event.ID,
+		ActorID:      event.ActorID,
+		Action:       event.Action,
+		ResourceType: event.ResourceType,
+		ResourceID:   event.ResourceID,
+		Before:       json.RawMessage(event.Before),
+		After:        json.RawMessage(event.After),
+		IPAddress:    event.IPAddress,
+		CreatedAt:    event.CreatedAt,
+	}
+	if event.Actor != nil {
+		response.ActorUsername = event.Actor.Username
+	}
+	return response
+}
@@ -30,6 +30,7 @@ func CourseToListResponse(course *entity.Course) *model.CourseListResponse {
 		GradeLevel: course.GradeLevel,
 		CreatedAt:  course.CreatedAt,
 		UpdatedAt:  course.UpdatedAt,
+		DeletedAt:  deletedAt(course.DeletedAt),
 		Subject:    *SubjectToResponse(&course.Subject),
 	}
 }
@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"encoding/json"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+)
+
+func MediaAssetToResponse(asset *entity.MediaAsset) *model.MediaAssetResponse {
+	response := &model.MediaAssetResponse{
+		ID:             asset.ID,
+		Hash:           asset.Hash,
+		OwnerID:        asset.OwnerID,
+		Category:       asset.Category,
+		FileName:       asset.FileName,
+		URL:            asset.URL,
+		PreviewURL:     asset.URL,
+		ContentType:    asset.ContentType,
+		Size:           asset.Size,
+		Width:          asset.Width,
+		Height:         asset.Height,
+		ReferenceCount: asset.ReferenceCount,
+		Variants:       MediaAssetVariants(asset),
+		CreatedAt:      asset.CreatedAt,
+	}
+	if asset.Owner != nil {
+		response.OwnerUsername = asset.Owner.Username
+	}
+	return response
+}
+
+// MediaAssetVariants decodes the variant URLs of an asset by name, nil when it has none.
+func MediaAssetVariants(asset *entity.MediaAsset) map[string]string {
+	var variants map[string]string
+	if err := json.Unmarshal(asset.Variants, &variants); err != nil || len(variants) == 0 {
+		return nil
+	}
+	return variants
+}
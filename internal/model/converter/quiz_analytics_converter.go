@@ -0,0 +1,46 @@
+package converter
+
+import (
+	"fp-designpattern/internal/analytics"
+	"fp-designpattern/internal/model"
+
+	"github.com/google/uuid"
+)
+
+func QuizAnalyticsToResponse(quizID uuid.UUID, report *analytics.Report) *model.QuizAnalyticsResponse {
+	buckets := make([]model.ScoreBucketResponse, len(report.ScoreDistribution))
+	for i, bucket := range report.ScoreDistribution {
+		buckets[i] = model.ScoreBucketResponse{
+			From:     bucket.From,
+			To:       bucket.To,
+			Attempts: bucket.Count,
+		}
+	}
+	questions := make([]model.QuestionAnalyticsResponse, len(report.Questions))
+	for i, question := range report.Questions {
+		questions[i] = model.QuestionAnalyticsResponse{
+			QuestionID:     question.QuestionID,
+			Type:           question.Type,
+			Presented:      question.Presented,
+			Answered:       question.Answered,
+			PValue:         question.PValue,
+			Discrimination: question.Discrimination,
+		}
+		for _, option := range question.Options {
+			questions[i].Options = append(questions[i].Options, model.OptionAnalyticsResponse{
+				OptionID: option.OptionID,
+				Option:   option.Text,
+				Picked:   option.Picked,
+				Share:    option.Share,
+			})
+		}
+	}
+	return &model.QuizAnalyticsResponse{
+		QuizID:                 quizID,
+		Attempts:               report.Attempts,
+		AverageScore:           report.AverageScore,
+		AverageDurationSeconds: report.AverageDuration.Seconds(),
+		ScoreDistribution:      buckets,
+		Questions:              questions,
+	}
+}
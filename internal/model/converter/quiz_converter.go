@@ -0,0 +1,147 @@
+package converter
+
+import (
+	"encoding/json"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"sort"
+)
+
+func QuizToResponse(quiz *entity.Quiz) *model.QuizResponse {
+	questions := make([]model.QuestionResponse, len(quiz.Questions))
+	for i, question := range quiz.Questions {
+		questions[i] = *QuestionToResponse(&question)
+	}
+	return &model.QuizResponse{
+		ID:              quiz.ID,
+		QuizName:        quiz.QuizName,
+		TimeLimit:       quiz.TimeLimit,
+		ShowAnswers:     quiz.ShowAnswers,
+		AnswersRevealAt: quiz.AnswersRevealAt,
+		DrawCount:       quiz.DrawCount,
+		BankSubjectID:   quiz.BankSubjectID,
+		BankGradeLevel:  quiz.BankGradeLevel,
+		Shuffle:         quiz.Shuffle,
+		CourseID:        quiz.CourseID,
+		CreatedAt:       quiz.CreatedAt,
+		UpdatedAt:       quiz.UpdatedAt,
+		Questions:       questions,
+	}
+}
+
+// QuizWithAnswerKeyToResponse is the admin view of a quiz including the answer key of every question.
+func QuizWithAnswerKeyToResponse(quiz *entity.Quiz, answers []entity.QuizAnswer) *model.QuizResponse {
+	response := QuizToResponse(quiz)
+	for i, question := range quiz.Questions {
+		response.Questions[i] = *QuestionWithAnswerKeyToResponse(&question, answers)
+	}
+	return response
+}
+
+func QuizToListResponse(quiz *entity.Quiz) *model.QuizListResponse {
+	return &model.QuizListResponse{
+		ID:              quiz.ID,
+		QuizName:        quiz.QuizName,
+		TimeLimit:       quiz.TimeLimit,
+		ShowAnswers:     quiz.ShowAnswers,
+		AnswersRevealAt: quiz.AnswersRevealAt,
+		DrawCount:       quiz.DrawCount,
+		Shuffle:         quiz.Shuffle,
+		CourseID:        quiz.CourseID,
+		CreatedAt:       quiz.CreatedAt,
+		UpdatedAt:       quiz.UpdatedAt,
+	}
+}
+
+// QuestionToResponse is the student view of a question, it never exposes the answer key.
+func QuestionToResponse(question *entity.Question) *model.QuestionResponse {
+	var content []model.ContentBlock
+	if err := json.Unmarshal(question.Content, &content); err != nil {
+		// fallback to empty slice or handle error as needed
+		content = []model.ContentBlock{}
+	}
+	options := make([]model.OptionResponse, len(question.Options))
+	for i, option := range question.Options {
+		options[i] = *OptionToResponse(&option)
+	}
+	return &model.QuestionResponse{
+		ID:           question.ID,
+		QuizID:       question.QuizID,
+		SubjectID:    question.SubjectID,
+		GradeLevel:   question.GradeLevel,
+		Type:         QuestionType(question),
+		Content:      content,
+		Options:      options,
+		MatchTargets: MatchTargets(question),
+	}
+}
+
+// QuestionWithAnswerKeyToResponse is the admin view of a question.
+func QuestionWithAnswerKeyToResponse(question *entity.Question, answers []entity.QuizAnswer) *model.QuestionResponse {
+	response := QuestionToResponse(question)
+	response.Options = make([]model.OptionResponse, len(question.Options))
+	for i, option := range question.Options {
+		response.Options[i] = *OptionWithAnswerKeyToResponse(&option)
+	}
+	for _, answer := range answers {
+		if answer.QuestionID == question.ID {
+			response.CorrectOptionIDs = append(response.CorrectOptionIDs, answer.OptionID)
+		}
+	}
+	response.AnswerKey = QuestionAnswerKey(question)
+	return response
+}
+
+func OptionToResponse(option *entity.QuestionOption) *model.OptionResponse {
+	return &model.OptionResponse{
+		ID:         option.ID,
+		QuestionID: option.QuestionID,
+		Option:     option.Option,
+	}
+}
+
+func OptionWithAnswerKeyToResponse(option *entity.QuestionOption) *model.OptionResponse {
+	response := OptionToResponse(option)
+	response.Position = option.Position
+	if option.MatchValue != nil {
+		response.Match = *option.MatchValue
+	}
+	return response
+}
+
+// QuestionType treats questions created before typed questions existed as single choice.
+func QuestionType(question *entity.Question) string {
+	if question.Type == "" {
+		return entity.QuestionTypeSingleChoice
+	}
+	return question.Type
+}
+
+func QuestionAnswerKey(question *entity.Question) *model.QuestionAnswerKey {
+	if len(question.AnswerKey) == 0 {
+		return nil
+	}
+	answerKey := new(model.QuestionAnswerKey)
+	if err := json.Unmarshal(question.AnswerKey, answerKey); err != nil {
+		return nil
+	}
+	return answerKey
+}
+
+// MatchTargets lists the distinct targets of a matching question in a stable order.
+func MatchTargets(question *entity.Question) []string {
+	if question.Type != entity.QuestionTypeMatching {
+		return nil
+	}
+	seen := make(map[string]bool)
+	targets := []string{}
+	for _, option := range question.Options {
+		if option.MatchValue == nil || seen[*option.MatchValue] {
+			continue
+		}
+		seen[*option.MatchValue] = true
+		targets = append(targets, *option.MatchValue)
+	}
+	sort.Strings(targets)
+	return targets
+}
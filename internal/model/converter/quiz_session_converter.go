@@ -0,0 +1,138 @@
+package converter
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/scoring"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func QuizSessionToResponse(session *entity.UserQuizSession, answers []model.UserAnswerResponse, now time.Time) *model.QuizSessionResponse {
+	deadline := session.Deadline(session.Quiz.TimeLimit)
+	var remaining int64
+	if !session.Submitted && now.Before(deadline) {
+		remaining = int64(deadline.Sub(now).Seconds())
+	}
+	return &model.QuizSessionResponse{
+		ID:               session.ID,
+		QuizID:           session.QuizID,
+		UserID:           session.UserID,
+		StartedAt:        session.StartedAt,
+		Deadline:         deadline,
+		EndedAt:          session.EndedAt,
+		Submitted:        session.Submitted,
+		AutoSubmitted:    session.AutoSubmitted,
+		Score:            session.Score,
+		RemainingSeconds: remaining,
+		Answers:          answers,
+	}
+}
+
+// AnswersToResponse lists the answered questions in quiz order.
+func AnswersToResponse(questions []entity.Question, answers map[uuid.UUID]scoring.Answer) []model.UserAnswerResponse {
+	responses := []model.UserAnswerResponse{}
+	for _, question := range questions {
+		answer, ok := answers[question.ID]
+		if !ok {
+			continue
+		}
+		responses = append(responses, AnswerToResponse(question.ID, answer))
+	}
+	return responses
+}
+
+func AnswerToResponse(questionID uuid.UUID, answer scoring.Answer) model.UserAnswerResponse {
+	return model.UserAnswerResponse{
+		QuestionID: questionID,
+		OptionIDs:  answer.OptionIDs,
+		Text:       answer.Text,
+		Order:      answer.Order,
+		Matches:    answer.Matches,
+	}
+}
+
+func QuizResultToResponse(result *scoring.Result, answersVisible bool) *model.QuizResultResponse {
+	questions := make([]model.QuestionResultResponse, len(result.Questions))
+	for i, question := range result.Questions {
+		questions[i] = model.QuestionResultResponse{
+			QuestionID: question.QuestionID,
+			Type:       question.Type,
+			Answer:     AnswerToResponse(question.QuestionID, question.Answer),
+			MaxPoints:  question.MaxPoints,
+		}
+		if answersVisible {
+			points := question.Points
+			questions[i].CorrectOptionIDs = question.Correct
+			questions[i].Points = &points
+			questions[i].Status = question.Status
+		}
+	}
+	return &model.QuizResultResponse{
+		Score:     result.Score,
+		Points:    result.Points,
+		MaxPoints: result.MaxPoints,
+		Questions: questions,
+	}
+}
+
+func QuizAttemptToResponse(session *entity.UserQuizSession, now time.Time) *model.QuizAttemptResponse {
+	endedAt := now
+	if session.EndedAt != nil {
+		endedAt = *session.EndedAt
+	}
+	return &model.QuizAttemptResponse{
+		ID:              session.ID,
+		QuizID:          session.QuizID,
+		StartedAt:       session.StartedAt,
+		EndedAt:         session.EndedAt,
+		Submitted:       session.Submitted,
+		AutoSubmitted:   session.AutoSubmitted,
+		Score:           session.Score,
+		DurationSeconds: int64(endedAt.Sub(session.StartedAt).Seconds()),
+	}
+}
+
+// QuizReviewToResponse pairs every question with the given answer, the answer key is only
+// included when the show answers policy of the quiz allows it.
+func QuizReviewToResponse(session *entity.UserQuizSession, quiz *entity.Quiz, result *scoring.Result, answersVisible bool, now time.Time) *model.QuizReviewResponse {
+	results := make(map[uuid.UUID]scoring.QuestionResult, len(result.Questions))
+	for _, questionResult := range result.Questions {
+		results[questionResult.QuestionID] = questionResult
+	}
+
+	questions := make([]model.QuestionReviewResponse, len(quiz.Questions))
+	for i, question := range quiz.Questions {
+		questionResponse := QuestionToResponse(&question)
+		questionResult := results[question.ID]
+		if answersVisible {
+			questionResponse = QuestionWithAnswerKeyToResponse(&question, nil)
+		}
+		questions[i] = model.QuestionReviewResponse{
+			ID:           question.ID,
+			Type:         questionResponse.Type,
+			Content:      questionResponse.Content,
+			Options:      questionResponse.Options,
+			MatchTargets: questionResponse.MatchTargets,
+			Answer:       AnswerToResponse(question.ID, questionResult.Answer),
+		}
+		if answersVisible {
+			points := questionResult.Points
+			questions[i].CorrectOptionIDs = questionResult.Correct
+			questions[i].AnswerKey = questionResponse.AnswerKey
+			questions[i].Status = questionResult.Status
+			questions[i].Points = &points
+		}
+	}
+
+	return &model.QuizReviewResponse{
+		Attempt:         *QuizAttemptToResponse(session, now),
+		QuizName:        quiz.QuizName,
+		AnswersVisible:  answersVisible,
+		AnswersRevealAt: quiz.AnswersRevealAt,
+		Points:          result.Points,
+		MaxPoints:       result.MaxPoints,
+		Questions:       questions,
+	}
+}
@@ -3,6 +3,9 @@ package converter
 import (
 	"fp-designpattern/internal/entity"
 	"fp-designpattern/internal/model"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 func SubjectToResponse(subject *entity.Subject) *model.SubjectResponse {
@@ -11,5 +14,14 @@ func SubjectToResponse(subject *entity.Subject) *model.SubjectResponse {
 		SubjectName: subject.SubjectName,
 		CreatedAt:   &subject.CreatedAt,
 		UpdatedAt:   &subject.UpdatedAt,
+		DeletedAt:   deletedAt(subject.DeletedAt),
+	}
+}
+
+// deletedAt is when the entity went to the trash, nil for one that is not in it.
+func deletedAt(deletedAt gorm.DeletedAt) *time.Time {
+	if !deletedAt.Valid {
+		return nil
 	}
+	return &deletedAt.Time
 }
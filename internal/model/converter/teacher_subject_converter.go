@@ -0,0 +1,13 @@
+package converter
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+)
+
+func TeacherSubjectToResponse(assignment *entity.TeacherSubject) *model.TeacherSubjectResponse {
+	return &model.TeacherSubjectResponse{
+		Subject:   *SubjectToResponse(&assignment.Subject),
+		CreatedAt: assignment.CreatedAt,
+	}
+}
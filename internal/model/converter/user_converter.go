@@ -3,26 +3,38 @@ package converter
 import (
 	"fp-designpattern/internal/entity"
 	"fp-designpattern/internal/model"
+	"time"
 )
 
 func UserToResponse(user *entity.User) *model.UserResponse {
 	return &model.UserResponse{
-		ID:          &user.ID,
-		Username:    user.Username,
-		Email:       user.Email,
-		PhoneNumber: user.PhoneNumber,
-		GradeLevel:  user.GradeLevel,
-		Role:        user.Role,
-		AvatarUrl:   user.AvatarUrl,
-		BirthDate:   &user.BirthDate,
-		Token:       user.Token,
-		CreatedAt:   &user.CreatedAt,
-		UpdatedAt:   &user.UpdatedAt,
+		ID:               &user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		PhoneNumber:      user.PhoneNumber,
+		GradeLevel:       user.GradeLevel,
+		Role:             user.Role,
+		AvatarUrl:        user.AvatarUrl,
+		BirthDate:        &user.BirthDate,
+		EmailVerifiedAt:  user.EmailVerifiedAt,
+		TwoFactorEnabled: user.TOTPEnabledAt != nil,
+		CreatedAt:        &user.CreatedAt,
+		UpdatedAt:        &user.UpdatedAt,
+		DeletedAt:        deletedAt(user.DeletedAt),
 	}
 }
 
-func UserToTokenResponse(user *entity.User) *model.UserResponse {
+func UserToTokenResponse(accessToken string, expiresAt time.Time, refreshToken string) *model.UserResponse {
 	return &model.UserResponse{
-		Token: user.Token,
+		Token:        accessToken,
+		ExpiresAt:    &expiresAt,
+		RefreshToken: refreshToken,
+	}
+}
+
+func UserToChallengeResponse(challenge string) *model.UserResponse {
+	return &model.UserResponse{
+		MFARequired: true,
+		Challenge:   challenge,
 	}
 }
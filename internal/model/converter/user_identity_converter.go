@@ -0,0 +1,15 @@
+package converter
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+)
+
+func UserIdentityToResponse(identity *entity.UserIdentity) *model.UserIdentityResponse {
+	return &model.UserIdentityResponse{
+		ID:        identity.ID,
+		Provider:  identity.Provider,
+		Email:     identity.Email,
+		CreatedAt: identity.CreatedAt,
+	}
+}
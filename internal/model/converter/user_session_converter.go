@@ -0,0 +1,19 @@
+package converter
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+)
+
+func UserSessionToResponse(session *entity.UserSession, currentID string) *model.UserSessionResponse {
+	return &model.UserSessionResponse{
+		ID:           session.ID,
+		Device:       session.Device,
+		IPAddress:    session.IPAddress,
+		UserAgent:    session.UserAgent,
+		Current:      session.ID.String() == currentID,
+		Impersonated: session.ImpersonatorID != nil,
+		CreatedAt:    session.CreatedAt,
+		LastSeenAt:   session.LastSeenAt,
+	}
+}
@@ -7,8 +7,11 @@ import (
 )
 
 type ContentBlock struct {
-	Type string `json:"type"` // "text" or "image"
-	Data string `json:"data"` // text content or image URL
+	Type string `json:"type"` // "text", "image" or "file"
+	Data string `json:"data"` // text content, image URL or attachment URL
+	// Srcset maps the variant names of an uploaded image to their URLs, it is filled in when a
+	// course is read and never stored.
+	Srcset map[string]string `json:"srcset,omitempty"`
 }
 
 type CourseResponse struct {
@@ -26,6 +29,7 @@ type CourseListResponse struct {
 	GradeLevel int             `json:"grade_level"`
 	CreatedAt  time.Time       `json:"created_at"`
 	UpdatedAt  time.Time       `json:"updated_at"`
+	DeletedAt  *time.Time      `json:"deleted_at,omitempty"`
 	Subject    SubjectResponse `json:"subject"`
 }
 
@@ -34,13 +38,20 @@ type CourseRequest struct {
 	Content    []ContentBlock `json:"content"`
 	GradeLevel int            `json:"grade_level"`
 	SubjectID  string         `json:"subject_id"`
+	Actor      *Auth          `json:"-"`
 }
 
 type GetCourseRequest struct {
-	ID string `json:"-"`
+	ID    string `json:"-"`
+	Actor *Auth  `json:"-"`
 }
 type DeleteCourseRequest struct {
-	ID string `json:"-"`
+	ID    string `json:"-"`
+	Actor *Auth  `json:"-"`
+}
+type RestoreCourseRequest struct {
+	ID    string `json:"-" validate:"required,uuid"`
+	Actor *Auth  `json:"-"`
 }
 type SearchCourseRequest struct {
 	CourseName string `json:"course_name"`
@@ -48,6 +59,9 @@ type SearchCourseRequest struct {
 	GradeLevel int    `json:"grade_level"`
 	Page       int    `json:"page"`
 	Size       int    `json:"size"`
+	// SubjectIDs limits the search to the subjects of a scoped actor, nil is no limit.
+	SubjectIDs []string `json:"-"`
+	Actor      *Auth    `json:"-"`
 }
 
 type UpdateCourseRequest struct {
@@ -56,4 +70,5 @@ type UpdateCourseRequest struct {
 	Content    []ContentBlock `json:"content"`
 	GradeLevel int            `json:"grade_level"`
 	SubjectID  string         `json:"subject_id"`
+	Actor      *Auth          `json:"-"`
 }
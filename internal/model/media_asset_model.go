@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MediaAssetResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	Hash          string     `json:"hash"`
+	OwnerID       *uuid.UUID `json:"owner_id,omitempty"`
+	OwnerUsername string     `json:"owner_username,omitempty"`
+	Category      string     `json:"category"`
+	FileName      string     `json:"file_name"`
+	// URL is what a course content block refers to the asset by.
+	URL string `json:"url"`
+	// PreviewURL fetches the asset, it differs from URL when the storage keeps files private.
+	PreviewURL     string `json:"preview_url"`
+	ContentType    string `json:"content_type"`
+	Size           int64  `json:"size"`
+	Width          int    `json:"width,omitempty"`
+	Height         int    `json:"height,omitempty"`
+	ReferenceCount int    `json:"reference_count"`
+	// Variants maps the names of the scaled down copies of an image to their URLs.
+	Variants  map[string]string `json:"variants,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type SearchMediaAssetRequest struct {
+	// Keyword matches the file name.
+	Keyword string `json:"keyword,omitempty" validate:"max=100"`
+	// ContentType matches exactly, or every type under it when it ends in a slash such as "image/".
+	ContentType string `json:"content_type,omitempty" validate:"max=100"`
+	Category    string `json:"category,omitempty" validate:"max=50"`
+	OwnerID     string `json:"owner_id,omitempty" validate:"omitempty,uuid"`
+	Page        int    `json:"page,omitempty" validate:"min=1"`
+	Size        int    `json:"size,omitempty" validate:"min=1,max=100"`
+}
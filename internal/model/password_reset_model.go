@@ -0,0 +1,10 @@
+package model
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,max=100"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required,max=255"`
+	Password string `json:"password" validate:"required,max=100"`
+}
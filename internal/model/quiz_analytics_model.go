@@ -0,0 +1,40 @@
+package model
+
+import "github.com/google/uuid"
+
+type QuizAnalyticsResponse struct {
+	QuizID                 uuid.UUID                   `json:"quiz_id"`
+	Attempts               int                         `json:"attempts"`
+	AverageScore           float64                     `json:"average_score"`
+	AverageDurationSeconds float64                     `json:"average_duration_seconds"`
+	ScoreDistribution      []ScoreBucketResponse       `json:"score_distribution"`
+	Questions              []QuestionAnalyticsResponse `json:"questions"`
+}
+
+type ScoreBucketResponse struct {
+	From     int `json:"from"`
+	To       int `json:"to"`
+	Attempts int `json:"attempts"`
+}
+
+type QuestionAnalyticsResponse struct {
+	QuestionID     uuid.UUID                 `json:"question_id"`
+	Type           string                    `json:"type"`
+	Presented      int                       `json:"presented"`
+	Answered       int                       `json:"answered"`
+	PValue         float64                   `json:"p_value"`
+	Discrimination *float64                  `json:"discrimination"`
+	Options        []OptionAnalyticsResponse `json:"options,omitempty"`
+}
+
+type OptionAnalyticsResponse struct {
+	OptionID uuid.UUID `json:"option_id"`
+	Option   string    `json:"option"`
+	Picked   int       `json:"picked"`
+	Share    float64   `json:"share"`
+}
+
+type QuizAnalyticsRequest struct {
+	QuizID string `json:"-" validate:"required,max=100"`
+	Actor  *Auth  `json:"-"`
+}
@@ -0,0 +1,217 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type QuizResponse struct {
+	ID              uuid.UUID          `json:"id"`
+	QuizName        string             `json:"quiz_name"`
+	TimeLimit       int                `json:"time_limit"`
+	ShowAnswers     string             `json:"show_answers"`
+	AnswersRevealAt *time.Time         `json:"answers_reveal_at,omitempty"`
+	DrawCount       int                `json:"draw_count"`
+	BankSubjectID   *uuid.UUID         `json:"bank_subject_id,omitempty"`
+	BankGradeLevel  *int               `json:"bank_grade_level,omitempty"`
+	Shuffle         bool               `json:"shuffle"`
+	CourseID        uuid.UUID          `json:"course_id"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+	Questions       []QuestionResponse `json:"questions,omitempty"`
+}
+
+type QuizListResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	QuizName        string     `json:"quiz_name"`
+	TimeLimit       int        `json:"time_limit"`
+	ShowAnswers     string     `json:"show_answers"`
+	AnswersRevealAt *time.Time `json:"answers_reveal_at,omitempty"`
+	DrawCount       int        `json:"draw_count"`
+	Shuffle         bool       `json:"shuffle"`
+	CourseID        uuid.UUID  `json:"course_id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type QuestionResponse struct {
+	ID               uuid.UUID          `json:"id"`
+	QuizID           *uuid.UUID         `json:"quiz_id,omitempty"`
+	SubjectID        *uuid.UUID         `json:"subject_id,omitempty"`
+	GradeLevel       *int               `json:"grade_level,omitempty"`
+	Type             string             `json:"type"`
+	Content          []ContentBlock     `json:"content"`
+	Options          []OptionResponse   `json:"options"`
+	MatchTargets     []string           `json:"match_targets,omitempty"`
+	CorrectOptionIDs []uuid.UUID        `json:"correct_option_ids,omitempty"`
+	AnswerKey        *QuestionAnswerKey `json:"answer_key,omitempty"`
+}
+
+type OptionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	QuestionID uuid.UUID `json:"question_id"`
+	Option     string    `json:"option"`
+	Position   *int      `json:"position,omitempty"`
+	Match      string    `json:"match,omitempty"`
+}
+
+// QuestionAnswerKey is the answer key of short answer and numeric questions, stored in questions.answer_key.
+type QuestionAnswerKey struct {
+	AcceptedAnswers []string `json:"accepted_answers,omitempty"`
+	CaseSensitive   bool     `json:"case_sensitive,omitempty"`
+	Value           *float64 `json:"value,omitempty"`
+	Tolerance       float64  `json:"tolerance,omitempty"`
+}
+
+type QuizRequest struct {
+	QuizName        string     `json:"quiz_name" validate:"required"`
+	TimeLimit       int        `json:"time_limit" validate:"required,min=1"`
+	CourseID        string     `json:"course_id" validate:"required,max=100"`
+	ShowAnswers     string     `json:"show_answers" validate:"omitempty,oneof=after_submit after_reveal_date never"`
+	AnswersRevealAt *time.Time `json:"answers_reveal_at"`
+	DrawCount       int        `json:"draw_count" validate:"min=0"`
+	BankSubjectID   string     `json:"bank_subject_id" validate:"max=100"`
+	BankGradeLevel  *int       `json:"bank_grade_level" validate:"omitempty,min=1"`
+	Shuffle         bool       `json:"shuffle"`
+	Actor           *Auth      `json:"-"`
+}
+
+type GetQuizRequest struct {
+	ID    string `json:"-" validate:"required,max=100"`
+	Actor *Auth  `json:"-"`
+}
+
+type SearchQuizRequest struct {
+	QuizName string `json:"quiz_name"`
+	CourseID string `json:"course_id"`
+	Page     int    `json:"page,omitempty" validate:"min=1"`
+	Size     int    `json:"size,omitempty" validate:"min=1,max=100"`
+	// SubjectIDs limits the search to the subjects of a scoped actor, nil is no limit.
+	SubjectIDs []string `json:"-"`
+	Actor      *Auth    `json:"-"`
+}
+
+type UpdateQuizRequest struct {
+	ID              string     `json:"-" validate:"required,max=100"`
+	QuizName        string     `json:"quiz_name"`
+	TimeLimit       int        `json:"time_limit" validate:"min=0"`
+	CourseID        string     `json:"course_id"`
+	ShowAnswers     string     `json:"show_answers" validate:"omitempty,oneof=after_submit after_reveal_date never"`
+	AnswersRevealAt *time.Time `json:"answers_reveal_at"`
+	DrawCount       *int       `json:"draw_count" validate:"omitempty,min=0"`
+	BankSubjectID   string     `json:"bank_subject_id" validate:"max=100"`
+	BankGradeLevel  *int       `json:"bank_grade_level" validate:"omitempty,min=1"`
+	Shuffle         *bool      `json:"shuffle"`
+	Actor           *Auth      `json:"-"`
+}
+
+type DeleteQuizRequest struct {
+	ID    string `json:"-" validate:"required,max=100"`
+	Actor *Auth  `json:"-"`
+}
+
+// QuestionRequest creates a question of the quiz, or a question of the question bank when QuizID is empty.
+type QuestionRequest struct {
+	QuizID     string             `json:"-" validate:"max=100"`
+	SubjectID  string             `json:"subject_id" validate:"max=100"`
+	GradeLevel int                `json:"grade_level" validate:"min=0"`
+	Type       string             `json:"type" validate:"omitempty,oneof=single_choice multiple_select true_false short_answer numeric ordering matching"`
+	Content    []ContentBlock     `json:"content" validate:"required,min=1"`
+	Options    []OptionRequest    `json:"options"`
+	AnswerKey  *QuestionAnswerKey `json:"answer_key"`
+	Actor      *Auth              `json:"-"`
+}
+
+type UpdateQuestionRequest struct {
+	ID         string             `json:"-" validate:"required,max=100"`
+	QuizID     string             `json:"-" validate:"max=100"`
+	SubjectID  string             `json:"subject_id" validate:"max=100"`
+	GradeLevel int                `json:"grade_level" validate:"min=0"`
+	Content    []ContentBlock     `json:"content"`
+	AnswerKey  *QuestionAnswerKey `json:"answer_key"`
+	Actor      *Auth              `json:"-"`
+}
+
+type DeleteQuestionRequest struct {
+	ID     string `json:"-" validate:"required,max=100"`
+	QuizID string `json:"-" validate:"max=100"`
+	Actor  *Auth  `json:"-"`
+}
+
+type OptionRequest struct {
+	QuizID     string `json:"-"`
+	QuestionID string `json:"-"`
+	Option     string `json:"option" validate:"required"`
+	Correct    bool   `json:"correct"`
+	Position   int    `json:"position" validate:"min=0"`
+	Match      string `json:"match"`
+	Actor      *Auth  `json:"-"`
+}
+
+type UpdateOptionRequest struct {
+	ID         string `json:"-" validate:"required,max=100"`
+	QuizID     string `json:"-" validate:"max=100"`
+	QuestionID string `json:"-" validate:"required,max=100"`
+	Option     string `json:"option"`
+	Position   int    `json:"position" validate:"min=0"`
+	Match      string `json:"match"`
+	Actor      *Auth  `json:"-"`
+}
+
+type DeleteOptionRequest struct {
+	ID         string `json:"-" validate:"required,max=100"`
+	QuizID     string `json:"-" validate:"max=100"`
+	QuestionID string `json:"-" validate:"required,max=100"`
+	Actor      *Auth  `json:"-"`
+}
+
+type AnswerKeyRequest struct {
+	QuizID     string   `json:"-" validate:"max=100"`
+	QuestionID string   `json:"-" validate:"required,max=100"`
+	OptionIDs  []string `json:"option_ids" validate:"required,min=1"`
+	Actor      *Auth    `json:"-"`
+}
+
+type GetBankQuestionRequest struct {
+	ID    string `json:"-" validate:"required,max=100"`
+	Actor *Auth  `json:"-"`
+}
+
+type SearchQuestionBankRequest struct {
+	SubjectID  string `json:"subject_id" validate:"max=100"`
+	GradeLevel int    `json:"grade_level" validate:"min=0"`
+	Type       string `json:"type" validate:"omitempty,oneof=single_choice multiple_select true_false short_answer numeric ordering matching"`
+	Page       int    `json:"page,omitempty" validate:"min=1"`
+	Size       int    `json:"size,omitempty" validate:"min=1,max=100"`
+	// SubjectIDs limits the search to the subjects of a scoped actor, nil is no limit.
+	SubjectIDs []string `json:"-"`
+	Actor      *Auth    `json:"-"`
+}
+
+type ImportQuizRequest struct {
+	CourseID  string `json:"course_id" validate:"required,max=100"`
+	QuizName  string `json:"quiz_name"`
+	TimeLimit int    `json:"time_limit" validate:"required,min=1"`
+	Format    string `json:"format" validate:"required,oneof=gift moodle_xml"`
+	Content   []byte `json:"-" validate:"required"`
+	Actor     *Auth  `json:"-"`
+}
+
+// ImportLineError is a question of an import file that could not be imported, Line is where it starts.
+type ImportLineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+type ExportQuizRequest struct {
+	ID     string `json:"-" validate:"required,max=100"`
+	Format string `json:"-" validate:"required,oneof=gift moodle_xml"`
+	Actor  *Auth  `json:"-"`
+}
+
+type QuizExportResponse struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type QuizSessionResponse struct {
+	ID               uuid.UUID            `json:"id"`
+	QuizID           uuid.UUID            `json:"quiz_id"`
+	UserID           uuid.UUID            `json:"user_id"`
+	StartedAt        time.Time            `json:"started_at"`
+	Deadline         time.Time            `json:"deadline"`
+	EndedAt          *time.Time           `json:"ended_at,omitempty"`
+	Submitted        bool                 `json:"submitted"`
+	AutoSubmitted    bool                 `json:"auto_submitted"`
+	Score            *int                 `json:"score,omitempty"`
+	RemainingSeconds int64                `json:"remaining_seconds"`
+	Answers          []UserAnswerResponse `json:"answers"`
+	Quiz             *QuizResponse        `json:"quiz,omitempty"`
+	Result           *QuizResultResponse  `json:"result,omitempty"`
+}
+
+type UserAnswerResponse struct {
+	QuestionID uuid.UUID            `json:"question_id"`
+	OptionIDs  []uuid.UUID          `json:"option_ids,omitempty"`
+	Text       string               `json:"text,omitempty"`
+	Order      []uuid.UUID          `json:"order,omitempty"`
+	Matches    map[uuid.UUID]string `json:"matches,omitempty"`
+}
+
+type QuizResultResponse struct {
+	Score     int                      `json:"score"`
+	Points    float64                  `json:"points"`
+	MaxPoints float64                  `json:"max_points"`
+	Questions []QuestionResultResponse `json:"questions"`
+}
+
+type QuestionResultResponse struct {
+	QuestionID       uuid.UUID          `json:"question_id"`
+	Type             string             `json:"type"`
+	Answer           UserAnswerResponse `json:"answer"`
+	CorrectOptionIDs []uuid.UUID        `json:"correct_option_ids,omitempty"`
+	Points           *float64           `json:"points,omitempty"`
+	MaxPoints        float64            `json:"max_points"`
+	Status           string             `json:"status,omitempty"`
+}
+
+type StartQuizSessionRequest struct {
+	QuizID string `json:"-" validate:"required,max=100"`
+	UserID string `json:"-" validate:"required,max=100"`
+}
+
+type GetQuizSessionRequest struct {
+	ID     string `json:"-" validate:"required,max=100"`
+	UserID string `json:"-" validate:"required,max=100"`
+}
+
+type SaveAnswerRequest struct {
+	SessionID  string            `json:"-" validate:"required,max=100"`
+	UserID     string            `json:"-" validate:"required,max=100"`
+	QuestionID string            `json:"question_id" validate:"required,max=100"`
+	OptionID   string            `json:"option_id" validate:"max=100"`
+	OptionIDs  []string          `json:"option_ids"`
+	Text       string            `json:"text" validate:"max=1000"`
+	Order      []string          `json:"order"`
+	Matches    map[string]string `json:"matches"`
+}
+
+type SubmitQuizSessionRequest struct {
+	ID     string `json:"-" validate:"required,max=100"`
+	UserID string `json:"-" validate:"required,max=100"`
+}
+
+type QuizAttemptResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	QuizID          uuid.UUID  `json:"quiz_id"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	Submitted       bool       `json:"submitted"`
+	AutoSubmitted   bool       `json:"auto_submitted"`
+	Score           *int       `json:"score,omitempty"`
+	DurationSeconds int64      `json:"duration_seconds"`
+}
+
+type QuizReviewResponse struct {
+	Attempt         QuizAttemptResponse      `json:"attempt"`
+	QuizName        string                   `json:"quiz_name"`
+	AnswersVisible  bool                     `json:"answers_visible"`
+	AnswersRevealAt *time.Time               `json:"answers_reveal_at,omitempty"`
+	Points          float64                  `json:"points"`
+	MaxPoints       float64                  `json:"max_points"`
+	Questions       []QuestionReviewResponse `json:"questions"`
+}
+
+type QuestionReviewResponse struct {
+	ID               uuid.UUID          `json:"id"`
+	Type             string             `json:"type"`
+	Content          []ContentBlock     `json:"content"`
+	Options          []OptionResponse   `json:"options"`
+	MatchTargets     []string           `json:"match_targets,omitempty"`
+	Answer           UserAnswerResponse `json:"answer"`
+	CorrectOptionIDs []uuid.UUID        `json:"correct_option_ids,omitempty"`
+	AnswerKey        *QuestionAnswerKey `json:"answer_key,omitempty"`
+	Status           string             `json:"status,omitempty"`
+	Points           *float64           `json:"points,omitempty"`
+}
+
+type SearchQuizAttemptRequest struct {
+	QuizID string `json:"-" validate:"required,max=100"`
+	UserID string `json:"-" validate:"required,max=100"`
+	Page   int    `json:"page,omitempty" validate:"min=1"`
+	Size   int    `json:"size,omitempty" validate:"min=1,max=100"`
+}
+
+type ReviewQuizSessionRequest struct {
+	ID     string `json:"-" validate:"required,max=100"`
+	UserID string `json:"-" validate:"required,max=100"`
+}
@@ -11,10 +11,12 @@ type SubjectResponse struct {
 	SubjectName string     `json:"subject_name,omitempty"`
 	CreatedAt   *time.Time `json:"created_at,omitempty"`
 	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
 type SubjectRequest struct {
 	SubjectName string `json:"subject_name,omitempty" validate:"required"`
+	Actor       *Auth  `json:"-"`
 }
 
 type GetSubjectRequest struct {
@@ -30,8 +32,15 @@ type SearchSubjectRequest struct {
 type UpdateSubjectRequest struct {
 	ID          string `json:"-,omitempty" validate:"required"`
 	SubjectName string `json:"subject_name,omitempty"`
+	Actor       *Auth  `json:"-"`
 }
 
 type DeleteSubjectRequest struct {
-	ID string `json:"-,omitempty" validate:"required"`
+	ID    string `json:"-,omitempty" validate:"required"`
+	Actor *Auth  `json:"-"`
+}
+
+type RestoreSubjectRequest struct {
+	ID    string `json:"-" validate:"required,uuid"`
+	Actor *Auth  `json:"-"`
 }
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+type TeacherSubjectResponse struct {
+	Subject   SubjectResponse `json:"subject"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type TeacherSubjectRequest struct {
+	UserID    string `json:"-" validate:"required,max=100"`
+	SubjectID string `json:"subject_id" validate:"required,max=100"`
+	Actor     *Auth  `json:"-"`
+}
+
+type ListTeacherSubjectRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+}
+
+type DeleteTeacherSubjectRequest struct {
+	UserID    string `json:"-" validate:"required,max=100"`
+	SubjectID string `json:"-" validate:"required,max=100"`
+	Actor     *Auth  `json:"-"`
+}
@@ -0,0 +1,10 @@
+package model
+
+// SearchTrashRequest lists the users, subjects or courses in the trash.
+type SearchTrashRequest struct {
+	Page int `json:"page,omitempty" validate:"min=1"`
+	Size int `json:"size,omitempty" validate:"min=1,max=100"`
+	// SubjectIDs limits the courses to the subjects of a scoped actor, nil is no limit.
+	SubjectIDs []string `json:"-"`
+	Actor      *Auth    `json:"-"`
+}
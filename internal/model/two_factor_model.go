@@ -0,0 +1,40 @@
+package model
+
+type TwoFactorEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type EnrollTwoFactorRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+}
+
+type ConfirmTwoFactorRequest struct {
+	UserID    string `json:"-" validate:"required,max=100"`
+	SessionID string `json:"-" validate:"required,max=100"`
+	Code      string `json:"code" validate:"required,max=20"`
+}
+
+// DisableTwoFactorRequest takes a TOTP code or a recovery code.
+type DisableTwoFactorRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+	Code   string `json:"code" validate:"required,max=20"`
+}
+
+type ResetTwoFactorRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+	Actor  *Auth  `json:"-"`
+}
+
+// LoginTwoFactorRequest is the second login step, Code is a TOTP code or a recovery code.
+type LoginTwoFactorRequest struct {
+	Challenge string `json:"challenge" validate:"required,max=2048"`
+	Code      string `json:"code" validate:"required,max=20"`
+	Device    string `json:"device,omitempty" validate:"max=100"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
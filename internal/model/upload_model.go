@@ -0,0 +1,23 @@
+package model
+
+import "io"
+
+type UploadFileRequest struct {
+	// Category picks the upload policy, one of the upload category constants.
+	Category string `validate:"required"`
+	// FileName is the name the client sent, it is normalised before the file is stored.
+	FileName string        `validate:"required,max=255"`
+	Size     int64         `validate:"min=1"`
+	File     io.ReadSeeker `validate:"required"`
+	// Actor becomes the owner of the media asset.
+	Actor *Auth
+}
+
+// UploadErrorResponse tells a client why an upload was rejected and what would be accepted.
+type UploadErrorResponse struct {
+	Category    string   `json:"category"`
+	MaxBytes    int64    `json:"max_bytes,omitempty"`
+	MaxPixels   int64    `json:"max_pixels,omitempty"`
+	ContentType string   `json:"content_type,omitempty"`
+	Allowed     []string `json:"allowed_extensions,omitempty"`
+}
@@ -22,6 +22,7 @@ type UserCourseListResponse struct {
 type UserCourseRequest struct {
 	UserID    string   `json:"user_id"`
 	CourseIDs []string `json:"course_ids"`
+	Actor     *Auth    `json:"-"`
 }
 
 type SearchUserCourseRequest struct {
@@ -31,6 +32,9 @@ type SearchUserCourseRequest struct {
 	AccessedAt time.Time `json:"accessed_at"`
 	Page       int       `json:"page,omitempty" validate:"min=1"`
 	Size       int       `json:"size,omitempty" validate:"min=1,max=100"`
+	// SubjectIDs limits the search to the subjects of a scoped actor, nil is no limit.
+	SubjectIDs []string `json:"-"`
+	Actor      *Auth    `json:"-"`
 }
 type GetUserCourseRequest struct {
 	CourseID string `json:"-" validate:"required,max=100"`
@@ -38,5 +42,6 @@ type GetUserCourseRequest struct {
 }
 
 type DeleteUserCourseRequest struct {
-	ID string `json:"-" validate:"required,max=100"`
+	ID    string `json:"-" validate:"required,max=100"`
+	Actor *Auth  `json:"-"`
 }
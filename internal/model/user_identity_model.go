@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserIdentityResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IdentityAuthorizationResponse is where to send the user, Flow goes into the flow cookie.
+type IdentityAuthorizationResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	Flow             string `json:"-"`
+}
+
+type StartIdentityRequest struct {
+	Provider string `json:"-" validate:"required,max=100"`
+	// LinkUserID is set when a signed in user links the identity to their account.
+	LinkUserID string `json:"-"`
+}
+
+// IdentityCallbackRequest carries the code and state the provider redirected back with.
+type IdentityCallbackRequest struct {
+	Provider  string `json:"-" validate:"required,max=100"`
+	Code      string `json:"code" validate:"required,max=2048"`
+	State     string `json:"state" validate:"required,max=255"`
+	Flow      string `json:"-" validate:"required"`
+	UserID    string `json:"-"`
+	Device    string `json:"device,omitempty" validate:"max=100"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+type ListUserIdentityRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+}
+
+type UnlinkUserIdentityRequest struct {
+	ID     string `json:"-" validate:"required,max=100"`
+	UserID string `json:"-" validate:"required,max=100"`
+}
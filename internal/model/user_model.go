@@ -7,21 +7,28 @@ import (
 )
 
 type UserResponse struct {
-	ID          *uuid.UUID `json:"id,omitempty"`
-	Username    string     `json:"username,omitempty"`
-	Email       string     `json:"email,omitempty"`
-	PhoneNumber string     `json:"phone_number,omitempty"`
-	GradeLevel  int        `json:"grade_level,omitempty"`
-	Role        string     `json:"role,omitempty" validate:"required,oneof=admin user"`
-	AvatarUrl   string     `json:"avatar_url,omitempty"`
-	BirthDate   *time.Time `json:"birth_date,omitempty"`
-	Token       string     `json:"token,omitempty"`
-	CreatedAt   *time.Time `json:"created_at,omitempty"`
-	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	ID               *uuid.UUID `json:"id,omitempty"`
+	Username         string     `json:"username,omitempty"`
+	Email            string     `json:"email,omitempty"`
+	PhoneNumber      string     `json:"phone_number,omitempty"`
+	GradeLevel       int        `json:"grade_level,omitempty"`
+	Role             string     `json:"role,omitempty"`
+	AvatarUrl        string     `json:"avatar_url,omitempty"`
+	BirthDate        *time.Time `json:"birth_date,omitempty"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at,omitempty"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled,omitempty"`
+	Token            string     `json:"token,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	RefreshToken     string     `json:"refresh_token,omitempty"`
+	MFARequired      bool       `json:"mfa_required,omitempty"`
+	Challenge        string     `json:"challenge,omitempty"`
+	CreatedAt        *time.Time `json:"created_at,omitempty"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
 }
 
 type VerifyUserRequest struct {
-	Token string `validate:"required,max=255" json:"token"`
+	Token string `validate:"required,max=2048" json:"token"`
 }
 
 type RegisterUserRequest struct {
@@ -34,10 +41,28 @@ type RegisterUserRequest struct {
 type LoginUserRequest struct {
 	Email    string `json:"email" validate:"required,max=100"`
 	Password string `json:"password" validate:"required,max=100"`
+	// Device is a name the client gives itself, shown in the session list.
+	Device    string `json:"device,omitempty" validate:"max=100"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required,max=2048"`
+}
+
+type ResendVerificationRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=255"`
+	IPAddress    string `json:"-"`
 }
 
 type LogoutUserRequest struct {
-	ID string `json:"id" validate:"required,max=100"`
+	ID        string `json:"id" validate:"required,max=100"`
+	SessionID string `json:"session_id" validate:"required,max=100"`
 }
 
 type GetUserRequest struct {
@@ -62,9 +87,48 @@ type UpdateUserRequest struct {
 	PhoneNumber string     `json:"phone_number,omitempty"`
 	GradeLevel  string     `json:"grade_level,omitempty"`
 	BirthDate   *time.Time `json:"birth_date,omitempty"`
-	AvatarUrl   string     `json:"avatar_url,omitempty"`
+	// AvatarUrl is only set by UploadAvatar to the file it stored, never from a request body.
+	AvatarUrl string `json:"-" form:"-"`
+	// Actor is the admin making the change, nil when users update themselves.
+	Actor *Auth `json:"-"`
+}
+
+type UpdateUserRoleRequest struct {
+	ID    string `json:"-" validate:"required,max=100"`
+	Role  string `json:"role" validate:"required,max=50"`
+	Actor *Auth  `json:"-"`
 }
 
 type DeleteUserRequest struct {
-	ID string `json:"id" validate:"required,max=100"`
+	ID    string `json:"id" validate:"required,max=100"`
+	Actor *Auth  `json:"-"`
+}
+
+type RestoreUserRequest struct {
+	ID    string `json:"-" validate:"required,uuid"`
+	Actor *Auth  `json:"-"`
+}
+
+type UnlockUserRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+	Actor  *Auth  `json:"-" validate:"required"`
+}
+
+type ImpersonateUserRequest struct {
+	UserID    string `json:"-" validate:"required,max=100"`
+	Actor     *Auth  `json:"-" validate:"required"`
+	Reason    string `json:"reason" validate:"required,max=500"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// ImpersonatedRequest is a request made while impersonating, kept for the audit trail.
+type ImpersonatedRequest struct {
+	SessionID      string `validate:"required,max=100"`
+	ImpersonatorID string `validate:"required,max=100"`
+	UserID         string `validate:"required,max=100"`
+	Method         string `validate:"required,max=10"`
+	Path           string `validate:"required"`
+	Status         int
+	IPAddress      string
 }
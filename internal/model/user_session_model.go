@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserSessionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Device    string    `json:"device,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Current   bool      `json:"current"`
+	// Impersonated is set on sessions an admin started to act as the user.
+	Impersonated bool      `json:"impersonated,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastSeenAt   time.Time `json:"last_seen_at"`
+}
+
+type ListUserSessionRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+	// SessionID is the session of the caller, flagged as current in the list.
+	SessionID string `json:"-"`
+}
+
+type RevokeUserSessionRequest struct {
+	ID     string `json:"-" validate:"required,max=100"`
+	UserID string `json:"-" validate:"required,max=100"`
+}
+
+type RevokeAllUserSessionRequest struct {
+	UserID string `json:"-" validate:"required,max=100"`
+	Actor  *Auth  `json:"-"`
+}
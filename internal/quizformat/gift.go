@@ -0,0 +1,468 @@
+package quizformat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseGIFT reads the GIFT format, questions are separated by blank lines.
+func ParseGIFT(r io.Reader) (*Quiz, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	quiz := new(Quiz)
+	parseErr := new(ParseError)
+	var block []string
+	start, lineNo := 0, 0
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		question, err := parseGIFTQuestion(strings.Join(block, "\n"))
+		if err != nil {
+			parseErr.add(start, "%s", err)
+		} else {
+			question.Line = start
+			quiz.Questions = append(quiz.Questions, *question)
+		}
+		block = nil
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "//"):
+			continue
+		case len(block) == 0 && strings.HasPrefix(trimmed, "$CATEGORY:"):
+			if quiz.Name == "" {
+				category := strings.TrimSpace(strings.TrimPrefix(trimmed, "$CATEGORY:"))
+				quiz.Name = category[strings.LastIndex(category, "/")+1:]
+			}
+		default:
+			if len(block) == 0 {
+				start = lineNo
+			}
+			block = append(block, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	if len(quiz.Questions) == 0 && len(parseErr.Errors) == 0 {
+		parseErr.add(1, "file has no questions")
+	}
+	if len(parseErr.Errors) > 0 {
+		return quiz, parseErr
+	}
+	return quiz, nil
+}
+
+func parseGIFTQuestion(text string) (*Question, error) {
+	question := new(Question)
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "::") {
+		end := indexUnescaped(text[2:], "::")
+		if end < 0 {
+			return nil, errors.New("question title is not closed with ::")
+		}
+		question.Name = unescapeGIFT(strings.TrimSpace(text[2 : 2+end]))
+		text = strings.TrimSpace(text[2+end+2:])
+	}
+
+	open := indexUnescaped(text, "{")
+	if open < 0 {
+		return nil, errors.New("question has no answer block, descriptions are not supported")
+	}
+	end := indexUnescaped(text[open+1:], "}")
+	if end < 0 {
+		return nil, errors.New("answer block is not closed with }")
+	}
+	end += open + 1
+
+	stem := strings.TrimSpace(text[:open])
+	if tail := strings.TrimSpace(text[end+1:]); tail != "" {
+		// Fill in the blank questions keep the gap in the text
+		stem += " _____ " + tail
+	}
+	isHTML := false
+	for _, format := range []string{"[html]", "[moodle]", "[plain]", "[markdown]"} {
+		if strings.HasPrefix(strings.ToLower(stem), format) {
+			isHTML = format == "[html]"
+			stem = strings.TrimSpace(stem[len(format):])
+			break
+		}
+	}
+	stem = unescapeGIFT(stem)
+	if isHTML {
+		question.Content = htmlToContent(stem)
+	} else if stem != "" {
+		question.Content = []model.ContentBlock{{Type: "text", Data: stem}}
+	}
+	if len(question.Content) == 0 {
+		return nil, errors.New("question text is empty")
+	}
+
+	if err := parseGIFTAnswers(question, text[open+1:end]); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+type giftAnswer struct {
+	correct bool
+	weight  *float64
+	text    string
+}
+
+func parseGIFTAnswers(question *Question, body string) error {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return errors.New("essay questions are not supported")
+	}
+
+	if body[0] == '#' {
+		question.Type = entity.QuestionTypeNumeric
+		return parseGIFTNumeric(question, body[1:])
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(cutFeedback(body))) {
+	case "T", "TRUE":
+		question.Type = entity.QuestionTypeTrueFalse
+		question.Options = []Option{{Text: "True", Correct: true}, {Text: "False"}}
+		return nil
+	case "F", "FALSE":
+		question.Type = entity.QuestionTypeTrueFalse
+		question.Options = []Option{{Text: "True"}, {Text: "False", Correct: true}}
+		return nil
+	}
+
+	answers, err := giftAnswers(body)
+	if err != nil {
+		return err
+	}
+
+	matching := false
+	for _, answer := range answers {
+		if indexUnescaped(answer.text, "->") >= 0 {
+			matching = true
+		}
+	}
+	if matching {
+		question.Type = entity.QuestionTypeMatching
+		for _, answer := range answers {
+			arrow := indexUnescaped(answer.text, "->")
+			if !answer.correct || arrow < 0 {
+				return errors.New("every matching answer must look like =item -> match")
+			}
+			question.Options = append(question.Options, Option{
+				Text:  unescapeGIFT(strings.TrimSpace(answer.text[:arrow])),
+				Match: unescapeGIFT(strings.TrimSpace(answer.text[arrow+2:])),
+			})
+		}
+		return nil
+	}
+
+	wrong := false
+	for _, answer := range answers {
+		if !answer.correct {
+			wrong = true
+		}
+	}
+	if !wrong {
+		// Only right answers means the student types one of them
+		question.Type = entity.QuestionTypeShortAnswer
+		for _, answer := range answers {
+			if answer.weight == nil || *answer.weight >= 100 {
+				question.Accepted = append(question.Accepted, unescapeGIFT(answer.text))
+			}
+		}
+		return nil
+	}
+
+	correct, weighted := 0, false
+	for _, answer := range answers {
+		isCorrect := answer.correct || (answer.weight != nil && *answer.weight > 0)
+		if !answer.correct && answer.weight != nil {
+			weighted = true
+		}
+		if isCorrect {
+			correct++
+		}
+		question.Options = append(question.Options, Option{Text: unescapeGIFT(answer.text), Correct: isCorrect})
+	}
+	if correct == 0 {
+		return errors.New("question has no correct answer")
+	}
+	question.Type = entity.QuestionTypeSingleChoice
+	if correct > 1 || weighted {
+		question.Type = entity.QuestionTypeMultipleSelect
+	}
+	return nil
+}
+
+// giftAnswers splits an answer block on its = and ~ markers.
+func giftAnswers(body string) ([]giftAnswer, error) {
+	var answers []giftAnswer
+	current := -1
+	var builder strings.Builder
+	finish := func() error {
+		if current < 0 {
+			if strings.TrimSpace(builder.String()) != "" {
+				return fmt.Errorf("unexpected text %q before the first = or ~", strings.TrimSpace(builder.String()))
+			}
+			return nil
+		}
+		text := strings.TrimSpace(cutFeedback(builder.String()))
+		if strings.HasPrefix(text, "%") {
+			end := strings.Index(text[1:], "%")
+			if end < 0 {
+				return fmt.Errorf("answer weight in %q is not closed with %%", text)
+			}
+			weight, err := strconv.ParseFloat(text[1:1+end], 64)
+			if err != nil {
+				return fmt.Errorf("answer weight %q is not a number", text[1:1+end])
+			}
+			answers[current].weight = &weight
+			text = strings.TrimSpace(text[end+2:])
+		}
+		if text == "" {
+			return errors.New("answer text is empty")
+		}
+		answers[current].text = text
+		return nil
+	}
+
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			builder.WriteByte(body[i])
+			if i+1 < len(body) {
+				i++
+				builder.WriteByte(body[i])
+			}
+		case '=', '~':
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			builder.Reset()
+			answers = append(answers, giftAnswer{correct: body[i] == '='})
+			current = len(answers) - 1
+		default:
+			builder.WriteByte(body[i])
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if len(answers) == 0 {
+		return nil, errors.New("answer block has no = or ~ answers")
+	}
+	return answers, nil
+}
+
+func parseGIFTNumeric(question *Question, body string) error {
+	body = strings.TrimSpace(body)
+	if indexUnescaped(body, "=") >= 0 {
+		answers, err := giftAnswers(body)
+		if err != nil {
+			return err
+		}
+		body = ""
+		for _, answer := range answers {
+			if answer.correct && (answer.weight == nil || *answer.weight >= 100) {
+				body = answer.text
+				break
+			}
+		}
+		if body == "" {
+			return errors.New("numeric question has no fully correct answer")
+		}
+	}
+	body = strings.TrimSpace(cutFeedback(body))
+
+	if low, high, ok := strings.Cut(body, ".."); ok {
+		min, err := strconv.ParseFloat(strings.TrimSpace(low), 64)
+		if err != nil {
+			return fmt.Errorf("numeric range start %q is not a number", low)
+		}
+		max, err := strconv.ParseFloat(strings.TrimSpace(high), 64)
+		if err != nil {
+			return fmt.Errorf("numeric range end %q is not a number", high)
+		}
+		value := (min + max) / 2
+		question.Value = &value
+		question.Tolerance = (max - min) / 2
+		return nil
+	}
+
+	valueText, toleranceText, hasTolerance := strings.Cut(body, ":")
+	value, err := strconv.ParseFloat(strings.TrimSpace(valueText), 64)
+	if err != nil {
+		return fmt.Errorf("numeric answer %q is not a number", valueText)
+	}
+	question.Value = &value
+	if hasTolerance {
+		question.Tolerance, err = strconv.ParseFloat(strings.TrimSpace(toleranceText), 64)
+		if err != nil {
+			return fmt.Errorf("numeric tolerance %q is not a number", toleranceText)
+		}
+	}
+	return nil
+}
+
+// WriteGIFT writes the quiz as GIFT. Nothing is written when a question cannot be expressed in
+// GIFT, the *WriteError lists every such question.
+func WriteGIFT(w io.Writer, quiz *Quiz) error {
+	names := make([]string, len(quiz.Questions))
+	answerBlocks := make([]string, len(quiz.Questions))
+	writeErr := new(WriteError)
+	for i, question := range quiz.Questions {
+		names[i] = question.Name
+		if names[i] == "" {
+			names[i] = fmt.Sprintf("Q%d", i+1)
+		}
+		answers, err := giftAnswerBlock(&question)
+		if err != nil {
+			writeErr.add(names[i], "%s", err)
+		}
+		answerBlocks[i] = answers
+	}
+	if len(writeErr.Errors) > 0 {
+		return writeErr
+	}
+
+	writer := bufio.NewWriter(w)
+	if quiz.Name != "" {
+		fmt.Fprintf(writer, "$CATEGORY: %s\n\n", strings.ReplaceAll(quiz.Name, "\n", " "))
+	}
+	for i, question := range quiz.Questions {
+		name, answers := names[i], answerBlocks[i]
+		text := ""
+		if plainContent(question.Content) {
+			text = escapeGIFT(question.Content[0].Data)
+		} else {
+			text = "[html]" + escapeGIFT(contentToHTML(question.Content))
+		}
+		fmt.Fprintf(writer, "::%s:: %s {%s}\n\n", escapeGIFT(name), text, answers)
+	}
+	return writer.Flush()
+}
+
+func giftAnswerBlock(question *Question) (string, error) {
+	var builder strings.Builder
+	switch question.Type {
+	case entity.QuestionTypeShortAnswer:
+		if len(question.Accepted) == 0 {
+			// An empty answer block reads back as an essay question
+			return "", errors.New("short answer question has no accepted answers")
+		}
+		for _, accepted := range question.Accepted {
+			builder.WriteString("=" + escapeGIFT(accepted) + " ")
+		}
+		return strings.TrimSpace(builder.String()), nil
+	case entity.QuestionTypeNumeric:
+		if question.Value == nil {
+			return "", errors.New("numeric question has no answer key")
+		}
+		return "#" + formatNumber(*question.Value) + ":" + formatNumber(question.Tolerance), nil
+	case entity.QuestionTypeMatching:
+		for _, option := range question.Options {
+			builder.WriteString("\n\t=" + escapeGIFT(option.Text) + " -> " + escapeGIFT(option.Match))
+		}
+		return builder.String() + "\n", nil
+	case entity.QuestionTypeOrdering:
+		return "", errors.New("ordering questions cannot be written as GIFT, export as Moodle XML")
+	case entity.QuestionTypeTrueFalse:
+		for _, option := range question.Options {
+			if option.Correct && strings.EqualFold(option.Text, "true") {
+				return "TRUE", nil
+			}
+			if option.Correct && strings.EqualFold(option.Text, "false") {
+				return "FALSE", nil
+			}
+		}
+	}
+
+	correct := 0
+	for _, option := range question.Options {
+		if option.Correct {
+			correct++
+		}
+	}
+	if correct == 0 {
+		return "", errors.New("question has no correct option")
+	}
+	for _, option := range question.Options {
+		switch {
+		case question.Type != entity.QuestionTypeMultipleSelect && option.Correct:
+			builder.WriteString("\n\t=")
+		case question.Type != entity.QuestionTypeMultipleSelect:
+			builder.WriteString("\n\t~")
+		case option.Correct:
+			builder.WriteString("\n\t~%" + formatWeight(100/float64(correct)) + "%")
+		default:
+			builder.WriteString("\n\t~%-100%")
+		}
+		builder.WriteString(escapeGIFT(option.Text))
+	}
+	return builder.String() + "\n", nil
+}
+
+// cutFeedback drops the #feedback that may follow an answer.
+func cutFeedback(text string) string {
+	if i := indexUnescaped(text, "#"); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
+
+// indexUnescaped is strings.Index that skips characters escaped with a backslash.
+func indexUnescaped(s string, substr string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	giftEscaper   = strings.NewReplacer(`\`, `\\`, `~`, `\~`, `=`, `\=`, `#`, `\#`, `{`, `\{`, `}`, `\}`, `:`, `\:`, "\n", `\n`)
+	giftUnescaper = strings.NewReplacer(`\\`, `\`, `\~`, `~`, `\=`, `=`, `\#`, `#`, `\{`, `{`, `\}`, `}`, `\:`, `:`, `\n`, "\n")
+)
+
+func escapeGIFT(text string) string {
+	return giftEscaper.Replace(text)
+}
+
+func unescapeGIFT(text string) string {
+	return strings.TrimSpace(giftUnescaper.Replace(text))
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatWeight rounds an answer weight to the five decimals Moodle uses for its grade fractions.
+func formatWeight(weight float64) string {
+	return strings.TrimRight(strings.TrimRight(strconv.FormatFloat(weight, 'f', 5, 64), "0"), ".")
+}
@@ -0,0 +1,162 @@
+package quizformat
+
+import (
+	"bytes"
+	"errors"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func float(value float64) *float64 {
+	return &value
+}
+
+func text(data string) []model.ContentBlock {
+	return []model.ContentBlock{{Type: "text", Data: data}}
+}
+
+// sampleQuiz holds a question of every type both formats can express.
+func sampleQuiz() *Quiz {
+	return &Quiz{
+		Name: "Fractions",
+		Questions: []Question{
+			{Name: "Q1", Type: entity.QuestionTypeSingleChoice, Content: text("1/2 + 1/4?"), Options: []Option{
+				{Text: "3/4", Correct: true}, {Text: "2/6"},
+			}},
+			{Name: "Q2", Type: entity.QuestionTypeMultipleSelect, Content: text("Which equal 1/2?"), Options: []Option{
+				{Text: "2/4", Correct: true}, {Text: "3/6", Correct: true}, {Text: "1/3"},
+			}},
+			{Name: "Q3", Type: entity.QuestionTypeTrueFalse, Content: text("1/2 > 1/3"), Options: []Option{
+				{Text: "True", Correct: true}, {Text: "False"},
+			}},
+			{Name: "Q4", Type: entity.QuestionTypeShortAnswer, Content: text("Name the top of a fraction"), Accepted: []string{"numerator", "the numerator"}},
+			{Name: "Q5", Type: entity.QuestionTypeNumeric, Content: text("1/8 as a decimal"), Value: float(0.125), Tolerance: 0.001},
+			{Name: "Q6", Type: entity.QuestionTypeMatching, Content: text("Match the halves"), Options: []Option{
+				{Text: "1/2", Match: "0.5"}, {Text: "1/4", Match: "0.25"},
+			}},
+			{Name: "Q7", Type: entity.QuestionTypeSingleChoice, Content: []model.ContentBlock{
+				{Type: "text", Data: "Which shape is shaded a third?"},
+				{Type: "image", Data: "https://cdn.example/shapes.png"},
+			}, Options: []Option{{Text: "A", Correct: true}, {Text: "B"}}},
+		},
+	}
+}
+
+// withoutLines clears the source lines parsing adds, so a parsed quiz compares with the written one.
+func withoutLines(quiz *Quiz) *Quiz {
+	for i := range quiz.Questions {
+		quiz.Questions[i].Line = 0
+	}
+	return quiz
+}
+
+func TestGIFTRoundTrip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := WriteGIFT(buf, sampleQuiz()); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseGIFT(buf)
+	if err != nil {
+		t.Fatalf("ParseGIFT: %v\n%s", err, buf)
+	}
+	if got, want := withoutLines(parsed), sampleQuiz(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip changed the quiz\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestGIFTEscaping(t *testing.T) {
+	quiz := &Quiz{Questions: []Question{{
+		Name:    "Braces {and} colons::",
+		Type:    entity.QuestionTypeSingleChoice,
+		Content: text(`Is a=b~c#d {x} \ y: z?`),
+		Options: []Option{{Text: "a = {1}", Correct: true}, {Text: `#2 ~ \3`}},
+	}}}
+	buf := new(bytes.Buffer)
+	if err := WriteGIFT(buf, quiz); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseGIFT(buf)
+	if err != nil {
+		t.Fatalf("ParseGIFT: %v\n%s", err, buf)
+	}
+	if got := withoutLines(parsed); !reflect.DeepEqual(got, quiz) {
+		t.Fatalf("round trip changed the quiz\ngot  %+v\nwant %+v", got, quiz)
+	}
+}
+
+func TestParseGIFT(t *testing.T) {
+	source := "\ufeff$CATEGORY: $course$/Maths/Fractions\n" +
+		"// a comment\n" +
+		"::Q1:: Two halves make {=one ~two#not quite}\n" +
+		"\n" +
+		"What is 2+2? {#4:0}\n" +
+		"\n" +
+		"Pick the primes {~%50%2 ~%50%3 ~%-100%4}\n"
+	quiz, err := ParseGIFT(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quiz.Name != "Fractions" {
+		t.Fatalf("Name = %q, want %q", quiz.Name, "Fractions")
+	}
+	want := []Question{
+		{Line: 3, Name: "Q1", Type: entity.QuestionTypeSingleChoice, Content: text("Two halves make"), Options: []Option{
+			{Text: "one", Correct: true}, {Text: "two"},
+		}},
+		{Line: 5, Type: entity.QuestionTypeNumeric, Content: text("What is 2+2?"), Value: float(4)},
+		{Line: 7, Type: entity.QuestionTypeMultipleSelect, Content: text("Pick the primes"), Options: []Option{
+			{Text: "2", Correct: true}, {Text: "3", Correct: true}, {Text: "4"},
+		}},
+	}
+	if !reflect.DeepEqual(quiz.Questions, want) {
+		t.Fatalf("Questions = %+v, want %+v", quiz.Questions, want)
+	}
+}
+
+func TestParseGIFTErrors(t *testing.T) {
+	source := "Good {T}\n" +
+		"\n" +
+		"// the essay below starts on line 4\n" +
+		"Essay {}\n" +
+		"\n" +
+		"No answers\n" +
+		"\n" +
+		"Unclosed {=a\n" +
+		"\n" +
+		"Wrong only {~a ~b}\n"
+	_, err := ParseGIFT(strings.NewReader(source))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("err = %v, want a *ParseError", err)
+	}
+	var lines []int
+	for _, lineError := range parseErr.Errors {
+		lines = append(lines, lineError.Line)
+	}
+	if want := []int{4, 6, 8, 10}; !reflect.DeepEqual(lines, want) {
+		t.Fatalf("error lines = %v, want %v (%v)", lines, want, err)
+	}
+}
+
+func TestWriteGIFTRejectsInexpressibleQuestions(t *testing.T) {
+	quiz := sampleQuiz()
+	quiz.Questions = append(quiz.Questions,
+		Question{Name: "Order", Type: entity.QuestionTypeOrdering, Content: text("Smallest first"), Options: []Option{{Text: "1/4"}, {Text: "1/2"}}},
+		Question{Name: "Blank", Type: entity.QuestionTypeShortAnswer, Content: text("Anything")},
+	)
+	buf := new(bytes.Buffer)
+	err := WriteGIFT(buf, quiz)
+	var writeErr *WriteError
+	if !errors.As(err, &writeErr) {
+		t.Fatalf("err = %v, want a *WriteError", err)
+	}
+	if len(writeErr.Errors) != 2 || writeErr.Errors[0].Name != "Order" || writeErr.Errors[1].Name != "Blank" {
+		t.Fatalf("Errors = %+v, want Order and Blank", writeErr.Errors)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("wrote %q, want nothing", buf)
+	}
+}
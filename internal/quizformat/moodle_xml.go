@@ -0,0 +1,347 @@
+package quizformat
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type moodleText struct {
+	Format string `xml:"format,attr,omitempty"`
+	Text   string `xml:"text"`
+}
+
+type moodleAnswer struct {
+	Fraction  string `xml:"fraction,attr"`
+	Format    string `xml:"format,attr,omitempty"`
+	Text      string `xml:"text"`
+	Tolerance string `xml:"tolerance,omitempty"`
+}
+
+type moodleSubQuestion struct {
+	Format string     `xml:"format,attr,omitempty"`
+	Text   string     `xml:"text"`
+	Answer moodleText `xml:"answer"`
+}
+
+type moodleQuestion struct {
+	XMLName      xml.Name            `xml:"question"`
+	Type         string              `xml:"type,attr"`
+	Category     *moodleText         `xml:"category,omitempty"`
+	Name         *moodleText         `xml:"name,omitempty"`
+	QuestionText *moodleText         `xml:"questiontext,omitempty"`
+	Single       string              `xml:"single,omitempty"`
+	UseCase      string              `xml:"usecase,omitempty"`
+	Answers      []moodleAnswer      `xml:"answer"`
+	SubQuestions []moodleSubQuestion `xml:"subquestion"`
+}
+
+// ParseMoodleXML reads the Moodle XML format, errors carry the line of the <question> element.
+func ParseMoodleXML(r io.Reader) (*Quiz, error) {
+	decoder := xml.NewDecoder(r)
+	quiz := new(Quiz)
+	parseErr := new(ParseError)
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			line, _ := decoder.InputPos()
+			parseErr.add(line, "invalid xml: %s", err)
+			return quiz, parseErr
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "question" {
+			continue
+		}
+
+		line, _ := decoder.InputPos()
+		element := new(moodleQuestion)
+		if err := decoder.DecodeElement(element, &start); err != nil {
+			parseErr.add(line, "invalid question: %s", err)
+			return quiz, parseErr
+		}
+		if element.Type == "category" {
+			if quiz.Name == "" && element.Category != nil {
+				category := strings.TrimSpace(element.Category.Text)
+				quiz.Name = category[strings.LastIndex(category, "/")+1:]
+			}
+			continue
+		}
+
+		question, err := parseMoodleQuestion(element)
+		if err != nil {
+			parseErr.add(line, "%s", err)
+			continue
+		}
+		question.Line = line
+		quiz.Questions = append(quiz.Questions, *question)
+	}
+
+	if len(quiz.Questions) == 0 && len(parseErr.Errors) == 0 {
+		parseErr.add(1, "file has no questions")
+	}
+	if len(parseErr.Errors) > 0 {
+		return quiz, parseErr
+	}
+	return quiz, nil
+}
+
+func parseMoodleQuestion(element *moodleQuestion) (*Question, error) {
+	question := new(Question)
+	if element.Name != nil {
+		question.Name = strings.TrimSpace(element.Name.Text)
+	}
+	if element.QuestionText != nil {
+		question.Content = htmlToContent(element.QuestionText.Text)
+	}
+	if len(question.Content) == 0 {
+		return nil, errors.New("question text is empty")
+	}
+	for _, block := range question.Content {
+		if block.Type == "image" && strings.HasPrefix(block.Data, "@@PLUGINFILE@@") {
+			return nil, errors.New("embedded files are not supported, link images by url")
+		}
+	}
+
+	switch element.Type {
+	case "multichoice":
+		question.Type = entity.QuestionTypeMultipleSelect
+		if strings.TrimSpace(element.Single) != "false" && strings.TrimSpace(element.Single) != "0" {
+			question.Type = entity.QuestionTypeSingleChoice
+		}
+		correct := 0
+		for _, answer := range element.Answers {
+			fraction, err := parseFraction(answer.Fraction)
+			if err != nil {
+				return nil, err
+			}
+			if fraction > 0 {
+				correct++
+			}
+			question.Options = append(question.Options, Option{Text: answerText(answer), Correct: fraction > 0})
+		}
+		if correct == 0 {
+			return nil, errors.New("question has no correct answer")
+		}
+	case "truefalse":
+		question.Type = entity.QuestionTypeTrueFalse
+		question.Options = []Option{{Text: "True"}, {Text: "False"}}
+		for _, answer := range element.Answers {
+			fraction, err := parseFraction(answer.Fraction)
+			if err != nil {
+				return nil, err
+			}
+			if fraction <= 0 {
+				continue
+			}
+			switch strings.ToLower(answerText(answer)) {
+			case "true":
+				question.Options[0].Correct = true
+			case "false":
+				question.Options[1].Correct = true
+			}
+		}
+	case "shortanswer":
+		question.Type = entity.QuestionTypeShortAnswer
+		question.CaseSensitive = strings.TrimSpace(element.UseCase) == "1"
+		for _, answer := range element.Answers {
+			fraction, err := parseFraction(answer.Fraction)
+			if err != nil {
+				return nil, err
+			}
+			if fraction >= 100 {
+				question.Accepted = append(question.Accepted, answerText(answer))
+			}
+		}
+		if len(question.Accepted) == 0 {
+			return nil, errors.New("short answer question has no fully correct answer")
+		}
+	case "numerical":
+		question.Type = entity.QuestionTypeNumeric
+		for _, answer := range element.Answers {
+			fraction, err := parseFraction(answer.Fraction)
+			if err != nil {
+				return nil, err
+			}
+			if fraction < 100 {
+				continue
+			}
+			value, err := strconv.ParseFloat(answerText(answer), 64)
+			if err != nil {
+				return nil, fmt.Errorf("numeric answer %q is not a number", answerText(answer))
+			}
+			question.Value = &value
+			if tolerance := strings.TrimSpace(answer.Tolerance); tolerance != "" {
+				if question.Tolerance, err = strconv.ParseFloat(tolerance, 64); err != nil {
+					return nil, fmt.Errorf("numeric tolerance %q is not a number", tolerance)
+				}
+			}
+			break
+		}
+		if question.Value == nil {
+			return nil, errors.New("numeric question has no fully correct answer")
+		}
+	case "matching":
+		question.Type = entity.QuestionTypeMatching
+		for _, subQuestion := range element.SubQuestions {
+			text := plainText(subQuestion.Text)
+			// Sub questions without text only add distractor targets, which matching questions do not keep
+			if text == "" {
+				continue
+			}
+			question.Options = append(question.Options, Option{Text: text, Match: strings.TrimSpace(subQuestion.Answer.Text)})
+		}
+	case "ordering":
+		question.Type = entity.QuestionTypeOrdering
+		for _, answer := range element.Answers {
+			question.Options = append(question.Options, Option{Text: answerText(answer)})
+		}
+	default:
+		return nil, fmt.Errorf("question type %q is not supported", element.Type)
+	}
+	return question, nil
+}
+
+// WriteMoodleXML writes the quiz as Moodle XML.
+func WriteMoodleXML(w io.Writer, quiz *Quiz) error {
+	if _, err := io.WriteString(w, xml.Header+"<quiz>\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("  ", "  ")
+	if quiz.Name != "" {
+		category := &moodleQuestion{Type: "category", Category: &moodleText{Text: "$course$/" + quiz.Name}}
+		if err := encoder.Encode(category); err != nil {
+			return err
+		}
+	}
+	for i, question := range quiz.Questions {
+		name := question.Name
+		if name == "" {
+			name = fmt.Sprintf("Q%d", i+1)
+		}
+		element := &moodleQuestion{
+			Name:         &moodleText{Text: name},
+			QuestionText: &moodleText{Format: "html", Text: contentToHTML(question.Content)},
+		}
+		switch question.Type {
+		case entity.QuestionTypeShortAnswer:
+			element.Type = "shortanswer"
+			element.UseCase = "0"
+			if question.CaseSensitive {
+				element.UseCase = "1"
+			}
+			for _, accepted := range question.Accepted {
+				element.Answers = append(element.Answers, moodleAnswer{Fraction: "100", Format: "moodle_auto_format", Text: accepted})
+			}
+		case entity.QuestionTypeNumeric:
+			element.Type = "numerical"
+			if question.Value != nil {
+				element.Answers = append(element.Answers, moodleAnswer{
+					Fraction:  "100",
+					Format:    "moodle_auto_format",
+					Text:      formatNumber(*question.Value),
+					Tolerance: formatNumber(question.Tolerance),
+				})
+			}
+		case entity.QuestionTypeMatching:
+			element.Type = "matching"
+			for _, option := range question.Options {
+				element.SubQuestions = append(element.SubQuestions, moodleSubQuestion{
+					Format: "html",
+					Text:   contentToHTML([]model.ContentBlock{{Type: "text", Data: option.Text}}),
+					Answer: moodleText{Text: option.Match},
+				})
+			}
+		case entity.QuestionTypeOrdering:
+			element.Type = "ordering"
+			for _, option := range question.Options {
+				element.Answers = append(element.Answers, moodleAnswer{Fraction: "1", Format: "moodle_auto_format", Text: option.Text})
+			}
+		case entity.QuestionTypeTrueFalse:
+			element.Type = "truefalse"
+			for _, option := range question.Options {
+				fraction := "0"
+				if option.Correct {
+					fraction = "100"
+				}
+				text := strings.ToLower(option.Text)
+				if text != "true" && text != "false" {
+					element.Type = "multichoice"
+					element.Single = "true"
+					text = option.Text
+				}
+				element.Answers = append(element.Answers, moodleAnswer{Fraction: fraction, Format: "moodle_auto_format", Text: text})
+			}
+		default:
+			element.Type = "multichoice"
+			element.Single = "true"
+			correct := 0
+			for _, option := range question.Options {
+				if option.Correct {
+					correct++
+				}
+			}
+			if question.Type == entity.QuestionTypeMultipleSelect {
+				element.Single = "false"
+			}
+			for _, option := range question.Options {
+				fraction := "0"
+				switch {
+				case option.Correct && element.Single == "true":
+					fraction = "100"
+				case option.Correct:
+					fraction = formatWeight(100 / float64(correct))
+				case element.Single == "false":
+					fraction = "-100"
+				}
+				element.Answers = append(element.Answers, moodleAnswer{Fraction: fraction, Format: "moodle_auto_format", Text: option.Text})
+			}
+		}
+		if err := encoder.Encode(element); err != nil {
+			return err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n</quiz>\n")
+	return err
+}
+
+func parseFraction(fraction string) (float64, error) {
+	if strings.TrimSpace(fraction) == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(fraction), 64)
+	if err != nil {
+		return 0, fmt.Errorf("answer fraction %q is not a number", fraction)
+	}
+	return value, nil
+}
+
+func answerText(answer moodleAnswer) string {
+	if answer.Format == "html" {
+		return plainText(answer.Text)
+	}
+	return strings.TrimSpace(answer.Text)
+}
+
+// plainText flattens html to the text of its blocks.
+func plainText(source string) string {
+	var parts []string
+	for _, block := range htmlToContent(source) {
+		if block.Type == "text" {
+			parts = append(parts, block.Data)
+		}
+	}
+	return strings.Join(parts, " ")
+}
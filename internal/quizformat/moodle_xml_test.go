@@ -0,0 +1,98 @@
+package quizformat
+
+import (
+	"bytes"
+	"errors"
+	"fp-designpattern/internal/entity"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMoodleXMLRoundTrip(t *testing.T) {
+	quiz := sampleQuiz()
+	quiz.Questions = append(quiz.Questions, Question{
+		Name:    "Q8",
+		Type:    entity.QuestionTypeOrdering,
+		Content: text("Smallest first"),
+		Options: []Option{{Text: "1/4"}, {Text: "1/2"}, {Text: "3/4"}},
+	})
+	quiz.Questions[3].CaseSensitive = true
+
+	buf := new(bytes.Buffer)
+	if err := WriteMoodleXML(buf, quiz); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseMoodleXML(buf)
+	if err != nil {
+		t.Fatalf("ParseMoodleXML: %v\n%s", err, buf)
+	}
+	if got := withoutLines(parsed); !reflect.DeepEqual(got, quiz) {
+		t.Fatalf("round trip changed the quiz\ngot  %+v\nwant %+v", got, quiz)
+	}
+}
+
+func TestMoodleXMLEscaping(t *testing.T) {
+	quiz := &Quiz{Questions: []Question{{
+		Name:    `Tags & "quotes"`,
+		Type:    entity.QuestionTypeSingleChoice,
+		Content: text(`Is <b>1 < 2</b> & "true"?`),
+		Options: []Option{{Text: "<yes> & more", Correct: true}, {Text: "]]> no"}},
+	}}}
+	buf := new(bytes.Buffer)
+	if err := WriteMoodleXML(buf, quiz); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseMoodleXML(buf)
+	if err != nil {
+		t.Fatalf("ParseMoodleXML: %v\n%s", err, buf)
+	}
+	if got := withoutLines(parsed); !reflect.DeepEqual(got, quiz) {
+		t.Fatalf("round trip changed the quiz\ngot  %+v\nwant %+v", got, quiz)
+	}
+}
+
+func TestParseMoodleXMLErrors(t *testing.T) {
+	source := `<?xml version="1.0"?>
+<quiz>
+  <question type="essay">
+    <questiontext format="html"><text>Discuss</text></questiontext>
+  </question>
+  <question type="shortanswer">
+    <questiontext format="html"><text>Nothing is right</text></questiontext>
+    <answer fraction="0"><text>x</text></answer>
+  </question>
+  <question type="multichoice">
+    <questiontext format="html"><text>Fine</text></questiontext>
+    <answer fraction="100"><text>a</text></answer>
+  </question>
+  <question type="multichoice">
+    <questiontext format="html"><text><![CDATA[<img src="@@PLUGINFILE@@/a.png">]]></text></questiontext>
+    <answer fraction="100"><text>a</text></answer>
+  </question>
+</quiz>
+`
+	quiz, err := ParseMoodleXML(strings.NewReader(source))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("err = %v, want a *ParseError", err)
+	}
+	var lines []int
+	for _, lineError := range parseErr.Errors {
+		lines = append(lines, lineError.Line)
+	}
+	if want := []int{3, 6, 14}; !reflect.DeepEqual(lines, want) {
+		t.Fatalf("error lines = %v, want %v (%v)", lines, want, err)
+	}
+	if len(quiz.Questions) != 1 || quiz.Questions[0].Line != 10 {
+		t.Fatalf("Questions = %+v, want the one on line 10", quiz.Questions)
+	}
+}
+
+func TestParseMoodleXMLInvalid(t *testing.T) {
+	_, err := ParseMoodleXML(strings.NewReader("<quiz>\n<question type=\"multichoice\">\n</quiz>"))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || len(parseErr.Errors) != 1 || parseErr.Errors[0].Line != 2 {
+		t.Fatalf("err = %v, want a *ParseError on line 2", err)
+	}
+}
@@ -0,0 +1,170 @@
+// Package quizformat reads and writes quizzes in the GIFT and Moodle XML exchange formats.
+package quizformat
+
+import (
+	"fmt"
+	"fp-designpattern/internal/model"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const (
+	FormatGIFT      = "gift"
+	FormatMoodleXML = "moodle_xml"
+)
+
+// Quiz is a parsed quiz file, Name comes from the first category of the file when there is one.
+type Quiz struct {
+	Name      string
+	Questions []Question
+}
+
+// Question is a question in a format neutral shape, Type uses the entity question types.
+type Question struct {
+	// Line is where the question starts in the source file.
+	Line    int
+	Name    string
+	Type    string
+	Content []model.ContentBlock
+	// Options lists the options, ordering questions list them in their correct order.
+	Options []Option
+	// Accepted, CaseSensitive, Value and Tolerance form the answer key of short answer and numeric questions.
+	Accepted      []string
+	CaseSensitive bool
+	Value         *float64
+	Tolerance     float64
+}
+
+type Option struct {
+	Text    string
+	Correct bool
+	Match   string
+}
+
+// LineError is a problem with the question starting at Line.
+type LineError struct {
+	Line    int
+	Message string
+}
+
+// ParseError collects every problem found in a file, so the whole file can be fixed in one go.
+type ParseError struct {
+	Errors []LineError
+}
+
+func (e *ParseError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, lineError := range e.Errors {
+		messages[i] = fmt.Sprintf("line %d: %s", lineError.Line, lineError.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ParseError) add(line int, format string, args ...any) {
+	e.Errors = append(e.Errors, LineError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// QuestionError is a problem with the question named Name.
+type QuestionError struct {
+	Name    string
+	Message string
+}
+
+// WriteError lists every question a format cannot express.
+type WriteError struct {
+	Errors []QuestionError
+}
+
+func (e *WriteError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, questionError := range e.Errors {
+		messages[i] = fmt.Sprintf("%s: %s", questionError.Name, questionError.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *WriteError) add(name string, format string, args ...any) {
+	e.Errors = append(e.Errors, QuestionError{Name: name, Message: fmt.Sprintf(format, args...)})
+}
+
+// Parse reads a quiz in the given format, a *ParseError lists the questions that could not be read.
+func Parse(format string, r io.Reader) (*Quiz, error) {
+	switch format {
+	case FormatGIFT:
+		return ParseGIFT(r)
+	case FormatMoodleXML:
+		return ParseMoodleXML(r)
+	}
+	return nil, fmt.Errorf("unknown quiz format %q", format)
+}
+
+// Write writes the quiz in the given format.
+func Write(format string, w io.Writer, quiz *Quiz) error {
+	switch format {
+	case FormatGIFT:
+		return WriteGIFT(w, quiz)
+	case FormatMoodleXML:
+		return WriteMoodleXML(w, quiz)
+	}
+	return fmt.Errorf("unknown quiz format %q", format)
+}
+
+var (
+	imagePattern = regexp.MustCompile(`(?i)<img[^>]*\ssrc\s*=\s*["']([^"']+)["'][^>]*>`)
+	breakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	tagPattern   = regexp.MustCompile(`<[^>]*>`)
+	spacePattern = regexp.MustCompile(`[ \t]+`)
+)
+
+// htmlToContent splits question html into text and image blocks.
+func htmlToContent(source string) []model.ContentBlock {
+	var content []model.ContentBlock
+	addText := func(fragment string) {
+		fragment = breakPattern.ReplaceAllString(fragment, "\n")
+		fragment = html.UnescapeString(tagPattern.ReplaceAllString(fragment, ""))
+		lines := strings.Split(fragment, "\n")
+		kept := lines[:0]
+		for _, line := range lines {
+			if line = strings.TrimSpace(spacePattern.ReplaceAllString(line, " ")); line != "" {
+				kept = append(kept, line)
+			}
+		}
+		if len(kept) > 0 {
+			content = append(content, model.ContentBlock{Type: "text", Data: strings.Join(kept, "\n")})
+		}
+	}
+
+	last := 0
+	for _, match := range imagePattern.FindAllStringSubmatchIndex(source, -1) {
+		addText(source[last:match[0]])
+		content = append(content, model.ContentBlock{Type: "image", Data: html.UnescapeString(source[match[2]:match[3]])})
+		last = match[1]
+	}
+	addText(source[last:])
+	return content
+}
+
+// contentToHTML renders text and image blocks as question html.
+func contentToHTML(content []model.ContentBlock) string {
+	var builder strings.Builder
+	for _, block := range content {
+		switch block.Type {
+		case "image":
+			builder.WriteString(`<img src="` + html.EscapeString(block.Data) + `" alt="">`)
+		default:
+			lines := strings.Split(block.Data, "\n")
+			for i, line := range lines {
+				lines[i] = html.EscapeString(line)
+			}
+			builder.WriteString("<p>" + strings.Join(lines, "<br>") + "</p>")
+		}
+	}
+	return builder.String()
+}
+
+// plainContent reports whether the content is a single text block that needs no html.
+func plainContent(content []model.ContentBlock) bool {
+	return len(content) == 1 && content[0].Type == "text" && !strings.Contains(content[0].Data, "\n")
+}
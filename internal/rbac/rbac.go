@@ -0,0 +1,133 @@
+// Package rbac maps roles to the named actions they may perform.
+package rbac
+
+const (
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+	RoleUser    = "user"
+
+	// All grants every permission.
+	All = "*"
+)
+
+const (
+	PermissionUserManage           = "user:manage"
+	PermissionUserImpersonate      = "user:impersonate"
+	PermissionSubjectCreate        = "subject:create"
+	PermissionSubjectUpdate        = "subject:update"
+	PermissionSubjectDelete        = "subject:delete"
+	PermissionTeacherSubjectManage = "teacher_subject:manage"
+	PermissionAuditRead            = "audit:read"
+	PermissionCourseRead           = "course:read"
+	PermissionCourseCreate         = "course:create"
+	PermissionCourseUpdate         = "course:update"
+	PermissionCourseDelete         = "course:delete"
+	PermissionMediaRead            = "media:read"
+	PermissionUserCourseRead       = "user_course:read"
+	PermissionUserCourseCreate     = "user_course:create"
+	PermissionUserCourseDelete     = "user_course:delete"
+	PermissionQuizRead             = "quiz:read"
+	PermissionQuizCreate           = "quiz:create"
+	PermissionQuizUpdate           = "quiz:update"
+	PermissionQuizDelete           = "quiz:delete"
+	PermissionQuestionBankRead     = "question_bank:read"
+	PermissionQuestionBankManage   = "question_bank:manage"
+)
+
+// Scopes of the routes every signed in user has whatever their role, API keys are limited to them
+// like to permissions but any key can be given them.
+const (
+	ScopeProfileRead  = "profile:read"
+	ScopeCourseAccess = "course:access"
+	ScopeQuizTake     = "quiz:take"
+)
+
+// SelfScopes lists the scopes of the routes every signed in user has.
+func SelfScopes() []string {
+	return []string{ScopeProfileRead, ScopeCourseAccess, ScopeQuizTake}
+}
+
+// Permissions lists every permission, API keys can only be scoped to these, SelfScopes or All.
+func Permissions() []string {
+	return []string{
+		PermissionUserManage, PermissionUserImpersonate, PermissionSubjectCreate, PermissionSubjectUpdate, PermissionSubjectDelete,
+		PermissionTeacherSubjectManage, PermissionAuditRead,
+		PermissionCourseRead, PermissionCourseCreate, PermissionCourseUpdate, PermissionCourseDelete, PermissionMediaRead,
+		PermissionUserCourseRead, PermissionUserCourseCreate, PermissionUserCourseDelete,
+		PermissionQuizRead, PermissionQuizCreate, PermissionQuizUpdate, PermissionQuizDelete,
+		PermissionQuestionBankRead, PermissionQuestionBankManage,
+	}
+}
+
+// InScopes reports whether scopes allow the permission, nil scopes allow everything the role can do.
+func InScopes(scopes []string, permission string) bool {
+	if scopes == nil {
+		return true
+	}
+	for _, scope := range scopes {
+		if scope == All || scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRoles are the grants used for the roles the config does not name.
+func DefaultRoles() map[string][]string {
+	return map[string][]string{
+		RoleAdmin: {All},
+		RoleTeacher: {
+			PermissionCourseRead, PermissionCourseCreate, PermissionCourseUpdate, PermissionCourseDelete, PermissionMediaRead,
+			PermissionUserCourseRead, PermissionUserCourseCreate, PermissionUserCourseDelete,
+			PermissionQuizRead, PermissionQuizCreate, PermissionQuizUpdate, PermissionQuizDelete,
+			PermissionQuestionBankRead, PermissionQuestionBankManage,
+		},
+		RoleUser: {},
+	}
+}
+
+// Policy answers what a role may do. Roles that are not unscoped only act on the subjects
+// assigned to the user, the usecases check that against the resource.
+type Policy struct {
+	grants   map[string]map[string]bool
+	unscoped map[string]bool
+}
+
+func NewPolicy(roles map[string][]string, unscoped []string) *Policy {
+	policy := &Policy{
+		grants:   make(map[string]map[string]bool, len(roles)),
+		unscoped: make(map[string]bool, len(unscoped)),
+	}
+	for role, permissions := range roles {
+		policy.grants[role] = make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			policy.grants[role][permission] = true
+		}
+	}
+	for _, role := range unscoped {
+		policy.unscoped[role] = true
+	}
+	return policy
+}
+
+func (p *Policy) Can(role string, permission string) bool {
+	grants := p.grants[role]
+	return grants[All] || grants[permission]
+}
+
+// Scoped reports whether the role is limited to the subjects assigned to the user.
+func (p *Policy) Scoped(role string) bool {
+	return !p.unscoped[role]
+}
+
+// Privileged reports whether the role is granted any permission, such accounts are not handed to
+// whoever proves an email at an identity provider.
+func (p *Policy) Privileged(role string) bool {
+	return len(p.grants[role]) > 0
+}
+
+// HasRole reports whether the role is known, users can only be given known roles.
+func (p *Policy) HasRole(role string) bool {
+	_, ok := p.grants[role]
+	return ok
+}
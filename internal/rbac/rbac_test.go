@@ -0,0 +1,113 @@
+package rbac
+
+import "testing"
+
+func TestPolicyCan(t *testing.T) {
+	policy := NewPolicy(map[string][]string{
+		RoleAdmin:   {All},
+		RoleTeacher: {PermissionCourseRead, PermissionCourseCreate},
+		RoleUser:    {},
+		"auditor":   {PermissionAuditRead, PermissionCourseRead},
+	}, []string{RoleAdmin, "auditor"})
+
+	tests := []struct {
+		role       string
+		permission string
+		want       bool
+	}{
+		{RoleAdmin, PermissionUserManage, true},
+		{RoleAdmin, PermissionQuizDelete, true},
+		{RoleAdmin, "anything:else", true},
+		{RoleTeacher, PermissionCourseRead, true},
+		{RoleTeacher, PermissionCourseCreate, true},
+		{RoleTeacher, PermissionCourseDelete, false},
+		{RoleTeacher, PermissionUserManage, false},
+		{RoleUser, PermissionCourseRead, false},
+		{"auditor", PermissionAuditRead, true},
+		{"auditor", PermissionCourseCreate, false},
+		{"unknown", PermissionCourseRead, false},
+		{"", PermissionCourseRead, false},
+		// All is a grant, not a permission that can be checked for
+		{RoleTeacher, All, false},
+	}
+	for _, tt := range tests {
+		if got := policy.Can(tt.role, tt.permission); got != tt.want {
+			t.Errorf("Can(%q, %q) = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyRoles(t *testing.T) {
+	policy := NewPolicy(DefaultRoles(), []string{RoleAdmin})
+	tests := []struct {
+		role       string
+		scoped     bool
+		known      bool
+		privileged bool
+	}{
+		{RoleAdmin, false, true, true},
+		{RoleTeacher, true, true, true},
+		{RoleUser, true, true, false},
+		// Unknown roles are limited as far as they can be
+		{"unknown", true, false, false},
+	}
+	for _, tt := range tests {
+		if got := policy.Scoped(tt.role); got != tt.scoped {
+			t.Errorf("Scoped(%q) = %v, want %v", tt.role, got, tt.scoped)
+		}
+		if got := policy.HasRole(tt.role); got != tt.known {
+			t.Errorf("HasRole(%q) = %v, want %v", tt.role, got, tt.known)
+		}
+		if got := policy.Privileged(tt.role); got != tt.privileged {
+			t.Errorf("Privileged(%q) = %v, want %v", tt.role, got, tt.privileged)
+		}
+	}
+}
+
+func TestInScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		want   bool
+	}{
+		{"unscoped", nil, true},
+		{"empty", []string{}, false},
+		{"granted", []string{PermissionQuizRead, PermissionCourseRead}, true},
+		{"other", []string{PermissionQuizRead}, false},
+		{"all", []string{All}, true},
+	}
+	for _, tt := range tests {
+		if got := InScopes(tt.scopes, PermissionCourseRead); got != tt.want {
+			t.Errorf("%s: InScopes = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultRolesUseKnownPermissions(t *testing.T) {
+	known := map[string]bool{All: true}
+	for _, permission := range Permissions() {
+		known[permission] = true
+	}
+	for role, permissions := range DefaultRoles() {
+		for _, permission := range permissions {
+			if !known[permission] {
+				t.Errorf("role %s grants unknown permission %q", role, permission)
+			}
+		}
+	}
+}
+
+func TestSelfScopesAreNotPermissions(t *testing.T) {
+	policy := NewPolicy(DefaultRoles(), nil)
+	for _, scope := range SelfScopes() {
+		for _, permission := range Permissions() {
+			if scope == permission {
+				t.Errorf("%s is both a scope and a permission", scope)
+			}
+		}
+		// Only the route scopes grant them, a role never does unless it has All
+		if policy.Can(RoleTeacher, scope) {
+			t.Errorf("teacher is granted %s", scope)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	Repository[entity.APIKey]
+	Log *logrus.Logger
+}
+
+func NewAPIKeyRepository(log *logrus.Logger) *APIKeyRepository {
+	return &APIKeyRepository{
+		Log: log,
+	}
+}
+
+// FindActiveByHash finds an unrevoked, unexpired key with its owner.
+func (r *APIKeyRepository) FindActiveByHash(db *gorm.DB, key *entity.APIKey, hash string, now time.Time) error {
+	return db.
+		Preload("User").
+		Where("key_hash = ? AND revoked_at IS NULL AND expires_at > ?", hash, now).
+		First(key).Error
+}
+
+func (r *APIKeyRepository) FindActiveByIdAndUserId(db *gorm.DB, key *entity.APIKey, id string, userID string) error {
+	return db.
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		First(key).Error
+}
+
+func (r *APIKeyRepository) FindActiveByUserId(db *gorm.DB, userID string) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+	if err := db.
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeByUserId revokes every active key of the user and returns how many there were.
+func (r *APIKeyRepository) RevokeByUserId(db *gorm.DB, userID string, now time.Time) (int64, error) {
+	result := db.Model(new(entity.APIKey)).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now)
+	return result.RowsAffected, result.Error
+}
+
+// Touch records the use of a key.
+func (r *APIKeyRepository) Touch(db *gorm.DB, id string, now time.Time) error {
+	return db.Model(new(entity.APIKey)).
+		Where("id = ?", id).
+		Update("last_used_at", now).Error
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type AuditEventRepository struct {
+	Repository[entity.AuditEvent]
+	Log *logrus.Logger
+}
+
+func NewAuditEventRepository(log *logrus.Logger) *AuditEventRepository {
+	return &AuditEventRepository{
+		Log: log,
+	}
+}
+
+func (r *AuditEventRepository) Search(db *gorm.DB, request *model.SearchAuditEventRequest) ([]entity.AuditEvent, int64, error) {
+	var events []entity.AuditEvent
+	if err := db.Preload("Actor").
+		Scopes(r.FilterAuditEvent(request)).
+		Order("created_at DESC").
+		Offset((request.Page - 1) * request.Size).
+		Limit(request.Size).
+		Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := db.Model(&entity.AuditEvent{}).
+		Scopes(r.FilterAuditEvent(request)).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+func (r *AuditEventRepository) FilterAuditEvent(request *model.SearchAuditEventRequest) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if actorID := request.ActorID; actorID != "" {
+			tx = tx.Where("actor_id = ?", actorID)
+		}
+		if action := request.Action; action != "" {
+			tx = tx.Where("action = ?", action)
+		}
+		if resourceType := request.ResourceType; resourceType != "" {
+			tx = tx.Where("resource_type = ?", resourceType)
+		}
+		if resourceID := request.ResourceID; resourceID != "" {
+			tx = tx.Where("resource_id = ?", resourceID)
+		}
+		if from := request.From; from != nil {
+			tx = tx.Where("created_at >= ?", from)
+		}
+		if to := request.To; to != nil {
+			tx = tx.Where("created_at < ?", to)
+		}
+		return tx
+	}
+}
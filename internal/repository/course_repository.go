@@ -3,6 +3,7 @@ package repository
 import (
 	"fp-designpattern/internal/entity"
 	"fp-designpattern/internal/model"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -45,6 +46,53 @@ func (r *CourseRepository) Search(db *gorm.DB, request *model.SearchCourseReques
 	return courses, total, nil
 }
 
+// SearchDeleted lists the courses in the trash with their subjects, trashed or not, most recently
+// deleted first.
+func (r *CourseRepository) SearchDeleted(db *gorm.DB, request *model.SearchTrashRequest) ([]entity.Course, int64, error) {
+	var courses []entity.Course
+	if err := db.Unscoped().
+		Preload("Subject", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Scopes(r.FilterDeletedCourse(request)).
+		Order("deleted_at DESC").
+		Offset((request.Page - 1) * request.Size).
+		Limit(request.Size).
+		Find(&courses).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := db.Unscoped().Model(&entity.Course{}).
+		Scopes(r.FilterDeletedCourse(request)).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	return courses, total, nil
+}
+
+func (r *CourseRepository) FilterDeletedCourse(request *model.SearchTrashRequest) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("deleted_at IS NOT NULL")
+		if request.SubjectIDs != nil {
+			tx = tx.Where("subject_id IN ?", request.SubjectIDs)
+		}
+		return tx
+	}
+}
+
+// DeleteBySubjectId puts the courses of the subject in the trash along with it.
+func (r *CourseRepository) DeleteBySubjectId(db *gorm.DB, subjectID string) error {
+	return db.Where("subject_id = ?", subjectID).Delete(new(entity.Course)).Error
+}
+
+// RestoreBySubjectId takes the courses of the subject put in the trash since the time out of it,
+// those trashed along with the subject rather than before it.
+func (r *CourseRepository) RestoreBySubjectId(db *gorm.DB, subjectID string, since time.Time) (int64, error) {
+	result := db.Unscoped().Model(new(entity.Course)).
+		Where("subject_id = ? AND deleted_at >= ?", subjectID, since).
+		Update("deleted_at", nil)
+	return result.RowsAffected, result.Error
+}
+
 func (r *CourseRepository) FilterCourse(request *model.SearchCourseRequest) func(tx *gorm.DB) *gorm.DB {
 	return func(tx *gorm.DB) *gorm.DB {
 		if courseName := request.CourseName; courseName != "" {
@@ -56,6 +104,9 @@ func (r *CourseRepository) FilterCourse(request *model.SearchCourseRequest) func
 		if gradeLevel := request.GradeLevel; gradeLevel != 0 {
 			tx = tx.Where("grade_level = ?", gradeLevel)
 		}
+		if request.SubjectIDs != nil {
+			tx = tx.Where("subject_id IN ?", request.SubjectIDs)
+		}
 		return tx
 	}
 }
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/storage"
+)
+
+// GCSFileRepository keeps files as objects of a Google Cloud Storage bucket.
+type GCSFileRepository struct {
+	Client *storage.Client
+	Bucket string
+	// BaseURL is where the objects are served from, the public URL of the bucket unless a CDN
+	// or an emulator serves them.
+	BaseURL string
+}
+
+func NewGCSFileRepository(client *storage.Client, bucket string, baseURL string) *GCSFileRepository {
+	if baseURL == "" {
+		baseURL = "https://storage.googleapis.com/" + bucket
+	}
+	return &GCSFileRepository{
+		Client:  client,
+		Bucket:  bucket,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (r *GCSFileRepository) UploadFile(ctx context.Context, file io.Reader, fileName string, contentType string) (string, error) {
+	// Cancelling the context before Close discards a partly written object
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writer := r.Client.Bucket(r.Bucket).Object(fileName).NewWriter(ctx)
+	writer.ContentType = contentType
+	if _, err := io.Copy(writer, file); err != nil {
+		cancel()
+		writer.Close()
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to upload object: %w", err)
+	}
+
+	return r.BaseURL + "/" + fileName, nil
+}
+
+func (r *GCSFileRepository) DeleteFile(ctx context.Context, fileURL string) error {
+	fileName, ok := strings.CutPrefix(fileURL, r.BaseURL+"/")
+	if !ok {
+		return fmt.Errorf("file %s is not served from %s", fileURL, r.BaseURL)
+	}
+	return r.Client.Bucket(r.Bucket).Object(fileName).Delete(ctx)
+}
+
+func (r *GCSFileRepository) SignURL(ctx context.Context, fileURL string) (string, error) {
+	return fileURL, nil
+}
+
+func (r *GCSFileRepository) StoredURL(fileURL string) string {
+	return fileURL
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+)
+
+// newEmulatedGCS connects to the fake-gcs-server emulator at STORAGE_EMULATOR_HOST and creates a
+// bucket for the test, run the emulator with
+//
+//	docker run -p 4443:4443 fsouza/fake-gcs-server -scheme http -public-host localhost:4443
+//	STORAGE_EMULATOR_HOST=localhost:4443 go test ./internal/repository
+func newEmulatedGCS(t *testing.T) (*storage.Client, string) {
+	t.Helper()
+	if os.Getenv("STORAGE_EMULATOR_HOST") == "" {
+		t.Skip("STORAGE_EMULATOR_HOST is not set")
+	}
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	bucket := "course-files-" + strings.ToLower(strings.ReplaceAll(t.Name(), "/", "-"))
+	if err := client.Bucket(bucket).Create(ctx, "test-project", nil); err != nil {
+		t.Fatal(err)
+	}
+	return client, bucket
+}
+
+func TestGCSFileRepositoryUploadAndDelete(t *testing.T) {
+	client, bucket := newEmulatedGCS(t)
+	ctx := context.Background()
+	files := NewGCSFileRepository(client, bucket, "")
+
+	url, err := files.UploadFile(ctx, strings.NewReader("course image"), "courses/cover.png", "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://storage.googleapis.com/" + bucket + "/courses/cover.png"; url != want {
+		t.Fatalf("url = %q, want %q", url, want)
+	}
+
+	object := client.Bucket(bucket).Object("courses/cover.png")
+	attrs, err := object.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs.ContentType != "image/png" {
+		t.Fatalf("content type = %q, want image/png", attrs.ContentType)
+	}
+	reader, err := object.NewReader(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "course image" {
+		t.Fatalf("content = %q, want %q", content, "course image")
+	}
+
+	if err := files.DeleteFile(ctx, url); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := object.Attrs(ctx); !errors.Is(err, storage.ErrObjectNotExist) {
+		t.Fatalf("object still exists after delete: %v", err)
+	}
+}
+
+func TestGCSFileRepositoryBaseURL(t *testing.T) {
+	client, bucket := newEmulatedGCS(t)
+	ctx := context.Background()
+	files := NewGCSFileRepository(client, bucket, "https://cdn.example/files/")
+
+	url, err := files.UploadFile(ctx, strings.NewReader("x"), "courses/notes.txt", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://cdn.example/files/courses/notes.txt" {
+		t.Fatalf("url = %q, want https://cdn.example/files/courses/notes.txt", url)
+	}
+	if err := files.DeleteFile(ctx, "https://storage.googleapis.com/"+bucket+"/courses/notes.txt"); err == nil {
+		t.Fatal("deleted a file not served from the base url")
+	}
+	if err := files.DeleteFile(ctx, url); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,11 +1,13 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 type LocalFileRepository struct {
@@ -20,9 +22,11 @@ func NewLocalFileRepository(basePath, baseURL string) *LocalFileRepository {
 	}
 }
 
-func (r *LocalFileRepository) UploadFile(file multipart.File, fileName string, contentType string) (string, error) {
+func (r *LocalFileRepository) UploadFile(ctx context.Context, file io.Reader, fileName string, contentType string) (string, error) {
+	// The URL names the file where it is stored, even when fileName reaches outside BasePath
+	fileName = r.name(fileName)
 	// Ensure the base path exists
-	fullPath := filepath.Join(r.BasePath, fileName)
+	fullPath := r.path(fileName)
 	dir := filepath.Dir(fullPath)
 	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 		return "", fmt.Errorf("failed to create directories: %w", err)
@@ -38,10 +42,31 @@ func (r *LocalFileRepository) UploadFile(file multipart.File, fileName string, c
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
-	return filepath.ToSlash(filepath.Join(r.BaseURL, fileName)), nil
+	return strings.TrimSuffix(r.BaseURL, "/") + "/" + fileName, nil
 }
 
-func (r *LocalFileRepository) DeleteFile(fileURL string) error {
-	filePath := filepath.Join(r.BasePath, filepath.Base(fileURL))
-	return os.Remove(filePath)
+func (r *LocalFileRepository) DeleteFile(ctx context.Context, fileURL string) error {
+	fileName, ok := strings.CutPrefix(fileURL, strings.TrimSuffix(r.BaseURL, "/")+"/")
+	if !ok {
+		return fmt.Errorf("file %s is not served from %s", fileURL, r.BaseURL)
+	}
+	return os.Remove(r.path(fileName))
+}
+
+func (r *LocalFileRepository) SignURL(ctx context.Context, fileURL string) (string, error) {
+	return fileURL, nil
+}
+
+func (r *LocalFileRepository) StoredURL(fileURL string) string {
+	return fileURL
+}
+
+// name cleans the file name to a slash separated path that cannot leave BasePath.
+func (r *LocalFileRepository) name(fileName string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(fileName)), "/")
+}
+
+// path keeps the file under BasePath whatever its name.
+func (r *LocalFileRepository) path(fileName string) string {
+	return filepath.Join(r.BasePath, filepath.FromSlash(r.name(fileName)))
 }
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalFileRepositoryUploadAndDelete(t *testing.T) {
+	ctx := context.Background()
+	basePath := t.TempDir()
+	files := NewLocalFileRepository(basePath, "/images")
+
+	url, err := files.UploadFile(ctx, strings.NewReader("course image"), "courses/cover.png", "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "/images/courses/cover.png" {
+		t.Fatalf("url = %q, want /images/courses/cover.png", url)
+	}
+	content, err := os.ReadFile(filepath.Join(basePath, "courses", "cover.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "course image" {
+		t.Fatalf("content = %q, want %q", content, "course image")
+	}
+
+	if err := files.DeleteFile(ctx, url); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(basePath, "courses", "cover.png")); !os.IsNotExist(err) {
+		t.Fatalf("file still exists after delete: %v", err)
+	}
+}
+
+func TestLocalFileRepositoryStaysInBasePath(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	basePath := filepath.Join(root, "images")
+	files := NewLocalFileRepository(basePath, "/images")
+
+	url, err := files.UploadFile(ctx, strings.NewReader("x"), "../../escaped.txt", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The URL is where the file was kept, so it can be served and deleted again
+	if url != "/images/escaped.txt" {
+		t.Fatalf("url = %q, want /images/escaped.txt", url)
+	}
+	if _, err := os.Stat(filepath.Join(basePath, "escaped.txt")); err != nil {
+		t.Fatalf("file not kept under the base path: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escaped.txt")); !os.IsNotExist(err) {
+		t.Fatalf("file written outside the base path: %v", err)
+	}
+	if err := files.DeleteFile(ctx, "https://elsewhere.example/escaped.txt"); err == nil {
+		t.Fatal("deleted a file not served from the base url")
+	}
+	if err := files.DeleteFile(ctx, url); err != nil {
+		t.Fatalf("delete by the returned url: %v", err)
+	}
+}
+
+func TestLocalFileRepositoryAbsoluteBaseURL(t *testing.T) {
+	files := NewLocalFileRepository(t.TempDir(), "https://cdn.example/images/")
+	url, err := files.UploadFile(context.Background(), strings.NewReader("x"), "courses//./cover.png", "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://cdn.example/images/courses/cover.png" {
+		t.Fatalf("url = %q, want https://cdn.example/images/courses/cover.png", url)
+	}
+}
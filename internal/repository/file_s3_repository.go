@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// S3FileRepository keeps files as objects of a bucket of an S3-compatible store such as MinIO.
+type S3FileRepository struct {
+	Client *minio.Client
+	Bucket string
+	// BaseURL is where the objects are served from, the path-style URL of the bucket unless a CDN
+	// serves them.
+	BaseURL string
+	// PartSize is the size of the parts a file is uploaded in once it outgrows a single part, zero
+	// leaves it to the client.
+	PartSize uint64
+	// PresignTTL is how long a signed URL of a private bucket stays valid, zero for a public bucket
+	// whose URLs are handed out as they are.
+	PresignTTL time.Duration
+}
+
+func NewS3FileRepository(client *minio.Client, bucket string, baseURL string, partSize uint64, presignTTL time.Duration) *S3FileRepository {
+	if baseURL == "" {
+		baseURL = client.EndpointURL().String() + "/" + bucket
+	}
+	return &S3FileRepository{
+		Client:     client,
+		Bucket:     bucket,
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		PartSize:   partSize,
+		PresignTTL: presignTTL,
+	}
+}
+
+func (r *S3FileRepository) UploadFile(ctx context.Context, file io.Reader, fileName string, contentType string) (string, error) {
+	// An unknown size streams the file in parts, only one part is held in memory at a time
+	_, err := r.Client.PutObject(ctx, r.Bucket, fileName, file, -1, minio.PutObjectOptions{
+		ContentType: contentType,
+		PartSize:    r.PartSize,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload object: %w", err)
+	}
+
+	return r.BaseURL + "/" + fileName, nil
+}
+
+func (r *S3FileRepository) DeleteFile(ctx context.Context, fileURL string) error {
+	fileName, err := r.objectName(fileURL)
+	if err != nil {
+		return err
+	}
+	return r.Client.RemoveObject(ctx, r.Bucket, fileName, minio.RemoveObjectOptions{})
+}
+
+// SignURL signs the URLs of objects of the bucket, URLs of files kept elsewhere, such as an image
+// a course links to, are returned as they are.
+func (r *S3FileRepository) SignURL(ctx context.Context, fileURL string) (string, error) {
+	if r.PresignTTL <= 0 {
+		return fileURL, nil
+	}
+	fileName, err := r.objectName(fileURL)
+	if err != nil {
+		return fileURL, nil
+	}
+	signed, err := r.Client.PresignedGetObject(ctx, r.Bucket, fileName, r.PresignTTL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign object url: %w", err)
+	}
+	return signed.String(), nil
+}
+
+// StoredURL turns a signed URL of an object of the bucket, served from the endpoint, back into
+// its URL under BaseURL.
+func (r *S3FileRepository) StoredURL(fileURL string) string {
+	parsed, err := url.Parse(fileURL)
+	if err != nil || parsed.Query().Get("X-Amz-Signature") == "" {
+		return fileURL
+	}
+	fileName, ok := strings.CutPrefix(parsed.Path, "/"+r.Bucket+"/")
+	if !ok || parsed.Host != r.Client.EndpointURL().Host {
+		return fileURL
+	}
+	return r.BaseURL + "/" + fileName
+}
+
+func (r *S3FileRepository) objectName(fileURL string) (string, error) {
+	fileName, ok := strings.CutPrefix(fileURL, r.BaseURL+"/")
+	if !ok {
+		return "", fmt.Errorf("file %s is not served from %s", fileURL, r.BaseURL)
+	}
+	return fileName, nil
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"io"
+)
+
+// FileStorage keeps uploaded files and returns the URL each is served from. The config picks the
+// backend, the usecases do not depend on which.
+type FileStorage interface {
+	UploadFile(ctx context.Context, file io.Reader, fileName string, contentType string) (string, error)
+	DeleteFile(ctx context.Context, fileURL string) error
+	// SignURL returns the URL a client fetches the file from, a short-lived signed one when the
+	// backend keeps it private and the stored URL itself otherwise.
+	SignURL(ctx context.Context, fileURL string) (string, error)
+	// StoredURL maps a URL SignURL handed out back to the stored URL, so a client sending back what
+	// it was given does not store a URL that expires. Other URLs are returned as they are.
+	StoredURL(fileURL string) string
+}
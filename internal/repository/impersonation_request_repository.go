@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ImpersonationRequestRepository struct {
+	Repository[entity.ImpersonationRequest]
+	Log *logrus.Logger
+}
+
+func NewImpersonationRequestRepository(log *logrus.Logger) *ImpersonationRequestRepository {
+	return &ImpersonationRequestRepository{
+		Log: log,
+	}
+}
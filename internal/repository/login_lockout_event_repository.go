@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/sirupsen/logrus"
+)
+
+type LoginLockoutEventRepository struct {
+	Repository[entity.LoginLockoutEvent]
+	Log *logrus.Logger
+}
+
+func NewLoginLockoutEventRepository(log *logrus.Logger) *LoginLockoutEventRepository {
+	return &LoginLockoutEventRepository{
+		Log: log,
+	}
+}
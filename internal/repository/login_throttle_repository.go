@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottleRepository struct {
+	Repository[entity.LoginThrottle]
+	Log *logrus.Logger
+}
+
+func NewLoginThrottleRepository(log *logrus.Logger) *LoginThrottleRepository {
+	return &LoginThrottleRepository{
+		Log: log,
+	}
+}
+
+func (r *LoginThrottleRepository) FindByKey(db *gorm.DB, throttle *entity.LoginThrottle, scope string, key string) error {
+	return db.Where("scope = ? AND key = ?", scope, key).Take(throttle).Error
+}
+
+// FindForUpdate locks the throttle of the key, creating it without failures when there is none,
+// so concurrent failures of the same key are counted one after the other.
+func (r *LoginThrottleRepository) FindForUpdate(db *gorm.DB, throttle *entity.LoginThrottle, scope string, key string) error {
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entity.LoginThrottle{Scope: scope, Key: key}).Error; err != nil {
+		return err
+	}
+	return db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("scope = ? AND key = ?", scope, key).
+		Take(throttle).Error
+}
+
+func (r *LoginThrottleRepository) DeleteByKey(db *gorm.DB, scope string, key string) (int64, error) {
+	result := db.Where("scope = ? AND key = ?", scope, key).Delete(new(entity.LoginThrottle))
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/upload"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MediaAssetRepository struct {
+	Repository[entity.MediaAsset]
+	Log *logrus.Logger
+}
+
+func NewMediaAssetRepository(log *logrus.Logger) *MediaAssetRepository {
+	return &MediaAssetRepository{
+		Log: log,
+	}
+}
+
+func (r *MediaAssetRepository) FindByHash(db *gorm.DB, asset *entity.MediaAsset, hash string) error {
+	return db.Where("hash = ?", hash).Take(asset).Error
+}
+
+func (r *MediaAssetRepository) FindByURLs(db *gorm.DB, urls []string) ([]entity.MediaAsset, error) {
+	var assets []entity.MediaAsset
+	if err := db.Where("url IN ?", urls).Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// CreateIfAbsent creates the asset unless one with its hash exists, it reports whether it did.
+// Two uploads of the same file racing each other end up with one asset.
+func (r *MediaAssetRepository) CreateIfAbsent(db *gorm.DB, asset *entity.MediaAsset) (bool, error) {
+	result := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "hash"}}, DoNothing: true}).Create(asset)
+	return result.RowsAffected > 0, result.Error
+}
+
+// referenceCount counts the courses with an image or file block of the asset URL, trashed ones
+// included as a restore brings them back, and the users with it as their avatar.
+const referenceCount = `(SELECT COUNT(*) FROM courses WHERE courses.content @> jsonb_build_array(jsonb_build_object('type', 'image', 'data', media_assets.url))
+		OR courses.content @> jsonb_build_array(jsonb_build_object('type', 'file', 'data', media_assets.url)))
+	+ (SELECT COUNT(*) FROM users WHERE users.avatar_url = media_assets.url)`
+
+// RecountReferences updates the reference count of the assets served from urls.
+func (r *MediaAssetRepository) RecountReferences(db *gorm.DB, urls []string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	return db.Model(&entity.MediaAsset{}).
+		Where("url IN ?", urls).
+		Update("reference_count", gorm.Expr(referenceCount)).Error
+}
+
+// RecountAllReferences updates the reference count of every referenced asset, after purged
+// courses and users took their references along.
+func (r *MediaAssetRepository) RecountAllReferences(db *gorm.DB) error {
+	return db.Model(&entity.MediaAsset{}).
+		Where("reference_count > 0").
+		Update("reference_count", gorm.Expr(referenceCount)).Error
+}
+
+func (r *MediaAssetRepository) Search(db *gorm.DB, request *model.SearchMediaAssetRequest) ([]entity.MediaAsset, int64, error) {
+	var assets []entity.MediaAsset
+	if err := db.Preload("Owner").
+		Scopes(r.FilterMediaAsset(request)).
+		Order("created_at DESC").
+		Offset((request.Page - 1) * request.Size).
+		Limit(request.Size).
+		Find(&assets).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := db.Model(&entity.MediaAsset{}).
+		Scopes(r.FilterMediaAsset(request)).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	return assets, total, nil
+}
+
+// FilterMediaAsset leaves avatars out, the library is for course content and an avatar is only
+// the business of its user.
+func (r *MediaAssetRepository) FilterMediaAsset(request *model.SearchMediaAssetRequest) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("category <> ?", upload.CategoryAvatar)
+		if keyword := request.Keyword; keyword != "" {
+			// File names are stored normalised to lowercase
+			tx = tx.Where("file_name LIKE ?", "%"+strings.ToLower(keyword)+"%")
+		}
+		if contentType := request.ContentType; strings.HasSuffix(contentType, "/") {
+			tx = tx.Where("content_type LIKE ?", contentType+"%")
+		} else if contentType != "" {
+			tx = tx.Where("content_type = ?", contentType)
+		}
+		if category := request.Category; category != "" {
+			tx = tx.Where("category = ?", category)
+		}
+		if ownerID := request.OwnerID; ownerID != "" {
+			tx = tx.Where("owner_id = ?", ownerID)
+		}
+		return tx
+	}
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PasswordResetTokenRepository struct {
+	Repository[entity.PasswordResetToken]
+	Log *logrus.Logger
+}
+
+func NewPasswordResetTokenRepository(log *logrus.Logger) *PasswordResetTokenRepository {
+	return &PasswordResetTokenRepository{
+		Log: log,
+	}
+}
+
+// FindByHashForUpdate locks the token row so it cannot be redeemed twice.
+func (r *PasswordResetTokenRepository) FindByHashForUpdate(db *gorm.DB, resetToken *entity.PasswordResetToken, hash string) error {
+	return db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("User").
+		Where("token_hash = ?", hash).
+		First(resetToken).Error
+}
+
+// InvalidateByUserId marks the unused tokens of the user as used, so only the latest mailed token works.
+func (r *PasswordResetTokenRepository) InvalidateByUserId(db *gorm.DB, userID string, now time.Time) error {
+	return db.Model(new(entity.PasswordResetToken)).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", now).Error
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type QuestionOptionRepository struct {
+	Repository[entity.QuestionOption]
+	Log *logrus.Logger
+}
+
+func NewQuestionOptionRepository(log *logrus.Logger) *QuestionOptionRepository {
+	return &QuestionOptionRepository{
+		Log: log,
+	}
+}
+
+func (r *QuestionOptionRepository) FindByIdAndQuestionId(db *gorm.DB, option *entity.QuestionOption, id string, questionID string) error {
+	return db.Where("id = ? AND question_id = ?", id, questionID).First(option).Error
+}
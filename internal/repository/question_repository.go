@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type QuestionRepository struct {
+	Repository[entity.Question]
+	Log *logrus.Logger
+}
+
+func NewQuestionRepository(log *logrus.Logger) *QuestionRepository {
+	return &QuestionRepository{
+		Log: log,
+	}
+}
+
+func (r *QuestionRepository) FindByIdAndQuizId(db *gorm.DB, question *entity.Question, id string, quizID string) error {
+	return db.
+		Preload("Options").
+		Where("id = ? AND quiz_id = ?", id, quizID).
+		First(question).Error
+}
+
+func (r *QuestionRepository) FindByQuizId(db *gorm.DB, quizID string) ([]entity.Question, error) {
+	var questions []entity.Question
+	if err := db.
+		Preload("Options").
+		Where("quiz_id = ?", quizID).
+		Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+func (r *QuestionRepository) FindBankQuestionById(db *gorm.DB, question *entity.Question, id string) error {
+	return db.
+		Preload("Options").
+		Where("id = ? AND quiz_id IS NULL", id).
+		First(question).Error
+}
+
+// FindBankBySubjectAndGrade lists the bank questions a quiz may draw from, a nil grade level matches every grade.
+func (r *QuestionRepository) FindBankBySubjectAndGrade(db *gorm.DB, subjectID string, gradeLevel *int) ([]entity.Question, error) {
+	request := &model.SearchQuestionBankRequest{SubjectID: subjectID}
+	if gradeLevel != nil {
+		request.GradeLevel = *gradeLevel
+	}
+	var questions []entity.Question
+	if err := db.
+		Preload("Options").
+		Scopes(r.FilterBank(request)).
+		Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+func (r *QuestionRepository) SearchBank(db *gorm.DB, request *model.SearchQuestionBankRequest) ([]entity.Question, int64, error) {
+	var questions []entity.Question
+	if err := db.
+		Preload("Options").
+		Scopes(r.FilterBank(request)).
+		Order("id").
+		Offset((request.Page - 1) * request.Size).
+		Limit(request.Size).
+		Find(&questions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := db.Model(&entity.Question{}).
+		Scopes(r.FilterBank(request)).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return questions, total, nil
+}
+
+func (r *QuestionRepository) FilterBank(request *model.SearchQuestionBankRequest) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("quiz_id IS NULL")
+		if subjectID := request.SubjectID; subjectID != "" {
+			_, err := uuid.Parse(subjectID)
+			if err == nil {
+				tx = tx.Where("subject_id = ?", subjectID)
+			}
+		}
+		if request.SubjectIDs != nil {
+			tx = tx.Where("subject_id IN ?", request.SubjectIDs)
+		}
+		if gradeLevel := request.GradeLevel; gradeLevel > 0 {
+			tx = tx.Where("grade_level = ?", gradeLevel)
+		}
+		if questionType := request.Type; questionType != "" {
+			tx = tx.Where("type = ?", questionType)
+		}
+		return tx
+	}
+}
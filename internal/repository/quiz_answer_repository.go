@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type QuizAnswerRepository struct {
+	Repository[entity.QuizAnswer]
+	Log *logrus.Logger
+}
+
+func NewQuizAnswerRepository(log *logrus.Logger) *QuizAnswerRepository {
+	return &QuizAnswerRepository{
+		Log: log,
+	}
+}
+
+func (r *QuizAnswerRepository) FindByQuizId(db *gorm.DB, quizID string) ([]entity.QuizAnswer, error) {
+	var answers []entity.QuizAnswer
+	if err := db.
+		Joins("JOIN questions ON questions.id = quiz_answers.question_id").
+		Where("questions.quiz_id = ?", quizID).
+		Find(&answers).Error; err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+func (r *QuizAnswerRepository) FindByQuestionId(db *gorm.DB, questionID string) ([]entity.QuizAnswer, error) {
+	var answers []entity.QuizAnswer
+	if err := db.Where("question_id = ?", questionID).Find(&answers).Error; err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+func (r *QuizAnswerRepository) FindByQuestionIds(db *gorm.DB, questionIDs []uuid.UUID) ([]entity.QuizAnswer, error) {
+	var answers []entity.QuizAnswer
+	if len(questionIDs) == 0 {
+		return answers, nil
+	}
+	if err := db.Where("question_id IN ?", questionIDs).Find(&answers).Error; err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+func (r *QuizAnswerRepository) DeleteByQuestionId(db *gorm.DB, questionID string) error {
+	return db.Where("question_id = ?", questionID).Delete(new(entity.QuizAnswer)).Error
+}
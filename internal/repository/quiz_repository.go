@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type QuizRepository struct {
+	Repository[entity.Quiz]
+	Log *logrus.Logger
+}
+
+func NewQuizRepository(log *logrus.Logger) *QuizRepository {
+	return &QuizRepository{
+		Log: log,
+	}
+}
+
+func (r *QuizRepository) FindById(db *gorm.DB, quiz *entity.Quiz, id string) error {
+	return db.Scopes(activeQuiz).Where("id = ?", id).First(quiz).Error
+}
+
+func (r *QuizRepository) FindByIdWithQuestions(db *gorm.DB, quiz *entity.Quiz, id string) error {
+	return db.
+		Preload("Questions").
+		Preload("Questions.Options").
+		Scopes(activeQuiz).
+		Where("id = ?", id).
+		First(quiz).Error
+}
+
+func (r *QuizRepository) Search(db *gorm.DB, request *model.SearchQuizRequest) ([]entity.Quiz, int64, error) {
+	var quizzes []entity.Quiz
+	if err := db.
+		Scopes(r.FilterQuiz(request)).
+		Order("created_at DESC").
+		Offset((request.Page - 1) * request.Size).
+		Limit(request.Size).
+		Find(&quizzes).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := db.Model(&entity.Quiz{}).
+		Scopes(r.FilterQuiz(request)).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return quizzes, total, nil
+}
+
+func (r *QuizRepository) FilterQuiz(request *model.SearchQuizRequest) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Scopes(activeQuiz)
+		if quizName := request.QuizName; quizName != "" {
+			tx = tx.Where("quiz_name LIKE ?", "%"+quizName+"%")
+		}
+		if courseID := request.CourseID; courseID != "" {
+			_, err := uuid.Parse(courseID)
+			if err == nil {
+				tx = tx.Where("course_id = ?", courseID)
+			}
+		}
+		if request.SubjectIDs != nil {
+			tx = tx.Where("course_id IN (SELECT id FROM courses WHERE subject_id IN ?)", request.SubjectIDs)
+		}
+		return tx
+	}
+}
+
+// activeQuiz leaves out the quizzes of courses that are in the trash, they go to the trash with
+// their course.
+func activeQuiz(tx *gorm.DB) *gorm.DB {
+	return tx.Where("quizzes.course_id IN (SELECT id FROM courses WHERE deleted_at IS NULL)")
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefreshTokenRepository struct {
+	Repository[entity.RefreshToken]
+	Log *logrus.Logger
+}
+
+func NewRefreshTokenRepository(log *logrus.Logger) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		Log: log,
+	}
+}
+
+// FindByHashForUpdate locks the token row so two refreshes with the same token cannot both rotate it.
+func (r *RefreshTokenRepository) FindByHashForUpdate(db *gorm.DB, refreshToken *entity.RefreshToken, hash string) error {
+	return db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("User").
+		Preload("Session").
+		Where("token_hash = ?", hash).
+		First(refreshToken).Error
+}
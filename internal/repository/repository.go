@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -41,6 +42,22 @@ func (r *Repository[T]) FindById(db *gorm.DB, entity *T, id any) error {
 	return db.Where("id = ?", id).Take(entity).Error
 }
 
+// FindDeletedById finds an entity in the trash, for entities with a DeletedAt field.
+func (r *Repository[T]) FindDeletedById(db *gorm.DB, entity *T, id any) error {
+	return db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Take(entity).Error
+}
+
+// Restore takes the entity out of the trash.
+func (r *Repository[T]) Restore(db *gorm.DB, entity *T) error {
+	return db.Unscoped().Model(entity).Update("deleted_at", nil).Error
+}
+
+// PurgeDeletedBefore removes the entities put in the trash before the time for good.
+func (r *Repository[T]) PurgeDeletedBefore(db *gorm.DB, before time.Time) (int64, error) {
+	result := db.Unscoped().Where("deleted_at < ?", before).Delete(new(T))
+	return result.RowsAffected, result.Error
+}
+
 func (r *Repository[T]) Upsert(db *gorm.DB, entity *T, conflictColumns []clause.Column, updateColumns []string) error {
 	return db.Clauses(clause.OnConflict{
 		Columns:   conflictColumns,
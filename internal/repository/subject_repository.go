@@ -32,6 +32,25 @@ func (r *SubjectRepository) Search(db *gorm.DB, request *model.SearchSubjectRequ
 	return subjects, total, nil
 }
 
+// SearchDeleted lists the subjects in the trash, most recently deleted first.
+func (r *SubjectRepository) SearchDeleted(db *gorm.DB, request *model.SearchTrashRequest) ([]entity.Subject, int64, error) {
+	var subjects []entity.Subject
+	if err := db.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Offset((request.Page - 1) * request.Size).
+		Limit(request.Size).
+		Find(&subjects).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := db.Unscoped().Model(&entity.Subject{}).Where("deleted_at IS NOT NULL").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	return subjects, total, nil
+}
+
 func (r *SubjectRepository) FilterSubject(request *model.SearchSubjectRequest) func(tx *gorm.DB) *gorm.DB {
 	return func(tx *gorm.DB) *gorm.DB {
 		if subjectName := request.SubjectName; subjectName != "" {
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type TeacherSubjectRepository struct {
+	Repository[entity.TeacherSubject]
+	Log *logrus.Logger
+}
+
+func NewTeacherSubjectRepository(log *logrus.Logger) *TeacherSubjectRepository {
+	return &TeacherSubjectRepository{
+		Log: log,
+	}
+}
+
+func (r *TeacherSubjectRepository) FindByUserId(db *gorm.DB, userID string) ([]entity.TeacherSubject, error) {
+	var assignments []entity.TeacherSubject
+	if err := db.
+		Preload("Subject").
+		Where("user_id = ? AND subject_id IN (SELECT id FROM subjects WHERE deleted_at IS NULL)", userID).
+		Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+func (r *TeacherSubjectRepository) FindSubjectIdsByUserId(db *gorm.DB, userID string) ([]string, error) {
+	subjectIDs := []string{}
+	err := db.Model(new(entity.TeacherSubject)).Where("user_id = ?", userID).Pluck("subject_id", &subjectIDs).Error
+	return subjectIDs, err
+}
+
+func (r *TeacherSubjectRepository) CountByUserIdAndSubjectId(db *gorm.DB, userID string, subjectID string) (int64, error) {
+	var total int64
+	err := db.Model(new(entity.TeacherSubject)).Where("user_id = ? AND subject_id = ?", userID, subjectID).Count(&total).Error
+	return total, err
+}
+
+func (r *TeacherSubjectRepository) DeleteByUserIdAndSubjectId(db *gorm.DB, userID string, subjectID string) (int64, error) {
+	result := db.Where("user_id = ? AND subject_id = ?", userID, subjectID).Delete(new(entity.TeacherSubject))
+	return result.RowsAffected, result.Error
+}
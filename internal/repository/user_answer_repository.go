@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type UserAnswerRepository struct {
+	Repository[entity.UserAnswer]
+	Log *logrus.Logger
+}
+
+func NewUserAnswerRepository(log *logrus.Logger) *UserAnswerRepository {
+	return &UserAnswerRepository{
+		Log: log,
+	}
+}
+
+func (r *UserAnswerRepository) FindBySessionId(db *gorm.DB, sessionID string) ([]entity.UserAnswer, error) {
+	var answers []entity.UserAnswer
+	if err := db.Where("session_id = ?", sessionID).Find(&answers).Error; err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+func (r *UserAnswerRepository) FindBySessionIds(db *gorm.DB, sessionIDs []uuid.UUID) ([]entity.UserAnswer, error) {
+	var answers []entity.UserAnswer
+	if len(sessionIDs) == 0 {
+		return answers, nil
+	}
+	if err := db.Where("session_id IN ?", sessionIDs).Find(&answers).Error; err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+func (r *UserAnswerRepository) DeleteBySessionIdAndQuestionId(db *gorm.DB, sessionID string, questionID string) error {
+	return db.Where("session_id = ? AND question_id = ?", sessionID, questionID).Delete(new(entity.UserAnswer)).Error
+}
@@ -28,6 +28,7 @@ func (r *UserCourseRepository) FindByCourseIdAndUserId(db *gorm.DB, userCourse *
 		Preload("Course").
 		Preload("User").
 		Preload("Course.Subject").
+		Scopes(activeCourse).
 		Where("course_id = ? AND user_id = ?", request.CourseID, request.UserID).
 		First(userCourse).Error
 }
@@ -59,6 +60,8 @@ func (r *UserCourseRepository) Search(db *gorm.DB, request *model.SearchUserCour
 
 func (r *UserCourseRepository) FilterUserCourse(request *model.SearchUserCourseRequest) func(tx *gorm.DB) *gorm.DB {
 	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Scopes(activeCourse).
+			Where("users_courses.user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)")
 		if userID := request.UserID; userID != "" {
 			_, err := uuid.Parse(userID)
 			if err == nil {
@@ -79,7 +82,21 @@ func (r *UserCourseRepository) FilterUserCourse(request *model.SearchUserCourseR
 					Where("courses.subject_id = ?", request.SubjectID)
 			}
 		}
+		if request.SubjectIDs != nil {
+			tx = tx.Where("users_courses.course_id IN (SELECT id FROM courses WHERE subject_id IN ?)", request.SubjectIDs)
+		}
 
 		return tx
 	}
 }
+
+func (r *UserCourseRepository) CountByCourseIdAndUserId(db *gorm.DB, courseID string, userID string) (int64, error) {
+	var total int64
+	err := db.Model(new(entity.UserCourse)).Scopes(activeCourse).Where("course_id = ? AND user_id = ?", courseID, userID).Count(&total).Error
+	return total, err
+}
+
+// activeCourse leaves out the enrollments in courses that are in the trash.
+func activeCourse(tx *gorm.DB) *gorm.DB {
+	return tx.Where("users_courses.course_id IN (SELECT id FROM courses WHERE deleted_at IS NULL)")
+}
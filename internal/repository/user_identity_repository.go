@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type UserIdentityRepository struct {
+	Repository[entity.UserIdentity]
+	Log *logrus.Logger
+}
+
+func NewUserIdentityRepository(log *logrus.Logger) *UserIdentityRepository {
+	return &UserIdentityRepository{
+		Log: log,
+	}
+}
+
+func (r *UserIdentityRepository) FindByProviderAndSubject(db *gorm.DB, identity *entity.UserIdentity, provider string, subject string) error {
+	return db.
+		Preload("User").
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(identity).Error
+}
+
+func (r *UserIdentityRepository) FindByIdAndUserId(db *gorm.DB, identity *entity.UserIdentity, id string, userID string) error {
+	return db.Where("id = ? AND user_id = ?", id, userID).First(identity).Error
+}
+
+func (r *UserIdentityRepository) FindByUserId(db *gorm.DB, userID string) ([]entity.UserIdentity, error) {
+	var identities []entity.UserIdentity
+	if err := db.
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *UserIdentityRepository) CountByUserId(db *gorm.DB, userID string) (int64, error) {
+	var total int64
+	err := db.Model(new(entity.UserIdentity)).Where("user_id = ?", userID).Count(&total).Error
+	return total, err
+}
+
+func (r *UserIdentityRepository) CountByUserIdAndProvider(db *gorm.DB, userID string, provider string) (int64, error) {
+	var total int64
+	err := db.Model(new(entity.UserIdentity)).Where("user_id = ? AND provider = ?", userID, provider).Count(&total).Error
+	return total, err
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type UserQuizSessionQuestionRepository struct {
+	Repository[entity.UserQuizSessionQuestion]
+	Log *logrus.Logger
+}
+
+func NewUserQuizSessionQuestionRepository(log *logrus.Logger) *UserQuizSessionQuestionRepository {
+	return &UserQuizSessionQuestionRepository{
+		Log: log,
+	}
+}
+
+func (r *UserQuizSessionQuestionRepository) FindBySessionId(db *gorm.DB, sessionID string) ([]entity.UserQuizSessionQuestion, error) {
+	var questions []entity.UserQuizSessionQuestion
+	if err := db.
+		Preload("Question").
+		Preload("Question.Options").
+		Where("session_id = ?", sessionID).
+		Order("position").
+		Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+func (r *UserQuizSessionQuestionRepository) FindBySessionIds(db *gorm.DB, sessionIDs []uuid.UUID) ([]entity.UserQuizSessionQuestion, error) {
+	var questions []entity.UserQuizSessionQuestion
+	if len(sessionIDs) == 0 {
+		return questions, nil
+	}
+	if err := db.
+		Preload("Question").
+		Preload("Question.Options").
+		Where("session_id IN ?", sessionIDs).
+		Order("session_id, position").
+		Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserQuizSessionRepository struct {
+	Repository[entity.UserQuizSession]
+	Log *logrus.Logger
+}
+
+func NewUserQuizSessionRepository(log *logrus.Logger) *UserQuizSessionRepository {
+	return &UserQuizSessionRepository{
+		Log: log,
+	}
+}
+
+func (r *UserQuizSessionRepository) FindByIdAndUserId(db *gorm.DB, session *entity.UserQuizSession, id string, userID string) error {
+	return db.
+		Preload("Quiz").
+		Preload("Answers").
+		Where("id = ? AND user_id = ?", id, userID).
+		First(session).Error
+}
+
+// FindByIdForUpdate locks the session row so submit, answer and the auto-submit worker never race.
+func (r *UserQuizSessionRepository) FindByIdForUpdate(db *gorm.DB, session *entity.UserQuizSession, id string) error {
+	return db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Quiz").
+		Preload("Answers").
+		Where("id = ?", id).
+		First(session).Error
+}
+
+func (r *UserQuizSessionRepository) FindActiveByQuizIdAndUserId(db *gorm.DB, session *entity.UserQuizSession, quizID string, userID string) error {
+	return db.
+		Preload("Quiz").
+		Preload("Answers").
+		Where("quiz_id = ? AND user_id = ? AND submitted = ?", quizID, userID, false).
+		Order("started_at DESC").
+		First(session).Error
+}
+
+// FindExpiredIds returns unsubmitted sessions whose quiz time limit has run out.
+func (r *UserQuizSessionRepository) FindExpiredIds(db *gorm.DB, now time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := db.Model(new(entity.UserQuizSession)).
+		Joins("JOIN quizzes ON quizzes.id = user_quiz_sessions.quiz_id").
+		Where("user_quiz_sessions.submitted = ?", false).
+		Where("user_quiz_sessions.started_at + quizzes.time_limit * INTERVAL '1 minute' <= ?", now).
+		Order("user_quiz_sessions.started_at").
+		Limit(limit).
+		Pluck("user_quiz_sessions.id", &ids).Error
+	return ids, err
+}
+
+func (r *UserQuizSessionRepository) FindSubmittedByQuizId(db *gorm.DB, quizID string) ([]entity.UserQuizSession, error) {
+	var sessions []entity.UserQuizSession
+	if err := db.
+		Where("quiz_id = ? AND submitted = ?", quizID, true).
+		Order("started_at").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *UserQuizSessionRepository) SearchAttempts(db *gorm.DB, request *model.SearchQuizAttemptRequest) ([]entity.UserQuizSession, int64, error) {
+	var sessions []entity.UserQuizSession
+	if err := db.
+		Preload("Quiz").
+		Where("quiz_id = ? AND user_id = ?", request.QuizID, request.UserID).
+		Order("started_at DESC").
+		Offset((request.Page - 1) * request.Size).
+		Limit(request.Size).
+		Find(&sessions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := db.Model(&entity.UserQuizSession{}).
+		Where("quiz_id = ? AND user_id = ?", request.QuizID, request.UserID).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return sessions, total, nil
+}
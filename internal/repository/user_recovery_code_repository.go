@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type UserRecoveryCodeRepository struct {
+	Repository[entity.UserRecoveryCode]
+	Log *logrus.Logger
+}
+
+func NewUserRecoveryCodeRepository(log *logrus.Logger) *UserRecoveryCodeRepository {
+	return &UserRecoveryCodeRepository{
+		Log: log,
+	}
+}
+
+func (r *UserRecoveryCodeRepository) FindUnusedByUserId(db *gorm.DB, userID string) ([]entity.UserRecoveryCode, error) {
+	var codes []entity.UserRecoveryCode
+	if err := db.
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Find(&codes).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (r *UserRecoveryCodeRepository) DeleteByUserId(db *gorm.DB, userID string) error {
+	return db.Where("user_id = ?", userID).Delete(new(entity.UserRecoveryCode)).Error
+}
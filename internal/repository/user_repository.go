@@ -6,6 +6,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
@@ -19,14 +20,21 @@ func NewUserRepository(log *logrus.Logger) *UserRepository {
 	}
 }
 
-func (r *UserRepository) FindByToken(db *gorm.DB, user *entity.User, token string) error {
-	return db.Where("token = ?", token).First(user).Error
+// FindByIdForUpdate locks the user row so a TOTP code cannot be accepted twice by parallel requests.
+func (r *UserRepository) FindByIdForUpdate(db *gorm.DB, user *entity.User, id string) error {
+	return db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(user).Error
 }
 
 func (r *UserRepository) FindByEmail(db *gorm.DB, user *entity.User, email string) error {
 	return db.Where("email = ?", email).First(user).Error
 }
 
+// FindByEmailFold matches the email ignoring case, the way identity providers compare them. Should
+// accounts differ only in case the oldest is returned.
+func (r *UserRepository) FindByEmailFold(db *gorm.DB, user *entity.User, email string) error {
+	return db.Where("lower(email) = lower(?)", email).Order("created_at").First(user).Error
+}
+
 func (r *UserRepository) CountByEmail(db *gorm.DB, email string) (int64, error) {
 	var total int64
 	err := db.Model(new(entity.User)).Where("email = ?", email).Count(&total).Error
@@ -45,6 +53,25 @@ func (r *UserRepository) Search(db *gorm.DB, request *model.SearchUserRequest) (
 	return users, total, nil
 }
 
+// SearchDeleted lists the users in the trash, most recently deleted first.
+func (r *UserRepository) SearchDeleted(db *gorm.DB, request *model.SearchTrashRequest) ([]entity.User, int64, error) {
+	var users []entity.User
+	if err := db.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Offset((request.Page - 1) * request.Size).
+		Limit(request.Size).
+		Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := db.Unscoped().Model(&entity.User{}).Where("deleted_at IS NOT NULL").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
 func (r *UserRepository) FilterUser(request *model.SearchUserRequest) func(tx *gorm.DB) *gorm.DB {
 	return func(tx *gorm.DB) *gorm.DB {
 		if username := request.Username; username != "" {
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"fp-designpattern/internal/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type UserSessionRepository struct {
+	Repository[entity.UserSession]
+	Log *logrus.Logger
+}
+
+func NewUserSessionRepository(log *logrus.Logger) *UserSessionRepository {
+	return &UserSessionRepository{
+		Log: log,
+	}
+}
+
+// Touch records activity on a session and returns 0 when the session is revoked or not the user's.
+func (r *UserSessionRepository) Touch(db *gorm.DB, id string, userID string, now time.Time) (int64, error) {
+	result := db.Model(new(entity.UserSession)).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("last_seen_at", now)
+	return result.RowsAffected, result.Error
+}
+
+func (r *UserSessionRepository) FindActiveByUserId(db *gorm.DB, userID string) ([]entity.UserSession, error) {
+	var sessions []entity.UserSession
+	if err := db.
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *UserSessionRepository) FindActiveByIdAndUserId(db *gorm.DB, session *entity.UserSession, id string, userID string) error {
+	return db.
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		First(session).Error
+}
+
+func (r *UserSessionRepository) RevokeByUserId(db *gorm.DB, userID string, now time.Time) (int64, error) {
+	result := db.Model(new(entity.UserSession)).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,279 @@
+package scoring
+
+import (
+	"fp-designpattern/internal/entity"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatusCorrect    = "correct"
+	StatusPartial    = "partial"
+	StatusIncorrect  = "incorrect"
+	StatusUnanswered = "unanswered"
+	StatusUngraded   = "ungraded"
+)
+
+// Question is a question of the quiz together with its answer key.
+type Question struct {
+	ID   uuid.UUID
+	Type string
+	// Correct holds the correct options of choice questions.
+	Correct []uuid.UUID
+	// Accepted holds the accepted patterns of short answer questions, "*" matches anything.
+	Accepted      []string
+	CaseSensitive bool
+	// Value and Tolerance grade numeric questions, Value is nil when no key was set.
+	Value     *float64
+	Tolerance float64
+	// Order lists the options of ordering questions in their correct order.
+	Order []uuid.UUID
+	// Matches maps every option of matching questions to its correct target.
+	Matches map[uuid.UUID]string
+}
+
+// Answer is what a student gave for a single question.
+type Answer struct {
+	OptionIDs []uuid.UUID
+	Text      string
+	Order     []uuid.UUID
+	Matches   map[uuid.UUID]string
+}
+
+type QuestionResult struct {
+	QuestionID uuid.UUID
+	Type       string
+	Answer     Answer
+	Selected   []uuid.UUID
+	Correct    []uuid.UUID
+	Points     float64
+	MaxPoints  float64
+	Status     string
+}
+
+type Result struct {
+	Score     int
+	Points    float64
+	MaxPoints float64
+	Questions []QuestionResult
+}
+
+type Scorer struct {
+	// PartialCredit awards a share of the point on multi-part questions.
+	PartialCredit bool
+}
+
+func NewScorer(partialCredit bool) *Scorer {
+	return &Scorer{
+		PartialCredit: partialCredit,
+	}
+}
+
+// Score grades the answers per question against the answer key.
+// Every graded question is worth one point, the score is the percentage of points earned.
+// Questions without an answer key are reported as ungraded and do not count towards the score.
+func (s *Scorer) Score(questions []Question, answers map[uuid.UUID]Answer) *Result {
+	result := &Result{
+		Questions: make([]QuestionResult, len(questions)),
+	}
+	for i, question := range questions {
+		answer := answers[question.ID]
+		answer.OptionIDs = unique(answer.OptionIDs)
+		questionResult := s.scoreQuestion(question, answer)
+		result.Points += questionResult.Points
+		result.MaxPoints += questionResult.MaxPoints
+		result.Questions[i] = questionResult
+	}
+	if result.MaxPoints > 0 {
+		result.Score = int(math.Round(result.Points / result.MaxPoints * 100))
+	}
+	return result
+}
+
+func (s *Scorer) scoreQuestion(question Question, answer Answer) QuestionResult {
+	questionResult := QuestionResult{
+		QuestionID: question.ID,
+		Type:       question.Type,
+		Answer:     answer,
+		Selected:   answer.OptionIDs,
+		Correct:    question.Correct,
+	}
+	if !hasKey(question) {
+		questionResult.Status = StatusUngraded
+		return questionResult
+	}
+	questionResult.MaxPoints = 1
+	if isEmpty(question, answer) {
+		questionResult.Status = StatusUnanswered
+		return questionResult
+	}
+
+	switch question.Type {
+	case entity.QuestionTypeShortAnswer:
+		questionResult.Points = all(matchesAccepted(question, answer.Text))
+	case entity.QuestionTypeNumeric:
+		questionResult.Points = all(withinTolerance(question, answer.Text))
+	case entity.QuestionTypeOrdering:
+		questionResult.Points = s.scoreOrdering(question, answer)
+	case entity.QuestionTypeMatching:
+		questionResult.Points = s.scoreMatching(question, answer)
+	default:
+		questionResult.Points = s.scoreChoice(question, answer)
+	}
+
+	switch {
+	case questionResult.Points >= 1:
+		questionResult.Points = 1
+		questionResult.Status = StatusCorrect
+	case questionResult.Points > 0:
+		questionResult.Status = StatusPartial
+	default:
+		questionResult.Points = 0
+		questionResult.Status = StatusIncorrect
+	}
+	return questionResult
+}
+
+func (s *Scorer) scoreChoice(question Question, answer Answer) float64 {
+	correct := make(map[uuid.UUID]bool, len(question.Correct))
+	for _, id := range question.Correct {
+		correct[id] = true
+	}
+	hits, misses := 0, 0
+	for _, id := range answer.OptionIDs {
+		if correct[id] {
+			hits++
+		} else {
+			misses++
+		}
+	}
+
+	switch {
+	case hits == len(correct) && misses == 0:
+		return 1
+	case s.PartialCredit && len(correct) > 1 && hits > misses:
+		// Every wrong pick cancels out a right one so selecting everything earns nothing
+		return float64(hits-misses) / float64(len(correct))
+	}
+	return 0
+}
+
+func (s *Scorer) scoreOrdering(question Question, answer Answer) float64 {
+	inPlace := 0
+	for i, id := range question.Order {
+		if i < len(answer.Order) && answer.Order[i] == id {
+			inPlace++
+		}
+	}
+	return s.share(inPlace, len(question.Order))
+}
+
+func (s *Scorer) scoreMatching(question Question, answer Answer) float64 {
+	matched := 0
+	for id, target := range question.Matches {
+		if given, ok := answer.Matches[id]; ok && normalize(given, false) == normalize(target, false) {
+			matched++
+		}
+	}
+	return s.share(matched, len(question.Matches))
+}
+
+func (s *Scorer) share(right int, total int) float64 {
+	if right == total {
+		return 1
+	}
+	if !s.PartialCredit {
+		return 0
+	}
+	return float64(right) / float64(total)
+}
+
+func hasKey(question Question) bool {
+	switch question.Type {
+	case entity.QuestionTypeShortAnswer:
+		return len(question.Accepted) > 0
+	case entity.QuestionTypeNumeric:
+		return question.Value != nil
+	case entity.QuestionTypeOrdering:
+		return len(question.Order) > 0
+	case entity.QuestionTypeMatching:
+		return len(question.Matches) > 0
+	}
+	return len(question.Correct) > 0
+}
+
+func isEmpty(question Question, answer Answer) bool {
+	switch question.Type {
+	case entity.QuestionTypeShortAnswer, entity.QuestionTypeNumeric:
+		return strings.TrimSpace(answer.Text) == ""
+	case entity.QuestionTypeOrdering:
+		return len(answer.Order) == 0
+	case entity.QuestionTypeMatching:
+		return len(answer.Matches) == 0
+	}
+	return len(answer.OptionIDs) == 0
+}
+
+func matchesAccepted(question Question, text string) bool {
+	given := normalize(text, question.CaseSensitive)
+	for _, pattern := range question.Accepted {
+		if PatternRegexp(normalize(pattern, question.CaseSensitive)).MatchString(given) {
+			return true
+		}
+	}
+	return false
+}
+
+// PatternRegexp compiles an accepted answer pattern where "*" stands for any run of characters.
+func PatternRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+func withinTolerance(question Question, text string) bool {
+	value, err := ParseNumber(text)
+	if err != nil {
+		return false
+	}
+	return math.Abs(value-*question.Value) <= question.Tolerance+1e-9
+}
+
+// ParseNumber reads a numeric answer, a decimal comma is accepted as well as a decimal point.
+func ParseNumber(text string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(text), ",", "."), 64)
+}
+
+func normalize(text string, caseSensitive bool) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if !caseSensitive {
+		text = strings.ToLower(text)
+	}
+	return text
+}
+
+func all(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+func unique(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+package scoring
+
+import (
+	"fp-designpattern/internal/entity"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestScoreChoice(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name          string
+		partialCredit bool
+		correct       []uuid.UUID
+		selected      []uuid.UUID
+		wantPoints    float64
+		wantMax       float64
+		wantStatus    string
+	}{
+		{"single correct", true, []uuid.UUID{a}, []uuid.UUID{a}, 1, 1, StatusCorrect},
+		{"single wrong", true, []uuid.UUID{a}, []uuid.UUID{b}, 0, 1, StatusIncorrect},
+		{"single with extra pick", true, []uuid.UUID{a}, []uuid.UUID{a, b}, 0, 1, StatusIncorrect},
+		{"unanswered", true, []uuid.UUID{a}, nil, 0, 1, StatusUnanswered},
+		{"no answer key", true, nil, []uuid.UUID{a}, 0, 0, StatusUngraded},
+		{"multi all correct", true, []uuid.UUID{a, b}, []uuid.UUID{b, a}, 1, 1, StatusCorrect},
+		{"multi duplicate picks", true, []uuid.UUID{a, b}, []uuid.UUID{a, a, b}, 1, 1, StatusCorrect},
+		{"multi half correct", true, []uuid.UUID{a, b}, []uuid.UUID{a}, 0.5, 1, StatusPartial},
+		{"multi two of three", true, []uuid.UUID{a, b, c}, []uuid.UUID{a, b}, 2.0 / 3.0, 1, StatusPartial},
+		{"multi hit cancelled by miss", true, []uuid.UUID{a, b}, []uuid.UUID{a, c}, 0, 1, StatusIncorrect},
+		{"multi select everything", true, []uuid.UUID{a, b}, []uuid.UUID{a, b, c, d}, 0, 1, StatusIncorrect},
+		{"multi partial disabled", false, []uuid.UUID{a, b}, []uuid.UUID{a}, 0, 1, StatusIncorrect},
+		{"multi only wrong", true, []uuid.UUID{a, b}, []uuid.UUID{c}, 0, 1, StatusIncorrect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := Question{ID: uuid.New(), Type: entity.QuestionTypeMultipleSelect, Correct: tt.correct}
+			answers := map[uuid.UUID]Answer{question.ID: {OptionIDs: tt.selected}}
+
+			result := NewScorer(tt.partialCredit).Score([]Question{question}, answers)
+
+			assertResult(t, result.Questions[0], tt.wantPoints, tt.wantMax, tt.wantStatus)
+		})
+	}
+}
+
+func TestScoreTypedQuestions(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	value := 9.81
+
+	shortAnswer := Question{Type: entity.QuestionTypeShortAnswer, Accepted: []string{"Jakarta", "DKI *"}}
+	caseSensitive := Question{Type: entity.QuestionTypeShortAnswer, Accepted: []string{"NaCl"}, CaseSensitive: true}
+	numeric := Question{Type: entity.QuestionTypeNumeric, Value: &value, Tolerance: 0.05}
+	ordering := Question{Type: entity.QuestionTypeOrdering, Order: []uuid.UUID{a, b, c}}
+	matching := Question{Type: entity.QuestionTypeMatching, Matches: map[uuid.UUID]string{a: "H2O", b: "CO2"}}
+	trueFalse := Question{Type: entity.QuestionTypeTrueFalse, Correct: []uuid.UUID{a}}
+
+	tests := []struct {
+		name          string
+		partialCredit bool
+		question      Question
+		answer        Answer
+		wantPoints    float64
+		wantMax       float64
+		wantStatus    string
+	}{
+		{"true false right", true, trueFalse, Answer{OptionIDs: []uuid.UUID{a}}, 1, 1, StatusCorrect},
+		{"true false wrong", true, trueFalse, Answer{OptionIDs: []uuid.UUID{b}}, 0, 1, StatusIncorrect},
+		{"short answer exact", true, shortAnswer, Answer{Text: "jakarta"}, 1, 1, StatusCorrect},
+		{"short answer extra spaces", true, shortAnswer, Answer{Text: "  Jakarta  "}, 1, 1, StatusCorrect},
+		{"short answer wildcard", true, shortAnswer, Answer{Text: "DKI Jakarta"}, 1, 1, StatusCorrect},
+		{"short answer wrong", true, shortAnswer, Answer{Text: "Bandung"}, 0, 1, StatusIncorrect},
+		{"short answer blank", true, shortAnswer, Answer{Text: "   "}, 0, 1, StatusUnanswered},
+		{"short answer regexp chars are literal", true, Question{Type: entity.QuestionTypeShortAnswer, Accepted: []string{"a.b"}}, Answer{Text: "axb"}, 0, 1, StatusIncorrect},
+		{"short answer case sensitive", true, caseSensitive, Answer{Text: "nacl"}, 0, 1, StatusIncorrect},
+		{"short answer no key", true, Question{Type: entity.QuestionTypeShortAnswer}, Answer{Text: "x"}, 0, 0, StatusUngraded},
+		{"numeric exact", true, numeric, Answer{Text: "9.81"}, 1, 1, StatusCorrect},
+		{"numeric within tolerance", true, numeric, Answer{Text: "9.86"}, 1, 1, StatusCorrect},
+		{"numeric decimal comma", true, numeric, Answer{Text: "9,8"}, 1, 1, StatusCorrect},
+		{"numeric outside tolerance", true, numeric, Answer{Text: "9.9"}, 0, 1, StatusIncorrect},
+		{"numeric not a number", true, numeric, Answer{Text: "ten"}, 0, 1, StatusIncorrect},
+		{"numeric no key", true, Question{Type: entity.QuestionTypeNumeric}, Answer{Text: "1"}, 0, 0, StatusUngraded},
+		{"ordering right", true, ordering, Answer{Order: []uuid.UUID{a, b, c}}, 1, 1, StatusCorrect},
+		{"ordering one in place", true, ordering, Answer{Order: []uuid.UUID{a, c, b}}, 1.0 / 3.0, 1, StatusPartial},
+		{"ordering partial disabled", false, ordering, Answer{Order: []uuid.UUID{a, c, b}}, 0, 1, StatusIncorrect},
+		{"ordering short answer", true, ordering, Answer{Order: []uuid.UUID{a}}, 1.0 / 3.0, 1, StatusPartial},
+		{"ordering unanswered", true, ordering, Answer{}, 0, 1, StatusUnanswered},
+		{"matching right", true, matching, Answer{Matches: map[uuid.UUID]string{a: "h2o", b: "CO2"}}, 1, 1, StatusCorrect},
+		{"matching half", true, matching, Answer{Matches: map[uuid.UUID]string{a: "H2O", b: "O2"}}, 0.5, 1, StatusPartial},
+		{"matching swapped", true, matching, Answer{Matches: map[uuid.UUID]string{a: "CO2", b: "H2O"}}, 0, 1, StatusIncorrect},
+		{"matching unanswered", true, matching, Answer{}, 0, 1, StatusUnanswered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := tt.question
+			question.ID = uuid.New()
+			answers := map[uuid.UUID]Answer{question.ID: tt.answer}
+
+			result := NewScorer(tt.partialCredit).Score([]Question{question}, answers)
+
+			assertResult(t, result.Questions[0], tt.wantPoints, tt.wantMax, tt.wantStatus)
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	q1 := Question{ID: uuid.New(), Correct: []uuid.UUID{a}}
+	q2 := Question{ID: uuid.New(), Type: entity.QuestionTypeMultipleSelect, Correct: []uuid.UUID{b, c}}
+	q3 := Question{ID: uuid.New(), Correct: []uuid.UUID{c}}
+	ungraded := Question{ID: uuid.New()}
+
+	tests := []struct {
+		name      string
+		questions []Question
+		answers   map[uuid.UUID]Answer
+		wantScore int
+	}{
+		{"no questions", nil, nil, 0},
+		{"nothing answered", []Question{q1, q2, q3}, nil, 0},
+		{"all correct", []Question{q1, q2, q3}, map[uuid.UUID]Answer{q1.ID: {OptionIDs: []uuid.UUID{a}}, q2.ID: {OptionIDs: []uuid.UUID{b, c}}, q3.ID: {OptionIDs: []uuid.UUID{c}}}, 100},
+		{"one of three", []Question{q1, q2, q3}, map[uuid.UUID]Answer{q1.ID: {OptionIDs: []uuid.UUID{a}}, q3.ID: {OptionIDs: []uuid.UUID{a}}}, 33},
+		{"partial credit counts", []Question{q1, q2}, map[uuid.UUID]Answer{q1.ID: {OptionIDs: []uuid.UUID{a}}, q2.ID: {OptionIDs: []uuid.UUID{b}}}, 75},
+		{"ungraded excluded", []Question{q1, ungraded}, map[uuid.UUID]Answer{q1.ID: {OptionIDs: []uuid.UUID{a}}}, 100},
+		{"only ungraded", []Question{ungraded}, map[uuid.UUID]Answer{ungraded.ID: {OptionIDs: []uuid.UUID{a}}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewScorer(true).Score(tt.questions, tt.answers)
+			if result.Score != tt.wantScore {
+				t.Errorf("score = %d, want %d", result.Score, tt.wantScore)
+			}
+			if len(result.Questions) != len(tt.questions) {
+				t.Errorf("breakdown has %d questions, want %d", len(result.Questions), len(tt.questions))
+			}
+		})
+	}
+}
+
+func assertResult(t *testing.T, got QuestionResult, wantPoints float64, wantMax float64, wantStatus string) {
+	t.Helper()
+	if got.Status != wantStatus {
+		t.Errorf("status = %q, want %q", got.Status, wantStatus)
+	}
+	if diff := got.Points - wantPoints; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("points = %v, want %v", got.Points, wantPoints)
+	}
+	if got.MaxPoints != wantMax {
+		t.Errorf("max points = %v, want %v", got.MaxPoints, wantMax)
+	}
+}
@@ -0,0 +1,258 @@
+// Package token issues the signed access tokens and opaque refresh tokens used to authenticate users.
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is an HMAC signing key, ID is written to the kid header of the tokens it signs.
+type Key struct {
+	ID     string `mapstructure:"id"`
+	Secret string `mapstructure:"secret"`
+}
+
+const (
+	audienceAccess       = "access"
+	audienceVerification = "email_verification"
+	audienceChallenge    = "mfa_challenge"
+	audienceFlow         = "oidc_flow"
+)
+
+// Claims are the claims of an access token, the user id is the subject.
+type Claims struct {
+	Role          string `json:"role"`
+	SessionID     string `json:"sid,omitempty"`
+	EmailVerified bool   `json:"ev,omitempty"`
+	// MFA is set when the session was signed in with a second factor.
+	MFA bool `json:"mfa,omitempty"`
+	// ImpersonatorID is the admin acting as the user, set on impersonation tokens only.
+	ImpersonatorID string `json:"imp,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// FlowClaims keep an OpenID Connect login between sending the user to the provider and the callback.
+// They travel in a cookie, so the callback only completes in the browser that started the login.
+type FlowClaims struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// LinkUserID is set when a signed in user links the identity instead of signing in with it.
+	LinkUserID string `json:"link,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// VerificationClaims are the claims of an email verification link, it only verifies the email it was sent to.
+type VerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// Manager signs access tokens with the active key and accepts tokens signed by any configured key,
+// so a key can be rotated by adding the new key, making it active and dropping the old one once
+// the tokens it signed have expired.
+type Manager struct {
+	Issuer          string
+	AccessTTL       time.Duration
+	RefreshTTL      time.Duration
+	VerificationTTL time.Duration
+	ChallengeTTL    time.Duration
+	FlowTTL         time.Duration
+	APIKeyTTL       time.Duration
+	APIKeyMaxTTL    time.Duration
+	// ImpersonationTTL is the lifetime of an impersonation token, it cannot be refreshed.
+	ImpersonationTTL time.Duration
+	keys             map[string][]byte
+	active           string
+}
+
+func NewManager(issuer string, keys []Key, active string, accessTTL time.Duration, refreshTTL time.Duration) (*Manager, error) {
+	manager := &Manager{
+		Issuer:           issuer,
+		AccessTTL:        accessTTL,
+		RefreshTTL:       refreshTTL,
+		VerificationTTL:  48 * time.Hour,
+		ChallengeTTL:     5 * time.Minute,
+		FlowTTL:          10 * time.Minute,
+		APIKeyTTL:        90 * 24 * time.Hour,
+		APIKeyMaxTTL:     365 * 24 * time.Hour,
+		ImpersonationTTL: 30 * time.Minute,
+		keys:             make(map[string][]byte, len(keys)),
+		active:           active,
+	}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("signing key without id")
+		}
+		if len(key.Secret) < 32 {
+			return nil, fmt.Errorf("signing key %q must be at least 32 bytes", key.ID)
+		}
+		if _, ok := manager.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate signing key %q", key.ID)
+		}
+		manager.keys[key.ID] = []byte(key.Secret)
+	}
+	if _, ok := manager.keys[active]; !ok {
+		return nil, fmt.Errorf("active signing key %q is not configured", active)
+	}
+	if accessTTL <= 0 || refreshTTL <= 0 {
+		return nil, errors.New("token ttl must be positive")
+	}
+	return manager, nil
+}
+
+// Sign issues an access token for the user in the subject of the claims and returns it with its expiry.
+func (m *Manager) Sign(claims *Claims, now time.Time) (string, time.Time, error) {
+	return m.signAccess(claims, now, now.Add(m.AccessTTL))
+}
+
+// SignImpersonation issues an access token for an admin acting as the user, it lasts ImpersonationTTL.
+func (m *Manager) SignImpersonation(claims *Claims, now time.Time) (string, time.Time, error) {
+	return m.signAccess(claims, now, now.Add(m.ImpersonationTTL))
+}
+
+func (m *Manager) signAccess(claims *Claims, now time.Time, expiresAt time.Time) (string, time.Time, error) {
+	claims.RegisteredClaims = m.registered(claims.Subject, audienceAccess, now, expiresAt)
+	signed, err := m.sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// Parse checks the signature, issuer, audience and expiry of an access token and returns its claims.
+func (m *Manager) Parse(raw string) (*Claims, error) {
+	claims := new(Claims)
+	if err := m.parse(raw, claims, audienceAccess); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// SignVerification issues the token of an email verification link.
+func (m *Manager) SignVerification(userID string, email string, now time.Time) (string, error) {
+	return m.sign(&VerificationClaims{
+		Email:            email,
+		RegisteredClaims: m.registered(userID, audienceVerification, now, now.Add(m.VerificationTTL)),
+	})
+}
+
+func (m *Manager) ParseVerification(raw string) (*VerificationClaims, error) {
+	claims := new(VerificationClaims)
+	if err := m.parse(raw, claims, audienceVerification); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// SignChallenge issues the challenge a user with two-factor authentication exchanges, with a code,
+// for a session after their password was checked. The challenge id is its jti, the caller stores it
+// so the challenge can only be exchanged once.
+func (m *Manager) SignChallenge(userID string, challengeID string, now time.Time) (string, error) {
+	claims := m.registered(userID, audienceChallenge, now, now.Add(m.ChallengeTTL))
+	claims.ID = challengeID
+	return m.sign(&claims)
+}
+
+// ParseChallenge returns the user id the challenge was issued to and the challenge id.
+func (m *Manager) ParseChallenge(raw string) (string, string, error) {
+	claims := new(jwt.RegisteredClaims)
+	if err := m.parse(raw, claims, audienceChallenge); err != nil {
+		return "", "", err
+	}
+	if claims.ID == "" {
+		return "", "", errors.New("challenge has no id")
+	}
+	return claims.Subject, claims.ID, nil
+}
+
+// SignFlow issues the cookie value of an OpenID Connect login, the state is its subject.
+func (m *Manager) SignFlow(claims *FlowClaims, now time.Time) (string, error) {
+	claims.RegisteredClaims = m.registered(claims.State, audienceFlow, now, now.Add(m.FlowTTL))
+	return m.sign(claims)
+}
+
+func (m *Manager) ParseFlow(raw string) (*FlowClaims, error) {
+	claims := new(FlowClaims)
+	if err := m.parse(raw, claims, audienceFlow); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (m *Manager) registered(subject string, audience string, now time.Time, expiresAt time.Time) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Issuer:    m.Issuer,
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+}
+
+func (m *Manager) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = m.active
+	return token.SignedString(m.keys[m.active])
+}
+
+// parse checks the token against any configured key, the audience keeps the token kinds apart.
+func (m *Manager) parse(raw string, claims jwt.Claims, audience string) error {
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := m.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(m.Issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return err
+	}
+	if subject, _ := claims.GetSubject(); subject == "" {
+		return errors.New("token has no subject")
+	}
+	return nil
+}
+
+// NewOpaque returns a random token, such as a refresh or reset token, and the hash to store in its place.
+func NewOpaque() (string, string, error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(buffer)
+	return raw, Hash(raw), nil
+}
+
+// APIKeyPrefix starts every API key, so a bearer token is told apart from an access token without parsing it.
+const APIKeyPrefix = "key_"
+
+// NewAPIKey returns a random API key and the hash to store in its place.
+func NewAPIKey() (string, string, error) {
+	raw, _, err := NewOpaque()
+	if err != nil {
+		return "", "", err
+	}
+	key := APIKeyPrefix + raw
+	return key, Hash(key), nil
+}
+
+// Hash is the sha256 of an opaque token, the tokens are random enough that no salt is needed.
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
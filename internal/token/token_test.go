@@ -0,0 +1,257 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	secretA = "0123456789abcdef0123456789abcdef"
+	secretB = "fedcba9876543210fedcba9876543210"
+)
+
+func newManager(t *testing.T, active string, keys ...Key) *Manager {
+	t.Helper()
+	if len(keys) == 0 {
+		keys = []Key{{ID: "a", Secret: secretA}}
+	}
+	manager, err := NewManager("quiz", keys, active, 15*time.Minute, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func TestNewManagerRejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []Key
+		active string
+	}{
+		{"no id", []Key{{Secret: secretA}}, ""},
+		{"short secret", []Key{{ID: "a", Secret: "short"}}, "a"},
+		{"duplicate id", []Key{{ID: "a", Secret: secretA}, {ID: "a", Secret: secretB}}, "a"},
+		{"unknown active key", []Key{{ID: "a", Secret: secretA}}, "b"},
+		{"no keys", nil, ""},
+	}
+	for _, tt := range tests {
+		if _, err := NewManager("quiz", tt.keys, tt.active, time.Minute, time.Hour); err == nil {
+			t.Errorf("%s: NewManager succeeded", tt.name)
+		}
+	}
+	if _, err := NewManager("quiz", []Key{{ID: "a", Secret: secretA}}, "a", 0, time.Hour); err == nil {
+		t.Error("NewManager accepted a zero access ttl")
+	}
+}
+
+func TestSignAndParse(t *testing.T) {
+	manager := newManager(t, "a")
+	now := time.Now()
+	raw, expiresAt, err := manager.Sign(&Claims{Role: "admin", SessionID: "s1", MFA: true, RegisteredClaims: jwt.RegisteredClaims{Subject: "u1"}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !expiresAt.Equal(now.Add(15 * time.Minute)) {
+		t.Fatalf("expiresAt = %v, want %v", expiresAt, now.Add(15*time.Minute))
+	}
+	claims, err := manager.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "u1" || claims.Role != "admin" || claims.SessionID != "s1" || !claims.MFA || claims.ImpersonatorID != "" {
+		t.Fatalf("claims = %+v", claims)
+	}
+}
+
+func TestParseRejectsExpiredTokens(t *testing.T) {
+	manager := newManager(t, "a")
+	raw, _, err := manager.Sign(&Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "u1"}}, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Parse(raw); err == nil {
+		t.Fatal("Parse accepted an expired token")
+	}
+
+	manager.ImpersonationTTL = time.Minute
+	raw, _, err = manager.SignImpersonation(&Claims{ImpersonatorID: "admin", RegisteredClaims: jwt.RegisteredClaims{Subject: "u1"}}, time.Now().Add(-2*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Parse(raw); err == nil {
+		t.Fatal("Parse accepted an expired impersonation token")
+	}
+}
+
+// TestParseRejectsOtherTokenKinds keeps every kind of token from being used as another, such as a
+// challenge issued before the second factor as an access token.
+func TestParseRejectsOtherTokenKinds(t *testing.T) {
+	manager := newManager(t, "a")
+	now := time.Now()
+	access, _, err := manager.Sign(&Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "u1"}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := manager.SignChallenge("u1", "c1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verification, err := manager.SignVerification("u1", "a@example.com", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flow, err := manager.SignFlow(&FlowClaims{Provider: "google", State: "state"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsers := map[string]func(string) error{
+		"access": func(raw string) error {
+			_, err := manager.Parse(raw)
+			return err
+		},
+		"challenge": func(raw string) error {
+			_, _, err := manager.ParseChallenge(raw)
+			return err
+		},
+		"verification": func(raw string) error {
+			_, err := manager.ParseVerification(raw)
+			return err
+		},
+		"flow": func(raw string) error {
+			_, err := manager.ParseFlow(raw)
+			return err
+		},
+	}
+	tokens := map[string]string{"access": access, "challenge": challenge, "verification": verification, "flow": flow}
+	for kind, raw := range tokens {
+		for parser, parse := range parsers {
+			err := parse(raw)
+			if kind == parser && err != nil {
+				t.Errorf("%s token rejected as %s: %v", kind, parser, err)
+			}
+			if kind != parser && err == nil {
+				t.Errorf("%s token accepted as %s", kind, parser)
+			}
+		}
+	}
+}
+
+func TestParseRejectsForgedTokens(t *testing.T) {
+	manager := newManager(t, "a")
+	claims := &Claims{Role: "admin", RegisteredClaims: manager.registered("u1", audienceAccess, time.Now(), time.Now().Add(time.Minute))}
+	signWith := func(method jwt.SigningMethod, kid string, key any) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		raw, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newManager(t, "other", Key{ID: "other", Secret: secretA})
+	otherIssuer, err := NewManager("elsewhere", []Key{{ID: "a", Secret: secretA}}, "a", time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromOtherIssuer, _, err := otherIssuer.Sign(&Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "u1"}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	noSubject, _, err := manager.Sign(&Claims{}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := signWith(jwt.SigningMethodHS256, "a", []byte(secretA))
+	parts := strings.Split(valid, ".")
+
+	tests := map[string]string{
+		"wrong secret": signWith(jwt.SigningMethodHS256, "a", []byte(secretB)),
+		"unknown kid":  signWith(jwt.SigningMethodHS256, "b", []byte(secretA)),
+		"no kid":       signWith(jwt.SigningMethodHS256, "", []byte(secretA)),
+		"HS512":        signWith(jwt.SigningMethodHS512, "a", []byte(secretA)),
+		"RS256":        signWith(jwt.SigningMethodRS256, "a", rsaKey),
+		"none":         signWith(jwt.SigningMethodNone, "a", jwt.UnsafeAllowNoneSignatureType),
+		"other manager key": func() string {
+			raw, _, _ := other.Sign(&Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "u1"}}, time.Now())
+			return raw
+		}(),
+		"other issuer":       fromOtherIssuer,
+		"no subject":         noSubject,
+		"tampered payload":   parts[0] + "." + parts[1] + "x." + parts[2],
+		"signature stripped": parts[0] + "." + parts[1] + ".",
+	}
+	if _, err := manager.Parse(valid); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	for name, raw := range tests {
+		if _, err := manager.Parse(raw); err == nil {
+			t.Errorf("%s: Parse accepted the token", name)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := newManager(t, "a", Key{ID: "a", Secret: secretA})
+	raw, _, err := old.Sign(&Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "u1"}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key signs, tokens of the old one stay valid until it is dropped
+	rotated := newManager(t, "b", Key{ID: "a", Secret: secretA}, Key{ID: "b", Secret: secretB})
+	if _, err := rotated.Parse(raw); err != nil {
+		t.Fatalf("token of the previous key rejected: %v", err)
+	}
+	fresh, _, err := rotated.Sign(&Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "u1"}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed, _ := jwt.Parse(fresh, nil); parsed == nil || parsed.Header["kid"] != "b" {
+		t.Fatal("new token is not signed with the active key")
+	}
+
+	dropped := newManager(t, "b", Key{ID: "b", Secret: secretB})
+	if _, err := dropped.Parse(raw); err == nil {
+		t.Fatal("token of a dropped key accepted")
+	}
+}
+
+// TestRefreshTokenRotation checks the opaque tokens a refresh swaps for a new pair: each is new,
+// only its hash is stored and the hash finds it again.
+func TestRefreshTokenRotation(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		raw, hash, err := NewOpaque()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[raw] {
+			t.Fatalf("NewOpaque returned %q twice", raw)
+		}
+		seen[raw] = true
+		if len(raw) != 43 {
+			t.Fatalf("raw token %q is %d characters, want the 43 of 32 random bytes", raw, len(raw))
+		}
+		if hash != Hash(raw) || hash == raw || len(hash) != 64 {
+			t.Fatalf("hash %q does not match token %q", hash, raw)
+		}
+	}
+
+	key, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix) || hash != Hash(key) {
+		t.Fatalf("API key %q with hash %q", key, hash)
+	}
+}
@@ -0,0 +1,111 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors, "12345678901234567890", in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestCodeRFC6238 checks the SHA1 vectors of RFC 6238 appendix B, cut to the last six of their eight digits.
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAcceptsLowerCaseAndPadding(t *testing.T) {
+	want, _ := Code(rfcSecret, 1)
+	for _, secret := range []string{"gezdgnbvgy3tqojqgezdgnbvgy3tqojq", rfcSecret + "===="} {
+		if got, err := Code(secret, 1); err != nil || got != want {
+			t.Errorf("Code(%q) = %s, %v, want %s", secret, got, err, want)
+		}
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code := func(step int64) string {
+		value, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	tests := []struct {
+		name string
+		code string
+		skew int
+		want bool
+		step int64
+	}{
+		{"current step", code(current), 1, true, current},
+		{"previous step", code(current - 1), 1, true, current - 1},
+		{"next step", code(current + 1), 1, true, current + 1},
+		{"two steps back", code(current - 2), 1, false, 0},
+		{"two steps ahead", code(current + 2), 1, false, 0},
+		{"previous step without skew", code(current - 1), 0, false, 0},
+		{"two steps back with skew 2", code(current - 2), 2, true, current - 2},
+		{"spaces", code(current)[:3] + " " + code(current)[3:] + " ", 1, true, current},
+		{"too short", code(current)[:5], 1, false, 0},
+		{"too long", code(current) + "0", 1, false, 0},
+	}
+	for _, tt := range tests {
+		step, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+		if ok != tt.want || step != tt.step {
+			t.Errorf("%s: Validate = %d, %v, want %d, %v", tt.name, step, ok, tt.step, tt.want)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 20 bytes are 32 base32 characters without padding
+	if len(secret) != 32 {
+		t.Fatalf("secret %q is %d characters, want 32", secret, len(secret))
+	}
+	if _, err := Code(secret, 1); err != nil {
+		t.Fatalf("generated secret is unusable: %v", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(ProvisioningURI(rfcSecret, "Quiz", "a@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := uri.Query()
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Quiz:a@example.com" {
+		t.Errorf("uri = %s", uri)
+	}
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "Quiz" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("query = %v", query)
+	}
+}
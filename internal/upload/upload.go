@@ -0,0 +1,188 @@
+// Package upload decides which uploaded files are accepted and under which name they are stored.
+package upload
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	CategoryCourseImage = "course_image"
+	CategoryAvatar      = "avatar"
+	CategoryAttachment  = "attachment"
+)
+
+// SniffLen is how much of the start of a file Detect looks at.
+const SniffLen = 512
+
+// DefaultMaxPixels is how many pixels an image may have by default, a few bytes of a compressed
+// file can declare far more than fit in memory once decoded.
+const DefaultMaxPixels = 40_000_000
+
+// Policy accepts files of at most MaxBytes whose content is one of the MIME types of Types and whose
+// extension is one listed for that type, and images of at most MaxPixels pixels. Images get a
+// variant scaled down to each width of Variants, by name.
+type Policy struct {
+	MaxBytes  int64
+	MaxPixels int64
+	Types     map[string][]string
+	Variants  map[string]int
+}
+
+// Error rejects an upload, with 413 for a file or an image over the limit and 415 for content or
+// an extension the policy does not accept.
+type Error struct {
+	Status      int
+	Message     string
+	Category    string
+	MaxBytes    int64
+	MaxPixels   int64
+	ContentType string
+	Allowed     []string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Check returns the MIME type of a file named fileName of size bytes starting with head, sniffed
+// from its content rather than taken from the client.
+func (p Policy) Check(category string, fileName string, size int64, head []byte) (string, error) {
+	if size > p.MaxBytes {
+		return "", &Error{
+			Status:   http.StatusRequestEntityTooLarge,
+			Message:  fmt.Sprintf("file is larger than the %d bytes allowed for %s", p.MaxBytes, category),
+			Category: category,
+			MaxBytes: p.MaxBytes,
+		}
+	}
+	contentType := Detect(head)
+	extensions, ok := p.Types[contentType]
+	if !ok || !slices.Contains(extensions, strings.ToLower(path.Ext(fileName))) {
+		return "", &Error{
+			Status:      http.StatusUnsupportedMediaType,
+			Message:     fmt.Sprintf("%s files with extension %q are not accepted for %s", contentType, path.Ext(fileName), category),
+			Category:    category,
+			ContentType: contentType,
+			Allowed:     p.Extensions(),
+		}
+	}
+	return contentType, nil
+}
+
+// CheckPixels rejects an image of width by height pixels that has more than MaxPixels, it is
+// called with the dimensions its header declares before the image is decoded.
+func (p Policy) CheckPixels(category string, width int, height int) error {
+	if int64(width)*int64(height) > p.MaxPixels {
+		return &Error{
+			Status:    http.StatusRequestEntityTooLarge,
+			Message:   fmt.Sprintf("image of %dx%d pixels is larger than the %d pixels allowed for %s", width, height, p.MaxPixels, category),
+			Category:  category,
+			MaxPixels: p.MaxPixels,
+		}
+	}
+	return nil
+}
+
+// Extensions lists the extensions of every accepted type.
+func (p Policy) Extensions() []string {
+	var extensions []string
+	for _, typeExtensions := range p.Types {
+		extensions = append(extensions, typeExtensions...)
+	}
+	slices.Sort(extensions)
+	return slices.Compact(extensions)
+}
+
+// Detect sniffs the MIME type from the magic bytes at the start of a file, without parameters.
+func Detect(head []byte) string {
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return contentType
+}
+
+// NormalizeFileName reduces a client-sent file name to its base name of lowercase letters, digits,
+// dashes and underscores plus its lowercased extension, so it is safe in a path and a URL.
+func NormalizeFileName(fileName string) string {
+	// Browsers on Windows may send the full path
+	fileName = path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	ext := strings.ToLower(path.Ext(fileName))
+	if len(ext) < 2 || !isSafe(ext[1:]) {
+		ext = ""
+	}
+	name := strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			return r
+		}
+		return '-'
+	}, strings.TrimSuffix(fileName, path.Ext(fileName)))
+	for strings.Contains(name, "--") {
+		name = strings.ReplaceAll(name, "--", "-")
+	}
+	name = strings.Trim(name, "-_")
+	if len(name) > 100 {
+		name = strings.TrimRight(name[:100], "-_")
+	}
+	if name == "" {
+		name = "file"
+	}
+	return name + ext
+}
+
+func isSafe(s string) bool {
+	for _, r := range s {
+		if r >= unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Policies holds the policy of every category, an unknown category accepts nothing.
+type Policies map[string]Policy
+
+// MaxBytes is the size of the largest file any category accepts.
+func (p Policies) MaxBytes() int64 {
+	var maxBytes int64
+	for _, policy := range p {
+		maxBytes = max(maxBytes, policy.MaxBytes)
+	}
+	return maxBytes
+}
+
+// DefaultPolicies are the built-in policies. SVG is left out on purpose, it can carry scripts.
+func DefaultPolicies() Policies {
+	images := map[string][]string{
+		"image/png":  {".png"},
+		"image/jpeg": {".jpg", ".jpeg"},
+		"image/gif":  {".gif"},
+		"image/webp": {".webp"},
+	}
+	attachments := map[string][]string{
+		"application/pdf": {".pdf"},
+		// Office documents are zip archives underneath
+		"application/zip": {".zip", ".docx", ".xlsx", ".pptx"},
+		"text/plain":      {".txt", ".csv", ".md"},
+	}
+	for contentType, extensions := range images {
+		attachments[contentType] = extensions
+	}
+	return Policies{
+		CategoryCourseImage: {
+			MaxBytes:  5 << 20,
+			MaxPixels: DefaultMaxPixels,
+			Types:     images,
+			Variants:  map[string]int{"thumbnail": 160, "medium": 640, "large": 1280},
+		},
+		CategoryAvatar:     {MaxBytes: 2 << 20, MaxPixels: DefaultMaxPixels, Types: images},
+		CategoryAttachment: {MaxBytes: 20 << 20, MaxPixels: DefaultMaxPixels, Types: attachments},
+	}
+}
@@ -0,0 +1,107 @@
+package upload
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+var (
+	pngHead = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdfHead = []byte("%PDF-1.7\n")
+	// An HTML page a client claims is a PNG
+	htmlHead = []byte("<!DOCTYPE html><script>alert(1)</script>")
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policies := DefaultPolicies()
+
+	tests := []struct {
+		name       string
+		category   string
+		fileName   string
+		size       int64
+		head       []byte
+		wantType   string
+		wantStatus int
+	}{
+		{"image", CategoryCourseImage, "diagram.PNG", 1024, pngHead, "image/png", 0},
+		{"image over the limit", CategoryCourseImage, "diagram.png", 5<<20 + 1, pngHead, "", http.StatusRequestEntityTooLarge},
+		{"avatar limit is smaller", CategoryAvatar, "me.png", 3 << 20, pngHead, "", http.StatusRequestEntityTooLarge},
+		{"html named png", CategoryCourseImage, "diagram.png", 1024, htmlHead, "", http.StatusUnsupportedMediaType},
+		{"png named jpg", CategoryCourseImage, "diagram.jpg", 1024, pngHead, "", http.StatusUnsupportedMediaType},
+		{"pdf is no image", CategoryCourseImage, "notes.pdf", 1024, pdfHead, "", http.StatusUnsupportedMediaType},
+		{"pdf attachment", CategoryAttachment, "notes.pdf", 10 << 20, pdfHead, "application/pdf", 0},
+		{"text attachment", CategoryAttachment, "grades.csv", 64, []byte("name,grade\nbudi,90\n"), "text/plain", 0},
+		{"no extension", CategoryAttachment, "notes", 1024, pdfHead, "", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, err := policies[tt.category].Check(tt.category, tt.fileName, tt.size, tt.head)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if contentType != tt.wantType {
+					t.Fatalf("contentType = %q, want %q", contentType, tt.wantType)
+				}
+				return
+			}
+			var rejected *Error
+			if !errors.As(err, &rejected) {
+				t.Fatalf("err = %v, want an *Error", err)
+			}
+			if rejected.Status != tt.wantStatus {
+				t.Fatalf("Status = %d, want %d", rejected.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckPixels(t *testing.T) {
+	policy := DefaultPolicies()[CategoryCourseImage]
+	tests := []struct {
+		width, height int
+		ok            bool
+	}{
+		{1280, 720, true},
+		{8000, 5000, true},
+		{8000, 5001, false},
+		// Too many for an int32 product
+		{100000, 100000, false},
+	}
+	for _, tt := range tests {
+		err := policy.CheckPixels(CategoryCourseImage, tt.width, tt.height)
+		var rejected *Error
+		switch {
+		case tt.ok && err != nil:
+			t.Errorf("%dx%d: %v", tt.width, tt.height, err)
+		case !tt.ok && !errors.As(err, &rejected):
+			t.Errorf("%dx%d: err = %v, want an *Error", tt.width, tt.height, err)
+		case !tt.ok && (rejected.Status != http.StatusRequestEntityTooLarge || rejected.MaxPixels != DefaultMaxPixels):
+			t.Errorf("%dx%d: rejected with %+v", tt.width, tt.height, rejected)
+		}
+	}
+}
+
+func TestNormalizeFileName(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     string
+	}{
+		{"Diagram 1.PNG", "diagram-1.png"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\guru\Materi Bab 2.pdf`, "materi-bab-2.pdf"},
+		{"..png", "file.png"},
+		{"laporan_akhir (final)!!.docx", "laporan_akhir-final.docx"},
+		{"rangkuman.p$f", "rangkuman"},
+		{"", "file"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeFileName(tt.fileName); got != tt.want {
+			t.Errorf("NormalizeFileName(%q) = %q, want %q", tt.fileName, got, tt.want)
+		}
+	}
+}
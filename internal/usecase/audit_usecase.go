@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/repository"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// AuditUsecase lets admins search the audit trail the Auditor records.
+type AuditUsecase struct {
+	DB                   *gorm.DB
+	Log                  *logrus.Logger
+	Validate             *validator.Validate
+	AuditEventRepository *repository.AuditEventRepository
+}
+
+func NewAuditUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, auditEventRepository *repository.AuditEventRepository) *AuditUsecase {
+	return &AuditUsecase{
+		DB:                   db,
+		Log:                  log,
+		Validate:             validate,
+		AuditEventRepository: auditEventRepository,
+	}
+}
+
+func (c *AuditUsecase) Search(ctx context.Context, request *model.SearchAuditEventRequest) ([]model.AuditEventResponse, int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.WithError(err).Warnf("Invalid request body")
+		return nil, 0, fiber.ErrBadRequest
+	}
+	events, total, err := c.AuditEventRepository.Search(tx, request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search audit events")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.WithError(err).Error("Failed to commit transaction")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.AuditEventResponse, len(events))
+	for i, event := range events {
+		responses[i] = *converter.AuditEventToResponse(&event)
+	}
+	return responses, total, nil
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fp-designpattern/internal/audit"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Auditor records administrative changes in the transaction that makes them, so a change is never
+// kept without its record nor recorded without being kept.
+type Auditor struct {
+	Log                  *logrus.Logger
+	AuditEventRepository *repository.AuditEventRepository
+}
+
+func NewAuditor(log *logrus.Logger, auditEventRepository *repository.AuditEventRepository) *Auditor {
+	return &Auditor{
+		Log:                  log,
+		AuditEventRepository: auditEventRepository,
+	}
+}
+
+// Record adds the change the actor made to the resource. Before and after are the resource as JSON
+// or a value to marshal, nil for a resource that did not exist. Changes without an actor are made
+// by users to their own account or by the application and are not recorded.
+func (a *Auditor) Record(tx *gorm.DB, actor *model.Auth, action string, resourceType string, resourceID string, before any, after any) error {
+	if actor == nil {
+		return nil
+	}
+	actorID, err := uuid.Parse(actor.ID)
+	if err != nil {
+		a.Log.Warnf("Invalid actor id : %+v", err)
+		return fiber.ErrInternalServerError
+	}
+	beforeJSON, afterJSON, err := audit.Diff(marshalAudit(before), marshalAudit(after))
+	if err != nil {
+		a.Log.Warnf("Failed diff audit event : %+v", err)
+		return fiber.ErrInternalServerError
+	}
+
+	if err := a.AuditEventRepository.Create(tx, &entity.AuditEvent{
+		ActorID:      &actorID,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Before:       datatypes.JSON(beforeJSON),
+		After:        datatypes.JSON(afterJSON),
+		IPAddress:    actor.IPAddress,
+	}); err != nil {
+		a.Log.Warnf("Failed create audit event : %+v", err)
+		return fiber.ErrInternalServerError
+	}
+	return nil
+}
+
+func marshalAudit(v any) json.RawMessage {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case json.RawMessage:
+		return value
+	}
+	return audit.Snapshot(v)
+}
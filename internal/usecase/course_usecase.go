@@ -3,11 +3,11 @@ package usecase
 import (
 	"context"
 	"encoding/json"
+	"fp-designpattern/internal/audit"
 	"fp-designpattern/internal/entity"
 	"fp-designpattern/internal/model"
 	"fp-designpattern/internal/model/converter"
 	"fp-designpattern/internal/repository"
-	"mime/multipart"
 
 	"github.com/go-playground/validator"
 	"github.com/gofiber/fiber/v2"
@@ -16,23 +16,32 @@ import (
 	"gorm.io/gorm"
 )
 
+var ErrSubjectDeleted = fiber.NewError(fiber.StatusConflict, "the subject of this course is deleted, restore the subject instead")
+
 type CourseUsecase struct {
 	DB                *gorm.DB
 	Log               *logrus.Logger
 	Validate          *validator.Validate
 	CourseRepository  *repository.CourseRepository
 	SubjectRepository *repository.SubjectRepository
-	FileRepository    *repository.LocalFileRepository
+	FileStorage       repository.FileStorage
+	// MediaAssetRepository keeps the reference counts of the images and files in the content up to date.
+	MediaAssetRepository *repository.MediaAssetRepository
+	Access               *SubjectAccess
+	Auditor              *Auditor
 }
 
-func NewCourseUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, courseRepository *repository.CourseRepository, subjectRepository *repository.SubjectRepository, fileRepository *repository.LocalFileRepository) *CourseUsecase {
+func NewCourseUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, courseRepository *repository.CourseRepository, subjectRepository *repository.SubjectRepository, fileStorage repository.FileStorage, mediaAssetRepository *repository.MediaAssetRepository, access *SubjectAccess, auditor *Auditor) *CourseUsecase {
 	return &CourseUsecase{
-		DB:                db,
-		Log:               log,
-		Validate:          validate,
-		CourseRepository:  courseRepository,
-		SubjectRepository: subjectRepository,
-		FileRepository:    fileRepository,
+		DB:                   db,
+		Log:                  log,
+		Validate:             validate,
+		CourseRepository:     courseRepository,
+		SubjectRepository:    subjectRepository,
+		FileStorage:          fileStorage,
+		MediaAssetRepository: mediaAssetRepository,
+		Access:               access,
+		Auditor:              auditor,
 	}
 }
 
@@ -47,7 +56,8 @@ func (c *CourseUsecase) Create(ctx context.Context, request *model.CourseRequest
 		c.Log.Warnf("Invalid request body : %+v", err)
 		return nil, fiber.ErrBadRequest
 	}
-	contentJSON, err := json.Marshal(request.Content)
+	content := storedContent(c.FileStorage, request.Content)
+	contentJSON, err := json.Marshal(content)
 	if err != nil {
 		c.Log.Warnf("Failed to marshal content: %+v", err)
 		return nil, fiber.ErrInternalServerError
@@ -58,6 +68,9 @@ func (c *CourseUsecase) Create(ctx context.Context, request *model.CourseRequest
 		c.Log.Warnf("Failed find subject by id : %+v", err)
 		return nil, fiber.ErrNotFound
 	}
+	if err := c.Access.Check(tx, request.Actor, subject.ID); err != nil {
+		return nil, err
+	}
 
 	course := &entity.Course{
 		CourseName: request.CourseName,
@@ -70,12 +83,19 @@ func (c *CourseUsecase) Create(ctx context.Context, request *model.CourseRequest
 		c.Log.Warnf("Failed to create subject: %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
+	if err := c.MediaAssetRepository.RecountReferences(tx, mediaURLs(content)); err != nil {
+		c.Log.Warnf("Failed to recount media asset references: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionCreate, audit.ResourceCourse, course.ID.String(), nil, converter.CourseToResponse(course)); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit().Error; err != nil {
 		c.Log.Warnf("Failed to commit transaction: %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.CourseToResponse(course), nil
+	return c.render(ctx, course)
 }
 
 func (c *CourseUsecase) Get(ctx context.Context, request *model.GetCourseRequest) (*model.CourseResponse, error) {
@@ -90,13 +110,15 @@ func (c *CourseUsecase) Get(ctx context.Context, request *model.GetCourseRequest
 		c.Log.Warnf("Failed find subject by id : %+v", err)
 		return nil, fiber.ErrNotFound
 	}
+	if err := c.Access.Check(tx, request.Actor, course.SubjectID); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit().Error; err != nil {
 		c.Log.Warnf("Failed commit transaction : %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.CourseToResponse(course), nil
-
+	return c.render(ctx, course)
 }
 
 func (c *CourseUsecase) Search(ctx context.Context, request *model.SearchCourseRequest) ([]model.CourseListResponse, int64, error) {
@@ -106,6 +128,11 @@ func (c *CourseUsecase) Search(ctx context.Context, request *model.SearchCourseR
 		c.Log.WithError(err).Warnf("Invalid request body")
 		return nil, 0, fiber.ErrBadRequest
 	}
+	subjectIDs, err := c.Access.Subjects(tx, request.Actor)
+	if err != nil {
+		return nil, 0, err
+	}
+	request.SubjectIDs = subjectIDs
 	courses, total, err := c.CourseRepository.Search(tx, request)
 	if err != nil {
 		c.Log.WithError(err).Warnf("Failed to search subject")
@@ -136,13 +163,21 @@ func (c *CourseUsecase) Update(ctx context.Context, request *model.UpdateCourseR
 		c.Log.Warnf("Failed find subject by id : %+v", err)
 		return nil, fiber.ErrNotFound
 	}
+	if err := c.Access.Check(tx, request.Actor, course.SubjectID); err != nil {
+		return nil, err
+	}
+	before := audit.Snapshot(converter.CourseToResponse(course))
 
 	if request.CourseName != "" {
 		course.CourseName = request.CourseName
 	}
 
+	// The media dropped from the content lose a reference, the added ones gain one
+	media := mediaURLs(converter.CourseToResponse(course).Content)
 	if request.Content != nil {
-		contentJSON, err := json.Marshal(request.Content)
+		content := storedContent(c.FileStorage, request.Content)
+		media = append(media, mediaURLs(content)...)
+		contentJSON, err := json.Marshal(content)
 		if err != nil {
 			c.Log.Warnf("Failed to marshal content: %+v", err)
 			return nil, fiber.ErrInternalServerError
@@ -160,6 +195,10 @@ func (c *CourseUsecase) Update(ctx context.Context, request *model.UpdateCourseR
 			c.Log.Warnf("Failed find subject by id : %+v", err)
 			return nil, fiber.ErrNotFound
 		}
+		// Moving the course needs access to the subject it moves to as well
+		if err := c.Access.Check(tx, request.Actor, subject.ID); err != nil {
+			return nil, err
+		}
 		course.SubjectID = subject.ID
 	}
 
@@ -167,12 +206,19 @@ func (c *CourseUsecase) Update(ctx context.Context, request *model.UpdateCourseR
 		c.Log.Warnf("Failed to update subject: %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
+	if err := c.MediaAssetRepository.RecountReferences(tx, media); err != nil {
+		c.Log.Warnf("Failed to recount media asset references: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionUpdate, audit.ResourceCourse, course.ID.String(), before, converter.CourseToResponse(course)); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit().Error; err != nil {
 		c.Log.Warnf("Failed to commit transaction: %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.CourseToResponse(course), nil
+	return c.render(ctx, course)
 }
 
 func (c *CourseUsecase) Delete(ctx context.Context, request *model.DeleteCourseRequest) (*model.CourseResponse, error) {
@@ -191,12 +237,18 @@ func (c *CourseUsecase) Delete(ctx context.Context, request *model.DeleteCourseR
 		c.Log.Warnf("Failed find subject by id : %+v", err)
 		return nil, fiber.ErrNotFound
 	}
+	if err := c.Access.Check(tx, request.Actor, course.SubjectID); err != nil {
+		return nil, err
+	}
 
-	// Delete course
+	// Move course to the trash, its quizzes and enrollments are hidden with it and come back on restore
 	if err := c.CourseRepository.Delete(tx, course); err != nil {
 		c.Log.Warnf("Failed delete subject : %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionDelete, audit.ResourceCourse, course.ID.String(), converter.CourseToResponse(course), nil); err != nil {
+		return nil, err
+	}
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
@@ -204,9 +256,150 @@ func (c *CourseUsecase) Delete(ctx context.Context, request *model.DeleteCourseR
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.CourseToResponse(course), nil
+	return c.render(ctx, course)
 }
 
-func (u *CourseUsecase) UploadFile(ctx context.Context, file multipart.File, fileName string, contentType string) (string, error) {
-	return u.FileRepository.UploadFile(file, fileName, contentType)
+// Trash lists the deleted courses that are not purged yet, those of their subjects for a scoped actor.
+func (c *CourseUsecase) Trash(ctx context.Context, request *model.SearchTrashRequest) ([]model.CourseListResponse, int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.WithError(err).Warnf("Invalid request body")
+		return nil, 0, fiber.ErrBadRequest
+	}
+	subjectIDs, err := c.Access.Subjects(tx, request.Actor)
+	if err != nil {
+		return nil, 0, err
+	}
+	request.SubjectIDs = subjectIDs
+	courses, total, err := c.CourseRepository.SearchDeleted(tx, request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search deleted courses")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.WithError(err).Error("Failed to commit transaction")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.CourseListResponse, len(courses))
+	for i, course := range courses {
+		responses[i] = *converter.CourseToListResponse(&course)
+	}
+	return responses, total, nil
+}
+
+// Restore takes a deleted course out of the trash. A course of a deleted subject comes back
+// with its subject instead.
+func (c *CourseUsecase) Restore(ctx context.Context, request *model.RestoreCourseRequest) (*model.CourseResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	course := new(entity.Course)
+	if err := c.CourseRepository.FindDeletedById(tx, course, request.ID); err != nil {
+		c.Log.Warnf("Failed find deleted course by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if err := c.Access.Check(tx, request.Actor, course.SubjectID); err != nil {
+		return nil, err
+	}
+	if err := c.SubjectRepository.FindById(tx, &course.Subject, course.SubjectID); err != nil {
+		c.Log.Warnf("Subject %s of deleted course %s is deleted : %+v", course.SubjectID, course.ID, err)
+		return nil, ErrSubjectDeleted
+	}
+
+	before := audit.Snapshot(converter.CourseToResponse(course))
+	if err := c.CourseRepository.Restore(tx, course); err != nil {
+		c.Log.Warnf("Failed restore course : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	course.DeletedAt = gorm.DeletedAt{}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionRestore, audit.ResourceCourse, course.ID.String(), before, converter.CourseToResponse(course)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return c.render(ctx, course)
+}
+
+// render converts the course for a client, with its content rendered by renderContent.
+func (c *CourseUsecase) render(ctx context.Context, course *entity.Course) (*model.CourseResponse, error) {
+	response := converter.CourseToResponse(course)
+	if err := renderContent(ctx, c.DB.WithContext(ctx), c.FileStorage, c.MediaAssetRepository, response.Content); err != nil {
+		c.Log.Warnf("Failed render content : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return response, nil
+}
+
+// isMediaBlock reports whether a content block of the type refers to an uploaded file by its URL.
+func isMediaBlock(blockType string) bool {
+	return blockType == "image" || blockType == "file"
+}
+
+// mediaURLs lists the URLs of the image and file blocks of content.
+func mediaURLs(content []model.ContentBlock) []string {
+	var urls []string
+	for _, block := range content {
+		if isMediaBlock(block.Type) {
+			urls = append(urls, block.Data)
+		}
+	}
+	return urls
+}
+
+// renderContent prepares the image and file blocks of content for a client, with URLs it can
+// fetch, signed ones when the storage keeps the files private, and the srcset of the variants of
+// uploaded images.
+func renderContent(ctx context.Context, db *gorm.DB, fileStorage repository.FileStorage, mediaAssetRepository *repository.MediaAssetRepository, content []model.ContentBlock) error {
+	urls := mediaURLs(content)
+	if len(urls) == 0 {
+		return nil
+	}
+	assets, err := mediaAssetRepository.FindByURLs(db, urls)
+	if err != nil {
+		return err
+	}
+	variants := make(map[string]map[string]string, len(assets))
+	for _, asset := range assets {
+		variants[asset.URL] = converter.MediaAssetVariants(&asset)
+	}
+
+	for i := range content {
+		if !isMediaBlock(content[i].Type) {
+			continue
+		}
+		if len(variants[content[i].Data]) > 0 {
+			content[i].Srcset = make(map[string]string, len(variants[content[i].Data]))
+			for name, url := range variants[content[i].Data] {
+				if content[i].Srcset[name], err = fileStorage.SignURL(ctx, url); err != nil {
+					return err
+				}
+			}
+		}
+		if content[i].Data, err = fileStorage.SignURL(ctx, content[i].Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storedContent drops what is filled in when a course is read, a client may send back the content
+// it was given, signed image and file URLs included.
+func storedContent(fileStorage repository.FileStorage, content []model.ContentBlock) []model.ContentBlock {
+	stored := make([]model.ContentBlock, len(content))
+	for i, block := range content {
+		stored[i] = model.ContentBlock{Type: block.Type, Data: block.Data}
+		if isMediaBlock(block.Type) {
+			stored[i].Data = fileStorage.StoredURL(block.Data)
+		}
+	}
+	return stored
 }
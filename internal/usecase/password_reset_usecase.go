@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/mailer"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/repository"
+	"fp-designpattern/internal/token"
+	"net/url"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var ErrResetTokenInvalid = fiber.NewError(fiber.StatusBadRequest, "reset token is invalid or expired")
+
+type PasswordResetUsecase struct {
+	DB                           *gorm.DB
+	Log                          *logrus.Logger
+	Validate                     *validator.Validate
+	UserRepository               *repository.UserRepository
+	PasswordResetTokenRepository *repository.PasswordResetTokenRepository
+	UserSessionRepository        *repository.UserSessionRepository
+	APIKeyRepository             *repository.APIKeyRepository
+	Mailer                       mailer.Mailer
+	// TTL is how long a mailed token stays valid, ResetURL the page the mail links to with ?token= added.
+	TTL      time.Duration
+	ResetURL string
+}
+
+func NewPasswordResetUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, userRepository *repository.UserRepository,
+	passwordResetTokenRepository *repository.PasswordResetTokenRepository, userSessionRepository *repository.UserSessionRepository,
+	apiKeyRepository *repository.APIKeyRepository, mailer mailer.Mailer, ttl time.Duration, resetURL string) *PasswordResetUsecase {
+	return &PasswordResetUsecase{
+		DB:                           db,
+		Log:                          log,
+		Validate:                     validate,
+		UserRepository:               userRepository,
+		PasswordResetTokenRepository: passwordResetTokenRepository,
+		UserSessionRepository:        userSessionRepository,
+		APIKeyRepository:             apiKeyRepository,
+		Mailer:                       mailer,
+		TTL:                          ttl,
+		ResetURL:                     resetURL,
+	}
+}
+
+// Forgot mails a reset token to the user. It reports success for unknown emails too, and only
+// queues the mail rather than wait on the mail server, so the endpoint cannot be used to find out
+// which emails have an account.
+func (c *PasswordResetUsecase) Forgot(ctx context.Context, request *model.ForgotPasswordRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindByEmail(tx, user, request.Email); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Log.Infof("Password reset requested for unknown email")
+			return true, nil
+		}
+		c.Log.Warnf("Failed find user by email : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+
+	now := time.Now()
+	if err := c.PasswordResetTokenRepository.InvalidateByUserId(tx, user.ID.String(), now); err != nil {
+		c.Log.Warnf("Failed invalidate reset tokens : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	raw, hash, err := token.NewOpaque()
+	if err != nil {
+		c.Log.Warnf("Failed generate reset token : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	resetToken := &entity.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(c.TTL),
+	}
+	if err := c.PasswordResetTokenRepository.Create(tx, resetToken); err != nil {
+		c.Log.Warnf("Failed create reset token : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+
+	if err := c.Mailer.Send(ctx, c.resetMessage(user, raw)); err != nil {
+		c.Log.Errorf("Failed queue reset mail to user %s : %+v", user.ID, err)
+	}
+	return true, nil
+}
+
+func (c *PasswordResetUsecase) resetMessage(user *entity.User, raw string) *mailer.Message {
+	instructions := "Use this token to reset your password: " + raw
+	if c.ResetURL != "" {
+		instructions = "Open this link to reset your password: " + c.ResetURL + "?token=" + url.QueryEscape(raw)
+	}
+	return &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password.\n%s\n\nThe token expires in %s. If you did not ask for this, you can ignore this email.\n",
+			user.Username, instructions, c.TTL),
+	}
+}
+
+// Reset redeems a reset token, sets the new password, signs the user out everywhere and revokes
+// their API keys, which whoever knew the old password may have created.
+func (c *PasswordResetUsecase) Reset(ctx context.Context, request *model.ResetPasswordRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	now := time.Now()
+	resetToken := new(entity.PasswordResetToken)
+	if err := c.PasswordResetTokenRepository.FindByHashForUpdate(tx, resetToken, token.Hash(request.Token)); err != nil {
+		c.Log.Warnf("Failed find reset token : %+v", err)
+		return false, ErrResetTokenInvalid
+	}
+	// The user is not loaded while in the trash
+	if resetToken.User.ID == uuid.Nil {
+		c.Log.Warnf("Reset token of deleted user %s", resetToken.UserID)
+		return false, ErrResetTokenInvalid
+	}
+	if resetToken.UsedAt != nil || !now.Before(resetToken.ExpiresAt) {
+		c.Log.Warnf("Reset token of user %s is used or expired", resetToken.UserID)
+		return false, ErrResetTokenInvalid
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.Log.Warnf("Failed to hash password : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	user := &resetToken.User
+	user.Password = string(password)
+	if err := c.UserRepository.Update(tx, user); err != nil {
+		c.Log.Warnf("Failed update user : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+
+	resetToken.UsedAt = &now
+	if err := c.PasswordResetTokenRepository.Update(tx, resetToken); err != nil {
+		c.Log.Warnf("Failed update reset token : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	if _, err := c.UserSessionRepository.RevokeByUserId(tx, user.ID.String(), now); err != nil {
+		c.Log.Warnf("Failed revoke sessions : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	if _, err := c.APIKeyRepository.RevokeByUserId(tx, user.ID.String(), now); err != nil {
+		c.Log.Warnf("Failed revoke api keys : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	return true, nil
+}
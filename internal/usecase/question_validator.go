@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"fmt"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func badQuestion(format string, args ...any) error {
+	return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf(format, args...))
+}
+
+// validateContent checks the text/image blocks shared with course content.
+func validateContent(content []model.ContentBlock) error {
+	for i, block := range content {
+		if block.Type != "text" && block.Type != "image" {
+			return badQuestion("content[%d]: type must be text or image", i)
+		}
+		if strings.TrimSpace(block.Data) == "" {
+			return badQuestion("content[%d]: data is required", i)
+		}
+	}
+	return nil
+}
+
+// validateQuestion applies the rules of the question type to a question created together with its options.
+func validateQuestion(request *model.QuestionRequest) error {
+	if err := validateContent(request.Content); err != nil {
+		return err
+	}
+
+	correct := 0
+	for _, option := range request.Options {
+		if option.Correct {
+			correct++
+		}
+	}
+
+	switch request.Type {
+	case entity.QuestionTypeShortAnswer, entity.QuestionTypeNumeric:
+		if len(request.Options) > 0 {
+			return badQuestion("%s questions have no options", request.Type)
+		}
+		return validateAnswerKey(request.Type, request.AnswerKey)
+	case entity.QuestionTypeOrdering:
+		if len(request.Options) < 2 {
+			return badQuestion("ordering questions need at least two options")
+		}
+		seen := make(map[int]bool, len(request.Options))
+		for i, option := range request.Options {
+			if option.Position < 1 || option.Position > len(request.Options) {
+				return badQuestion("options[%d]: position must be between 1 and %d", i, len(request.Options))
+			}
+			if seen[option.Position] {
+				return badQuestion("options[%d]: position %d is used twice", i, option.Position)
+			}
+			seen[option.Position] = true
+		}
+	case entity.QuestionTypeMatching:
+		if len(request.Options) < 2 {
+			return badQuestion("matching questions need at least two options")
+		}
+		for i, option := range request.Options {
+			if strings.TrimSpace(option.Match) == "" {
+				return badQuestion("options[%d]: match is required", i)
+			}
+		}
+	case entity.QuestionTypeTrueFalse:
+		if len(request.Options) > 0 && (len(request.Options) != 2 || correct != 1) {
+			return badQuestion("true_false questions need exactly two options with one correct")
+		}
+	case entity.QuestionTypeMultipleSelect:
+		if len(request.Options) > 0 && (len(request.Options) < 2 || correct < 1) {
+			return badQuestion("multiple_select questions need at least two options with one or more correct")
+		}
+	default:
+		if len(request.Options) > 0 && (len(request.Options) < 2 || correct != 1) {
+			return badQuestion("single_choice questions need at least two options with exactly one correct")
+		}
+	}
+
+	if request.AnswerKey != nil {
+		return badQuestion("answer_key is only used by short_answer and numeric questions")
+	}
+	return nil
+}
+
+func validateAnswerKey(questionType string, answerKey *model.QuestionAnswerKey) error {
+	if answerKey == nil {
+		return badQuestion("%s questions need an answer_key", questionType)
+	}
+	switch questionType {
+	case entity.QuestionTypeShortAnswer:
+		if len(answerKey.AcceptedAnswers) == 0 {
+			return badQuestion("answer_key.accepted_answers needs at least one pattern")
+		}
+		for i, pattern := range answerKey.AcceptedAnswers {
+			if strings.TrimSpace(strings.ReplaceAll(pattern, "*", "")) == "" {
+				return badQuestion("answer_key.accepted_answers[%d] must contain more than wildcards", i)
+			}
+		}
+	case entity.QuestionTypeNumeric:
+		if answerKey.Value == nil {
+			return badQuestion("answer_key.value is required")
+		}
+		if answerKey.Tolerance < 0 {
+			return badQuestion("answer_key.tolerance must not be negative")
+		}
+	default:
+		return badQuestion("answer_key is only used by short_answer and numeric questions")
+	}
+	return nil
+}
+
+// validateOption checks an option added to or changed on an existing question.
+func validateOption(question *entity.Question, optionID string, correct bool, position int, match string, answers []entity.QuizAnswer) error {
+	others := make([]entity.QuestionOption, 0, len(question.Options))
+	for _, option := range question.Options {
+		if option.ID.String() != optionID {
+			others = append(others, option)
+		}
+	}
+
+	switch question.Type {
+	case entity.QuestionTypeShortAnswer, entity.QuestionTypeNumeric:
+		return badQuestion("%s questions have no options", question.Type)
+	case entity.QuestionTypeOrdering:
+		if position < 1 {
+			return badQuestion("position is required for ordering questions")
+		}
+		for _, option := range others {
+			if option.Position != nil && *option.Position == position {
+				return badQuestion("position %d is already used", position)
+			}
+		}
+	case entity.QuestionTypeMatching:
+		if strings.TrimSpace(match) == "" {
+			return badQuestion("match is required for matching questions")
+		}
+	case entity.QuestionTypeTrueFalse:
+		if optionID == "" && len(others) >= 2 {
+			return badQuestion("true_false questions have exactly two options")
+		}
+	}
+
+	if correct && !question.IsChoice() {
+		return badQuestion("correct is only used by choice questions")
+	}
+	if correct && question.Type != entity.QuestionTypeMultipleSelect {
+		for _, answer := range answers {
+			if answer.OptionID.String() != optionID {
+				return badQuestion("%s questions have exactly one correct option", converter.QuestionType(question))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateQuestion(t *testing.T) {
+	content := []model.ContentBlock{{Type: "text", Data: "question"}}
+	value := 3.5
+	options := func(correct ...bool) []model.OptionRequest {
+		requests := make([]model.OptionRequest, len(correct))
+		for i, c := range correct {
+			requests[i] = model.OptionRequest{Option: "option", Correct: c}
+		}
+		return requests
+	}
+
+	tests := []struct {
+		name    string
+		request model.QuestionRequest
+		wantErr bool
+	}{
+		{"single choice", model.QuestionRequest{Type: entity.QuestionTypeSingleChoice, Content: content, Options: options(true, false)}, false},
+		{"single choice without options", model.QuestionRequest{Type: entity.QuestionTypeSingleChoice, Content: content}, false},
+		{"single choice with one option", model.QuestionRequest{Type: entity.QuestionTypeSingleChoice, Content: content, Options: options(true)}, true},
+		{"single choice with two correct", model.QuestionRequest{Type: entity.QuestionTypeSingleChoice, Content: content, Options: options(true, true)}, true},
+		{"single choice with none correct", model.QuestionRequest{Type: entity.QuestionTypeSingleChoice, Content: content, Options: options(false, false)}, true},
+		{"single choice with an answer key", model.QuestionRequest{Type: entity.QuestionTypeSingleChoice, Content: content, Options: options(true, false), AnswerKey: &model.QuestionAnswerKey{Value: &value}}, true},
+		{"multiple select", model.QuestionRequest{Type: entity.QuestionTypeMultipleSelect, Content: content, Options: options(true, true, false)}, false},
+		{"multiple select with none correct", model.QuestionRequest{Type: entity.QuestionTypeMultipleSelect, Content: content, Options: options(false, false)}, true},
+		{"true false", model.QuestionRequest{Type: entity.QuestionTypeTrueFalse, Content: content, Options: options(false, true)}, false},
+		{"true false with three options", model.QuestionRequest{Type: entity.QuestionTypeTrueFalse, Content: content, Options: options(true, false, false)}, true},
+		{"short answer", model.QuestionRequest{Type: entity.QuestionTypeShortAnswer, Content: content, AnswerKey: &model.QuestionAnswerKey{AcceptedAnswers: []string{"photo*"}}}, false},
+		{"short answer without a key", model.QuestionRequest{Type: entity.QuestionTypeShortAnswer, Content: content}, true},
+		{"short answer with only wildcards", model.QuestionRequest{Type: entity.QuestionTypeShortAnswer, Content: content, AnswerKey: &model.QuestionAnswerKey{AcceptedAnswers: []string{" * "}}}, true},
+		{"short answer with options", model.QuestionRequest{Type: entity.QuestionTypeShortAnswer, Content: content, Options: options(true, false), AnswerKey: &model.QuestionAnswerKey{AcceptedAnswers: []string{"a"}}}, true},
+		{"numeric", model.QuestionRequest{Type: entity.QuestionTypeNumeric, Content: content, AnswerKey: &model.QuestionAnswerKey{Value: &value, Tolerance: 0.1}}, false},
+		{"numeric without a value", model.QuestionRequest{Type: entity.QuestionTypeNumeric, Content: content, AnswerKey: &model.QuestionAnswerKey{Tolerance: 0.1}}, true},
+		{"numeric with a negative tolerance", model.QuestionRequest{Type: entity.QuestionTypeNumeric, Content: content, AnswerKey: &model.QuestionAnswerKey{Value: &value, Tolerance: -1}}, true},
+		{"ordering", model.QuestionRequest{Type: entity.QuestionTypeOrdering, Content: content, Options: []model.OptionRequest{{Option: "b", Position: 2}, {Option: "a", Position: 1}}}, false},
+		{"ordering with one option", model.QuestionRequest{Type: entity.QuestionTypeOrdering, Content: content, Options: []model.OptionRequest{{Option: "a", Position: 1}}}, true},
+		{"ordering with a repeated position", model.QuestionRequest{Type: entity.QuestionTypeOrdering, Content: content, Options: []model.OptionRequest{{Option: "a", Position: 1}, {Option: "b", Position: 1}}}, true},
+		{"ordering with a position out of range", model.QuestionRequest{Type: entity.QuestionTypeOrdering, Content: content, Options: []model.OptionRequest{{Option: "a", Position: 1}, {Option: "b", Position: 3}}}, true},
+		{"matching", model.QuestionRequest{Type: entity.QuestionTypeMatching, Content: content, Options: []model.OptionRequest{{Option: "a", Match: "1"}, {Option: "b", Match: "2"}}}, false},
+		{"matching without a match", model.QuestionRequest{Type: entity.QuestionTypeMatching, Content: content, Options: []model.OptionRequest{{Option: "a", Match: "1"}, {Option: "b", Match: " "}}}, true},
+		{"unknown content block", model.QuestionRequest{Type: entity.QuestionTypeSingleChoice, Content: []model.ContentBlock{{Type: "video", Data: "clip"}}}, true},
+		{"empty content block", model.QuestionRequest{Type: entity.QuestionTypeSingleChoice, Content: []model.ContentBlock{{Type: "text", Data: " "}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateQuestion(&tt.request); (err != nil) != tt.wantErr {
+				t.Errorf("validateQuestion() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateOption(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	one, two := 1, 2
+	choice := &entity.Question{Type: entity.QuestionTypeSingleChoice, Options: []entity.QuestionOption{{ID: a}, {ID: b}}}
+	multiple := &entity.Question{Type: entity.QuestionTypeMultipleSelect, Options: []entity.QuestionOption{{ID: a}, {ID: b}}}
+	trueFalse := &entity.Question{Type: entity.QuestionTypeTrueFalse, Options: []entity.QuestionOption{{ID: a}, {ID: b}}}
+	ordering := &entity.Question{Type: entity.QuestionTypeOrdering, Options: []entity.QuestionOption{{ID: a, Position: &one}, {ID: b, Position: &two}}}
+	matching := &entity.Question{Type: entity.QuestionTypeMatching}
+	numeric := &entity.Question{Type: entity.QuestionTypeNumeric}
+	keyA := []entity.QuizAnswer{{OptionID: a}}
+
+	tests := []struct {
+		name     string
+		question *entity.Question
+		optionID string
+		correct  bool
+		position int
+		match    string
+		answers  []entity.QuizAnswer
+		wantErr  bool
+	}{
+		{"wrong option of a single choice", choice, "", false, 0, "", keyA, false},
+		{"second correct option of a single choice", choice, "", true, 0, "", keyA, true},
+		{"correct option of a single choice without a key", choice, "", true, 0, "", nil, false},
+		{"second correct option of a multiple select", multiple, "", true, 0, "", keyA, false},
+		{"third option of a true false", trueFalse, "", false, 0, "", keyA, true},
+		{"option of a numeric question", numeric, "", false, 0, "", nil, true},
+		{"ordering option at a new position", ordering, "", false, 3, "", nil, false},
+		{"ordering option at a used position", ordering, "", false, 2, "", nil, true},
+		{"ordering option keeping its position", ordering, b.String(), false, 2, "", nil, false},
+		{"ordering option without a position", ordering, "", false, 0, "", nil, true},
+		{"correct ordering option", ordering, "", true, 3, "", nil, true},
+		{"matching option", matching, "", false, 0, "1", nil, false},
+		{"matching option without a match", matching, "", false, 0, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOption(tt.question, tt.optionID, tt.correct, tt.position, tt.match, tt.answers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateOption() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/scoring"
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// answerKeyOf builds the scoring answer key from the questions, their options and the quiz_answers rows.
+func answerKeyOf(questions []entity.Question, keys []entity.QuizAnswer) []scoring.Question {
+	correct := make(map[uuid.UUID][]uuid.UUID)
+	for _, key := range keys {
+		correct[key.QuestionID] = append(correct[key.QuestionID], key.OptionID)
+	}
+
+	answerKey := make([]scoring.Question, len(questions))
+	for i, question := range questions {
+		scored := scoring.Question{
+			ID:      question.ID,
+			Type:    converter.QuestionType(&question),
+			Correct: correct[question.ID],
+		}
+		switch question.Type {
+		case entity.QuestionTypeShortAnswer, entity.QuestionTypeNumeric:
+			if key := converter.QuestionAnswerKey(&question); key != nil {
+				scored.Accepted = key.AcceptedAnswers
+				scored.CaseSensitive = key.CaseSensitive
+				scored.Value = key.Value
+				scored.Tolerance = key.Tolerance
+			}
+		case entity.QuestionTypeOrdering:
+			options := make([]entity.QuestionOption, 0, len(question.Options))
+			for _, option := range question.Options {
+				if option.Position != nil {
+					options = append(options, option)
+				}
+			}
+			sort.Slice(options, func(i, j int) bool {
+				return *options[i].Position < *options[j].Position
+			})
+			for _, option := range options {
+				scored.Order = append(scored.Order, option.ID)
+			}
+		case entity.QuestionTypeMatching:
+			scored.Matches = make(map[uuid.UUID]string)
+			for _, option := range question.Options {
+				if option.MatchValue != nil {
+					scored.Matches[option.ID] = *option.MatchValue
+				}
+			}
+		}
+		answerKey[i] = scored
+	}
+	return answerKey
+}
+
+// answersOf turns the user_answers rows into one answer per question.
+// Choice questions store one row per picked option, short answer and numeric questions a single
+// row with the text in value, ordering questions the position and matching questions the target.
+func answersOf(questions []entity.Question, rows []entity.UserAnswer) map[uuid.UUID]scoring.Answer {
+	types := make(map[uuid.UUID]string, len(questions))
+	for _, question := range questions {
+		types[question.ID] = question.Type
+	}
+
+	positions := make(map[uuid.UUID]map[uuid.UUID]int)
+	answers := make(map[uuid.UUID]scoring.Answer)
+	for _, row := range rows {
+		answer := answers[row.QuestionID]
+		value := ""
+		if row.Value != nil {
+			value = *row.Value
+		}
+		switch types[row.QuestionID] {
+		case entity.QuestionTypeShortAnswer, entity.QuestionTypeNumeric:
+			answer.Text = value
+		case entity.QuestionTypeOrdering:
+			if row.OptionID != nil {
+				position, _ := strconv.Atoi(value)
+				if positions[row.QuestionID] == nil {
+					positions[row.QuestionID] = make(map[uuid.UUID]int)
+				}
+				positions[row.QuestionID][*row.OptionID] = position
+				answer.Order = append(answer.Order, *row.OptionID)
+			}
+		case entity.QuestionTypeMatching:
+			if row.OptionID != nil {
+				if answer.Matches == nil {
+					answer.Matches = make(map[uuid.UUID]string)
+				}
+				answer.Matches[*row.OptionID] = value
+			}
+		default:
+			if row.OptionID != nil {
+				answer.OptionIDs = append(answer.OptionIDs, *row.OptionID)
+			}
+		}
+		answers[row.QuestionID] = answer
+	}
+
+	for questionID, position := range positions {
+		answer := answers[questionID]
+		sort.SliceStable(answer.Order, func(i, j int) bool {
+			return position[answer.Order[i]] < position[answer.Order[j]]
+		})
+		answers[questionID] = answer
+	}
+	return answers
+}
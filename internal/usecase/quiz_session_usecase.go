@@ -0,0 +1,843 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fp-designpattern/internal/analytics"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/repository"
+	"fp-designpattern/internal/scoring"
+	"fp-designpattern/pkg/timezone"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrQuizSessionSubmitted = fiber.NewError(fiber.StatusConflict, "quiz session already submitted")
+	ErrQuizSessionExpired   = fiber.NewError(fiber.StatusConflict, "quiz time limit exceeded, session was auto submitted")
+	ErrQuizBankEmpty        = fiber.NewError(fiber.StatusConflict, "question bank has no questions for this quiz")
+)
+
+type QuizSessionUsecase struct {
+	DB                        *gorm.DB
+	Log                       *logrus.Logger
+	Validate                  *validator.Validate
+	QuizRepository            *repository.QuizRepository
+	QuestionRepository        *repository.QuestionRepository
+	QuestionOptionRepository  *repository.QuestionOptionRepository
+	UserCourseRepository      *repository.UserCourseRepository
+	UserQuizSessionRepository *repository.UserQuizSessionRepository
+	UserAnswerRepository      *repository.UserAnswerRepository
+	QuizAnswerRepository      *repository.QuizAnswerRepository
+	SessionQuestionRepository *repository.UserQuizSessionQuestionRepository
+	Scorer                    *scoring.Scorer
+	Access                    *SubjectAccess
+}
+
+func NewQuizSessionUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, quizRepository *repository.QuizRepository, questionRepository *repository.QuestionRepository, questionOptionRepository *repository.QuestionOptionRepository, userCourseRepository *repository.UserCourseRepository, userQuizSessionRepository *repository.UserQuizSessionRepository, userAnswerRepository *repository.UserAnswerRepository, quizAnswerRepository *repository.QuizAnswerRepository, sessionQuestionRepository *repository.UserQuizSessionQuestionRepository, scorer *scoring.Scorer, access *SubjectAccess) *QuizSessionUsecase {
+	return &QuizSessionUsecase{
+		DB:                        db,
+		Log:                       log,
+		Validate:                  validate,
+		QuizRepository:            quizRepository,
+		QuestionRepository:        questionRepository,
+		QuestionOptionRepository:  questionOptionRepository,
+		UserCourseRepository:      userCourseRepository,
+		UserQuizSessionRepository: userQuizSessionRepository,
+		UserAnswerRepository:      userAnswerRepository,
+		QuizAnswerRepository:      quizAnswerRepository,
+		SessionQuestionRepository: sessionQuestionRepository,
+		Scorer:                    scorer,
+		Access:                    access,
+	}
+}
+
+func (c *QuizSessionUsecase) Start(ctx context.Context, request *model.StartQuizSessionRequest) (*model.QuizSessionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		c.Log.Warnf("Failed to start transaction: %+v", tx.Error)
+		return nil, fiber.ErrInternalServerError
+	}
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	quiz := new(entity.Quiz)
+	if err := c.QuizRepository.FindByIdWithQuestions(tx, quiz, request.QuizID); err != nil {
+		c.Log.Warnf("Failed find quiz by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+
+	// Only students enrolled in the course of the quiz may take it
+	total, err := c.UserCourseRepository.CountByCourseIdAndUserId(tx, quiz.CourseID.String(), request.UserID)
+	if err != nil {
+		c.Log.Warnf("Failed to count user course : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if total == 0 {
+		c.Log.Warnf("User %s has no access to course %s", request.UserID, quiz.CourseID)
+		return nil, fiber.ErrForbidden
+	}
+
+	now := time.Now().In(timezone.WIB)
+
+	// Resume the running session instead of opening a second one
+	session := new(entity.UserQuizSession)
+	err = c.UserQuizSessionRepository.FindActiveByQuizIdAndUserId(tx, session, request.QuizID, request.UserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.Warnf("Failed find active quiz session : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err == nil {
+		if now.Before(session.Deadline(quiz.TimeLimit)) {
+			questions, err := c.sessionQuestions(tx, session)
+			if err != nil {
+				c.Log.Warnf("Failed find session questions : %+v", err)
+				return nil, fiber.ErrInternalServerError
+			}
+			if err := tx.Commit().Error; err != nil {
+				c.Log.Warnf("Failed to commit transaction: %+v", err)
+				return nil, fiber.ErrInternalServerError
+			}
+			return c.toResponse(session, quiz, questions, now), nil
+		}
+		if _, err := c.finish(tx, session, now); err != nil {
+			c.Log.Warnf("Failed to auto submit quiz session : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+	}
+
+	session = &entity.UserQuizSession{
+		StartedAt:     now,
+		Submitted:     false,
+		AutoSubmitted: false,
+		UserID:        uuid.MustParse(request.UserID),
+		QuizID:        quiz.ID,
+	}
+	if err := c.UserQuizSessionRepository.Create(tx, session); err != nil {
+		c.Log.Warnf("Failed to create quiz session: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	questions, err := c.assemble(tx, session, quiz)
+	if errors.Is(err, ErrQuizBankEmpty) {
+		c.Log.Warnf("Quiz %s has no bank questions to draw", quiz.ID)
+		return nil, err
+	}
+	if err != nil {
+		c.Log.Warnf("Failed to assemble quiz session: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return c.toResponse(session, quiz, questions, now), nil
+}
+
+func (c *QuizSessionUsecase) Get(ctx context.Context, request *model.GetQuizSessionRequest) (*model.QuizSessionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	session, err := c.lockOwnedSession(tx, request.ID, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(timezone.WIB)
+	if !session.Submitted && !now.Before(session.Deadline(session.Quiz.TimeLimit)) {
+		if _, err := c.finish(tx, session, now); err != nil {
+			c.Log.Warnf("Failed to auto submit quiz session : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+	}
+
+	quiz := new(entity.Quiz)
+	if err := c.QuizRepository.FindById(tx, quiz, session.QuizID.String()); err != nil {
+		c.Log.Warnf("Failed find quiz by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	questions, err := c.sessionQuestions(tx, session)
+	if err != nil {
+		c.Log.Warnf("Failed find session questions : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return c.toResponse(session, quiz, questions, now), nil
+}
+
+func (c *QuizSessionUsecase) SaveAnswer(ctx context.Context, request *model.SaveAnswerRequest) (*model.QuizSessionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	session, err := c.lockOwnedSession(tx, request.SessionID, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Submitted {
+		c.Log.Warnf("Quiz session %s already submitted", session.ID)
+		return nil, ErrQuizSessionSubmitted
+	}
+
+	now := time.Now().In(timezone.WIB)
+	if !now.Before(session.Deadline(session.Quiz.TimeLimit)) {
+		// Persist the auto submit before refusing the late answer
+		if _, err := c.finish(tx, session, now); err != nil {
+			c.Log.Warnf("Failed to auto submit quiz session : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		if err := tx.Commit().Error; err != nil {
+			c.Log.Warnf("Failed commit transaction : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		return nil, ErrQuizSessionExpired
+	}
+
+	// Only questions drawn for this session can be answered
+	questions, err := c.sessionQuestions(tx, session)
+	if err != nil {
+		c.Log.Warnf("Failed find session questions : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	var question *entity.Question
+	for i := range questions {
+		if questions[i].ID.String() == request.QuestionID {
+			question = &questions[i]
+		}
+	}
+	if question == nil {
+		c.Log.Warnf("Question %s is not part of quiz session %s", request.QuestionID, session.ID)
+		return nil, fiber.ErrNotFound
+	}
+
+	answers, err := c.answerRows(session, question, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// The new selection replaces the previous one, an empty selection clears the answer
+	if err := c.UserAnswerRepository.DeleteBySessionIdAndQuestionId(tx, session.ID.String(), question.ID.String()); err != nil {
+		c.Log.Warnf("Failed to clear answer : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if len(answers) > 0 {
+		if err := c.UserAnswerRepository.CreateBatch(tx, answers); err != nil {
+			c.Log.Warnf("Failed to save answer : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+	}
+
+	saved, err := c.answersResponse(tx, session)
+	if err != nil {
+		c.Log.Warnf("Failed find answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.QuizSessionToResponse(session, saved, now), nil
+}
+
+func (c *QuizSessionUsecase) Submit(ctx context.Context, request *model.SubmitQuizSessionRequest) (*model.QuizSessionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	session, err := c.lockOwnedSession(tx, request.ID, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Submitted {
+		c.Log.Warnf("Quiz session %s already submitted", session.ID)
+		return nil, ErrQuizSessionSubmitted
+	}
+
+	now := time.Now().In(timezone.WIB)
+	result, err := c.finish(tx, session, now)
+	if err != nil {
+		c.Log.Warnf("Failed to submit quiz session : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	answers, err := c.answersResponse(tx, session)
+	if err != nil {
+		c.Log.Warnf("Failed find answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	response := converter.QuizSessionToResponse(session, answers, now)
+	response.Result = converter.QuizResultToResponse(result, session.Quiz.AnswersVisible(now))
+	return response, nil
+}
+
+func (c *QuizSessionUsecase) Attempts(ctx context.Context, request *model.SearchQuizAttemptRequest) ([]model.QuizAttemptResponse, int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.WithError(err).Warnf("Invalid request body")
+		return nil, 0, fiber.ErrBadRequest
+	}
+	sessions, total, err := c.UserQuizSessionRepository.SearchAttempts(tx, request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search quiz attempts")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.WithError(err).Error("Failed to commit transaction")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+
+	now := time.Now().In(timezone.WIB)
+	responses := make([]model.QuizAttemptResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = *converter.QuizAttemptToResponse(&session, now)
+	}
+	return responses, total, nil
+}
+
+func (c *QuizSessionUsecase) Review(ctx context.Context, request *model.ReviewQuizSessionRequest) (*model.QuizReviewResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	session := new(entity.UserQuizSession)
+	if err := c.UserQuizSessionRepository.FindByIdAndUserId(tx, session, request.ID, request.UserID); err != nil {
+		c.Log.Warnf("Failed find quiz session by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if !session.Submitted {
+		c.Log.Warnf("Quiz session %s is not submitted yet", session.ID)
+		return nil, fiber.NewError(fiber.StatusConflict, "quiz session is not submitted yet")
+	}
+
+	questions, err := c.sessionQuestions(tx, session)
+	if err != nil {
+		c.Log.Warnf("Failed find session questions : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	quiz := &session.Quiz
+	quiz.Questions = questions
+	result, err := c.grade(tx, session, quiz.Questions)
+	if err != nil {
+		c.Log.Warnf("Failed to grade quiz session : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	now := time.Now().In(timezone.WIB)
+	return converter.QuizReviewToResponse(session, quiz, result, quiz.AnswersVisible(now), now), nil
+}
+
+func (c *QuizSessionUsecase) Analytics(ctx context.Context, request *model.QuizAnalyticsRequest) (*model.QuizAnalyticsResponse, error) {
+	quiz, report, err := c.analyticsReport(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return converter.QuizAnalyticsToResponse(quiz.ID, report), nil
+}
+
+func (c *QuizSessionUsecase) AnalyticsCSV(ctx context.Context, request *model.QuizAnalyticsRequest) ([]byte, error) {
+	_, report, err := c.analyticsReport(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := analytics.WriteCSV(buffer, report); err != nil {
+		c.Log.Warnf("Failed to write analytics csv : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return buffer.Bytes(), nil
+}
+
+// analyticsReport analyzes the quiz of the request once the actor is allowed to see it.
+func (c *QuizSessionUsecase) analyticsReport(ctx context.Context, request *model.QuizAnalyticsRequest) (*entity.Quiz, *analytics.Report, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, nil, fiber.ErrBadRequest
+	}
+	quiz := new(entity.Quiz)
+	if err := c.QuizRepository.FindById(tx, quiz, request.QuizID); err != nil {
+		c.Log.Warnf("Failed find quiz by id : %+v", err)
+		return nil, nil, fiber.ErrNotFound
+	}
+	if err := c.Access.CheckCourse(tx, request.Actor, quiz.CourseID); err != nil {
+		return nil, nil, err
+	}
+	report, err := c.analyze(tx, quiz)
+	if err != nil {
+		c.Log.Warnf("Failed to analyze quiz : %+v", err)
+		return nil, nil, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, nil, fiber.ErrInternalServerError
+	}
+	return quiz, report, nil
+}
+
+// AutoSubmitExpired submits every session whose time limit has passed and returns how many were closed.
+func (c *QuizSessionUsecase) AutoSubmitExpired(ctx context.Context) (int, error) {
+	now := time.Now().In(timezone.WIB)
+	ids, err := c.UserQuizSessionRepository.FindExpiredIds(c.DB.WithContext(ctx), now, 100)
+	if err != nil {
+		return 0, err
+	}
+
+	submitted := 0
+	for _, id := range ids {
+		tx := c.DB.WithContext(ctx).Begin()
+		session := new(entity.UserQuizSession)
+		if err := c.UserQuizSessionRepository.FindByIdForUpdate(tx, session, id); err != nil {
+			tx.Rollback()
+			c.Log.Warnf("Failed find quiz session %s : %+v", id, err)
+			continue
+		}
+		// Another request may have submitted the session in the meantime
+		if session.Submitted || now.Before(session.Deadline(session.Quiz.TimeLimit)) {
+			tx.Rollback()
+			continue
+		}
+		if _, err := c.finish(tx, session, now); err != nil {
+			tx.Rollback()
+			c.Log.Warnf("Failed to auto submit quiz session %s : %+v", id, err)
+			continue
+		}
+		if err := tx.Commit().Error; err != nil {
+			c.Log.Warnf("Failed commit transaction : %+v", err)
+			continue
+		}
+		submitted++
+	}
+	return submitted, nil
+}
+
+// RunAutoSubmit closes expired sessions on every tick until ctx is cancelled.
+func (c *QuizSessionUsecase) RunAutoSubmit(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			total, err := c.AutoSubmitExpired(ctx)
+			if err != nil {
+				c.Log.Warnf("Failed to auto submit expired quiz sessions : %+v", err)
+				continue
+			}
+			if total > 0 {
+				c.Log.Infof("Auto submitted %d expired quiz sessions", total)
+			}
+		}
+	}
+}
+
+func (c *QuizSessionUsecase) lockOwnedSession(tx *gorm.DB, id string, userID string) (*entity.UserQuizSession, error) {
+	session := new(entity.UserQuizSession)
+	if err := c.UserQuizSessionRepository.FindByIdForUpdate(tx, session, id); err != nil {
+		c.Log.Warnf("Failed find quiz session by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if session.UserID.String() != userID {
+		c.Log.Warnf("Quiz session %s does not belong to user %s", id, userID)
+		return nil, fiber.ErrNotFound
+	}
+	return session, nil
+}
+
+// finish grades and closes the session, once the deadline has passed it is recorded as auto submitted.
+func (c *QuizSessionUsecase) finish(tx *gorm.DB, session *entity.UserQuizSession, now time.Time) (*scoring.Result, error) {
+	questions, err := c.sessionQuestions(tx, session)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.grade(tx, session, questions)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := session.Deadline(session.Quiz.TimeLimit)
+	endedAt := now
+	autoSubmitted := false
+	if !now.Before(deadline) {
+		endedAt = deadline
+		autoSubmitted = true
+	}
+	session.Submitted = true
+	session.AutoSubmitted = autoSubmitted
+	session.EndedAt = &endedAt
+	session.Score = &result.Score
+	if err := c.UserQuizSessionRepository.Update(tx, session); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// grade scores the answers of the session against the answer key of the questions.
+func (c *QuizSessionUsecase) grade(tx *gorm.DB, session *entity.UserQuizSession, questions []entity.Question) (*scoring.Result, error) {
+	ids := make([]uuid.UUID, len(questions))
+	for i, question := range questions {
+		ids[i] = question.ID
+	}
+	keys, err := c.QuizAnswerRepository.FindByQuestionIds(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.UserAnswerRepository.FindBySessionId(tx, session.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	session.Answers = rows
+
+	return c.Scorer.Score(answerKeyOf(questions, keys), answersOf(questions, rows)), nil
+}
+
+func (c *QuizSessionUsecase) answersResponse(tx *gorm.DB, session *entity.UserQuizSession) ([]model.UserAnswerResponse, error) {
+	questions, err := c.sessionQuestions(tx, session)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.UserAnswerRepository.FindBySessionId(tx, session.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	session.Answers = rows
+	return converter.AnswersToResponse(questions, answersOf(questions, rows)), nil
+}
+
+// answerRows validates the answer against the type of the question and turns it into user_answers rows.
+func (c *QuizSessionUsecase) answerRows(session *entity.UserQuizSession, question *entity.Question, request *model.SaveAnswerRequest) ([]*entity.UserAnswer, error) {
+	options := make(map[string]*entity.QuestionOption, len(question.Options))
+	for i := range question.Options {
+		options[question.Options[i].ID.String()] = &question.Options[i]
+	}
+	newRow := func(optionID *uuid.UUID, value *string) *entity.UserAnswer {
+		return &entity.UserAnswer{
+			SessionID:  session.ID,
+			QuestionID: question.ID,
+			OptionID:   optionID,
+			Value:      value,
+		}
+	}
+
+	rows := []*entity.UserAnswer{}
+	switch question.Type {
+	case entity.QuestionTypeShortAnswer, entity.QuestionTypeNumeric:
+		text := strings.TrimSpace(request.Text)
+		if text == "" {
+			return rows, nil
+		}
+		if question.Type == entity.QuestionTypeNumeric {
+			if _, err := scoring.ParseNumber(text); err != nil {
+				c.Log.Warnf("Invalid numeric answer : %+v", err)
+				return nil, fiber.NewError(fiber.StatusBadRequest, "answer must be a number")
+			}
+		}
+		rows = append(rows, newRow(nil, &text))
+	case entity.QuestionTypeOrdering:
+		for i, optionID := range request.Order {
+			option, ok := options[optionID]
+			if !ok {
+				c.Log.Warnf("Option %s does not belong to question %s", optionID, question.ID)
+				return nil, fiber.ErrNotFound
+			}
+			position := strconv.Itoa(i + 1)
+			for _, row := range rows {
+				if *row.OptionID == option.ID {
+					return nil, fiber.NewError(fiber.StatusBadRequest, "order must not repeat an option")
+				}
+			}
+			rows = append(rows, newRow(&option.ID, &position))
+		}
+	case entity.QuestionTypeMatching:
+		targets := make(map[string]bool)
+		for _, target := range converter.MatchTargets(question) {
+			targets[target] = true
+		}
+		for optionID, target := range request.Matches {
+			option, ok := options[optionID]
+			if !ok {
+				c.Log.Warnf("Option %s does not belong to question %s", optionID, question.ID)
+				return nil, fiber.ErrNotFound
+			}
+			if !targets[target] {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "match target is not part of the question")
+			}
+			rows = append(rows, newRow(&option.ID, &target))
+		}
+	default:
+		optionIDs := request.OptionIDs
+		if request.OptionID != "" {
+			optionIDs = append(optionIDs, request.OptionID)
+		}
+		picked := make(map[uuid.UUID]bool, len(optionIDs))
+		for _, optionID := range optionIDs {
+			option, ok := options[optionID]
+			if !ok {
+				c.Log.Warnf("Option %s does not belong to question %s", optionID, question.ID)
+				return nil, fiber.ErrNotFound
+			}
+			if picked[option.ID] {
+				continue
+			}
+			picked[option.ID] = true
+			rows = append(rows, newRow(&option.ID, nil))
+		}
+		if question.Type != entity.QuestionTypeMultipleSelect && len(rows) > 1 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "only one option may be selected")
+		}
+	}
+	return rows, nil
+}
+
+// analyze regrades every submitted session of the quiz against its own question set and the current answer key.
+func (c *QuizSessionUsecase) analyze(tx *gorm.DB, quiz *entity.Quiz) (*analytics.Report, error) {
+	sessions, err := c.UserQuizSessionRepository.FindSubmittedByQuizId(tx, quiz.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	sessionIDs := make([]uuid.UUID, len(sessions))
+	for i, session := range sessions {
+		sessionIDs[i] = session.ID
+	}
+	rows, err := c.SessionQuestionRepository.FindBySessionIds(tx, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+	drawn := make(map[uuid.UUID][]entity.Question)
+	for _, row := range rows {
+		drawn[row.SessionID] = append(drawn[row.SessionID], row.Question)
+	}
+	answers, err := c.UserAnswerRepository.FindBySessionIds(tx, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+	answered := make(map[uuid.UUID][]entity.UserAnswer)
+	for _, answer := range answers {
+		answered[answer.SessionID] = append(answered[answer.SessionID], answer)
+	}
+	quizQuestions, err := c.QuestionRepository.FindByQuizId(tx, quiz.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// The report lists the questions of the quiz first, then bank questions in the order they were first drawn
+	var questions []entity.Question
+	seen := make(map[uuid.UUID]bool)
+	add := func(question entity.Question) {
+		if !seen[question.ID] {
+			seen[question.ID] = true
+			questions = append(questions, question)
+		}
+	}
+	for _, question := range quizQuestions {
+		add(question)
+	}
+	for _, session := range sessions {
+		for _, question := range drawn[session.ID] {
+			add(question)
+		}
+	}
+	questionIDs := make([]uuid.UUID, len(questions))
+	for i, question := range questions {
+		questionIDs[i] = question.ID
+	}
+	keys, err := c.QuizAnswerRepository.FindByQuestionIds(tx, questionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]analytics.Attempt, len(sessions))
+	for i, session := range sessions {
+		sessionQuestions, ok := drawn[session.ID]
+		if !ok {
+			sessionQuestions = quizQuestions
+		}
+		result := c.Scorer.Score(answerKeyOf(sessionQuestions, keys), answersOf(sessionQuestions, answered[session.ID]))
+		endedAt := session.StartedAt
+		if session.EndedAt != nil {
+			endedAt = *session.EndedAt
+		}
+		attempts[i] = analytics.Attempt{
+			Score:     result.Score,
+			Duration:  endedAt.Sub(session.StartedAt),
+			Questions: result.Questions,
+		}
+	}
+
+	analyzed := make([]analytics.Question, len(questions))
+	for i, question := range questions {
+		analyzed[i] = analytics.Question{ID: question.ID, Type: converter.QuestionType(&question)}
+		// Picks are only meaningful for options that are selected as a whole
+		if question.IsChoice() {
+			for _, option := range question.Options {
+				analyzed[i].Options = append(analyzed[i].Options, analytics.Option{ID: option.ID, Text: option.Option})
+			}
+		}
+	}
+	return analytics.Analyze(analyzed, attempts), nil
+}
+
+// assemble picks the questions of a new session, drawing them from the question bank when the quiz
+// says so, shuffles them and saves the drawn set with its option order.
+func (c *QuizSessionUsecase) assemble(tx *gorm.DB, session *entity.UserQuizSession, quiz *entity.Quiz) ([]entity.Question, error) {
+	questions := quiz.Questions
+	shuffle := quiz.Shuffle
+	if quiz.DrawsFromBank() {
+		bank, err := c.QuestionRepository.FindBankBySubjectAndGrade(tx, quiz.BankSubjectID.String(), quiz.BankGradeLevel)
+		if err != nil {
+			return nil, err
+		}
+		if len(bank) == 0 {
+			return nil, ErrQuizBankEmpty
+		}
+		rand.Shuffle(len(bank), func(i, j int) {
+			bank[i], bank[j] = bank[j], bank[i]
+		})
+		if len(bank) > quiz.DrawCount {
+			bank = bank[:quiz.DrawCount]
+		}
+		questions = bank
+		shuffle = true
+	} else if shuffle {
+		rand.Shuffle(len(questions), func(i, j int) {
+			questions[i], questions[j] = questions[j], questions[i]
+		})
+	}
+
+	rows := make([]*entity.UserQuizSessionQuestion, len(questions))
+	for i := range questions {
+		options := questions[i].Options
+		// The stored order of an ordering question is its answer, so it is always shuffled
+		if shuffle || questions[i].Type == entity.QuestionTypeOrdering {
+			rand.Shuffle(len(options), func(i, j int) {
+				options[i], options[j] = options[j], options[i]
+			})
+		}
+		optionOrder := make([]uuid.UUID, len(options))
+		for j, option := range options {
+			optionOrder[j] = option.ID
+		}
+		optionOrderJSON, err := json.Marshal(optionOrder)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = &entity.UserQuizSessionQuestion{
+			SessionID:   session.ID,
+			QuestionID:  questions[i].ID,
+			Position:    i + 1,
+			OptionOrder: optionOrderJSON,
+		}
+	}
+	if len(rows) > 0 {
+		if err := c.SessionQuestionRepository.CreateBatch(tx, rows); err != nil {
+			return nil, err
+		}
+	}
+	return questions, nil
+}
+
+// sessionQuestions returns the questions drawn for the session in the order the student sees them.
+// Sessions started before questions were drawn per session fall back to the questions of the quiz.
+func (c *QuizSessionUsecase) sessionQuestions(tx *gorm.DB, session *entity.UserQuizSession) ([]entity.Question, error) {
+	rows, err := c.SessionQuestionRepository.FindBySessionId(tx, session.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		questions, err := c.QuestionRepository.FindByQuizId(tx, session.QuizID.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, question := range questions {
+			if question.Type == entity.QuestionTypeOrdering {
+				sort.Slice(question.Options, func(i, j int) bool {
+					return question.Options[i].ID.String() < question.Options[j].ID.String()
+				})
+			}
+		}
+		return questions, nil
+	}
+
+	questions := make([]entity.Question, len(rows))
+	for i, row := range rows {
+		var optionOrder []uuid.UUID
+		if err := json.Unmarshal(row.OptionOrder, &optionOrder); err != nil {
+			return nil, err
+		}
+		position := make(map[uuid.UUID]int, len(optionOrder))
+		for j, optionID := range optionOrder {
+			position[optionID] = j
+		}
+		question := row.Question
+		// Options added after the session started go last
+		sort.SliceStable(question.Options, func(i, j int) bool {
+			pi, ok := position[question.Options[i].ID]
+			if !ok {
+				pi = len(optionOrder)
+			}
+			pj, ok := position[question.Options[j].ID]
+			if !ok {
+				pj = len(optionOrder)
+			}
+			return pi < pj
+		})
+		questions[i] = question
+	}
+	return questions, nil
+}
+
+func (c *QuizSessionUsecase) toResponse(session *entity.UserQuizSession, quiz *entity.Quiz, questions []entity.Question, now time.Time) *model.QuizSessionResponse {
+	quiz.Questions = questions
+	session.Quiz = *quiz
+	answers := converter.AnswersToResponse(quiz.Questions, answersOf(quiz.Questions, session.Answers))
+	response := converter.QuizSessionToResponse(session, answers, now)
+	response.Quiz = converter.QuizToResponse(quiz)
+	return response
+}
@@ -0,0 +1,397 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
+	"fp-designpattern/internal/repository"
+	"fp-designpattern/internal/scoring"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func newTestQuizSessionUsecase(db *gorm.DB) *QuizSessionUsecase {
+	log := newTestLogger()
+	courseRepository := repository.NewCourseRepository(log)
+	access := NewSubjectAccess(log, rbac.NewPolicy(rbac.DefaultRoles(), []string{rbac.RoleAdmin}), repository.NewTeacherSubjectRepository(log), courseRepository)
+	return NewQuizSessionUsecase(db, log, validator.New(), repository.NewQuizRepository(log), repository.NewQuestionRepository(log),
+		repository.NewQuestionOptionRepository(log), repository.NewUserCourseRepository(log), repository.NewUserQuizSessionRepository(log),
+		repository.NewUserAnswerRepository(log), repository.NewQuizAnswerRepository(log), repository.NewUserQuizSessionQuestionRepository(log),
+		scoring.NewScorer(false), access)
+}
+
+// newTestQuestion stores the question as a single choice question with a right and a wrong option,
+// removed with its quiz or subject, and returns its id and the id of the right option.
+func newTestQuestion(t *testing.T, db *gorm.DB, question *entity.Question) (uuid.UUID, uuid.UUID) {
+	t.Helper()
+	question.Type = entity.QuestionTypeSingleChoice
+	question.Content = datatypes.JSON(`[{"type":"text","data":"test"}]`)
+	if err := db.Omit("Quiz", "Subject", "Options").Create(question).Error; err != nil {
+		t.Fatal(err)
+	}
+	right := &entity.QuestionOption{Option: "right", QuestionID: question.ID}
+	wrong := &entity.QuestionOption{Option: "wrong", QuestionID: question.ID}
+	for _, option := range []*entity.QuestionOption{right, wrong} {
+		if err := db.Omit("Question").Create(option).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&entity.QuizAnswer{QuestionID: question.ID, OptionID: right.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return question.ID, right.ID
+}
+
+// newTestQuiz stores the quiz with one question, see newTestQuestion.
+func newTestQuiz(t *testing.T, db *gorm.DB, quiz *entity.Quiz) (uuid.UUID, uuid.UUID) {
+	t.Helper()
+	if err := db.Omit("Course", "Questions").Create(quiz).Error; err != nil {
+		t.Fatal(err)
+	}
+	return newTestQuestion(t, db, &entity.Question{QuizID: &quiz.ID})
+}
+
+// newTestStudent stores a user enrolled in the course.
+func newTestStudent(t *testing.T, db *gorm.DB, course *entity.Course) *entity.User {
+	t.Helper()
+	user := newTestUser(t, db, rbac.RoleUser)
+	if err := db.Omit("User", "Course").Create(&entity.UserCourse{UserID: user.ID, CourseID: course.ID, AccessedAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestQuizSessionTimeLimit(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestQuizSessionUsecase(db)
+	ctx := context.Background()
+	_, course := newTestSubject(t, db)
+	quiz := &entity.Quiz{QuizName: "test", TimeLimit: 10, CourseID: course.ID}
+	questionID, rightID := newTestQuiz(t, db, quiz)
+	user := newTestStudent(t, db, course)
+	userID := user.ID.String()
+
+	started, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: quiz.ID.String(), UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !started.Deadline.Equal(started.StartedAt.Add(10*time.Minute)) || started.RemainingSeconds < 9*60 || started.RemainingSeconds > 10*60 {
+		t.Errorf("deadline %v with %ds left, want the time limit after %v", started.Deadline, started.RemainingSeconds, started.StartedAt)
+	}
+	resumed, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: quiz.ID.String(), UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.ID != started.ID {
+		t.Errorf("starting again opened session %s, want the running %s", resumed.ID, started.ID)
+	}
+	answer := &model.SaveAnswerRequest{SessionID: started.ID.String(), UserID: userID, QuestionID: questionID.String(), OptionID: rightID.String()}
+	if _, err := c.SaveAnswer(ctx, answer); err != nil {
+		t.Fatal(err)
+	}
+	submitted, err := c.Submit(ctx, &model.SubmitQuizSessionRequest{ID: started.ID.String(), UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !submitted.Submitted || submitted.AutoSubmitted || submitted.Score == nil || *submitted.Score != 100 {
+		t.Errorf("submitted %+v, want a score of 100 submitted in time", submitted)
+	}
+
+	stranger := newTestUser(t, db, rbac.RoleUser)
+	tests := []struct {
+		name string
+		run  func() error
+		want int
+	}{
+		{"answer after submitting", func() error { _, err := c.SaveAnswer(ctx, answer); return err }, fiber.StatusConflict},
+		{"submit twice", func() error {
+			_, err := c.Submit(ctx, &model.SubmitQuizSessionRequest{ID: started.ID.String(), UserID: userID})
+			return err
+		}, fiber.StatusConflict},
+		{"session of another user", func() error {
+			_, err := c.Get(ctx, &model.GetQuizSessionRequest{ID: started.ID.String(), UserID: stranger.ID.String()})
+			return err
+		}, fiber.StatusNotFound},
+		{"start without enrollment", func() error {
+			_, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: quiz.ID.String(), UserID: stranger.ID.String()})
+			return err
+		}, fiber.StatusForbidden},
+		{"unknown quiz", func() error {
+			_, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: uuid.NewString(), UserID: userID})
+			return err
+		}, fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := statusOf(tt.run()); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// A submitted session is not resumed
+	again, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: quiz.ID.String(), UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID == started.ID {
+		t.Error("starting after submitting resumed the submitted session")
+	}
+}
+
+func TestQuizSessionAutoSubmit(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestQuizSessionUsecase(db)
+	ctx := context.Background()
+	_, course := newTestSubject(t, db)
+	quiz := &entity.Quiz{QuizName: "test", TimeLimit: 10, CourseID: course.ID}
+	questionID, rightID := newTestQuiz(t, db, quiz)
+
+	tests := []struct {
+		name string
+		run  func(sessionID string, userID string) error
+		want error
+	}{
+		{"answer after the deadline", func(sessionID string, userID string) error {
+			_, err := c.SaveAnswer(ctx, &model.SaveAnswerRequest{SessionID: sessionID, UserID: userID, QuestionID: questionID.String(), OptionID: rightID.String()})
+			return err
+		}, ErrQuizSessionExpired},
+		{"read after the deadline", func(sessionID string, userID string) error {
+			_, err := c.Get(ctx, &model.GetQuizSessionRequest{ID: sessionID, UserID: userID})
+			return err
+		}, nil},
+		{"start after the deadline", func(sessionID string, userID string) error {
+			_, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: quiz.ID.String(), UserID: userID})
+			return err
+		}, nil},
+		{"auto submit job", func(sessionID string, userID string) error {
+			_, err := c.AutoSubmitExpired(ctx)
+			return err
+		}, nil},
+	}
+	for _, tt := range tests {
+		user := newTestStudent(t, db, course)
+		userID := user.ID.String()
+		started, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: quiz.ID.String(), UserID: userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.SaveAnswer(ctx, &model.SaveAnswerRequest{SessionID: started.ID.String(), UserID: userID, QuestionID: questionID.String(), OptionID: rightID.String()}); err != nil {
+			t.Fatal(err)
+		}
+		// The time limit ran out a minute ago
+		if err := db.Model(&entity.UserQuizSession{}).Where("id = ?", started.ID).Update("started_at", time.Now().Add(-11*time.Minute)).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tt.run(started.ID.String(), userID); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+		session := new(entity.UserQuizSession)
+		if err := db.First(session, "id = ?", started.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		// The session ends at its deadline with the answers given in time
+		if !session.Submitted || !session.AutoSubmitted || session.EndedAt == nil || !session.EndedAt.Equal(session.Deadline(quiz.TimeLimit)) {
+			t.Errorf("%s: session %+v, want auto submitted at the deadline", tt.name, session)
+		}
+		if session.Score == nil || *session.Score != 100 {
+			t.Errorf("%s: score %v, want 100", tt.name, session.Score)
+		}
+	}
+
+	// A session still running is left alone by the job
+	user := newTestStudent(t, db, course)
+	running, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: quiz.ID.String(), UserID: user.ID.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AutoSubmitExpired(ctx); err != nil {
+		t.Fatal(err)
+	}
+	session := new(entity.UserQuizSession)
+	if err := db.First(session, "id = ?", running.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if session.Submitted {
+		t.Error("auto submit closed a session before its deadline")
+	}
+}
+
+func TestQuizSessionRevealsAnswers(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestQuizSessionUsecase(db)
+	ctx := context.Background()
+	_, course := newTestSubject(t, db)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name            string
+		showAnswers     string
+		answersRevealAt *time.Time
+		want            bool
+	}{
+		{"after submit", entity.ShowAnswersAfterSubmit, nil, true},
+		{"never", entity.ShowAnswersNever, nil, false},
+		{"before the reveal date", entity.ShowAnswersAfterRevealDate, &future, false},
+		{"after the reveal date", entity.ShowAnswersAfterRevealDate, &past, true},
+	}
+	for _, tt := range tests {
+		quiz := &entity.Quiz{QuizName: "test", TimeLimit: 10, CourseID: course.ID, ShowAnswers: tt.showAnswers, AnswersRevealAt: tt.answersRevealAt}
+		questionID, rightID := newTestQuiz(t, db, quiz)
+		user := newTestStudent(t, db, course)
+		userID := user.ID.String()
+		started, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: quiz.ID.String(), UserID: userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.SaveAnswer(ctx, &model.SaveAnswerRequest{SessionID: started.ID.String(), UserID: userID, QuestionID: questionID.String(), OptionID: rightID.String()}); err != nil {
+			t.Fatal(err)
+		}
+		// Nothing is reviewed before the attempt is submitted
+		if _, err := c.Review(ctx, &model.ReviewQuizSessionRequest{ID: started.ID.String(), UserID: userID}); statusOf(err) != fiber.StatusConflict {
+			t.Errorf("%s: review before submitting = %v, want 409", tt.name, err)
+		}
+
+		submitted, err := c.Submit(ctx, &model.SubmitQuizSessionRequest{ID: started.ID.String(), UserID: userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The score is always shown, the answer key only when the policy allows it
+		result := submitted.Result.Questions[0]
+		if submitted.Result.Score != 100 || (len(result.CorrectOptionIDs) == 1) != tt.want || (result.Points != nil) != tt.want {
+			t.Errorf("%s: submit result %+v, want answers shown %v", tt.name, submitted.Result, tt.want)
+		}
+		review, err := c.Review(ctx, &model.ReviewQuizSessionRequest{ID: started.ID.String(), UserID: userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		question := review.Questions[0]
+		if review.AnswersVisible != tt.want || (len(question.CorrectOptionIDs) == 1) != tt.want || (question.Status != "") != tt.want {
+			t.Errorf("%s: review %+v, want answers shown %v", tt.name, review, tt.want)
+		}
+		if len(question.Answer.OptionIDs) != 1 || question.Answer.OptionIDs[0] != rightID {
+			t.Errorf("%s: review answer %+v, want the option picked", tt.name, question.Answer)
+		}
+	}
+}
+
+func TestQuizSessionDrawsFromBank(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestQuizSessionUsecase(db)
+	ctx := context.Background()
+	subject, course := newTestSubject(t, db)
+	emptySubject, _ := newTestSubject(t, db)
+	grade10, grade11 := 10, 11
+	bank := make(map[uuid.UUID]int)
+	for _, grade := range []int{grade10, grade10, grade10, grade10, grade10, grade11} {
+		questionID, _ := newTestQuestion(t, db, &entity.Question{SubjectID: &subject.ID, GradeLevel: &grade})
+		bank[questionID] = grade
+	}
+
+	tests := []struct {
+		name       string
+		drawCount  int
+		subjectID  uuid.UUID
+		gradeLevel *int
+		want       int
+		wantErr    error
+	}{
+		{"fewer than the bank holds", 3, subject.ID, &grade10, 3, nil},
+		{"more than the bank holds", 10, subject.ID, &grade10, 5, nil},
+		{"every grade level", 10, subject.ID, nil, 6, nil},
+		{"empty bank", 3, emptySubject.ID, nil, 0, ErrQuizBankEmpty},
+	}
+	for _, tt := range tests {
+		quiz := &entity.Quiz{QuizName: "test", TimeLimit: 10, CourseID: course.ID, DrawCount: tt.drawCount, BankSubjectID: &tt.subjectID, BankGradeLevel: tt.gradeLevel}
+		ownID, _ := newTestQuiz(t, db, quiz)
+		user := newTestStudent(t, db, course)
+		userID := user.ID.String()
+		started, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: quiz.ID.String(), UserID: userID})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		drawn := started.Quiz.Questions
+		seen := make(map[uuid.UUID]bool, len(drawn))
+		for _, question := range drawn {
+			grade, ok := bank[question.ID]
+			if !ok || seen[question.ID] || (tt.gradeLevel != nil && grade != *tt.gradeLevel) {
+				t.Errorf("%s: drew question %s, want distinct bank questions of the grade", tt.name, question.ID)
+			}
+			seen[question.ID] = true
+		}
+		if len(drawn) != tt.want || seen[ownID] {
+			t.Errorf("%s: drew %d questions, want %d from the bank", tt.name, len(drawn), tt.want)
+		}
+
+		// The draw belongs to the session, reading it again shows the same questions in the same order
+		read, err := c.Get(ctx, &model.GetQuizSessionRequest{ID: started.ID.String(), UserID: userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, question := range read.Quiz.Questions {
+			if i >= len(drawn) || question.ID != drawn[i].ID || len(question.Options) != len(drawn[i].Options) || question.Options[0].ID != drawn[i].Options[0].ID {
+				t.Errorf("%s: question %d of the session changed from %s to %s", tt.name, i, drawn[min(i, len(drawn)-1)].ID, question.ID)
+			}
+		}
+		if len(read.Quiz.Questions) != len(drawn) {
+			t.Errorf("%s: read %d questions, drew %d", tt.name, len(read.Quiz.Questions), len(drawn))
+		}
+		// Questions left out of the draw cannot be answered
+		if _, err := c.SaveAnswer(ctx, &model.SaveAnswerRequest{SessionID: started.ID.String(), UserID: userID, QuestionID: ownID.String(), OptionID: uuid.NewString()}); statusOf(err) != fiber.StatusNotFound {
+			t.Errorf("%s: answering a question not drawn = %v, want 404", tt.name, err)
+		}
+	}
+}
+
+func TestQuizSessionShufflesQuestions(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestQuizSessionUsecase(db)
+	ctx := context.Background()
+	_, course := newTestSubject(t, db)
+	quiz := &entity.Quiz{QuizName: "test", TimeLimit: 10, CourseID: course.ID, Shuffle: true}
+	firstID, _ := newTestQuiz(t, db, quiz)
+	questions := map[uuid.UUID]bool{firstID: true}
+	for range 9 {
+		questionID, _ := newTestQuestion(t, db, &entity.Question{QuizID: &quiz.ID})
+		questions[questionID] = true
+	}
+
+	// Every student gets every question of the quiz, the order is their own
+	orders := make(map[string]bool)
+	for range 5 {
+		user := newTestStudent(t, db, course)
+		started, err := c.Start(ctx, &model.StartQuizSessionRequest{QuizID: quiz.ID.String(), UserID: user.ID.String()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		order := ""
+		seen := make(map[uuid.UUID]bool)
+		for _, question := range started.Quiz.Questions {
+			if !questions[question.ID] || seen[question.ID] {
+				t.Errorf("session holds question %s, want each question of the quiz once", question.ID)
+			}
+			seen[question.ID] = true
+			order += question.ID.String()
+		}
+		if len(seen) != len(questions) {
+			t.Errorf("session holds %d questions, want %d", len(seen), len(questions))
+		}
+		orders[order] = true
+	}
+	// Five sessions of ten questions share an order by chance only once in about 10^26 runs
+	if len(orders) == 1 {
+		t.Error("every session got the questions in the same order")
+	}
+}
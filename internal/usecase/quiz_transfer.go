@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"fmt"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/quizformat"
+	"sort"
+	"strings"
+)
+
+// ImportError lists every question of an import file that could not be imported.
+type ImportError struct {
+	Lines []model.ImportLineError
+}
+
+func (e *ImportError) Error() string {
+	messages := make([]string, len(e.Lines))
+	for i, line := range e.Lines {
+		messages[i] = fmt.Sprintf("line %d: %s", line.Line, line.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// importedQuestionRequest turns a parsed question into the request CreateQuestion validates.
+func importedQuestionRequest(question *quizformat.Question) *model.QuestionRequest {
+	request := &model.QuestionRequest{
+		Type:    question.Type,
+		Content: question.Content,
+	}
+	for i, option := range question.Options {
+		optionRequest := model.OptionRequest{
+			Option:  option.Text,
+			Correct: option.Correct,
+			Match:   option.Match,
+		}
+		if question.Type == entity.QuestionTypeOrdering {
+			optionRequest.Position = i + 1
+		}
+		request.Options = append(request.Options, optionRequest)
+	}
+	if question.Type == entity.QuestionTypeShortAnswer || question.Type == entity.QuestionTypeNumeric {
+		request.AnswerKey = &model.QuestionAnswerKey{
+			AcceptedAnswers: question.Accepted,
+			CaseSensitive:   question.CaseSensitive,
+			Value:           question.Value,
+			Tolerance:       question.Tolerance,
+		}
+	}
+	return request
+}
+
+// exportedQuestion turns a stored question and its quiz_answers rows into the format neutral shape.
+func exportedQuestion(question *entity.Question, keys []entity.QuizAnswer, number int) quizformat.Question {
+	response := converter.QuestionWithAnswerKeyToResponse(question, keys)
+	correct := make(map[string]bool, len(response.CorrectOptionIDs))
+	for _, optionID := range response.CorrectOptionIDs {
+		correct[optionID.String()] = true
+	}
+
+	exported := quizformat.Question{
+		Name:    fmt.Sprintf("Q%d", number),
+		Type:    response.Type,
+		Content: response.Content,
+	}
+	options := response.Options
+	if exported.Type == entity.QuestionTypeOrdering {
+		sort.SliceStable(options, func(i, j int) bool {
+			return options[i].Position != nil && (options[j].Position == nil || *options[i].Position < *options[j].Position)
+		})
+	}
+	for _, option := range options {
+		exported.Options = append(exported.Options, quizformat.Option{
+			Text:    option.Option,
+			Correct: correct[option.ID.String()],
+			Match:   option.Match,
+		})
+	}
+	if key := response.AnswerKey; key != nil {
+		exported.Accepted = key.AcceptedAnswers
+		exported.CaseSensitive = key.CaseSensitive
+		exported.Value = key.Value
+		exported.Tolerance = key.Tolerance
+	}
+	return exported
+}
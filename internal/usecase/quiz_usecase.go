@@ -0,0 +1,1024 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fp-designpattern/internal/audit"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/quizformat"
+	"fp-designpattern/internal/repository"
+	"sort"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type QuizUsecase struct {
+	DB                       *gorm.DB
+	Log                      *logrus.Logger
+	Validate                 *validator.Validate
+	QuizRepository           *repository.QuizRepository
+	QuestionRepository       *repository.QuestionRepository
+	QuestionOptionRepository *repository.QuestionOptionRepository
+	QuizAnswerRepository     *repository.QuizAnswerRepository
+	CourseRepository         *repository.CourseRepository
+	SubjectRepository        *repository.SubjectRepository
+	Access                   *SubjectAccess
+	Auditor                  *Auditor
+}
+
+func NewQuizUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, quizRepository *repository.QuizRepository, questionRepository *repository.QuestionRepository, questionOptionRepository *repository.QuestionOptionRepository, quizAnswerRepository *repository.QuizAnswerRepository, courseRepository *repository.CourseRepository, subjectRepository *repository.SubjectRepository, access *SubjectAccess, auditor *Auditor) *QuizUsecase {
+	return &QuizUsecase{
+		DB:                       db,
+		Log:                      log,
+		Validate:                 validate,
+		QuizRepository:           quizRepository,
+		QuestionRepository:       questionRepository,
+		QuestionOptionRepository: questionOptionRepository,
+		QuizAnswerRepository:     quizAnswerRepository,
+		CourseRepository:         courseRepository,
+		SubjectRepository:        subjectRepository,
+		Access:                   access,
+		Auditor:                  auditor,
+	}
+}
+
+func (c *QuizUsecase) Create(ctx context.Context, request *model.QuizRequest) (*model.QuizResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		c.Log.Warnf("Failed to start transaction: %+v", tx.Error)
+		return nil, fiber.ErrInternalServerError
+	}
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	course := new(entity.Course)
+	if err := c.CourseRepository.FindById(tx, course, request.CourseID); err != nil {
+		c.Log.Warnf("Failed find course by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if err := c.Access.Check(tx, request.Actor, course.SubjectID); err != nil {
+		return nil, err
+	}
+
+	quiz := &entity.Quiz{
+		QuizName:        request.QuizName,
+		TimeLimit:       request.TimeLimit,
+		ShowAnswers:     entity.ShowAnswersAfterSubmit,
+		AnswersRevealAt: request.AnswersRevealAt,
+		DrawCount:       request.DrawCount,
+		BankGradeLevel:  request.BankGradeLevel,
+		Shuffle:         request.Shuffle,
+		CourseID:        course.ID,
+	}
+	if request.ShowAnswers != "" {
+		quiz.ShowAnswers = request.ShowAnswers
+	}
+	if request.BankSubjectID != "" {
+		subject := new(entity.Subject)
+		if err := c.SubjectRepository.FindById(tx, subject, request.BankSubjectID); err != nil {
+			c.Log.Warnf("Failed find subject by id : %+v", err)
+			return nil, fiber.ErrNotFound
+		}
+		if err := c.Access.Check(tx, request.Actor, subject.ID); err != nil {
+			return nil, err
+		}
+		quiz.BankSubjectID = &subject.ID
+	}
+	if quiz.DrawCount > 0 && quiz.BankSubjectID == nil {
+		c.Log.Warnf("Quiz drawing %d questions has no bank subject", quiz.DrawCount)
+		return nil, fiber.NewError(fiber.StatusBadRequest, "bank_subject_id is required when draw_count is set")
+	}
+	if quiz.ShowAnswers == entity.ShowAnswersAfterRevealDate && quiz.AnswersRevealAt == nil {
+		c.Log.Warnf("Quiz with show_answers %s requires answers_reveal_at", quiz.ShowAnswers)
+		return nil, fiber.NewError(fiber.StatusBadRequest, "answers_reveal_at is required when show_answers is after_reveal_date")
+	}
+	if err := c.QuizRepository.Create(tx, quiz); err != nil {
+		c.Log.Warnf("Failed to create quiz: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionCreate, audit.ResourceQuiz, quiz.ID.String(), nil, converter.QuizToResponse(quiz)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.QuizToResponse(quiz), nil
+}
+
+func (c *QuizUsecase) Get(ctx context.Context, request *model.GetQuizRequest) (*model.QuizResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	quiz := new(entity.Quiz)
+	if err := c.QuizRepository.FindByIdWithQuestions(tx, quiz, request.ID); err != nil {
+		c.Log.Warnf("Failed find quiz by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if err := c.Access.CheckCourse(tx, request.Actor, quiz.CourseID); err != nil {
+		return nil, err
+	}
+	answers, err := c.QuizAnswerRepository.FindByQuizId(tx, request.ID)
+	if err != nil {
+		c.Log.Warnf("Failed find quiz answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.QuizWithAnswerKeyToResponse(quiz, answers), nil
+}
+
+func (c *QuizUsecase) Search(ctx context.Context, request *model.SearchQuizRequest) ([]model.QuizListResponse, int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.WithError(err).Warnf("Invalid request body")
+		return nil, 0, fiber.ErrBadRequest
+	}
+	subjectIDs, err := c.Access.Subjects(tx, request.Actor)
+	if err != nil {
+		return nil, 0, err
+	}
+	request.SubjectIDs = subjectIDs
+	quizzes, total, err := c.QuizRepository.Search(tx, request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search quiz")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.WithError(err).Error("Failed to commit transaction")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.QuizListResponse, len(quizzes))
+	for i, quiz := range quizzes {
+		responses[i] = *converter.QuizToListResponse(&quiz)
+	}
+	return responses, total, nil
+}
+
+func (c *QuizUsecase) Update(ctx context.Context, request *model.UpdateQuizRequest) (*model.QuizResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	quiz := new(entity.Quiz)
+	if err := c.QuizRepository.FindById(tx, quiz, request.ID); err != nil {
+		c.Log.Warnf("Failed find quiz by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if err := c.Access.CheckCourse(tx, request.Actor, quiz.CourseID); err != nil {
+		return nil, err
+	}
+	before := audit.Snapshot(converter.QuizToResponse(quiz))
+
+	if request.QuizName != "" {
+		quiz.QuizName = request.QuizName
+	}
+	if request.TimeLimit != 0 {
+		quiz.TimeLimit = request.TimeLimit
+	}
+	if request.CourseID != "" {
+		course := new(entity.Course)
+		if err := c.CourseRepository.FindById(tx, course, request.CourseID); err != nil {
+			c.Log.Warnf("Failed find course by id : %+v", err)
+			return nil, fiber.ErrNotFound
+		}
+		// Moving the quiz needs access to the course it moves to as well
+		if err := c.Access.Check(tx, request.Actor, course.SubjectID); err != nil {
+			return nil, err
+		}
+		quiz.CourseID = course.ID
+	}
+	if request.ShowAnswers != "" {
+		quiz.ShowAnswers = request.ShowAnswers
+	}
+	if request.AnswersRevealAt != nil {
+		quiz.AnswersRevealAt = request.AnswersRevealAt
+	}
+	if request.DrawCount != nil {
+		quiz.DrawCount = *request.DrawCount
+	}
+	if request.BankSubjectID != "" {
+		subject := new(entity.Subject)
+		if err := c.SubjectRepository.FindById(tx, subject, request.BankSubjectID); err != nil {
+			c.Log.Warnf("Failed find subject by id : %+v", err)
+			return nil, fiber.ErrNotFound
+		}
+		if err := c.Access.Check(tx, request.Actor, subject.ID); err != nil {
+			return nil, err
+		}
+		quiz.BankSubjectID = &subject.ID
+	}
+	if request.BankGradeLevel != nil {
+		quiz.BankGradeLevel = request.BankGradeLevel
+	}
+	if request.Shuffle != nil {
+		quiz.Shuffle = *request.Shuffle
+	}
+	if quiz.DrawCount > 0 && quiz.BankSubjectID == nil {
+		c.Log.Warnf("Quiz drawing %d questions has no bank subject", quiz.DrawCount)
+		return nil, fiber.NewError(fiber.StatusBadRequest, "bank_subject_id is required when draw_count is set")
+	}
+	if quiz.ShowAnswers == entity.ShowAnswersAfterRevealDate && quiz.AnswersRevealAt == nil {
+		c.Log.Warnf("Quiz with show_answers %s requires answers_reveal_at", quiz.ShowAnswers)
+		return nil, fiber.NewError(fiber.StatusBadRequest, "answers_reveal_at is required when show_answers is after_reveal_date")
+	}
+
+	if err := c.QuizRepository.Update(tx, quiz); err != nil {
+		c.Log.Warnf("Failed to update quiz: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionUpdate, audit.ResourceQuiz, quiz.ID.String(), before, converter.QuizToResponse(quiz)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.QuizToResponse(quiz), nil
+}
+
+func (c *QuizUsecase) Delete(ctx context.Context, request *model.DeleteQuizRequest) (*model.QuizResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	// Validate request
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	// Find quiz by id
+	quiz := new(entity.Quiz)
+	if err := c.QuizRepository.FindById(tx, quiz, request.ID); err != nil {
+		c.Log.Warnf("Failed find quiz by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if err := c.Access.CheckCourse(tx, request.Actor, quiz.CourseID); err != nil {
+		return nil, err
+	}
+
+	// Delete quiz, questions and options are removed by the cascade
+	if err := c.QuizRepository.Delete(tx, quiz); err != nil {
+		c.Log.Warnf("Failed delete quiz : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionDelete, audit.ResourceQuiz, quiz.ID.String(), converter.QuizToResponse(quiz), nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.QuizToResponse(quiz), nil
+}
+
+func (c *QuizUsecase) CreateQuestion(ctx context.Context, request *model.QuestionRequest) (*model.QuestionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		c.Log.Warnf("Failed to start transaction: %+v", tx.Error)
+		return nil, fiber.ErrInternalServerError
+	}
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	for _, option := range request.Options {
+		if err := c.Validate.Struct(option); err != nil {
+			c.Log.Warnf("Invalid option : %+v", err)
+			return nil, fiber.ErrBadRequest
+		}
+	}
+	if request.Type == "" {
+		request.Type = entity.QuestionTypeSingleChoice
+	}
+	if err := validateQuestion(request); err != nil {
+		c.Log.Warnf("Invalid %s question : %+v", request.Type, err)
+		return nil, err
+	}
+
+	question := &entity.Question{
+		Type: request.Type,
+	}
+	if request.QuizID != "" {
+		quiz := new(entity.Quiz)
+		if err := c.QuizRepository.FindById(tx, quiz, request.QuizID); err != nil {
+			c.Log.Warnf("Failed find quiz by id : %+v", err)
+			return nil, fiber.ErrNotFound
+		}
+		if err := c.Access.CheckCourse(tx, request.Actor, quiz.CourseID); err != nil {
+			return nil, err
+		}
+		question.QuizID = &quiz.ID
+	} else {
+		// Bank questions are tagged by subject and grade level instead of belonging to a quiz
+		if request.SubjectID == "" || request.GradeLevel == 0 {
+			c.Log.Warnf("Bank question without subject or grade level")
+			return nil, fiber.NewError(fiber.StatusBadRequest, "subject_id and grade_level are required for bank questions")
+		}
+		subject := new(entity.Subject)
+		if err := c.SubjectRepository.FindById(tx, subject, request.SubjectID); err != nil {
+			c.Log.Warnf("Failed find subject by id : %+v", err)
+			return nil, fiber.ErrNotFound
+		}
+		if err := c.Access.Check(tx, request.Actor, subject.ID); err != nil {
+			return nil, err
+		}
+		question.SubjectID = &subject.ID
+		question.GradeLevel = &request.GradeLevel
+	}
+	keys, err := c.createQuestion(tx, question, request)
+	if err != nil {
+		c.Log.Warnf("Failed to create question: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionCreate, audit.ResourceQuestion, question.ID.String(), nil, converter.QuestionWithAnswerKeyToResponse(question, keys)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.QuestionWithAnswerKeyToResponse(question, keys), nil
+}
+
+func (c *QuizUsecase) UpdateQuestion(ctx context.Context, request *model.UpdateQuestionRequest) (*model.QuestionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	question := new(entity.Question)
+	if err := c.findQuestion(tx, question, request.ID, request.QuizID, request.Actor); err != nil {
+		return nil, err
+	}
+	keys, err := c.QuizAnswerRepository.FindByQuestionId(tx, question.ID.String())
+	if err != nil {
+		c.Log.Warnf("Failed find quiz answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	before := audit.Snapshot(converter.QuestionWithAnswerKeyToResponse(question, keys))
+
+	if request.Content != nil {
+		if err := validateContent(request.Content); err != nil {
+			c.Log.Warnf("Invalid question content : %+v", err)
+			return nil, err
+		}
+		contentJSON, err := json.Marshal(request.Content)
+		if err != nil {
+			c.Log.Warnf("Failed to marshal content: %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		question.Content = contentJSON
+	}
+	if question.QuizID == nil {
+		if request.SubjectID != "" {
+			subject := new(entity.Subject)
+			if err := c.SubjectRepository.FindById(tx, subject, request.SubjectID); err != nil {
+				c.Log.Warnf("Failed find subject by id : %+v", err)
+				return nil, fiber.ErrNotFound
+			}
+			if err := c.Access.Check(tx, request.Actor, subject.ID); err != nil {
+				return nil, err
+			}
+			question.SubjectID = &subject.ID
+		}
+		if request.GradeLevel != 0 {
+			question.GradeLevel = &request.GradeLevel
+		}
+	}
+	if request.AnswerKey != nil {
+		if err := validateAnswerKey(converter.QuestionType(question), request.AnswerKey); err != nil {
+			c.Log.Warnf("Invalid answer key : %+v", err)
+			return nil, err
+		}
+		answerKeyJSON, err := json.Marshal(request.AnswerKey)
+		if err != nil {
+			c.Log.Warnf("Failed to marshal answer key: %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		question.AnswerKey = answerKeyJSON
+	}
+
+	if err := c.QuestionRepository.Update(tx, question); err != nil {
+		c.Log.Warnf("Failed to update question: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionUpdate, audit.ResourceQuestion, question.ID.String(), before, converter.QuestionWithAnswerKeyToResponse(question, keys)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.QuestionWithAnswerKeyToResponse(question, keys), nil
+}
+
+func (c *QuizUsecase) DeleteQuestion(ctx context.Context, request *model.DeleteQuestionRequest) (*model.QuestionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	// Validate request
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	// Find question by id
+	question := new(entity.Question)
+	if err := c.findQuestion(tx, question, request.ID, request.QuizID, request.Actor); err != nil {
+		return nil, err
+	}
+	keys, err := c.QuizAnswerRepository.FindByQuestionId(tx, question.ID.String())
+	if err != nil {
+		c.Log.Warnf("Failed find quiz answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	// Delete question
+	if err := c.QuestionRepository.Delete(tx, question); err != nil {
+		c.Log.Warnf("Failed delete question : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionDelete, audit.ResourceQuestion, question.ID.String(), converter.QuestionWithAnswerKeyToResponse(question, keys), nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.QuestionToResponse(question), nil
+}
+
+func (c *QuizUsecase) CreateOption(ctx context.Context, request *model.OptionRequest) (*model.OptionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		c.Log.Warnf("Failed to start transaction: %+v", tx.Error)
+		return nil, fiber.ErrInternalServerError
+	}
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	question := new(entity.Question)
+	if err := c.findQuestion(tx, question, request.QuestionID, request.QuizID, request.Actor); err != nil {
+		return nil, err
+	}
+
+	keys, err := c.QuizAnswerRepository.FindByQuestionId(tx, question.ID.String())
+	if err != nil {
+		c.Log.Warnf("Failed find quiz answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := validateOption(question, "", request.Correct, request.Position, request.Match, keys); err != nil {
+		c.Log.Warnf("Invalid option : %+v", err)
+		return nil, err
+	}
+
+	option := &entity.QuestionOption{
+		Option:     request.Option,
+		QuestionID: question.ID,
+	}
+	if question.Type == entity.QuestionTypeOrdering {
+		option.Position = &request.Position
+	}
+	if question.Type == entity.QuestionTypeMatching {
+		option.MatchValue = &request.Match
+	}
+	if err := c.QuestionOptionRepository.Create(tx, option); err != nil {
+		c.Log.Warnf("Failed to create option: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if request.Correct {
+		answer := &entity.QuizAnswer{
+			QuestionID: question.ID,
+			OptionID:   option.ID,
+		}
+		if err := c.QuizAnswerRepository.Create(tx, answer); err != nil {
+			c.Log.Warnf("Failed to create quiz answer: %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionCreate, audit.ResourceOption, option.ID.String(), nil, converter.OptionWithAnswerKeyToResponse(option)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.OptionWithAnswerKeyToResponse(option), nil
+}
+
+func (c *QuizUsecase) UpdateOption(ctx context.Context, request *model.UpdateOptionRequest) (*model.OptionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	question := new(entity.Question)
+	if err := c.findQuestion(tx, question, request.QuestionID, request.QuizID, request.Actor); err != nil {
+		return nil, err
+	}
+	option := new(entity.QuestionOption)
+	if err := c.QuestionOptionRepository.FindByIdAndQuestionId(tx, option, request.ID, request.QuestionID); err != nil {
+		c.Log.Warnf("Failed find option by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	before := audit.Snapshot(converter.OptionWithAnswerKeyToResponse(option))
+
+	if request.Option != "" {
+		option.Option = request.Option
+	}
+	switch question.Type {
+	case entity.QuestionTypeOrdering:
+		if request.Position != 0 {
+			if err := validateOption(question, option.ID.String(), false, request.Position, "", nil); err != nil {
+				c.Log.Warnf("Invalid option : %+v", err)
+				return nil, err
+			}
+			option.Position = &request.Position
+		}
+	case entity.QuestionTypeMatching:
+		if request.Match != "" {
+			option.MatchValue = &request.Match
+		}
+	}
+
+	if err := c.QuestionOptionRepository.Update(tx, option); err != nil {
+		c.Log.Warnf("Failed to update option: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionUpdate, audit.ResourceOption, option.ID.String(), before, converter.OptionWithAnswerKeyToResponse(option)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.OptionWithAnswerKeyToResponse(option), nil
+}
+
+func (c *QuizUsecase) DeleteOption(ctx context.Context, request *model.DeleteOptionRequest) (*model.OptionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	// Validate request
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	// Find option by id within the question of the quiz
+	question := new(entity.Question)
+	if err := c.findQuestion(tx, question, request.QuestionID, request.QuizID, request.Actor); err != nil {
+		return nil, err
+	}
+	option := new(entity.QuestionOption)
+	if err := c.QuestionOptionRepository.FindByIdAndQuestionId(tx, option, request.ID, request.QuestionID); err != nil {
+		c.Log.Warnf("Failed find option by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+
+	// Delete option
+	if err := c.QuestionOptionRepository.Delete(tx, option); err != nil {
+		c.Log.Warnf("Failed delete option : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionDelete, audit.ResourceOption, option.ID.String(), converter.OptionWithAnswerKeyToResponse(option), nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.OptionToResponse(option), nil
+}
+
+func (c *QuizUsecase) SetAnswerKey(ctx context.Context, request *model.AnswerKeyRequest) (*model.QuestionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	question := new(entity.Question)
+	if err := c.findQuestion(tx, question, request.QuestionID, request.QuizID, request.Actor); err != nil {
+		return nil, err
+	}
+	if !question.IsChoice() {
+		c.Log.Warnf("Question %s of type %s has no option answer key", question.ID, question.Type)
+		return nil, fiber.NewError(fiber.StatusBadRequest, "only choice questions have an option answer key")
+	}
+
+	answers := make([]*entity.QuizAnswer, 0, len(request.OptionIDs))
+	picked := make(map[string]bool, len(request.OptionIDs))
+	for _, optionID := range request.OptionIDs {
+		option := new(entity.QuestionOption)
+		if err := c.QuestionOptionRepository.FindByIdAndQuestionId(tx, option, optionID, question.ID.String()); err != nil {
+			c.Log.Warnf("Failed find option by id : %+v", err)
+			return nil, fiber.ErrNotFound
+		}
+		if picked[option.ID.String()] {
+			continue
+		}
+		picked[option.ID.String()] = true
+		answers = append(answers, &entity.QuizAnswer{
+			QuestionID: question.ID,
+			OptionID:   option.ID,
+		})
+	}
+
+	if question.Type != entity.QuestionTypeMultipleSelect && len(answers) != 1 {
+		c.Log.Warnf("Question %s of type %s needs exactly one correct option", question.ID, converter.QuestionType(question))
+		return nil, fiber.NewError(fiber.StatusBadRequest, "exactly one correct option is required")
+	}
+
+	previous, err := c.QuizAnswerRepository.FindByQuestionId(tx, question.ID.String())
+	if err != nil {
+		c.Log.Warnf("Failed find quiz answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	// The answer key is replaced as a whole
+	if err := c.QuizAnswerRepository.DeleteByQuestionId(tx, question.ID.String()); err != nil {
+		c.Log.Warnf("Failed to clear quiz answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.QuizAnswerRepository.CreateBatch(tx, answers); err != nil {
+		c.Log.Warnf("Failed to bulk create quiz answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	keys := make([]entity.QuizAnswer, len(answers))
+	for i, answer := range answers {
+		keys[i] = *answer
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionUpdate, audit.ResourceQuestion, question.ID.String(), converter.QuestionWithAnswerKeyToResponse(question, previous), converter.QuestionWithAnswerKeyToResponse(question, keys)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return converter.QuestionWithAnswerKeyToResponse(question, keys), nil
+}
+
+func (c *QuizUsecase) GetBankQuestion(ctx context.Context, request *model.GetBankQuestionRequest) (*model.QuestionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	question := new(entity.Question)
+	if err := c.QuestionRepository.FindBankQuestionById(tx, question, request.ID); err != nil {
+		c.Log.Warnf("Failed find bank question by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if err := c.Access.Check(tx, request.Actor, bankSubject(question)); err != nil {
+		return nil, err
+	}
+	keys, err := c.QuizAnswerRepository.FindByQuestionId(tx, question.ID.String())
+	if err != nil {
+		c.Log.Warnf("Failed find quiz answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.QuestionWithAnswerKeyToResponse(question, keys), nil
+}
+
+func (c *QuizUsecase) SearchBank(ctx context.Context, request *model.SearchQuestionBankRequest) ([]model.QuestionResponse, int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.WithError(err).Warnf("Invalid request body")
+		return nil, 0, fiber.ErrBadRequest
+	}
+	subjectIDs, err := c.Access.Subjects(tx, request.Actor)
+	if err != nil {
+		return nil, 0, err
+	}
+	request.SubjectIDs = subjectIDs
+	questions, total, err := c.QuestionRepository.SearchBank(tx, request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search question bank")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+	ids := make([]uuid.UUID, len(questions))
+	for i, question := range questions {
+		ids[i] = question.ID
+	}
+	keys, err := c.QuizAnswerRepository.FindByQuestionIds(tx, ids)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed find quiz answers")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.WithError(err).Error("Failed to commit transaction")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.QuestionResponse, len(questions))
+	for i, question := range questions {
+		responses[i] = *converter.QuestionWithAnswerKeyToResponse(&question, keys)
+	}
+	return responses, total, nil
+}
+
+// Import creates the quiz with every question of a GIFT or Moodle XML file in one transaction.
+// Problems are collected per question and returned together as an *ImportError.
+func (c *QuizUsecase) Import(ctx context.Context, request *model.ImportQuizRequest) (*model.QuizResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		c.Log.Warnf("Failed to start transaction: %+v", tx.Error)
+		return nil, fiber.ErrInternalServerError
+	}
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	course := new(entity.Course)
+	if err := c.CourseRepository.FindById(tx, course, request.CourseID); err != nil {
+		c.Log.Warnf("Failed find course by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if err := c.Access.Check(tx, request.Actor, course.SubjectID); err != nil {
+		return nil, err
+	}
+
+	importErr := new(ImportError)
+	parsed, err := quizformat.Parse(request.Format, bytes.NewReader(request.Content))
+	var parseErr *quizformat.ParseError
+	if errors.As(err, &parseErr) {
+		for _, lineError := range parseErr.Errors {
+			importErr.Lines = append(importErr.Lines, model.ImportLineError{Line: lineError.Line, Message: lineError.Message})
+		}
+	} else if err != nil {
+		c.Log.Warnf("Failed to parse %s file : %+v", request.Format, err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	requests := make([]*model.QuestionRequest, len(parsed.Questions))
+	for i := range parsed.Questions {
+		requests[i] = importedQuestionRequest(&parsed.Questions[i])
+		for _, option := range requests[i].Options {
+			if err := c.Validate.Struct(option); err != nil {
+				importErr.Lines = append(importErr.Lines, model.ImportLineError{Line: parsed.Questions[i].Line, Message: "every option needs a text"})
+				break
+			}
+		}
+		if err := validateQuestion(requests[i]); err != nil {
+			importErr.Lines = append(importErr.Lines, model.ImportLineError{Line: parsed.Questions[i].Line, Message: err.Error()})
+		}
+	}
+	if len(importErr.Lines) > 0 {
+		sort.SliceStable(importErr.Lines, func(i, j int) bool {
+			return importErr.Lines[i].Line < importErr.Lines[j].Line
+		})
+		c.Log.Warnf("Failed to import quiz : %+v", importErr)
+		return nil, importErr
+	}
+
+	quiz := &entity.Quiz{
+		QuizName:    request.QuizName,
+		TimeLimit:   request.TimeLimit,
+		ShowAnswers: entity.ShowAnswersAfterSubmit,
+		CourseID:    course.ID,
+	}
+	if quiz.QuizName == "" {
+		quiz.QuizName = parsed.Name
+	}
+	if quiz.QuizName == "" {
+		c.Log.Warnf("Imported quiz has no name")
+		return nil, fiber.NewError(fiber.StatusBadRequest, "quiz_name is required when the file has no category")
+	}
+	if err := c.QuizRepository.Create(tx, quiz); err != nil {
+		c.Log.Warnf("Failed to create quiz: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	for _, questionRequest := range requests {
+		question := &entity.Question{
+			Type:   questionRequest.Type,
+			QuizID: &quiz.ID,
+		}
+		if _, err := c.createQuestion(tx, question, questionRequest); err != nil {
+			c.Log.Warnf("Failed to create question: %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		quiz.Questions = append(quiz.Questions, *question)
+	}
+	keys, err := c.QuizAnswerRepository.FindByQuizId(tx, quiz.ID.String())
+	if err != nil {
+		c.Log.Warnf("Failed find quiz answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionImport, audit.ResourceQuiz, quiz.ID.String(), nil, converter.QuizWithAnswerKeyToResponse(quiz, keys)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return converter.QuizWithAnswerKeyToResponse(quiz, keys), nil
+}
+
+// Export writes the quiz with its answer key as GIFT or Moodle XML.
+func (c *QuizUsecase) Export(ctx context.Context, request *model.ExportQuizRequest) (*model.QuizExportResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	quiz := new(entity.Quiz)
+	if err := c.QuizRepository.FindByIdWithQuestions(tx, quiz, request.ID); err != nil {
+		c.Log.Warnf("Failed find quiz by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if err := c.Access.CheckCourse(tx, request.Actor, quiz.CourseID); err != nil {
+		return nil, err
+	}
+	keys, err := c.QuizAnswerRepository.FindByQuizId(tx, request.ID)
+	if err != nil {
+		c.Log.Warnf("Failed find quiz answers : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	exported := &quizformat.Quiz{Name: quiz.QuizName}
+	for i, question := range quiz.Questions {
+		exported.Questions = append(exported.Questions, exportedQuestion(&question, keys, i+1))
+	}
+	buffer := new(bytes.Buffer)
+	if err := quizformat.Write(request.Format, buffer, exported); err != nil {
+		var writeErr *quizformat.WriteError
+		if errors.As(err, &writeErr) {
+			c.Log.Warnf("Quiz cannot be written as %s : %+v", request.Format, err)
+			return nil, fiber.NewError(fiber.StatusUnprocessableEntity, writeErr.Error())
+		}
+		c.Log.Warnf("Failed to write %s file : %+v", request.Format, err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	response := &model.QuizExportResponse{
+		FileName:    quiz.ID.String() + ".gift",
+		ContentType: "text/plain; charset=utf-8",
+		Content:     buffer.Bytes(),
+	}
+	if request.Format == quizformat.FormatMoodleXML {
+		response.FileName = quiz.ID.String() + ".xml"
+		response.ContentType = "application/xml; charset=utf-8"
+	}
+	return response, nil
+}
+
+// findQuestion looks the question up in the quiz, an empty quizID addresses the question bank,
+// and fails unless the actor may manage it.
+func (c *QuizUsecase) findQuestion(tx *gorm.DB, question *entity.Question, id string, quizID string, actor *model.Auth) error {
+	if quizID == "" {
+		if err := c.QuestionRepository.FindBankQuestionById(tx, question, id); err != nil {
+			c.Log.Warnf("Failed find question by id : %+v", err)
+			return fiber.ErrNotFound
+		}
+		return c.Access.Check(tx, actor, bankSubject(question))
+	}
+
+	quiz := new(entity.Quiz)
+	if err := c.QuizRepository.FindById(tx, quiz, quizID); err != nil {
+		c.Log.Warnf("Failed find quiz by id : %+v", err)
+		return fiber.ErrNotFound
+	}
+	if err := c.Access.CheckCourse(tx, actor, quiz.CourseID); err != nil {
+		return err
+	}
+	if err := c.QuestionRepository.FindByIdAndQuizId(tx, question, id, quizID); err != nil {
+		c.Log.Warnf("Failed find question by id : %+v", err)
+		return fiber.ErrNotFound
+	}
+	return nil
+}
+
+// bankSubject is the subject of a bank question, the nil id when it has none.
+func bankSubject(question *entity.Question) uuid.UUID {
+	if question.SubjectID == nil {
+		return uuid.Nil
+	}
+	return *question.SubjectID
+}
+
+// createQuestion stores a validated question together with its options and the quiz_answers rows of the correct options.
+func (c *QuizUsecase) createQuestion(tx *gorm.DB, question *entity.Question, request *model.QuestionRequest) ([]entity.QuizAnswer, error) {
+	var err error
+	question.Content, err = json.Marshal(request.Content)
+	if err != nil {
+		return nil, err
+	}
+	if request.AnswerKey != nil {
+		question.AnswerKey, err = json.Marshal(request.AnswerKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := c.QuestionRepository.Create(tx, question); err != nil {
+		return nil, err
+	}
+	if len(request.Options) == 0 {
+		return nil, nil
+	}
+
+	options := make([]*entity.QuestionOption, len(request.Options))
+	for i, option := range request.Options {
+		options[i] = &entity.QuestionOption{
+			Option:     option.Option,
+			QuestionID: question.ID,
+		}
+		if question.Type == entity.QuestionTypeOrdering {
+			options[i].Position = &option.Position
+		}
+		if question.Type == entity.QuestionTypeMatching {
+			options[i].MatchValue = &option.Match
+		}
+	}
+	if err := c.QuestionOptionRepository.CreateBatch(tx, options); err != nil {
+		return nil, err
+	}
+	question.Options = make([]entity.QuestionOption, len(options))
+	for i, option := range options {
+		question.Options[i] = *option
+	}
+
+	var answers []*entity.QuizAnswer
+	for i, option := range request.Options {
+		if option.Correct && question.IsChoice() {
+			answers = append(answers, &entity.QuizAnswer{
+				QuestionID: question.ID,
+				OptionID:   options[i].ID,
+			})
+		}
+	}
+	if len(answers) == 0 {
+		return nil, nil
+	}
+	if err := c.QuizAnswerRepository.CreateBatch(tx, answers); err != nil {
+		return nil, err
+	}
+	keys := make([]entity.QuizAnswer, len(answers))
+	for i, answer := range answers {
+		keys[i] = *answer
+	}
+	return keys, nil
+}
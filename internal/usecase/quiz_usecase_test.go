@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
+	"fp-designpattern/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func newTestQuizUsecase(db *gorm.DB) *QuizUsecase {
+	log := newTestLogger()
+	courseRepository := repository.NewCourseRepository(log)
+	access := NewSubjectAccess(log, rbac.NewPolicy(rbac.DefaultRoles(), []string{rbac.RoleAdmin}), repository.NewTeacherSubjectRepository(log), courseRepository)
+	return NewQuizUsecase(db, log, validator.New(), repository.NewQuizRepository(log), repository.NewQuestionRepository(log),
+		repository.NewQuestionOptionRepository(log), repository.NewQuizAnswerRepository(log), courseRepository,
+		repository.NewSubjectRepository(log), access, NewAuditor(log, repository.NewAuditEventRepository(log)))
+}
+
+func TestCreateQuiz(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestQuizUsecase(db)
+	admin := newTestUser(t, db, rbac.RoleAdmin)
+	actor := &model.Auth{ID: admin.ID.String(), Role: admin.Role}
+	teacher := newTestUser(t, db, rbac.RoleTeacher)
+	subject, course := newTestSubject(t, db)
+	revealAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		request model.QuizRequest
+		want    int
+	}{
+		{"quiz", model.QuizRequest{QuizName: "test", TimeLimit: 10, CourseID: course.ID.String()}, 0},
+		{"quiz drawn from the bank", model.QuizRequest{QuizName: "test", TimeLimit: 10, CourseID: course.ID.String(), DrawCount: 5, BankSubjectID: subject.ID.String()}, 0},
+		{"answers revealed on a date", model.QuizRequest{QuizName: "test", TimeLimit: 10, CourseID: course.ID.String(), ShowAnswers: entity.ShowAnswersAfterRevealDate, AnswersRevealAt: &revealAt}, 0},
+		{"no name", model.QuizRequest{TimeLimit: 10, CourseID: course.ID.String()}, fiber.StatusBadRequest},
+		{"no time limit", model.QuizRequest{QuizName: "test", CourseID: course.ID.String()}, fiber.StatusBadRequest},
+		{"unknown show answers", model.QuizRequest{QuizName: "test", TimeLimit: 10, CourseID: course.ID.String(), ShowAnswers: "always"}, fiber.StatusBadRequest},
+		{"unknown course", model.QuizRequest{QuizName: "test", TimeLimit: 10, CourseID: uuid.NewString()}, fiber.StatusNotFound},
+		{"unknown bank subject", model.QuizRequest{QuizName: "test", TimeLimit: 10, CourseID: course.ID.String(), DrawCount: 5, BankSubjectID: uuid.NewString()}, fiber.StatusNotFound},
+		{"draw count without a bank subject", model.QuizRequest{QuizName: "test", TimeLimit: 10, CourseID: course.ID.String(), DrawCount: 5}, fiber.StatusBadRequest},
+		{"reveal date missing", model.QuizRequest{QuizName: "test", TimeLimit: 10, CourseID: course.ID.String(), ShowAnswers: entity.ShowAnswersAfterRevealDate}, fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		tt.request.Actor = actor
+		response, err := c.Create(context.Background(), &tt.request)
+		if got := statusOf(err); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if response.QuizName != tt.request.QuizName || response.CourseID != course.ID || response.DrawCount != tt.request.DrawCount {
+			t.Errorf("%s: created %+v", tt.name, response)
+		}
+		if tt.request.ShowAnswers == "" && response.ShowAnswers != entity.ShowAnswersAfterSubmit {
+			t.Errorf("%s: show answers %q, want %q by default", tt.name, response.ShowAnswers, entity.ShowAnswersAfterSubmit)
+		}
+	}
+
+	// A teacher only creates quizzes in the courses of their subjects
+	request := &model.QuizRequest{QuizName: "test", TimeLimit: 10, CourseID: course.ID.String(), Actor: &model.Auth{ID: teacher.ID.String(), Role: teacher.Role}}
+	if _, err := c.Create(context.Background(), request); statusOf(err) != fiber.StatusForbidden {
+		t.Errorf("teacher of another subject: %v, want 403", err)
+	}
+}
+
+func TestQuizReadUpdateDelete(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestQuizUsecase(db)
+	ctx := context.Background()
+	admin := newTestUser(t, db, rbac.RoleAdmin)
+	actor := &model.Auth{ID: admin.ID.String(), Role: admin.Role}
+	subject, course := newTestSubject(t, db)
+	_, otherCourse := newTestSubject(t, db)
+
+	created, err := c.Create(ctx, &model.QuizRequest{QuizName: "test", TimeLimit: 10, CourseID: course.ID.String(), Actor: actor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.ID.String()
+	got, err := c.Get(ctx, &model.GetQuizRequest{ID: id, Actor: actor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != created.ID || got.QuizName != "test" {
+		t.Errorf("got %+v, want %+v", got, created)
+	}
+	listed, total, err := c.Search(ctx, &model.SearchQuizRequest{CourseID: course.ID.String(), Page: 1, Size: 10, Actor: actor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(listed) != 1 || listed[0].ID != created.ID {
+		t.Errorf("listed %d of %d, want the quiz", len(listed), total)
+	}
+
+	drawCount, noDraw, shuffle := 3, 0, true
+	tests := []struct {
+		name    string
+		request model.UpdateQuizRequest
+		want    int
+		check   func(*model.QuizResponse) bool
+	}{
+		{"rename", model.UpdateQuizRequest{QuizName: "renamed"}, 0,
+			func(r *model.QuizResponse) bool { return r.QuizName == "renamed" && r.TimeLimit == 10 }},
+		{"time limit", model.UpdateQuizRequest{TimeLimit: 20}, 0,
+			func(r *model.QuizResponse) bool { return r.QuizName == "renamed" && r.TimeLimit == 20 }},
+		{"move to another course", model.UpdateQuizRequest{CourseID: otherCourse.ID.String()}, 0,
+			func(r *model.QuizResponse) bool { return r.CourseID == otherCourse.ID }},
+		{"draw from the bank", model.UpdateQuizRequest{DrawCount: &drawCount, BankSubjectID: subject.ID.String(), Shuffle: &shuffle}, 0,
+			func(r *model.QuizResponse) bool {
+				return r.DrawCount == 3 && r.BankSubjectID != nil && *r.BankSubjectID == subject.ID && r.Shuffle
+			}},
+		{"stop drawing", model.UpdateQuizRequest{DrawCount: &noDraw}, 0,
+			func(r *model.QuizResponse) bool { return r.DrawCount == 0 }},
+		{"reveal date missing", model.UpdateQuizRequest{ShowAnswers: entity.ShowAnswersAfterRevealDate}, fiber.StatusBadRequest, nil},
+		{"unknown course", model.UpdateQuizRequest{CourseID: uuid.NewString()}, fiber.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		tt.request.ID = id
+		tt.request.Actor = actor
+		response, err := c.Update(ctx, &tt.request)
+		if got := statusOf(err); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+			continue
+		}
+		if tt.check != nil && !tt.check(response) {
+			t.Errorf("%s: updated %+v", tt.name, response)
+		}
+	}
+	if _, err := c.Update(ctx, &model.UpdateQuizRequest{ID: uuid.NewString(), QuizName: "test", Actor: actor}); statusOf(err) != fiber.StatusNotFound {
+		t.Errorf("updating an unknown quiz: %v, want 404", err)
+	}
+
+	if _, err := c.Delete(ctx, &model.DeleteQuizRequest{ID: id, Actor: actor}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, &model.GetQuizRequest{ID: id, Actor: actor}); statusOf(err) != fiber.StatusNotFound {
+		t.Errorf("getting a deleted quiz: %v, want 404", err)
+	}
+	if _, err := c.Delete(ctx, &model.DeleteQuizRequest{ID: id, Actor: actor}); statusOf(err) != fiber.StatusNotFound {
+		t.Errorf("deleting a deleted quiz: %v, want 404", err)
+	}
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
+	"fp-designpattern/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var ErrSubjectForbidden = fiber.NewError(fiber.StatusForbidden, "You do not have access to this subject")
+
+// SubjectAccess is the resource-level check behind the permission middleware: users of a scoped
+// role, such as teachers, only act on the courses, quizzes and bank questions of their subjects.
+type SubjectAccess struct {
+	Log                      *logrus.Logger
+	Policy                   *rbac.Policy
+	TeacherSubjectRepository *repository.TeacherSubjectRepository
+	CourseRepository         *repository.CourseRepository
+}
+
+func NewSubjectAccess(log *logrus.Logger, policy *rbac.Policy, teacherSubjectRepository *repository.TeacherSubjectRepository, courseRepository *repository.CourseRepository) *SubjectAccess {
+	return &SubjectAccess{
+		Log:                      log,
+		Policy:                   policy,
+		TeacherSubjectRepository: teacherSubjectRepository,
+		CourseRepository:         courseRepository,
+	}
+}
+
+// Check fails unless the actor may act on the subject. A nil actor is the application itself.
+func (a *SubjectAccess) Check(tx *gorm.DB, actor *model.Auth, subjectID uuid.UUID) error {
+	if actor == nil || !a.Policy.Scoped(actor.Role) {
+		return nil
+	}
+	total, err := a.TeacherSubjectRepository.CountByUserIdAndSubjectId(tx, actor.ID, subjectID.String())
+	if err != nil {
+		a.Log.Warnf("Failed count teacher subjects : %+v", err)
+		return fiber.ErrInternalServerError
+	}
+	if total == 0 {
+		a.Log.Warnf("User %s has no access to subject %s", actor.ID, subjectID)
+		return ErrSubjectForbidden
+	}
+	return nil
+}
+
+// CheckCourse fails unless the actor may act on the subject of the course.
+func (a *SubjectAccess) CheckCourse(tx *gorm.DB, actor *model.Auth, courseID uuid.UUID) error {
+	if actor == nil || !a.Policy.Scoped(actor.Role) {
+		return nil
+	}
+	course := new(entity.Course)
+	if err := a.CourseRepository.FindById(tx, course, courseID.String()); err != nil {
+		a.Log.Warnf("Failed find course by id : %+v", err)
+		return fiber.ErrNotFound
+	}
+	return a.Check(tx, actor, course.SubjectID)
+}
+
+// Subjects returns the ids of the subjects a scoped actor is limited to, and nil for an actor
+// that is not limited. Searches filter on it, an empty list matches nothing.
+func (a *SubjectAccess) Subjects(tx *gorm.DB, actor *model.Auth) ([]string, error) {
+	if actor == nil || !a.Policy.Scoped(actor.Role) {
+		return nil, nil
+	}
+	subjectIDs, err := a.TeacherSubjectRepository.FindSubjectIdsByUserId(tx, actor.ID)
+	if err != nil {
+		a.Log.Warnf("Failed find teacher subjects : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return subjectIDs, nil
+}
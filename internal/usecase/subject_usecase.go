@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"fp-designpattern/internal/audit"
 	"fp-designpattern/internal/entity"
 	"fp-designpattern/internal/model"
 	"fp-designpattern/internal/model/converter"
@@ -18,14 +19,18 @@ type SubjectUsecase struct {
 	Log               *logrus.Logger
 	Validate          *validator.Validate
 	SubjectRepository *repository.SubjectRepository
+	CourseRepository  *repository.CourseRepository
+	Auditor           *Auditor
 }
 
-func NewSubjectUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, subjectRepository *repository.SubjectRepository) *SubjectUsecase {
+func NewSubjectUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, subjectRepository *repository.SubjectRepository, courseRepository *repository.CourseRepository, auditor *Auditor) *SubjectUsecase {
 	return &SubjectUsecase{
 		DB:                db,
 		Log:               log,
 		Validate:          validate,
 		SubjectRepository: subjectRepository,
+		CourseRepository:  courseRepository,
+		Auditor:           auditor,
 	}
 }
 
@@ -48,6 +53,9 @@ func (c *SubjectUsecase) Create(ctx context.Context, request *model.SubjectReque
 		c.Log.Warnf("Failed to create subject: %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionCreate, audit.ResourceSubject, subject.ID.String(), nil, converter.SubjectToResponse(subject)); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit().Error; err != nil {
 		c.Log.Warnf("Failed to commit transaction: %+v", err)
 		return nil, fiber.ErrInternalServerError
@@ -89,6 +97,7 @@ func (c *SubjectUsecase) Update(ctx context.Context, request *model.UpdateSubjec
 		c.Log.Warnf("Failed find subject by id : %+v", err)
 		return nil, fiber.ErrNotFound
 	}
+	before := audit.Snapshot(converter.SubjectToResponse(subject))
 
 	if request.SubjectName != "" {
 		subject.SubjectName = request.SubjectName
@@ -98,6 +107,9 @@ func (c *SubjectUsecase) Update(ctx context.Context, request *model.UpdateSubjec
 		c.Log.Warnf("Failed to update subject: %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionUpdate, audit.ResourceSubject, subject.ID.String(), before, converter.SubjectToResponse(subject)); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit().Error; err != nil {
 		c.Log.Warnf("Failed to commit transaction: %+v", err)
 		return nil, fiber.ErrInternalServerError
@@ -146,11 +158,18 @@ func (c *SubjectUsecase) Delete(ctx context.Context, request *model.DeleteSubjec
 		return nil, fiber.ErrNotFound
 	}
 
-	// Delete subject
+	// Move subject to the trash, its courses go along and come back with it
 	if err := c.SubjectRepository.Delete(tx, subject); err != nil {
 		c.Log.Warnf("Failed delete subject : %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
+	if err := c.CourseRepository.DeleteBySubjectId(tx, subject.ID.String()); err != nil {
+		c.Log.Warnf("Failed delete courses of subject : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionDelete, audit.ResourceSubject, subject.ID.String(), converter.SubjectToResponse(subject), nil); err != nil {
+		return nil, err
+	}
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
@@ -160,3 +179,64 @@ func (c *SubjectUsecase) Delete(ctx context.Context, request *model.DeleteSubjec
 
 	return converter.SubjectToResponse(subject), nil
 }
+
+// Trash lists the deleted subjects that are not purged yet.
+func (c *SubjectUsecase) Trash(ctx context.Context, request *model.SearchTrashRequest) ([]model.SubjectResponse, int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.WithError(err).Warnf("Invalid request body")
+		return nil, 0, fiber.ErrBadRequest
+	}
+	subjects, total, err := c.SubjectRepository.SearchDeleted(tx, request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search deleted subjects")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.WithError(err).Error("Failed to commit transaction")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.SubjectResponse, len(subjects))
+	for i, subject := range subjects {
+		responses[i] = *converter.SubjectToResponse(&subject)
+	}
+	return responses, total, nil
+}
+
+// Restore takes a deleted subject out of the trash with the courses deleted along with it. Courses
+// deleted before the subject stay in the trash.
+func (c *SubjectUsecase) Restore(ctx context.Context, request *model.RestoreSubjectRequest) (*model.SubjectResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	subject := new(entity.Subject)
+	if err := c.SubjectRepository.FindDeletedById(tx, subject, request.ID); err != nil {
+		c.Log.Warnf("Failed find deleted subject by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	before := audit.Snapshot(converter.SubjectToResponse(subject))
+	if _, err := c.CourseRepository.RestoreBySubjectId(tx, subject.ID.String(), subject.DeletedAt.Time); err != nil {
+		c.Log.Warnf("Failed restore courses of subject : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.SubjectRepository.Restore(tx, subject); err != nil {
+		c.Log.Warnf("Failed restore subject : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	subject.DeletedAt = gorm.DeletedAt{}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionRestore, audit.ResourceSubject, subject.ID.String(), before, converter.SubjectToResponse(subject)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed to commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return converter.SubjectToResponse(subject), nil
+}
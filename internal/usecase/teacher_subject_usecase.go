@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"fp-designpattern/internal/audit"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/rbac"
+	"fp-designpattern/internal/repository"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var ErrRoleNotScoped = fiber.NewError(fiber.StatusConflict, "the role of this user is not limited to subjects")
+
+// TeacherSubjectUsecase assigns subjects to the users of scoped roles, such as teachers.
+type TeacherSubjectUsecase struct {
+	DB                       *gorm.DB
+	Log                      *logrus.Logger
+	Validate                 *validator.Validate
+	TeacherSubjectRepository *repository.TeacherSubjectRepository
+	SubjectRepository        *repository.SubjectRepository
+	UserRepository           *repository.UserRepository
+	Policy                   *rbac.Policy
+	Auditor                  *Auditor
+}
+
+func NewTeacherSubjectUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, teacherSubjectRepository *repository.TeacherSubjectRepository,
+	subjectRepository *repository.SubjectRepository, userRepository *repository.UserRepository, policy *rbac.Policy, auditor *Auditor) *TeacherSubjectUsecase {
+	return &TeacherSubjectUsecase{
+		DB:                       db,
+		Log:                      log,
+		Validate:                 validate,
+		TeacherSubjectRepository: teacherSubjectRepository,
+		SubjectRepository:        subjectRepository,
+		UserRepository:           userRepository,
+		Policy:                   policy,
+		Auditor:                  auditor,
+	}
+}
+
+func (c *TeacherSubjectUsecase) Assign(ctx context.Context, request *model.TeacherSubjectRequest) (*model.TeacherSubjectResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if !c.Policy.Scoped(user.Role) {
+		c.Log.Warnf("Subject assigned to user %s of unscoped role %s", user.ID, user.Role)
+		return nil, ErrRoleNotScoped
+	}
+	subject := new(entity.Subject)
+	if err := c.SubjectRepository.FindById(tx, subject, request.SubjectID); err != nil {
+		c.Log.Warnf("Failed find subject by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+
+	total, err := c.TeacherSubjectRepository.CountByUserIdAndSubjectId(tx, request.UserID, request.SubjectID)
+	if err != nil {
+		c.Log.Warnf("Failed count teacher subjects : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if total > 0 {
+		c.Log.Warnf("Subject %s already assigned to user %s", subject.ID, user.ID)
+		return nil, fiber.ErrConflict
+	}
+	assignment := &entity.TeacherSubject{
+		UserID:    user.ID,
+		SubjectID: subject.ID,
+		Subject:   *subject,
+	}
+	if err := c.TeacherSubjectRepository.Create(tx.Omit("Subject"), assignment); err != nil {
+		c.Log.Warnf("Failed create teacher subject : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	// Assignments are recorded against the user, whose access they change
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionCreate, audit.ResourceTeacherSubject, user.ID.String(), nil, converter.TeacherSubjectToResponse(assignment)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return converter.TeacherSubjectToResponse(assignment), nil
+}
+
+func (c *TeacherSubjectUsecase) List(ctx context.Context, request *model.ListTeacherSubjectRequest) ([]model.TeacherSubjectResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	assignments, err := c.TeacherSubjectRepository.FindByUserId(tx, request.UserID)
+	if err != nil {
+		c.Log.Warnf("Failed find teacher subjects : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.TeacherSubjectResponse, len(assignments))
+	for i, assignment := range assignments {
+		responses[i] = *converter.TeacherSubjectToResponse(&assignment)
+	}
+	return responses, nil
+}
+
+func (c *TeacherSubjectUsecase) Unassign(ctx context.Context, request *model.DeleteTeacherSubjectRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	total, err := c.TeacherSubjectRepository.DeleteByUserIdAndSubjectId(tx, request.UserID, request.SubjectID)
+	if err != nil {
+		c.Log.Warnf("Failed delete teacher subject : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	if total == 0 {
+		c.Log.Warnf("Subject %s is not assigned to user %s", request.SubjectID, request.UserID)
+		return false, fiber.ErrNotFound
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionDelete, audit.ResourceTeacherSubject, request.UserID, map[string]string{"subject_id": request.SubjectID}, nil); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"fp-designpattern/internal/repository"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// TrashUsecase purges the users, subjects and courses that stayed in the trash past the retention
+// period. Purging deletes for good, with everything the database cascades to.
+type TrashUsecase struct {
+	DB                *gorm.DB
+	Log               *logrus.Logger
+	UserRepository    *repository.UserRepository
+	SubjectRepository *repository.SubjectRepository
+	CourseRepository  *repository.CourseRepository
+	// MediaAssetRepository drops the references of purged courses and users.
+	MediaAssetRepository *repository.MediaAssetRepository
+	Retention            time.Duration
+}
+
+func NewTrashUsecase(db *gorm.DB, log *logrus.Logger, userRepository *repository.UserRepository, subjectRepository *repository.SubjectRepository,
+	courseRepository *repository.CourseRepository, mediaAssetRepository *repository.MediaAssetRepository, retention time.Duration) *TrashUsecase {
+	return &TrashUsecase{
+		DB:                   db,
+		Log:                  log,
+		UserRepository:       userRepository,
+		SubjectRepository:    subjectRepository,
+		CourseRepository:     courseRepository,
+		MediaAssetRepository: mediaAssetRepository,
+		Retention:            retention,
+	}
+}
+
+// Purge deletes what was put in the trash before the retention period and returns how much.
+func (c *TrashUsecase) Purge(ctx context.Context, now time.Time) (int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	before := now.Add(-c.Retention)
+	// Courses before their subjects, the cascade would take them without counting
+	courses, err := c.CourseRepository.PurgeDeletedBefore(tx, before)
+	if err != nil {
+		return 0, err
+	}
+	subjects, err := c.SubjectRepository.PurgeDeletedBefore(tx, before)
+	if err != nil {
+		return 0, err
+	}
+	users, err := c.UserRepository.PurgeDeletedBefore(tx, before)
+	if err != nil {
+		return 0, err
+	}
+	if courses+subjects+users > 0 {
+		if err := c.MediaAssetRepository.RecountAllReferences(tx); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return 0, err
+	}
+	return courses + subjects + users, nil
+}
+
+// RunPurge purges the trash on every tick until ctx is cancelled.
+func (c *TrashUsecase) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			total, err := c.Purge(ctx, time.Now())
+			if err != nil {
+				c.Log.Warnf("Failed to purge trash : %+v", err)
+				continue
+			}
+			if total > 0 {
+				c.Log.Infof("Purged %d users, subjects and courses from the trash", total)
+			}
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
+	"fp-designpattern/internal/repository"
+	"fp-designpattern/internal/token"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// newTestSubject stores a subject with a course, both removed after the test.
+func newTestSubject(t *testing.T, db *gorm.DB) (*entity.Subject, *entity.Course) {
+	t.Helper()
+	subject := &entity.Subject{ID: uuid.New(), SubjectName: "test"}
+	if err := db.Create(subject).Error; err != nil {
+		t.Fatal(err)
+	}
+	course := &entity.Course{ID: uuid.New(), CourseName: "test", Content: datatypes.JSON("[]"), GradeLevel: 10, SubjectID: subject.ID}
+	if err := db.Omit("Subject").Create(course).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&entity.Course{}, "id = ?", course.ID)
+		db.Unscoped().Delete(&entity.Subject{}, "id = ?", subject.ID)
+	})
+	return subject, course
+}
+
+// trash puts the row in the trash as if it was deleted at the time.
+func trash(t *testing.T, db *gorm.DB, model any, id uuid.UUID, at time.Time) {
+	t.Helper()
+	if err := db.Unscoped().Model(model).Where("id = ?", id).Update("deleted_at", at).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exists reports whether the row is still stored, in the trash or not.
+func exists(t *testing.T, db *gorm.DB, model any, id uuid.UUID) bool {
+	t.Helper()
+	var total int64
+	if err := db.Unscoped().Model(model).Where("id = ?", id).Count(&total).Error; err != nil {
+		t.Fatal(err)
+	}
+	return total > 0
+}
+
+func TestTrashPurge(t *testing.T) {
+	db := newTestDB(t)
+	log := newTestLogger()
+	c := NewTrashUsecase(db, log, repository.NewUserRepository(log), repository.NewSubjectRepository(log),
+		repository.NewCourseRepository(log), repository.NewMediaAssetRepository(log), time.Hour)
+	now := time.Now()
+
+	expiredUser := newTestUser(t, db, rbac.RoleUser)
+	trash(t, db, &entity.User{}, expiredUser.ID, now.Add(-2*time.Hour))
+	recentUser := newTestUser(t, db, rbac.RoleUser)
+	trash(t, db, &entity.User{}, recentUser.ID, now.Add(-time.Minute))
+	activeUser := newTestUser(t, db, rbac.RoleUser)
+	expiredSubject, expiredCourse := newTestSubject(t, db)
+	trash(t, db, &entity.Course{}, expiredCourse.ID, now.Add(-2*time.Hour))
+	trash(t, db, &entity.Subject{}, expiredSubject.ID, now.Add(-2*time.Hour))
+	_, recentCourse := newTestSubject(t, db)
+	trash(t, db, &entity.Course{}, recentCourse.ID, now.Add(-time.Minute))
+
+	total, err := c.Purge(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Other rows may have expired in a shared database, these three at least
+	if total < 3 {
+		t.Fatalf("purged %d, want at least 3", total)
+	}
+	tests := []struct {
+		name  string
+		model any
+		id    uuid.UUID
+		want  bool
+	}{
+		{"expired user", &entity.User{}, expiredUser.ID, false},
+		{"recently deleted user", &entity.User{}, recentUser.ID, true},
+		{"active user", &entity.User{}, activeUser.ID, true},
+		{"expired subject", &entity.Subject{}, expiredSubject.ID, false},
+		{"expired course", &entity.Course{}, expiredCourse.ID, false},
+		{"recently deleted course", &entity.Course{}, recentCourse.ID, true},
+	}
+	for _, tt := range tests {
+		if got := exists(t, db, tt.model, tt.id); got != tt.want {
+			t.Errorf("%s stored = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRestoreUser(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestUserUseCase(t, db)
+	ctx := context.Background()
+
+	user := newTestUser(t, db, rbac.RoleUser)
+	trash(t, db, &entity.User{}, user.ID, time.Now())
+	restored, err := c.Restore(ctx, &model.RestoreUserRequest{ID: user.ID.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ID == nil || *restored.ID != user.ID {
+		t.Fatalf("restored %s, want %s", restored.ID, user.ID)
+	}
+	if _, err := c.Restore(ctx, &model.RestoreUserRequest{ID: user.ID.String()}); !errors.Is(err, fiber.ErrNotFound) {
+		t.Fatalf("restoring a user not in the trash = %v, want 404", err)
+	}
+
+	// The email was registered again while the user was in the trash
+	taken := newTestUser(t, db, rbac.RoleUser)
+	trash(t, db, &entity.User{}, taken.ID, time.Now())
+	other := newTestUser(t, db, rbac.RoleUser)
+	if err := db.Model(other).Update("email", taken.Email).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Restore(ctx, &model.RestoreUserRequest{ID: taken.ID.String()}); !errors.Is(err, ErrRestoreEmailTaken) {
+		t.Fatalf("restoring a user whose email is taken = %v, want conflict", err)
+	}
+}
+
+func TestResetTokenOfTrashedUser(t *testing.T) {
+	db := newTestDB(t)
+	log := newTestLogger()
+	users := newTestUserUseCase(t, db)
+	resets := NewPasswordResetUsecase(db, log, validator.New(), repository.NewUserRepository(log), repository.NewPasswordResetTokenRepository(log),
+		repository.NewUserSessionRepository(log), repository.NewAPIKeyRepository(log), nil, time.Hour, "")
+	ctx := context.Background()
+
+	// newResetToken stores an unused reset token of the user and returns the raw token
+	newResetToken := func(user *entity.User) (string, *entity.PasswordResetToken) {
+		t.Helper()
+		raw, hash, err := token.NewOpaque()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resetToken := &entity.PasswordResetToken{UserID: user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := db.Omit("User").Create(resetToken).Error; err != nil {
+			t.Fatal(err)
+		}
+		return raw, resetToken
+	}
+
+	// Deleting a user voids its tokens
+	deleted := newTestUser(t, db, rbac.RoleUser)
+	_, voided := newResetToken(deleted)
+	if _, err := users.Delete(ctx, &model.DeleteUserRequest{ID: deleted.ID.String()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(voided, "id = ?", voided.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if voided.UsedAt == nil {
+		t.Error("reset token of a deleted user is still unused")
+	}
+
+	// A token left over in the trash is refused rather than redeemed for a user that is not loaded
+	trashed := newTestUser(t, db, rbac.RoleUser)
+	raw, _ := newResetToken(trashed)
+	trash(t, db, &entity.User{}, trashed.ID, time.Now())
+	if _, err := resets.Reset(ctx, &model.ResetPasswordRequest{Token: raw, Password: "new" + testPassword}); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("reset of a trashed user = %v, want invalid token", err)
+	}
+	var total int64
+	if err := db.Unscoped().Model(&entity.User{}).Where("id = ?", uuid.Nil).Count(&total).Error; err != nil {
+		t.Fatal(err)
+	}
+	if total != 0 {
+		t.Error("reset of a trashed user stored a user with the nil id")
+	}
+}
+
+func TestRestoreSubjectAndCourse(t *testing.T) {
+	db := newTestDB(t)
+	log := newTestLogger()
+	ctx := context.Background()
+	subjectRepository, courseRepository := repository.NewSubjectRepository(log), repository.NewCourseRepository(log)
+	auditor := NewAuditor(log, repository.NewAuditEventRepository(log))
+	subjects := NewSubjectUsecase(db, log, validator.New(), subjectRepository, courseRepository, auditor)
+	access := NewSubjectAccess(log, rbac.NewPolicy(rbac.DefaultRoles(), []string{rbac.RoleAdmin}), repository.NewTeacherSubjectRepository(log), courseRepository)
+	courses := NewCourseUsecase(db, log, validator.New(), courseRepository, subjectRepository, nil, repository.NewMediaAssetRepository(log), access, auditor)
+
+	// A course deleted before its subject stays in the trash when the subject comes back
+	subject, deletedWith := newTestSubject(t, db)
+	deletedBefore := &entity.Course{ID: uuid.New(), CourseName: "test", Content: datatypes.JSON("[]"), GradeLevel: 10, SubjectID: subject.ID}
+	if err := db.Omit("Subject").Create(deletedBefore).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Unscoped().Delete(&entity.Course{}, "id = ?", deletedBefore.ID) })
+	deletedAt := time.Now().Truncate(time.Microsecond)
+	trash(t, db, &entity.Course{}, deletedBefore.ID, deletedAt.Add(-time.Hour))
+	trash(t, db, &entity.Course{}, deletedWith.ID, deletedAt)
+	trash(t, db, &entity.Subject{}, subject.ID, deletedAt)
+
+	// A course cannot come back before its subject
+	if _, err := courses.Restore(ctx, &model.RestoreCourseRequest{ID: deletedBefore.ID.String()}); !errors.Is(err, ErrSubjectDeleted) {
+		t.Fatalf("restoring a course of a deleted subject = %v, want subject deleted", err)
+	}
+	if _, err := subjects.Restore(ctx, &model.RestoreSubjectRequest{ID: subject.ID.String()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := courseRepository.FindById(db, new(entity.Course), deletedWith.ID.String()); err != nil {
+		t.Errorf("course deleted with the subject was not restored: %v", err)
+	}
+	if err := courseRepository.FindById(db, new(entity.Course), deletedBefore.ID.String()); err == nil {
+		t.Error("course deleted before the subject was restored with it")
+	}
+
+	if _, err := courses.Restore(ctx, &model.RestoreCourseRequest{ID: deletedBefore.ID.String()}); err != nil {
+		t.Fatalf("restoring the course once its subject is back = %v", err)
+	}
+	if err := courseRepository.FindById(db, new(entity.Course), deletedBefore.ID.String()); err != nil {
+		t.Errorf("course was not restored: %v", err)
+	}
+}
+
+func TestQuizzesOfTrashedCourse(t *testing.T) {
+	db := newTestDB(t)
+	log := newTestLogger()
+	quizzes := repository.NewQuizRepository(log)
+	_, course := newTestSubject(t, db)
+	quiz := &entity.Quiz{QuizName: "test", TimeLimit: 10, CourseID: course.ID}
+	if err := db.Omit("Course").Create(quiz).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		deletedAt any
+		want      bool
+	}{
+		{"course in the trash", time.Now(), false},
+		{"course restored", nil, true},
+	}
+	for _, tt := range tests {
+		if err := db.Unscoped().Model(&entity.Course{}).Where("id = ?", course.ID).Update("deleted_at", tt.deletedAt).Error; err != nil {
+			t.Fatal(err)
+		}
+		found := quizzes.FindById(db, new(entity.Quiz), quiz.ID.String()) == nil
+		foundWithQuestions := quizzes.FindByIdWithQuestions(db, new(entity.Quiz), quiz.ID.String()) == nil
+		listed, _, err := quizzes.Search(db, &model.SearchQuizRequest{CourseID: course.ID.String(), Page: 1, Size: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != tt.want || foundWithQuestions != tt.want || (len(listed) == 1) != tt.want {
+			t.Errorf("%s: found %v, with questions %v, listed %d, want found %v", tt.name, found, foundWithQuestions, len(listed), tt.want)
+		}
+	}
+}
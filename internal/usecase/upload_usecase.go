@@ -0,0 +1,339 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/imaging"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/repository"
+	"fp-designpattern/internal/upload"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	_ "golang.org/x/image/webp"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var ErrImageUnreadable = fiber.NewError(fiber.StatusUnprocessableEntity, "the image could not be read")
+
+type UploadUsecase struct {
+	DB                   *gorm.DB
+	Log                  *logrus.Logger
+	Validate             *validator.Validate
+	FileStorage          repository.FileStorage
+	MediaAssetRepository *repository.MediaAssetRepository
+	Policies             upload.Policies
+}
+
+func NewUploadUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, fileStorage repository.FileStorage, mediaAssetRepository *repository.MediaAssetRepository, policies upload.Policies) *UploadUsecase {
+	return &UploadUsecase{
+		DB:                   db,
+		Log:                  log,
+		Validate:             validate,
+		FileStorage:          fileStorage,
+		MediaAssetRepository: mediaAssetRepository,
+		Policies:             policies,
+	}
+}
+
+// Upload stores a file accepted by the policy of its category under the SHA-256 hash of its
+// content and records it as a media asset. A file uploaded before is not stored again, the
+// existing asset is returned. Images are stored without metadata, with the size variants of the
+// policy. A rejected file fails with an *upload.Error.
+func (c *UploadUsecase) Upload(ctx context.Context, request *model.UploadFileRequest) (*model.MediaAssetResponse, error) {
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	policy, ok := c.Policies[request.Category]
+	if !ok {
+		c.Log.Warnf("Unknown upload category : %s", request.Category)
+		return nil, fiber.ErrBadRequest
+	}
+
+	head := make([]byte, upload.SniffLen)
+	n, err := io.ReadFull(request.File, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.Log.Warnf("Failed to read file : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	contentType, err := policy.Check(request.Category, request.FileName, request.Size, head[:n])
+	if err != nil {
+		c.Log.Warnf("Rejected upload of %s : %+v", request.FileName, err)
+		return nil, err
+	}
+
+	// Images are stored without their metadata, the hash is of what is stored
+	file, size := request.File, request.Size
+	var data []byte
+	if strings.HasPrefix(contentType, "image/") {
+		// The dimensions the header declares are checked before anything decodes the image
+		config, err := c.imageConfig(request.File)
+		if err != nil {
+			c.Log.Warnf("Failed to read image dimensions of %s : %+v", request.FileName, err)
+			return nil, ErrImageUnreadable
+		}
+		if err := policy.CheckPixels(request.Category, config.Width, config.Height); err != nil {
+			c.Log.Warnf("Rejected upload of %s : %+v", request.FileName, err)
+			return nil, err
+		}
+		if data, err = c.stripImage(request.File, contentType); err != nil {
+			c.Log.Warnf("Failed to strip metadata of %s : %+v", request.FileName, err)
+			return nil, ErrImageUnreadable
+		}
+		file, size = bytes.NewReader(data), int64(len(data))
+	}
+	hash, err := c.hash(file)
+	if err != nil {
+		c.Log.Warnf("Failed to hash file : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	asset := new(entity.MediaAsset)
+	if err := c.MediaAssetRepository.FindByHash(tx, asset, hash); err == nil {
+		if data == nil || len(policy.Variants) == 0 || converter.MediaAssetVariants(asset) != nil {
+			return c.render(ctx, asset)
+		}
+		// Uploaded before for a category without variants
+		if asset.Variants, err = c.renderVariants(ctx, hash, data, contentType, policy.Variants); err != nil {
+			c.Log.Warnf("Failed to render image variants : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		if err := c.MediaAssetRepository.Update(tx, asset); err != nil {
+			c.Log.Warnf("Failed update media asset : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		if err := tx.Commit().Error; err != nil {
+			c.Log.Warnf("Failed commit transaction : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		return c.render(ctx, asset)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.Warnf("Failed find media asset by hash : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	fileName := upload.NormalizeFileName(request.FileName)
+	asset = &entity.MediaAsset{
+		Hash:        hash,
+		Category:    request.Category,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+	}
+	if request.Actor != nil {
+		if ownerID, err := uuid.Parse(request.Actor.ID); err == nil {
+			asset.OwnerID = &ownerID
+		}
+	}
+	if data != nil {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			asset.Width, asset.Height = config.Width, config.Height
+		} else {
+			c.Log.Warnf("Failed to read image dimensions of %s : %+v", request.FileName, err)
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.Log.Warnf("Failed to rewind file : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	// The same content always lands on the same object, a racing upload of it only overwrites it
+	asset.URL, err = c.FileStorage.UploadFile(ctx, file, mediaObjectName(hash, "", path.Ext(fileName)), contentType)
+	if err != nil {
+		c.Log.Warnf("Failed to upload file : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if asset.Variants, err = c.renderVariants(ctx, hash, data, contentType, policy.Variants); err != nil {
+		c.Log.Warnf("Failed to render image variants : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	created, err := c.MediaAssetRepository.CreateIfAbsent(tx, asset)
+	if err != nil {
+		c.Log.Warnf("Failed create media asset : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if !created {
+		if err := c.MediaAssetRepository.FindByHash(tx, asset, hash); err != nil {
+			c.Log.Warnf("Failed find media asset by hash : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return c.render(ctx, asset)
+}
+
+// imageConfig reads the dimensions an image declares in its header without decoding it.
+func (c *UploadUsecase) imageConfig(file io.ReadSeeker) (image.Config, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return image.Config{}, err
+	}
+	config, _, err := image.DecodeConfig(file)
+	return config, err
+}
+
+// stripImage reads an image whole and drops its metadata. A JPEG taken sideways is turned upright
+// first, as its orientation goes with the metadata.
+func (c *UploadUsecase) stripImage(file io.ReadSeeker, contentType string) ([]byte, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if orientation := imaging.Orientation(data); orientation != 1 {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		buf := new(bytes.Buffer)
+		if err := imaging.Encode(buf, imaging.Orient(img, orientation), contentType); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return imaging.StripMetadata(data, contentType)
+}
+
+// renderVariants stores a copy of an image scaled down to each of widths it is wider than, and a
+// WebP copy at the largest of them when it is smaller than the copy it stands in for, and returns
+// their URLs by name. Animated GIFs are left alone.
+func (c *UploadUsecase) renderVariants(ctx context.Context, hash string, data []byte, contentType string, widths map[string]int) (datatypes.JSON, error) {
+	variants := make(map[string]string)
+	if data != nil && len(widths) > 0 && contentType != "image/gif" {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		largest := 0
+		// The size of the variant of each width, the WebP copy is weighed against it
+		sizes := make(map[int]int)
+		for name, width := range widths {
+			largest = max(largest, width)
+			resized, ok := imaging.Resize(img, width)
+			if !ok {
+				continue
+			}
+			buf := new(bytes.Buffer)
+			if err := imaging.Encode(buf, resized, contentType); err != nil {
+				return nil, err
+			}
+			sizes[width] = buf.Len()
+			if variants[name], err = c.storeImage(ctx, buf, hash, "_"+name, contentType); err != nil {
+				return nil, err
+			}
+		}
+		if contentType != "image/webp" {
+			resized, ok := imaging.Resize(img, largest)
+			source := len(data)
+			if ok {
+				source = sizes[largest]
+			}
+			// The WebP encoder is lossless, its copy of a photo is often larger than the JPEG
+			buf := new(bytes.Buffer)
+			if err := imaging.Encode(buf, resized, "image/webp"); err != nil {
+				return nil, err
+			}
+			if buf.Len() < source {
+				if variants["webp"], err = c.storeImage(ctx, buf, hash, "_webp", "image/webp"); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return json.Marshal(variants)
+}
+
+func (c *UploadUsecase) storeImage(ctx context.Context, encoded io.Reader, hash string, suffix string, contentType string) (string, error) {
+	return c.FileStorage.UploadFile(ctx, encoded, mediaObjectName(hash, suffix, imaging.Extension(contentType)), contentType)
+}
+
+// mediaObjectName spreads the media over folders by the first byte of the hash.
+func mediaObjectName(hash string, suffix string, ext string) string {
+	return "media/" + hash[:2] + "/" + hash + suffix + ext
+}
+
+// hash is the hex SHA-256 of the whole file.
+func (c *UploadUsecase) hash(file io.ReadSeeker) (string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Search lists the media library for authors to reuse an asset rather than upload it again.
+func (c *UploadUsecase) Search(ctx context.Context, request *model.SearchMediaAssetRequest) ([]model.MediaAssetResponse, int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.WithError(err).Warnf("Invalid request body")
+		return nil, 0, fiber.ErrBadRequest
+	}
+
+	assets, total, err := c.MediaAssetRepository.Search(tx, request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search media assets")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.WithError(err).Error("Failed to commit transaction")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.MediaAssetResponse, len(assets))
+	for i, asset := range assets {
+		response, err := c.render(ctx, &asset)
+		if err != nil {
+			return nil, 0, err
+		}
+		responses[i] = *response
+	}
+	return responses, total, nil
+}
+
+// render converts the asset for a client. URL stays the stored one a content block refers to,
+// PreviewURL and the variants are signed when the storage keeps the files private.
+func (c *UploadUsecase) render(ctx context.Context, asset *entity.MediaAsset) (*model.MediaAssetResponse, error) {
+	response := converter.MediaAssetToResponse(asset)
+	var err error
+	if response.PreviewURL, err = c.FileStorage.SignURL(ctx, asset.URL); err != nil {
+		c.Log.Warnf("Failed to sign media asset url : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	for name, url := range response.Variants {
+		if response.Variants[name], err = c.FileStorage.SignURL(ctx, url); err != nil {
+			c.Log.Warnf("Failed to sign media asset variant url : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+	}
+	return response, nil
+}
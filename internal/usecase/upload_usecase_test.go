@@ -0,0 +1,389 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/imaging"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
+	"fp-designpattern/internal/repository"
+	"fp-designpattern/internal/upload"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// memoryStorage keeps uploaded files in memory. Its signed URLs are the stored ones with a query.
+type memoryStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{files: make(map[string][]byte)}
+}
+
+func (s *memoryStorage) UploadFile(ctx context.Context, file io.Reader, fileName string, contentType string) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	url := "https://media.example.com/" + fileName
+	s.files[url] = data
+	return url, nil
+}
+
+func (s *memoryStorage) DeleteFile(ctx context.Context, fileURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, fileURL)
+	return nil
+}
+
+func (s *memoryStorage) SignURL(ctx context.Context, fileURL string) (string, error) {
+	return fileURL + "?signed", nil
+}
+
+func (s *memoryStorage) StoredURL(fileURL string) string {
+	return strings.TrimSuffix(fileURL, "?signed")
+}
+
+func newTestUploadUsecase(db *gorm.DB, storage repository.FileStorage) *UploadUsecase {
+	log := newTestLogger()
+	return NewUploadUsecase(db, log, validator.New(), storage, repository.NewMediaAssetRepository(log), upload.DefaultPolicies())
+}
+
+// testPNG encodes an image of the size, its colours depend on seed so that every seed hashes apart.
+func testPNG(t *testing.T, width, height int, seed uint8) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: seed, A: 255})
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// hugePNG is a PNG of a few bytes whose header declares width by height pixels, with no pixel data.
+func hugePNG(width, height uint32) []byte {
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:], width)
+	binary.BigEndian.PutUint32(header[4:], height)
+	// 8-bit truecolour with alpha
+	header[8], header[9] = 8, 6
+	chunk := append([]byte("IHDR"), header...)
+	data := append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0d"), chunk...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+}
+
+func uploadRequest(category string, fileName string, data []byte, actor *model.Auth) *model.UploadFileRequest {
+	return &model.UploadFileRequest{Category: category, FileName: fileName, Size: int64(len(data)), File: bytes.NewReader(data), Actor: actor}
+}
+
+// TestUploadRejects covers the uploads turned away before anything is stored, which needs no database.
+func TestUploadRejects(t *testing.T) {
+	storage := newMemoryStorage()
+	c := newTestUploadUsecase(nil, storage)
+	tests := []struct {
+		name    string
+		request *model.UploadFileRequest
+		status  int
+	}{
+		{"too large", uploadRequest(upload.CategoryAvatar, "a.png", make([]byte, 3<<20), nil), http.StatusRequestEntityTooLarge},
+		{"wrong type", uploadRequest(upload.CategoryCourseImage, "a.pdf", []byte("%PDF-1.4\n"), nil), http.StatusUnsupportedMediaType},
+		{"extension of another type", uploadRequest(upload.CategoryCourseImage, "a.jpg", testPNG(t, 2, 2, 0), nil), http.StatusUnsupportedMediaType},
+		{"unreadable image", uploadRequest(upload.CategoryCourseImage, "a.png", []byte("\x89PNG\r\n\x1a\nnot a png"), nil), http.StatusUnprocessableEntity},
+		{"too many pixels", uploadRequest(upload.CategoryCourseImage, "a.png", hugePNG(100000, 100000), nil), http.StatusRequestEntityTooLarge},
+		{"too many pixels to attach", uploadRequest(upload.CategoryAttachment, "a.png", hugePNG(8000, 5001), nil), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		_, err := c.Upload(context.Background(), tt.request)
+		var uploadErr *upload.Error
+		switch {
+		case errors.As(err, &uploadErr):
+			if uploadErr.Status != tt.status {
+				t.Errorf("%s: status %d, want %d", tt.name, uploadErr.Status, tt.status)
+			}
+		case errors.Is(err, ErrImageUnreadable):
+			if tt.status != http.StatusUnprocessableEntity {
+				t.Errorf("%s: image unreadable, want %d", tt.name, tt.status)
+			}
+		default:
+			t.Errorf("%s: Upload = %v, want %d", tt.name, err, tt.status)
+		}
+	}
+	if _, err := c.Upload(context.Background(), uploadRequest("unknown", "a.png", testPNG(t, 2, 2, 0), nil)); err == nil {
+		t.Error("upload of an unknown category succeeded")
+	}
+	if len(storage.files) != 0 {
+		t.Errorf("rejected uploads stored %d files", len(storage.files))
+	}
+}
+
+// deleteMediaAsset removes the asset of the upload after the test.
+func deleteMediaAsset(t *testing.T, db *gorm.DB, response *model.MediaAssetResponse) {
+	t.Cleanup(func() { db.Delete(&entity.MediaAsset{}, "id = ?", response.ID) })
+}
+
+func TestUploadDeduplicates(t *testing.T) {
+	db := newTestDB(t)
+	storage := newMemoryStorage()
+	c := newTestUploadUsecase(db, storage)
+	ctx := context.Background()
+	user := newTestUser(t, db, rbac.RoleTeacher)
+	actor := &model.Auth{ID: user.ID.String(), Role: user.Role}
+	data := testPNG(t, 800, 10, uint8(uuid.New().ID()))
+
+	// First uploaded as an attachment, which has no variants
+	attachment, err := c.Upload(ctx, uploadRequest(upload.CategoryAttachment, "Notes.PNG", data, actor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteMediaAsset(t, db, attachment)
+	if attachment.Width != 800 || attachment.Height != 10 || len(attachment.Variants) != 0 || attachment.OwnerID == nil || *attachment.OwnerID != user.ID {
+		t.Fatalf("attachment = %+v", attachment)
+	}
+	if len(storage.files) != 1 {
+		t.Fatalf("stored %d files, want the original", len(storage.files))
+	}
+
+	// The same content under another name is the same asset, rendered the variants it lacked
+	courseImage, err := c.Upload(ctx, uploadRequest(upload.CategoryCourseImage, "diagram.png", data, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if courseImage.ID != attachment.ID || courseImage.URL != attachment.URL || courseImage.FileName != attachment.FileName {
+		t.Fatalf("second upload = %+v, want the asset %+v", courseImage, attachment)
+	}
+	// 800 pixels wide is scaled down to 160 and 640, not up to 1280
+	for _, name := range []string{"thumbnail", "medium"} {
+		if _, ok := courseImage.Variants[name]; !ok {
+			t.Errorf("variant %s missing from %v", name, courseImage.Variants)
+		}
+	}
+	stored := len(storage.files)
+	if _, ok := courseImage.Variants["large"]; ok || stored != 1+len(courseImage.Variants) {
+		t.Fatalf("variants = %v with %d files stored, want thumbnail and medium", courseImage.Variants, stored)
+	}
+
+	again, err := c.Upload(ctx, uploadRequest(upload.CategoryCourseImage, "again.png", data, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != attachment.ID || len(storage.files) != stored {
+		t.Fatalf("third upload = %+v with %d files stored, want the asset without new files", again, len(storage.files))
+	}
+}
+
+// TestMediaLibrary checks what counts as a reference to an asset and that avatars stay out of the
+// library.
+func TestMediaLibrary(t *testing.T) {
+	db := newTestDB(t)
+	log := newTestLogger()
+	storage := newMemoryStorage()
+	uploads := newTestUploadUsecase(db, storage)
+	courseRepository := repository.NewCourseRepository(log)
+	access := NewSubjectAccess(log, rbac.NewPolicy(rbac.DefaultRoles(), []string{rbac.RoleAdmin}), repository.NewTeacherSubjectRepository(log), courseRepository)
+	courses := NewCourseUsecase(db, log, validator.New(), courseRepository, repository.NewSubjectRepository(log), storage,
+		uploads.MediaAssetRepository, access, NewAuditor(log, repository.NewAuditEventRepository(log)))
+	ctx := context.Background()
+	user := newTestUser(t, db, rbac.RoleUser)
+	subject, _ := newTestSubject(t, db)
+	seed := uint8(uuid.New().ID())
+
+	picture, err := uploads.Upload(ctx, uploadRequest(upload.CategoryCourseImage, "picture.png", testPNG(t, 4, 4, seed), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteMediaAsset(t, db, picture)
+	attachment, err := uploads.Upload(ctx, uploadRequest(upload.CategoryAttachment, "notes.txt", []byte("notes "+uuid.NewString()), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteMediaAsset(t, db, attachment)
+	avatar, err := uploads.Upload(ctx, uploadRequest(upload.CategoryAvatar, "me.png", testPNG(t, 4, 4, seed+1), &model.Auth{ID: user.ID.String(), Role: user.Role}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteMediaAsset(t, db, avatar)
+
+	// Signed URLs sent back are stored, and counted, as the URLs of the assets
+	course, err := courses.Create(ctx, &model.CourseRequest{CourseName: "test", GradeLevel: 10, SubjectID: subject.ID.String(), Content: []model.ContentBlock{
+		{Type: "text", Data: attachment.URL},
+		{Type: "image", Data: picture.URL + "?signed"},
+		{Type: "file", Data: attachment.URL + "?signed"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Unscoped().Delete(&entity.Course{}, "id = ?", course.ID) })
+	if err := db.Model(user).Update("avatar_url", avatar.URL).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := uploads.MediaAssetRepository.RecountReferences(db, []string{avatar.URL}); err != nil {
+		t.Fatal(err)
+	}
+	references := func(asset *model.MediaAssetResponse) int {
+		stored := new(entity.MediaAsset)
+		if err := db.Take(stored, "id = ?", asset.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return stored.ReferenceCount
+	}
+	for name, asset := range map[string]*model.MediaAssetResponse{"image": picture, "attachment": attachment, "avatar": avatar} {
+		if got := references(asset); got != 1 {
+			t.Errorf("%s has %d references, want 1", name, got)
+		}
+	}
+
+	// Dropping the file block takes its reference away, a text block naming the URL is none
+	if _, err := courses.Update(ctx, &model.UpdateCourseRequest{ID: course.ID.String(), CourseName: "test", GradeLevel: 10, SubjectID: subject.ID.String(), Content: []model.ContentBlock{
+		{Type: "text", Data: attachment.URL},
+		{Type: "image", Data: picture.URL},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := references(attachment); got != 0 {
+		t.Errorf("attachment has %d references after it was dropped, want 0", got)
+	}
+
+	for _, request := range []*model.SearchMediaAssetRequest{
+		{OwnerID: user.ID.String(), Page: 1, Size: 100},
+		{Category: upload.CategoryAvatar, Page: 1, Size: 100},
+	} {
+		assets, _, err := uploads.Search(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, asset := range assets {
+			if asset.ID == avatar.ID {
+				t.Errorf("search %+v listed an avatar", request)
+			}
+		}
+	}
+}
+
+// TestRenderVariantsWebP keeps the lossless WebP copy only where it saves bytes: it does for flat
+// artwork, not for a noisy photo the JPEG encoder throws detail away from.
+func TestRenderVariantsWebP(t *testing.T) {
+	storage := newMemoryStorage()
+	c := newTestUploadUsecase(nil, storage)
+	widths := map[string]int{"thumbnail": 160, "medium": 640}
+	flat := image.NewNRGBA(image.Rect(0, 0, 800, 600))
+	noisy := image.NewNRGBA(image.Rect(0, 0, 800, 600))
+	random := rand.New(rand.NewPCG(1, 2))
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 800; x++ {
+			flat.SetNRGBA(x, y, color.NRGBA{R: 200, G: 30, B: 30, A: 255})
+			noisy.SetNRGBA(x, y, color.NRGBA{R: uint8(random.Uint32()), G: uint8(random.Uint32()), B: uint8(random.Uint32()), A: 255})
+		}
+	}
+	tests := []struct {
+		name        string
+		img         image.Image
+		contentType string
+		webp        bool
+	}{
+		{"flat png", flat, "image/png", true},
+		{"noisy jpeg", noisy, "image/jpeg", false},
+	}
+	for _, tt := range tests {
+		buf := new(bytes.Buffer)
+		if err := imaging.Encode(buf, tt.img, tt.contentType); err != nil {
+			t.Fatal(err)
+		}
+		raw, err := c.renderVariants(context.Background(), strings.Repeat("ab", 32), buf.Bytes(), tt.contentType, widths)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var variants map[string]string
+		if err := json.Unmarshal(raw, &variants); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := variants["webp"]; ok != tt.webp || variants["thumbnail"] == "" || variants["medium"] == "" {
+			t.Errorf("%s: variants = %v, want a WebP copy %v", tt.name, variants, tt.webp)
+		}
+	}
+}
+
+func TestMediaURLs(t *testing.T) {
+	storage := newMemoryStorage()
+	content := []model.ContentBlock{
+		{Type: "text", Data: "https://media.example.com/a.png"},
+		{Type: "image", Data: "https://media.example.com/b.png?signed", Srcset: map[string]string{"thumbnail": "t"}},
+		{Type: "file", Data: "https://media.example.com/c.pdf?signed"},
+	}
+	stored := storedContent(storage, content)
+	want := []model.ContentBlock{
+		{Type: "text", Data: "https://media.example.com/a.png"},
+		{Type: "image", Data: "https://media.example.com/b.png"},
+		{Type: "file", Data: "https://media.example.com/c.pdf"},
+	}
+	for i := range want {
+		if stored[i].Type != want[i].Type || stored[i].Data != want[i].Data || stored[i].Srcset != nil {
+			t.Errorf("stored block %d = %+v, want %+v", i, stored[i], want[i])
+		}
+	}
+	urls := mediaURLs(stored)
+	if len(urls) != 2 || urls[0] != want[1].Data || urls[1] != want[2].Data {
+		t.Errorf("mediaURLs = %q, want the image and the file", urls)
+	}
+}
+
+// TestRenderSignsURLs checks that every URL handed to a client is signed for a private storage,
+// but for the stored URL content blocks refer to an upload by.
+func TestRenderSignsURLs(t *testing.T) {
+	storage := newMemoryStorage()
+	ctx := context.Background()
+
+	uploads := newTestUploadUsecase(nil, storage)
+	asset := &entity.MediaAsset{URL: "https://media.example.com/a.png", Variants: []byte(`{"thumbnail": "https://media.example.com/a-160.png"}`)}
+	response, err := uploads.render(ctx, asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.URL != asset.URL || response.PreviewURL != asset.URL+"?signed" || response.Variants["thumbnail"] != "https://media.example.com/a-160.png?signed" {
+		t.Errorf("media asset response = %+v, want signed preview and variants", response)
+	}
+
+	tests := []struct {
+		avatar string
+		want   string
+	}{
+		{"https://media.example.com/avatar.png", "https://media.example.com/avatar.png?signed"},
+		// No avatar stays empty rather than become a signed empty URL
+		{"", ""},
+	}
+	users := &UserUseCase{Log: newTestLogger(), FileStorage: storage}
+	for _, tt := range tests {
+		response, err := users.render(ctx, &entity.User{AvatarUrl: tt.avatar})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.AvatarUrl != tt.want {
+			t.Errorf("avatar %q rendered as %q, want %q", tt.avatar, response.AvatarUrl, tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"fp-designpattern/internal/audit"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/rbac"
+	"fp-designpattern/internal/token"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAPIKeyScope  = fiber.NewError(fiber.StatusBadRequest, "scopes must be permissions granted to your role or scopes of your own account")
+	ErrAPIKeyExpiry = fiber.NewError(fiber.StatusBadRequest, "expires_at must be in the future and within the maximum lifetime of a key")
+)
+
+// apiKeyPrefixLength is how much of a key is kept in clear, the prefix and a few random characters.
+const apiKeyPrefixLength = len(token.APIKeyPrefix) + 8
+
+// verifyAPIKey authenticates a request made with an API key as its owner, limited to the key's scopes.
+func (c *UserUseCase) verifyAPIKey(tx *gorm.DB, raw string) (*model.Auth, error) {
+	now := time.Now()
+	key := new(entity.APIKey)
+	if err := c.APIKeyRepository.FindActiveByHash(tx, key, token.Hash(raw), now); err != nil {
+		c.Log.Warnf("Failed find api key : %+v", err)
+		return nil, fiber.ErrUnauthorized
+	}
+	// The owner is not loaded while in the trash
+	if key.User.ID == uuid.Nil {
+		c.Log.Warnf("API key %s of deleted user %s", key.ID, key.UserID)
+		return nil, fiber.ErrUnauthorized
+	}
+	if err := c.APIKeyRepository.Touch(tx, key.ID.String(), now); err != nil {
+		c.Log.Warnf("Failed touch api key : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return &model.Auth{
+		ID:            key.UserID.String(),
+		Role:          key.User.Role,
+		EmailVerified: key.User.EmailVerifiedAt != nil,
+		MFA:           key.MFAVerified,
+		APIKeyID:      key.ID.String(),
+		Scopes:        scopes,
+	}, nil
+}
+
+// CreateAPIKey issues a key for the user. The key is returned once, only its hash is kept.
+func (c *UserUseCase) CreateAPIKey(ctx context.Context, request *model.CreateAPIKeyRequest) (*model.APIKeyResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	for _, scope := range request.Scopes {
+		if slices.Contains(rbac.SelfScopes(), scope) {
+			continue
+		}
+		if (scope != rbac.All && !slices.Contains(rbac.Permissions(), scope)) || !c.Policy.Can(user.Role, scope) {
+			c.Log.Warnf("User %s of role %s cannot scope a key to %s", user.ID, user.Role, scope)
+			return nil, ErrAPIKeyScope
+		}
+	}
+	now := time.Now()
+	expiresAt := now.Add(c.Tokens.APIKeyTTL)
+	if request.ExpiresAt != nil {
+		expiresAt = *request.ExpiresAt
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(c.Tokens.APIKeyMaxTTL)) {
+		c.Log.Warnf("Invalid api key expiry %s", expiresAt)
+		return nil, ErrAPIKeyExpiry
+	}
+
+	raw, hash, err := token.NewAPIKey()
+	if err != nil {
+		c.Log.Warnf("Failed generate api key : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	key := &entity.APIKey{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		Name:        request.Name,
+		Prefix:      raw[:apiKeyPrefixLength],
+		KeyHash:     hash,
+		Scopes:      slices.Compact(slices.Sorted(slices.Values(request.Scopes))),
+		MFAVerified: request.MFA,
+		ExpiresAt:   expiresAt,
+	}
+	if err := c.APIKeyRepository.Create(tx, key); err != nil {
+		c.Log.Warnf("Failed create api key : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	response := converter.APIKeyToResponse(key)
+	response.Key = raw
+	return response, nil
+}
+
+func (c *UserUseCase) APIKeys(ctx context.Context, request *model.ListAPIKeyRequest) ([]model.APIKeyResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	keys, err := c.APIKeyRepository.FindActiveByUserId(tx, request.UserID)
+	if err != nil {
+		c.Log.Warnf("Failed find api keys : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = *converter.APIKeyToResponse(&key)
+	}
+	return responses, nil
+}
+
+func (c *UserUseCase) RevokeAPIKey(ctx context.Context, request *model.RevokeAPIKeyRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	key := new(entity.APIKey)
+	if err := c.APIKeyRepository.FindActiveByIdAndUserId(tx, key, request.ID, request.UserID); err != nil {
+		c.Log.Warnf("Failed find api key by id : %+v", err)
+		return false, fiber.ErrNotFound
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := c.APIKeyRepository.Update(tx, key); err != nil {
+		c.Log.Warnf("Failed revoke api key : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	return true, nil
+}
+
+// RevokeAllAPIKeys lets an admin revoke every key of a user, such as when one leaked.
+func (c *UserUseCase) RevokeAllAPIKeys(ctx context.Context, request *model.RevokeAllAPIKeyRequest) (int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return 0, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return 0, fiber.ErrNotFound
+	}
+	revoked, err := c.APIKeyRepository.RevokeByUserId(tx, request.UserID, time.Now())
+	if err != nil {
+		c.Log.Warnf("Failed revoke api keys : %+v", err)
+		return 0, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionRevokeAPIKeys, audit.ResourceUser, user.ID.String(), nil, map[string]int64{"revoked_api_keys": revoked}); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return 0, fiber.ErrInternalServerError
+	}
+	return revoked, nil
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCreateAPIKeyScopes(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestUserUseCase(t, db)
+	ctx := context.Background()
+	student := newTestUser(t, db, rbac.RoleUser)
+	teacher := newTestUser(t, db, rbac.RoleTeacher)
+
+	tests := []struct {
+		name   string
+		userID string
+		scopes []string
+		ok     bool
+	}{
+		{"student to own account", student.ID.String(), []string{rbac.ScopeQuizTake, rbac.ScopeProfileRead}, true},
+		{"student to a permission", student.ID.String(), []string{rbac.PermissionCourseRead}, false},
+		{"student to everything", student.ID.String(), []string{rbac.All}, false},
+		{"teacher to a permission and own account", teacher.ID.String(), []string{rbac.PermissionCourseRead, rbac.ScopeCourseAccess}, true},
+		{"teacher to a permission of another role", teacher.ID.String(), []string{rbac.PermissionUserManage}, false},
+		{"unknown scope", teacher.ID.String(), []string{"course:everything"}, false},
+	}
+	for _, tt := range tests {
+		_, err := c.CreateAPIKey(ctx, &model.CreateAPIKeyRequest{UserID: tt.userID, Name: tt.name, Scopes: tt.scopes})
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrAPIKeyScope) {
+			t.Errorf("%s: err = %v, want scope error", tt.name, err)
+		}
+	}
+}
+
+// TestAPIKeysRevoked checks that every way of cutting a user off takes their API keys with it.
+func TestAPIKeysRevoked(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestUserUseCase(t, db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		revoke func(userID string) error
+	}{
+		{"revoke all sessions", func(userID string) error {
+			_, err := c.RevokeAllSessions(ctx, &model.RevokeAllUserSessionRequest{UserID: userID})
+			return err
+		}},
+		{"revoke all keys", func(userID string) error {
+			_, err := c.RevokeAllAPIKeys(ctx, &model.RevokeAllAPIKeyRequest{UserID: userID})
+			return err
+		}},
+		{"role change", func(userID string) error {
+			_, err := c.SetRole(ctx, &model.UpdateUserRoleRequest{ID: userID, Role: rbac.RoleUser})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		user := newTestUser(t, db, rbac.RoleTeacher)
+		key, err := c.CreateAPIKey(ctx, &model.CreateAPIKeyRequest{UserID: user.ID.String(), Name: tt.name, Scopes: []string{rbac.PermissionCourseRead}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Verify(ctx, &model.VerifyUserRequest{Token: key.Key}); err != nil {
+			t.Fatalf("%s: new key rejected: %v", tt.name, err)
+		}
+		if err := tt.revoke(user.ID.String()); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if _, err := c.Verify(ctx, &model.VerifyUserRequest{Token: key.Key}); !errors.Is(err, fiber.ErrUnauthorized) {
+			t.Errorf("%s: key after revocation = %v, want 401", tt.name, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"fp-designpattern/internal/audit"
 	"fp-designpattern/internal/entity"
 	"fp-designpattern/internal/model"
 	"fp-designpattern/internal/model/converter"
@@ -23,9 +24,13 @@ type UserCourseUsecase struct {
 	CourseRepository     *repository.CourseRepository
 	UserRepository       *repository.UserRepository
 	UserCourseRepository *repository.UserCourseRepository
+	FileStorage          repository.FileStorage
+	MediaAssetRepository *repository.MediaAssetRepository
+	Access               *SubjectAccess
+	Auditor              *Auditor
 }
 
-func NewUserCourseUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, courseRepository *repository.CourseRepository, userRepository *repository.UserRepository, userCourseRepository *repository.UserCourseRepository) *UserCourseUsecase {
+func NewUserCourseUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, courseRepository *repository.CourseRepository, userRepository *repository.UserRepository, userCourseRepository *repository.UserCourseRepository, fileStorage repository.FileStorage, mediaAssetRepository *repository.MediaAssetRepository, access *SubjectAccess, auditor *Auditor) *UserCourseUsecase {
 	return &UserCourseUsecase{
 		DB:                   db,
 		Log:                  log,
@@ -33,6 +38,10 @@ func NewUserCourseUsecase(db *gorm.DB, log *logrus.Logger, validate *validator.V
 		CourseRepository:     courseRepository,
 		UserRepository:       userRepository,
 		UserCourseRepository: userCourseRepository,
+		FileStorage:          fileStorage,
+		MediaAssetRepository: mediaAssetRepository,
+		Access:               access,
+		Auditor:              auditor,
 	}
 }
 
@@ -68,6 +77,9 @@ func (c *UserCourseUsecase) Create(ctx context.Context, request *model.UserCours
 			c.Log.Warnf("Course not found: %s, %+v", courseID, err)
 			return nil, fiber.ErrNotFound
 		}
+		if err := c.Access.Check(tx, request.Actor, course.SubjectID); err != nil {
+			return nil, err
+		}
 
 		userCourses[i] = &entity.UserCourse{
 			UserID:   uuid.MustParse(request.UserID),
@@ -79,6 +91,11 @@ func (c *UserCourseUsecase) Create(ctx context.Context, request *model.UserCours
 		c.Log.Warnf("Failed to bulk create user courses: %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
+	for _, userCourse := range userCourses {
+		if err := c.Auditor.Record(tx, request.Actor, audit.ActionCreate, audit.ResourceUserCourse, userCourse.ID.String(), nil, converter.UserCourseToResponse(userCourse)); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := tx.Commit().Error; err != nil {
 		c.Log.Warnf("Failed to commit transaction: %+v", err)
@@ -120,7 +137,7 @@ func (c *UserCourseUsecase) Get(ctx context.Context, request *model.GetUserCours
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.UserCourseToResponse(userCourse), nil
+	return c.render(ctx, userCourse)
 }
 
 func (c *UserCourseUsecase) Search(ctx context.Context, request *model.SearchUserCourseRequest) ([]model.UserCourseListResponse, int64, error) {
@@ -130,6 +147,11 @@ func (c *UserCourseUsecase) Search(ctx context.Context, request *model.SearchUse
 		c.Log.WithError(err).Warnf("Invalid request body")
 		return nil, 0, fiber.ErrBadRequest
 	}
+	subjectIDs, err := c.Access.Subjects(tx, request.Actor)
+	if err != nil {
+		return nil, 0, err
+	}
+	request.SubjectIDs = subjectIDs
 	userCourses, total, err := c.UserCourseRepository.Search(tx, request)
 	if err != nil {
 		c.Log.WithError(err).Warnf("Failed to search user course")
@@ -143,6 +165,10 @@ func (c *UserCourseUsecase) Search(ctx context.Context, request *model.SearchUse
 	responses := make([]model.UserCourseListResponse, len(userCourses))
 	for i, userCourse := range userCourses {
 		responses[i] = *converter.UserCourseListToResponse(&userCourse)
+		if err := renderUser(ctx, c.FileStorage, &responses[i].User); err != nil {
+			c.Log.WithError(err).Warnf("Failed to render user")
+			return nil, 0, fiber.ErrInternalServerError
+		}
 	}
 	return responses, total, nil
 }
@@ -163,11 +189,17 @@ func (c *UserCourseUsecase) Delete(ctx context.Context, request *model.DeleteUse
 		c.Log.Warnf("Failed find user course by id : %+v", err)
 		return nil, fiber.ErrNotFound
 	}
+	if err := c.Access.Check(tx, request.Actor, userCourse.Course.SubjectID); err != nil {
+		return nil, err
+	}
 	// Delete course
 	if err := c.UserCourseRepository.Delete(tx, userCourse); err != nil {
 		c.Log.Warnf("Failed delete user course : %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionDelete, audit.ResourceUserCourse, userCourse.ID.String(), converter.UserCourseToResponse(userCourse), nil); err != nil {
+		return nil, err
+	}
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
@@ -175,5 +207,20 @@ func (c *UserCourseUsecase) Delete(ctx context.Context, request *model.DeleteUse
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.UserCourseToResponse(userCourse), nil
+	return c.render(ctx, userCourse)
+}
+
+// render converts the enrollment for a client, with the content of the course and the avatar of the
+// user rendered as in their own responses.
+func (c *UserCourseUsecase) render(ctx context.Context, userCourse *entity.UserCourse) (*model.UserCourseResponse, error) {
+	response := converter.UserCourseToResponse(userCourse)
+	if err := renderContent(ctx, c.DB.WithContext(ctx), c.FileStorage, c.MediaAssetRepository, response.Course.Content); err != nil {
+		c.Log.Warnf("Failed to render content: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := renderUser(ctx, c.FileStorage, &response.User); err != nil {
+		c.Log.Warnf("Failed to render user: %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return response, nil
 }
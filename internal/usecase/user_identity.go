@@ -0,0 +1,342 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/identity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/token"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentityFlowInvalid     = fiber.NewError(fiber.StatusUnauthorized, "sign in with the identity provider is invalid or expired")
+	ErrIdentityEmailUnverified = fiber.NewError(fiber.StatusForbidden, "identity provider has not verified the email")
+	ErrIdentityNoAccount       = fiber.NewError(fiber.StatusForbidden, "no account exists for this email")
+	ErrIdentityLinked          = fiber.NewError(fiber.StatusConflict, "identity is linked to another account")
+	ErrIdentityProviderLinked  = fiber.NewError(fiber.StatusConflict, "an identity of this provider is already linked")
+	ErrIdentityLastSignIn      = fiber.NewError(fiber.StatusConflict, "the only way left to sign in cannot be unlinked")
+	ErrIdentityLinkRequired    = fiber.NewError(fiber.StatusForbidden, "sign in with your password and link this identity from your account first")
+)
+
+// StartIdentity returns where to send the user to sign in with the provider, and the signed flow
+// the callback has to present.
+func (c *UserUseCase) StartIdentity(ctx context.Context, request *model.StartIdentityRequest) (*model.IdentityAuthorizationResponse, error) {
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	provider, err := c.IdentityProviders.Get(request.Provider)
+	if err != nil {
+		c.Log.Warnf("Failed get identity provider : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	flow := identity.NewFlow()
+	authorizationURL, err := provider.AuthCodeURL(ctx, flow)
+	if err != nil {
+		c.Log.Errorf("Failed build authorization url : %+v", err)
+		return nil, fiber.ErrBadGateway
+	}
+	signed, err := c.Tokens.SignFlow(&token.FlowClaims{
+		Provider:   request.Provider,
+		State:      flow.State,
+		Nonce:      flow.Nonce,
+		Verifier:   flow.Verifier,
+		LinkUserID: request.LinkUserID,
+	}, time.Now())
+	if err != nil {
+		c.Log.Warnf("Failed sign flow : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return &model.IdentityAuthorizationResponse{AuthorizationURL: authorizationURL, Flow: signed}, nil
+}
+
+// exchangeIdentity checks the callback against the flow it started with and redeems its code.
+// linkUserID is empty for a sign in, a flow started to link an identity only completes a link.
+func (c *UserUseCase) exchangeIdentity(ctx context.Context, request *model.IdentityCallbackRequest, linkUserID string) (*identity.Claims, error) {
+	flow, err := c.Tokens.ParseFlow(request.Flow)
+	if err != nil {
+		c.Log.Warnf("Failed parse flow : %+v", err)
+		return nil, ErrIdentityFlowInvalid
+	}
+	if flow.Provider != request.Provider || flow.State != request.State || flow.LinkUserID != linkUserID {
+		c.Log.Warnf("Callback of provider %s does not match its flow", request.Provider)
+		return nil, ErrIdentityFlowInvalid
+	}
+
+	provider, err := c.IdentityProviders.Get(request.Provider)
+	if err != nil {
+		c.Log.Warnf("Failed get identity provider : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	claims, err := provider.Exchange(ctx, &identity.Flow{State: flow.State, Nonce: flow.Nonce, Verifier: flow.Verifier}, request.Code)
+	if err != nil {
+		c.Log.Warnf("Failed exchange code : %+v", err)
+		return nil, ErrIdentityFlowInvalid
+	}
+	return claims, nil
+}
+
+// IdentityLogin completes a sign in with a provider. The user is found by the linked identity,
+// else by the verified email the provider reports, which links the identity, else an account is
+// provisioned when the registry allows it.
+func (c *UserUseCase) IdentityLogin(ctx context.Context, request *model.IdentityCallbackRequest) (*model.UserResponse, error) {
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	claims, err := c.exchangeIdentity(ctx, request, "")
+	if err != nil {
+		return nil, err
+	}
+
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	user := new(entity.User)
+	link := new(entity.UserIdentity)
+	err = c.UserIdentityRepository.FindByProviderAndSubject(tx, link, request.Provider, claims.Subject)
+	switch {
+	case err == nil:
+		// The owner is not loaded while in the trash
+		if link.User.ID == uuid.Nil {
+			c.Log.Warnf("Identity %s of %s belongs to deleted user %s", claims.Subject, request.Provider, link.UserID)
+			return nil, fiber.ErrUnauthorized
+		}
+		user = &link.User
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if claims.Email == "" || !claims.EmailVerified {
+			c.Log.Warnf("Provider %s did not verify the email of %s", request.Provider, claims.Subject)
+			return nil, ErrIdentityEmailUnverified
+		}
+		user, err = c.findOrProvisionUser(tx, claims)
+		if err != nil {
+			return nil, err
+		}
+		link = &entity.UserIdentity{UserID: user.ID, Provider: request.Provider, Subject: claims.Subject}
+	default:
+		c.Log.Warnf("Failed find identity : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	link.Email = claims.Email
+	if err := c.UserIdentityRepository.Update(tx.Omit("User"), link); err != nil {
+		c.Log.Warnf("Failed save identity : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	// The provider vouches for the email, so it no longer needs a verification link
+	if user.EmailVerifiedAt == nil && claims.EmailVerified && strings.EqualFold(user.Email, claims.Email) {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := c.UserRepository.Update(tx, user); err != nil {
+			c.Log.Warnf("Failed update user : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+	}
+
+	var response *model.UserResponse
+	if user.TOTPEnabledAt != nil {
+		challenge, err := c.issueChallenge(tx, user, time.Now())
+		if err != nil {
+			c.Log.Warnf("Failed issue challenge : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		response = converter.UserToChallengeResponse(challenge)
+	} else {
+		response, err = c.startSession(tx, user, &model.LoginUserRequest{
+			Device:    request.Device,
+			IPAddress: request.IPAddress,
+			UserAgent: request.UserAgent,
+		}, false)
+		if err != nil {
+			c.Log.Warnf("Failed start session : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return response, nil
+}
+
+// findOrProvisionUser returns the user with the verified email of the claims, creating one
+// without a password when there is none and the registry allows it. Accounts whose role grants
+// permissions are only linked by their owner signed in, through LinkIdentity.
+func (c *UserUseCase) findOrProvisionUser(tx *gorm.DB, claims *identity.Claims) (*entity.User, error) {
+	user := new(entity.User)
+	err := c.UserRepository.FindByEmailFold(tx, user, claims.Email)
+	if err == nil {
+		if c.Policy.Privileged(user.Role) {
+			c.Log.Warnf("Refused to link an identity to %s user %s by email", user.Role, user.ID)
+			return nil, ErrIdentityLinkRequired
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.Warnf("Failed find user by email : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if !c.IdentityProviders.AutoProvision {
+		c.Log.Warnf("No account for %s and provisioning is off", claims.Email)
+		return nil, ErrIdentityNoAccount
+	}
+
+	now := time.Now()
+	user = &entity.User{
+		Email:           claims.Email,
+		Username:        identityUsername(claims),
+		GradeLevel:      c.IdentityProviders.DefaultGradeLevel,
+		Role:            c.IdentityProviders.DefaultRole,
+		EmailVerifiedAt: &now,
+	}
+	if err := c.UserRepository.Create(tx, user); err != nil {
+		c.Log.Warnf("Failed to create user to database: %v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return user, nil
+}
+
+func identityUsername(claims *identity.Claims) string {
+	if claims.PreferredUsername != "" {
+		return claims.PreferredUsername
+	}
+	if claims.Name != "" {
+		return claims.Name
+	}
+	local, _, _ := strings.Cut(claims.Email, "@")
+	return local
+}
+
+// LinkIdentity completes linking an identity of the provider to the signed in user.
+func (c *UserUseCase) LinkIdentity(ctx context.Context, request *model.IdentityCallbackRequest) (*model.UserIdentityResponse, error) {
+	if err := c.Validate.Struct(request); err != nil || request.UserID == "" {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	claims, err := c.exchangeIdentity(ctx, request, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	link := new(entity.UserIdentity)
+	err = c.UserIdentityRepository.FindByProviderAndSubject(tx, link, request.Provider, claims.Subject)
+	if err == nil {
+		if link.UserID.String() != request.UserID {
+			c.Log.Warnf("Identity %s of %s is linked to user %s", claims.Subject, request.Provider, link.UserID)
+			return nil, ErrIdentityLinked
+		}
+		return converter.UserIdentityToResponse(link), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.Warnf("Failed find identity : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	total, err := c.UserIdentityRepository.CountByUserIdAndProvider(tx, request.UserID, request.Provider)
+	if err != nil {
+		c.Log.Warnf("Failed count identities : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if total > 0 {
+		c.Log.Warnf("User %s already has an identity of %s", request.UserID, request.Provider)
+		return nil, ErrIdentityProviderLinked
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	link = &entity.UserIdentity{UserID: user.ID, Provider: request.Provider, Subject: claims.Subject, Email: claims.Email}
+	if err := c.UserIdentityRepository.Create(tx, link); err != nil {
+		c.Log.Warnf("Failed create identity : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return converter.UserIdentityToResponse(link), nil
+}
+
+func (c *UserUseCase) Identities(ctx context.Context, request *model.ListUserIdentityRequest) ([]model.UserIdentityResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	identities, err := c.UserIdentityRepository.FindByUserId(tx, request.UserID)
+	if err != nil {
+		c.Log.Warnf("Failed find identities : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.UserIdentityResponse, len(identities))
+	for i, identity := range identities {
+		responses[i] = *converter.UserIdentityToResponse(&identity)
+	}
+	return responses, nil
+}
+
+// UnlinkIdentity removes a linked identity, unless the user has no password and it is their last one.
+func (c *UserUseCase) UnlinkIdentity(ctx context.Context, request *model.UnlinkUserIdentityRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindByIdForUpdate(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return false, fiber.ErrNotFound
+	}
+	link := new(entity.UserIdentity)
+	if err := c.UserIdentityRepository.FindByIdAndUserId(tx, link, request.ID, request.UserID); err != nil {
+		c.Log.Warnf("Failed find identity : %+v", err)
+		return false, fiber.ErrNotFound
+	}
+	if user.Password == "" {
+		total, err := c.UserIdentityRepository.CountByUserId(tx, request.UserID)
+		if err != nil {
+			c.Log.Warnf("Failed count identities : %+v", err)
+			return false, fiber.ErrInternalServerError
+		}
+		if total <= 1 {
+			c.Log.Warnf("Identity %s is the last sign in of user %s", link.ID, user.ID)
+			return false, ErrIdentityLastSignIn
+		}
+	}
+
+	if err := c.UserIdentityRepository.Delete(tx, link); err != nil {
+		c.Log.Warnf("Failed delete identity : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	return true, nil
+}
@@ -0,0 +1,29 @@
+package usecase
+
+import (
+	"errors"
+	"fp-designpattern/internal/identity"
+	"fp-designpattern/internal/rbac"
+	"strings"
+	"testing"
+)
+
+func TestFindOrProvisionUserLinksByEmail(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestUserUseCase(t, db)
+	student := newTestUser(t, db, rbac.RoleUser)
+	admin := newTestUser(t, db, rbac.RoleAdmin)
+
+	// Providers do not keep the case of the address the account was registered with
+	user, err := c.findOrProvisionUser(db, &identity.Claims{Subject: "s1", Email: strings.ToUpper(student.Email), EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != student.ID {
+		t.Fatalf("linked user %s, want %s", user.ID, student.ID)
+	}
+
+	if _, err := c.findOrProvisionUser(db, &identity.Claims{Subject: "s2", Email: admin.Email, EmailVerified: true}); !errors.Is(err, ErrIdentityLinkRequired) {
+		t.Fatalf("admin linked by email = %v, want link required", err)
+	}
+}
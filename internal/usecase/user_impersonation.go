@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"fp-designpattern/internal/audit"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/rbac"
+	"fp-designpattern/internal/token"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+var ErrImpersonationForbidden = fiber.NewError(fiber.StatusForbidden, "this user cannot be impersonated")
+
+// Impersonate issues an admin a session acting as the user, to see what the user sees. It cannot
+// be refreshed, ends when its token expires or on logout, and its requests are recorded.
+func (c *UserUseCase) Impersonate(ctx context.Context, request *model.ImpersonateUserRequest) (*model.UserResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	actorID, err := uuid.Parse(request.Actor.ID)
+	if err != nil {
+		c.Log.Warnf("Invalid actor id : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	// Staff are not impersonated, that would hand their permissions to whoever impersonates them
+	if user.ID == actorID || c.Policy.Can(user.Role, rbac.PermissionUserManage) || c.Policy.Can(user.Role, rbac.PermissionUserImpersonate) {
+		c.Log.Warnf("User %s cannot impersonate user %s of role %s", actorID, user.ID, user.Role)
+		return nil, ErrImpersonationForbidden
+	}
+
+	now := time.Now()
+	session := &entity.UserSession{
+		UserID:              user.ID,
+		Device:              "impersonation",
+		IPAddress:           request.IPAddress,
+		UserAgent:           request.UserAgent,
+		LastSeenAt:          now,
+		ImpersonatorID:      &actorID,
+		ImpersonationReason: request.Reason,
+	}
+	if err := c.UserSessionRepository.Create(tx, session); err != nil {
+		c.Log.Warnf("Failed create session : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	claims := &token.Claims{
+		Role:           user.Role,
+		SessionID:      session.ID.String(),
+		EmailVerified:  user.EmailVerifiedAt != nil,
+		ImpersonatorID: actorID.String(),
+	}
+	claims.Subject = user.ID.String()
+	accessToken, expiresAt, err := c.Tokens.SignImpersonation(claims, now)
+	if err != nil {
+		c.Log.Warnf("Failed sign access token : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionImpersonate, audit.ResourceUser, user.ID.String(), nil, map[string]any{
+		"session_id": session.ID,
+		"reason":     request.Reason,
+		"expires_at": expiresAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	c.Log.Infof("User %s started impersonating user %s until %s: %s", actorID, user.ID, expiresAt, request.Reason)
+	return converter.UserToTokenResponse(accessToken, expiresAt, ""), nil
+}
+
+// RecordImpersonatedRequest adds a request made while impersonating to the audit trail.
+func (c *UserUseCase) RecordImpersonatedRequest(ctx context.Context, request *model.ImpersonatedRequest) error {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return fiber.ErrBadRequest
+	}
+
+	sessionID, err := uuid.Parse(request.SessionID)
+	if err != nil {
+		c.Log.Warnf("Invalid session id : %+v", err)
+		return fiber.ErrBadRequest
+	}
+	impersonatorID, err := uuid.Parse(request.ImpersonatorID)
+	if err != nil {
+		c.Log.Warnf("Invalid impersonator id : %+v", err)
+		return fiber.ErrBadRequest
+	}
+	userID, err := uuid.Parse(request.UserID)
+	if err != nil {
+		c.Log.Warnf("Invalid user id : %+v", err)
+		return fiber.ErrBadRequest
+	}
+	if err := c.ImpersonationRequestRepository.Create(tx, &entity.ImpersonationRequest{
+		SessionID:      &sessionID,
+		ImpersonatorID: &impersonatorID,
+		UserID:         &userID,
+		Method:         request.Method,
+		Path:           request.Path,
+		Status:         request.Status,
+		IPAddress:      request.IPAddress,
+	}); err != nil {
+		c.Log.Warnf("Failed record impersonated request : %+v", err)
+		return fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return fiber.ErrInternalServerError
+	}
+	return nil
+}
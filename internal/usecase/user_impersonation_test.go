@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestImpersonateRefusesStaffAndSelf(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestUserUseCase(t, db)
+	admin := newTestUser(t, db, rbac.RoleAdmin)
+	actor := &model.Auth{ID: admin.ID.String(), Role: admin.Role}
+
+	tests := []struct {
+		name   string
+		target *entity.User
+		want   error
+	}{
+		{"self", admin, ErrImpersonationForbidden},
+		{"another admin", newTestUser(t, db, rbac.RoleAdmin), ErrImpersonationForbidden},
+		{"user", newTestUser(t, db, rbac.RoleUser), nil},
+	}
+	for _, tt := range tests {
+		_, err := c.Impersonate(context.Background(), &model.ImpersonateUserRequest{UserID: tt.target.ID.String(), Actor: actor, Reason: "support ticket"})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("impersonating %s = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestImpersonationExpires(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestUserUseCase(t, db)
+	ctx := context.Background()
+	admin := newTestUser(t, db, rbac.RoleAdmin)
+	user := newTestUser(t, db, rbac.RoleUser)
+	request := &model.ImpersonateUserRequest{UserID: user.ID.String(), Actor: &model.Auth{ID: admin.ID.String(), Role: admin.Role}, Reason: "support ticket"}
+
+	c.Tokens.ImpersonationTTL = time.Minute
+	started := time.Now()
+	response, err := c.Impersonate(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The token lasts the impersonation TTL and comes with no refresh token to extend it
+	if response.ExpiresAt == nil || response.ExpiresAt.Before(started.Add(time.Minute).Truncate(time.Second)) || response.ExpiresAt.After(time.Now().Add(time.Minute)) {
+		t.Errorf("expires at %v, want a minute after %v", response.ExpiresAt, started)
+	}
+	if response.RefreshToken != "" {
+		t.Error("impersonation issued a refresh token")
+	}
+	auth, err := c.Verify(ctx, &model.VerifyUserRequest{Token: response.Token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.ID != user.ID.String() || auth.ImpersonatorID != admin.ID.String() || auth.Role != rbac.RoleUser {
+		t.Errorf("impersonating auth = %+v, want the user impersonated by the admin", auth)
+	}
+
+	// A token past its TTL is refused
+	c.Tokens.ImpersonationTTL = -time.Minute
+	response, err = c.Impersonate(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Verify(ctx, &model.VerifyUserRequest{Token: response.Token}); !errors.Is(err, fiber.ErrUnauthorized) {
+		t.Errorf("verifying an expired impersonation = %v, want 401", err)
+	}
+}
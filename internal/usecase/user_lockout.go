@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fp-designpattern/internal/audit"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/lockout"
+	"fp-designpattern/internal/model"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrLoginLocked = fiber.NewError(fiber.StatusTooManyRequests, "too many failed logins, try again later")
+
+// loginScopes are the throttles a login from the IP address to the email counts against.
+func loginScopes(email string, ipAddress string) map[string]string {
+	scopes := map[string]string{lockout.ScopeAccount: strings.ToLower(strings.TrimSpace(email))}
+	if ipAddress != "" {
+		scopes[lockout.ScopeIP] = ipAddress
+	}
+	return scopes
+}
+
+// checkLockout fails with ErrLoginLocked while the account or the IP address is locked out.
+// It does not lock the throttles, so logins from one school network are not serialized.
+func (c *UserUseCase) checkLockout(tx *gorm.DB, email string, ipAddress string, now time.Time) error {
+	for scope, key := range loginScopes(email, ipAddress) {
+		throttle := new(entity.LoginThrottle)
+		err := c.LoginThrottleRepository.FindByKey(tx, throttle, scope, key)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			c.Log.Warnf("Failed find login throttle : %+v", err)
+			return fiber.ErrInternalServerError
+		}
+		if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+			c.Log.Warnf("Login of %s %s rejected, locked out until %s", scope, key, throttle.LockedUntil)
+			return ErrLoginLocked
+		}
+	}
+	return nil
+}
+
+// loginFailed counts a failed login against the account and the IP address, locking out the ones
+// over their threshold, and commits so the failure is kept although the login is rejected.
+func (c *UserUseCase) loginFailed(tx *gorm.DB, email string, ipAddress string, userID *uuid.UUID, now time.Time) error {
+	// Account before IP address, so two failures never wait on each other's throttle
+	for _, scope := range []string{lockout.ScopeAccount, lockout.ScopeIP} {
+		key, ok := loginScopes(email, ipAddress)[scope]
+		if !ok {
+			continue
+		}
+		throttle := new(entity.LoginThrottle)
+		if err := c.LoginThrottleRepository.FindForUpdate(tx, throttle, scope, key); err != nil {
+			c.Log.Warnf("Failed lock login throttle : %+v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		policy := c.LoginLimiter.Policy(scope)
+		throttle.Failures = policy.Failures(throttle.Failures, throttle.LastFailureAt, throttle.LockedUntil, now)
+		throttle.LastFailureAt = now
+		if delay := policy.Delay(throttle.Failures); delay > 0 {
+			lockedUntil := now.Add(delay)
+			throttle.LockedUntil = &lockedUntil
+			event := &entity.LoginLockoutEvent{
+				Event:       lockout.EventLocked,
+				Scope:       scope,
+				Key:         key,
+				Failures:    throttle.Failures,
+				LockedUntil: &lockedUntil,
+				IPAddress:   ipAddress,
+			}
+			if scope == lockout.ScopeAccount {
+				event.UserID = userID
+			}
+			if err := c.LoginLockoutEventRepository.Create(tx, event); err != nil {
+				c.Log.Warnf("Failed record lockout : %+v", err)
+				return fiber.ErrInternalServerError
+			}
+			c.Log.Warnf("Locked out %s %s for %s after %d failed logins", scope, key, delay, throttle.Failures)
+		}
+		if err := c.LoginThrottleRepository.Update(tx, throttle); err != nil {
+			c.Log.Warnf("Failed update login throttle : %+v", err)
+			return fiber.ErrInternalServerError
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return fiber.ErrInternalServerError
+	}
+	return fiber.ErrUnauthorized
+}
+
+// loginSucceeded forgets the failures of the account. Those of the IP address are kept, else a
+// valid account would let its owner keep guessing the passwords of others.
+func (c *UserUseCase) loginSucceeded(tx *gorm.DB, email string) error {
+	_, err := c.LoginThrottleRepository.DeleteByKey(tx, lockout.ScopeAccount, loginScopes(email, "")[lockout.ScopeAccount])
+	return err
+}
+
+// UnlockLogin lets an admin lift the lockout of a user's account before it runs out.
+func (c *UserUseCase) UnlockLogin(ctx context.Context, request *model.UnlockUserRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return false, fiber.ErrNotFound
+	}
+	actorID, err := uuid.Parse(request.Actor.ID)
+	if err != nil {
+		c.Log.Warnf("Invalid actor id : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	key := loginScopes(user.Email, "")[lockout.ScopeAccount]
+	total, err := c.LoginThrottleRepository.DeleteByKey(tx, lockout.ScopeAccount, key)
+	if err != nil {
+		c.Log.Warnf("Failed delete login throttle : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	if total > 0 {
+		if err := c.LoginLockoutEventRepository.Create(tx, &entity.LoginLockoutEvent{
+			UserID:  &user.ID,
+			ActorID: &actorID,
+			Event:   lockout.EventUnlocked,
+			Scope:   lockout.ScopeAccount,
+			Key:     key,
+		}); err != nil {
+			c.Log.Warnf("Failed record unlock : %+v", err)
+			return false, fiber.ErrInternalServerError
+		}
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionUnlock, audit.ResourceUser, user.ID.String(), nil, map[string]bool{"unlocked": total > 0}); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	return total > 0, nil
+}
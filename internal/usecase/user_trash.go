@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"context"
+	"fp-designpattern/internal/audit"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var ErrRestoreEmailTaken = fiber.NewError(fiber.StatusConflict, "another user has registered the email of this user")
+
+// Trash lists the deleted users that are not purged yet.
+func (c *UserUseCase) Trash(ctx context.Context, request *model.SearchTrashRequest) ([]model.UserResponse, int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.WithError(err).Warnf("Invalid request body")
+		return nil, 0, fiber.ErrBadRequest
+	}
+	users, total, err := c.UserRepository.SearchDeleted(tx, request)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to search deleted users")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.WithError(err).Error("Failed to commit transaction")
+		return nil, 0, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.UserResponse, len(users))
+	for i, user := range users {
+		response, err := c.render(ctx, &user)
+		if err != nil {
+			return nil, 0, err
+		}
+		responses[i] = *response
+	}
+	return responses, total, nil
+}
+
+// Restore takes a deleted user out of the trash. Their sessions stay revoked, they log in again.
+func (c *UserUseCase) Restore(ctx context.Context, request *model.RestoreUserRequest) (*model.UserResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindDeletedById(tx, user, request.ID); err != nil {
+		c.Log.Warnf("Failed find deleted user by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	total, err := c.UserRepository.CountByEmail(tx, user.Email)
+	if err != nil {
+		c.Log.Warnf("Failed count user by email : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if total > 0 {
+		c.Log.Warnf("Email of deleted user %s is registered again", user.ID)
+		return nil, ErrRestoreEmailTaken
+	}
+
+	before := audit.Snapshot(converter.UserToResponse(user))
+	if err := c.UserRepository.Restore(tx, user); err != nil {
+		c.Log.Warnf("Failed restore user : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionRestore, audit.ResourceUser, user.ID.String(), before, converter.UserToResponse(user)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return c.render(ctx, user)
+}
@@ -0,0 +1,371 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fp-designpattern/internal/audit"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/totp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount = 10
+	// recoveryCodeLength is the length of a recovery code without its dash, 5 random bytes in base32.
+	recoveryCodeLength = 8
+	// challengeAttempts is how many codes a challenge takes before the user has to sign in again.
+	challengeAttempts = 5
+)
+
+var (
+	ErrTwoFactorEnabled     = fiber.NewError(fiber.StatusConflict, "two-factor authentication is already enabled")
+	ErrTwoFactorDisabled    = fiber.NewError(fiber.StatusConflict, "two-factor authentication is not enabled")
+	ErrTwoFactorCodeInvalid = fiber.NewError(fiber.StatusUnauthorized, "two-factor code is invalid")
+)
+
+// EnrollTwoFactor generates a new secret for the user to add to an authenticator app,
+// it only takes effect once ConfirmTwoFactor receives a code generated from it.
+func (c *UserUseCase) EnrollTwoFactor(ctx context.Context, request *model.EnrollTwoFactorRequest) (*model.TwoFactorEnrollResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.Log.Warnf("Failed generate totp secret : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := c.UserRepository.Update(tx, user); err != nil {
+		c.Log.Warnf("Failed update user : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return &model.TwoFactorEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(secret, c.Tokens.Issuer, user.Email),
+	}, nil
+}
+
+// ConfirmTwoFactor turns two-factor authentication on and returns the recovery codes, the only time
+// they are shown. The session confirming it proved the second factor, so it counts as signed in with it.
+func (c *UserUseCase) ConfirmTwoFactor(ctx context.Context, request *model.ConfirmTwoFactorRequest) (*model.TwoFactorRecoveryCodesResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindByIdForUpdate(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, fiber.NewError(fiber.StatusConflict, "two-factor enrollment was not started")
+	}
+
+	now := time.Now()
+	if !c.checkTOTP(user, request.Code, now) {
+		c.Log.Warnf("Invalid totp code confirming enrollment of user %s", user.ID)
+		return nil, ErrTwoFactorCodeInvalid
+	}
+	user.TOTPEnabledAt = &now
+	if err := c.UserRepository.Update(tx, user); err != nil {
+		c.Log.Warnf("Failed update user : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	codes, err := c.replaceRecoveryCodes(tx, user)
+	if err != nil {
+		c.Log.Warnf("Failed create recovery codes : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	session := new(entity.UserSession)
+	if err := c.UserSessionRepository.FindActiveByIdAndUserId(tx, session, request.SessionID, request.UserID); err != nil {
+		c.Log.Warnf("Failed find session by id : %+v", err)
+		return nil, fiber.ErrUnauthorized
+	}
+	session.MFAVerified = true
+	if err := c.UserSessionRepository.Update(tx, session); err != nil {
+		c.Log.Warnf("Failed update session : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return &model.TwoFactorRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (c *UserUseCase) DisableTwoFactor(ctx context.Context, request *model.DisableTwoFactorRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindByIdForUpdate(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return false, fiber.ErrNotFound
+	}
+	if user.TOTPEnabledAt == nil {
+		return false, ErrTwoFactorDisabled
+	}
+
+	ok, err := c.checkSecondFactor(tx, user, request.Code, time.Now())
+	if err != nil {
+		c.Log.Warnf("Failed check second factor : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	if !ok {
+		c.Log.Warnf("Invalid second factor disabling two-factor of user %s", user.ID)
+		return false, ErrTwoFactorCodeInvalid
+	}
+
+	if err := c.clearTwoFactor(tx, user); err != nil {
+		c.Log.Warnf("Failed disable two-factor : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	return true, nil
+}
+
+// ResetTwoFactor lets an admin turn off two-factor authentication for a user who lost both
+// their authenticator and their recovery codes.
+func (c *UserUseCase) ResetTwoFactor(ctx context.Context, request *model.ResetTwoFactorRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return false, fiber.ErrNotFound
+	}
+	before := audit.Snapshot(converter.UserToResponse(user))
+	if err := c.clearTwoFactor(tx, user); err != nil {
+		c.Log.Warnf("Failed reset two-factor : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionResetTwoFactor, audit.ResourceUser, user.ID.String(), before, converter.UserToResponse(user)); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	return true, nil
+}
+
+// LoginTwoFactor is the second login step, it exchanges the challenge from Login and a TOTP or
+// recovery code for a session.
+func (c *UserUseCase) LoginTwoFactor(ctx context.Context, request *model.LoginTwoFactorRequest) (*model.UserResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	userID, challengeID, err := c.Tokens.ParseChallenge(request.Challenge)
+	if err != nil {
+		c.Log.Warnf("Failed parse challenge : %+v", err)
+		return nil, fiber.ErrUnauthorized
+	}
+	user := new(entity.User)
+	if err := c.UserRepository.FindByIdForUpdate(tx, user, userID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return nil, fiber.ErrUnauthorized
+	}
+	if user.TOTPEnabledAt == nil {
+		c.Log.Warnf("Challenge for user %s without two-factor", user.ID)
+		return nil, fiber.ErrUnauthorized
+	}
+	// Only the last challenge issued counts, and only until it is exchanged or out of attempts
+	if user.MFAChallengeID == "" || user.MFAChallengeID != challengeID {
+		c.Log.Warnf("Challenge for user %s was used or replaced", user.ID)
+		return nil, fiber.ErrUnauthorized
+	}
+	now := time.Now()
+	if err := c.checkLockout(tx, user.Email, request.IPAddress, now); err != nil {
+		return nil, err
+	}
+
+	ok, err := c.checkSecondFactor(tx, user, request.Code, now)
+	if err != nil {
+		c.Log.Warnf("Failed check second factor : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if !ok {
+		c.Log.Warnf("Invalid second factor for user %s", user.ID)
+		user.MFAChallengeAttempts++
+		if user.MFAChallengeAttempts >= challengeAttempts {
+			user.MFAChallengeID = ""
+		}
+		if err := c.UserRepository.Update(tx, user); err != nil {
+			c.Log.Warnf("Failed update user : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		if err := c.loginFailed(tx, user.Email, request.IPAddress, &user.ID, now); err != fiber.ErrUnauthorized {
+			return nil, err
+		}
+		return nil, ErrTwoFactorCodeInvalid
+	}
+	user.MFAChallengeID = ""
+	user.MFAChallengeAttempts = 0
+	if err := c.UserRepository.Update(tx, user); err != nil {
+		c.Log.Warnf("Failed update user : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.loginSucceeded(tx, user.Email); err != nil {
+		c.Log.Warnf("Failed reset login throttle : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	response, err := c.startSession(tx, user, &model.LoginUserRequest{
+		Device:    request.Device,
+		IPAddress: request.IPAddress,
+		UserAgent: request.UserAgent,
+	}, true)
+	if err != nil {
+		c.Log.Warnf("Failed start session : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return response, nil
+}
+
+// issueChallenge signs a challenge for the user and stores its id, replacing any earlier challenge.
+func (c *UserUseCase) issueChallenge(tx *gorm.DB, user *entity.User, now time.Time) (string, error) {
+	user.MFAChallengeID = uuid.NewString()
+	user.MFAChallengeAttempts = 0
+	if err := c.UserRepository.Update(tx, user); err != nil {
+		return "", err
+	}
+	return c.Tokens.SignChallenge(user.ID.String(), user.MFAChallengeID, now)
+}
+
+// checkTOTP accepts a code of the current step or one step either side, but never a step at or
+// before the last accepted one. The caller saves the user to record the step.
+func (c *UserUseCase) checkTOTP(user *entity.User, code string, now time.Time) bool {
+	step, ok := totp.Validate(user.TOTPSecret, code, now, 1)
+	if !ok || step <= user.TOTPLastStep {
+		return false
+	}
+	user.TOTPLastStep = step
+	return true
+}
+
+// checkSecondFactor accepts a TOTP code or an unused recovery code, which is then used up.
+func (c *UserUseCase) checkSecondFactor(tx *gorm.DB, user *entity.User, code string, now time.Time) (bool, error) {
+	if c.checkTOTP(user, code, now) {
+		return true, c.UserRepository.Update(tx, user)
+	}
+
+	// Anything that cannot be a recovery code is not worth a bcrypt comparison per code
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return false, nil
+	}
+	codes, err := c.RecoveryCodeRepository.FindUnusedByUserId(tx, user.ID.String())
+	if err != nil {
+		return false, err
+	}
+	for _, recoveryCode := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(recoveryCode.CodeHash), []byte(normalized)) != nil {
+			continue
+		}
+		recoveryCode.UsedAt = &now
+		return true, c.RecoveryCodeRepository.Update(tx, &recoveryCode)
+	}
+	return false, nil
+}
+
+// replaceRecoveryCodes drops the recovery codes of the user and returns a fresh set.
+func (c *UserUseCase) replaceRecoveryCodes(tx *gorm.DB, user *entity.User) ([]string, error) {
+	if err := c.RecoveryCodeRepository.DeleteByUserId(tx, user.ID.String()); err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buffer := make([]byte, 5)
+		if _, err := rand.Read(buffer); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(buffer))
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.RecoveryCodeRepository.Create(tx, &entity.UserRecoveryCode{UserID: user.ID, CodeHash: string(hash)}); err != nil {
+			return nil, err
+		}
+		codes[i] = code[:4] + "-" + code[4:]
+	}
+	return codes, nil
+}
+
+func (c *UserUseCase) clearTwoFactor(tx *gorm.DB, user *entity.User) error {
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	if err := c.UserRepository.Update(tx, user); err != nil {
+		return err
+	}
+	return c.RecoveryCodeRepository.DeleteByUserId(tx, user.ID.String())
+}
+
+// normalizeRecoveryCode lets users type recovery codes without the dash or in upper case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -2,36 +2,95 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"fp-designpattern/internal/audit"
 	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/identity"
+	"fp-designpattern/internal/lockout"
+	"fp-designpattern/internal/mailer"
 	"fp-designpattern/internal/model"
 	"fp-designpattern/internal/model/converter"
+	"fp-designpattern/internal/rbac"
 	"fp-designpattern/internal/repository"
+	"fp-designpattern/internal/token"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type UserUseCase struct {
-	DB             *gorm.DB
-	Log            *logrus.Logger
-	Validate       *validator.Validate
-	UserRepository *repository.UserRepository
+	DB                     *gorm.DB
+	Log                    *logrus.Logger
+	Validate               *validator.Validate
+	UserRepository         *repository.UserRepository
+	RefreshTokenRepository *repository.RefreshTokenRepository
+	UserSessionRepository  *repository.UserSessionRepository
+	RecoveryCodeRepository *repository.UserRecoveryCodeRepository
+	UserIdentityRepository *repository.UserIdentityRepository
+	APIKeyRepository       *repository.APIKeyRepository
+	// PasswordResetTokenRepository is used to void the reset tokens of users moved to the trash.
+	PasswordResetTokenRepository *repository.PasswordResetTokenRepository
+	// ImpersonationRequestRepository keeps the requests admins make while impersonating users.
+	ImpersonationRequestRepository *repository.ImpersonationRequestRepository
+	// MediaAssetRepository keeps the reference counts of avatars up to date, FileStorage signs their URLs.
+	MediaAssetRepository *repository.MediaAssetRepository
+	FileStorage          repository.FileStorage
+	// LoginThrottleRepository and LoginLimiter lock out accounts and IP addresses after failed logins.
+	LoginThrottleRepository     *repository.LoginThrottleRepository
+	LoginLockoutEventRepository *repository.LoginLockoutEventRepository
+	LoginLimiter                *lockout.Limiter
+	Tokens                      *token.Manager
+	IdentityProviders           *identity.Registry
+	Policy                      *rbac.Policy
+	Auditor                     *Auditor
+	Mailer                      mailer.Mailer
+	// VerifyEmailURL is the page verification mails link to with ?token= added.
+	VerifyEmailURL string
 }
 
-func NewUserUseCase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, userRepository *repository.UserRepository) *UserUseCase {
+var ErrVerificationTokenInvalid = fiber.NewError(fiber.StatusBadRequest, "verification link is invalid or expired")
+
+func NewUserUseCase(db *gorm.DB, log *logrus.Logger, validate *validator.Validate, userRepository *repository.UserRepository,
+	refreshTokenRepository *repository.RefreshTokenRepository, userSessionRepository *repository.UserSessionRepository,
+	recoveryCodeRepository *repository.UserRecoveryCodeRepository, userIdentityRepository *repository.UserIdentityRepository, apiKeyRepository *repository.APIKeyRepository,
+	passwordResetTokenRepository *repository.PasswordResetTokenRepository, impersonationRequestRepository *repository.ImpersonationRequestRepository, mediaAssetRepository *repository.MediaAssetRepository, fileStorage repository.FileStorage,
+	loginThrottleRepository *repository.LoginThrottleRepository, loginLockoutEventRepository *repository.LoginLockoutEventRepository, loginLimiter *lockout.Limiter,
+	tokens *token.Manager, identityProviders *identity.Registry, policy *rbac.Policy, auditor *Auditor, mailer mailer.Mailer, verifyEmailURL string) *UserUseCase {
 	return &UserUseCase{
-		DB:             db,
-		Log:            log,
-		Validate:       validate,
-		UserRepository: userRepository,
+		DB:                             db,
+		Log:                            log,
+		Validate:                       validate,
+		UserRepository:                 userRepository,
+		RefreshTokenRepository:         refreshTokenRepository,
+		UserSessionRepository:          userSessionRepository,
+		RecoveryCodeRepository:         recoveryCodeRepository,
+		UserIdentityRepository:         userIdentityRepository,
+		APIKeyRepository:               apiKeyRepository,
+		PasswordResetTokenRepository:   passwordResetTokenRepository,
+		ImpersonationRequestRepository: impersonationRequestRepository,
+		MediaAssetRepository:           mediaAssetRepository,
+		FileStorage:                    fileStorage,
+		LoginThrottleRepository:        loginThrottleRepository,
+		LoginLockoutEventRepository:    loginLockoutEventRepository,
+		LoginLimiter:                   loginLimiter,
+		Tokens:                         tokens,
+		IdentityProviders:              identityProviders,
+		Policy:                         policy,
+		Auditor:                        auditor,
+		Mailer:                         mailer,
+		VerifyEmailURL:                 verifyEmailURL,
 	}
 }
 
+// Verify checks a signed access token and records activity on its session, rejecting revoked sessions.
+// API keys are accepted in place of access tokens.
 func (c *UserUseCase) Verify(ctx context.Context, request *model.VerifyUserRequest) (*model.Auth, error) {
 	tx := c.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
@@ -41,16 +100,24 @@ func (c *UserUseCase) Verify(ctx context.Context, request *model.VerifyUserReque
 		c.Log.Warnf("Invalid request body : %+v", err)
 		return nil, fiber.ErrBadRequest
 	}
+	if strings.HasPrefix(request.Token, token.APIKeyPrefix) {
+		return c.verifyAPIKey(tx, request.Token)
+	}
 
-	user := new(entity.User)
-	if err := c.UserRepository.FindByToken(tx, user, request.Token); err != nil {
-		c.Log.Warnf("Failed find user by token : %+v", err)
-		return nil, fiber.ErrNotFound
+	claims, err := c.Tokens.Parse(request.Token)
+	if err != nil {
+		c.Log.Warnf("Failed parse access token : %+v", err)
+		return nil, fiber.ErrUnauthorized
 	}
 
-	auth := &model.Auth{
-		ID:   user.ID.String(),
-		Role: user.Role,
+	touched, err := c.UserSessionRepository.Touch(tx, claims.SessionID, claims.Subject, time.Now())
+	if err != nil {
+		c.Log.Warnf("Failed touch session : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if touched == 0 {
+		c.Log.Warnf("Session %s of user %s is revoked", claims.SessionID, claims.Subject)
+		return nil, fiber.ErrUnauthorized
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -58,7 +125,14 @@ func (c *UserUseCase) Verify(ctx context.Context, request *model.VerifyUserReque
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return auth, nil
+	return &model.Auth{
+		ID:             claims.Subject,
+		Role:           claims.Role,
+		SessionID:      claims.SessionID,
+		EmailVerified:  claims.EmailVerified,
+		MFA:            claims.MFA,
+		ImpersonatorID: claims.ImpersonatorID,
+	}, nil
 }
 
 func (c *UserUseCase) Create(ctx context.Context, request *model.RegisterUserRequest) (*model.UserResponse, error) {
@@ -105,7 +179,119 @@ func (c *UserUseCase) Create(ctx context.Context, request *model.RegisterUserReq
 		c.Log.Warnf("Failed to commit transaction: %v", err)
 		return nil, fiber.ErrInternalServerError
 	}
-	return converter.UserToResponse(user), nil
+
+	if err := c.sendVerification(ctx, user); err != nil {
+		c.Log.Errorf("Failed to send verification mail to user %s: %v", user.ID, err)
+	}
+	return c.render(ctx, user)
+}
+
+// sendVerification mails the user a signed link that verifies their current email.
+func (c *UserUseCase) sendVerification(ctx context.Context, user *entity.User) error {
+	raw, err := c.Tokens.SignVerification(user.ID.String(), user.Email, time.Now())
+	if err != nil {
+		return err
+	}
+	instructions := "Use this token to verify your email address: " + raw
+	if c.VerifyEmailURL != "" {
+		instructions = "Open this link to verify your email address: " + c.VerifyEmailURL + "?token=" + url.QueryEscape(raw)
+	}
+	return c.Mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\n%s\n\nThe link expires in %s.\n",
+			user.Username, instructions, c.Tokens.VerificationTTL),
+	})
+}
+
+func (c *UserUseCase) VerifyEmail(ctx context.Context, request *model.VerifyEmailRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	claims, err := c.Tokens.ParseVerification(request.Token)
+	if err != nil {
+		c.Log.Warnf("Failed parse verification token : %+v", err)
+		return false, ErrVerificationTokenInvalid
+	}
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, claims.Subject); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return false, ErrVerificationTokenInvalid
+	}
+	// The link only verifies the email it was sent to, a changed email needs a new link
+	if user.Email != claims.Email {
+		c.Log.Warnf("Verification token of user %s was sent to a previous email", user.ID)
+		return false, ErrVerificationTokenInvalid
+	}
+	if user.EmailVerifiedAt != nil {
+		return true, nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := c.UserRepository.Update(tx, user); err != nil {
+		c.Log.Warnf("Failed update user : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	return true, nil
+}
+
+func (c *UserUseCase) ResendVerification(ctx context.Context, request *model.ResendVerificationRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return false, fiber.ErrNotFound
+	}
+	if user.EmailVerifiedAt != nil {
+		return false, fiber.NewError(fiber.StatusConflict, "email is already verified")
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+
+	if err := c.sendVerification(ctx, user); err != nil {
+		c.Log.Warnf("Failed send verification mail : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	return true, nil
+}
+
+// EmailVerified reads whether the user has verified their email, for tokens issued before they did.
+func (c *UserUseCase) EmailVerified(ctx context.Context, request *model.GetUserRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.ID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return false, fiber.ErrNotFound
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
+	return user.EmailVerifiedAt != nil, nil
 }
 
 func (c *UserUseCase) Login(ctx context.Context, request *model.LoginUserRequest) (*model.UserResponse, error) {
@@ -116,20 +302,128 @@ func (c *UserUseCase) Login(ctx context.Context, request *model.LoginUserRequest
 		return nil, fiber.ErrBadRequest
 	}
 
+	now := time.Now()
+	if err := c.checkLockout(tx, request.Email, request.IPAddress, now); err != nil {
+		return nil, err
+	}
+
 	user := new(entity.User)
 	if err := c.UserRepository.FindByEmail(tx, user, request.Email); err != nil {
 		c.Log.Warnf("Failed find user by email : %+v", err)
-		return nil, fiber.ErrUnauthorized
+		return nil, c.loginFailed(tx, request.Email, request.IPAddress, nil, now)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
 		c.Log.Warnf("Failed compare password : %+v", err)
+		return nil, c.loginFailed(tx, request.Email, request.IPAddress, &user.ID, now)
+	}
+
+	// Users with two-factor authentication get a challenge to exchange with a code for the session
+	if user.TOTPEnabledAt != nil {
+		challenge, err := c.issueChallenge(tx, user, now)
+		if err != nil {
+			c.Log.Warnf("Failed issue challenge : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		if err := tx.Commit().Error; err != nil {
+			c.Log.Warnf("Failed commit transaction : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		return converter.UserToChallengeResponse(challenge), nil
+	}
+
+	if err := c.loginSucceeded(tx, request.Email); err != nil {
+		c.Log.Warnf("Failed reset login throttle : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	response, err := c.startSession(tx, user, request, false)
+	if err != nil {
+		c.Log.Warnf("Failed start session : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return response, nil
+}
+
+// startSession records the device signing in and issues its first tokens.
+func (c *UserUseCase) startSession(tx *gorm.DB, user *entity.User, request *model.LoginUserRequest, mfa bool) (*model.UserResponse, error) {
+	session := &entity.UserSession{
+		UserID:      user.ID,
+		Device:      request.Device,
+		IPAddress:   request.IPAddress,
+		UserAgent:   request.UserAgent,
+		LastSeenAt:  time.Now(),
+		MFAVerified: mfa,
+	}
+	if err := c.UserSessionRepository.Create(tx, session); err != nil {
+		return nil, err
+	}
+	return c.issueTokens(tx, user, session, session.LastSeenAt)
+}
+
+// Refresh rotates a refresh token: the presented token is revoked and a new pair is issued for its session.
+// A token that was already rotated is being replayed, so its whole session is revoked.
+func (c *UserUseCase) Refresh(ctx context.Context, request *model.RefreshTokenRequest) (*model.UserResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	now := time.Now()
+	refreshToken := new(entity.RefreshToken)
+	if err := c.RefreshTokenRepository.FindByHashForUpdate(tx, refreshToken, token.Hash(request.RefreshToken)); err != nil {
+		c.Log.Warnf("Failed find refresh token : %+v", err)
 		return nil, fiber.ErrUnauthorized
 	}
 
-	user.Token = uuid.New().String()
-	if err := c.UserRepository.Update(tx, user); err != nil {
-		c.Log.Warnf("Failed update user : %+v", err)
+	session := &refreshToken.Session
+	if session.RevokedAt != nil {
+		c.Log.Warnf("Session %s of user %s is revoked", session.ID, session.UserID)
+		return nil, fiber.ErrUnauthorized
+	}
+	if refreshToken.RevokedAt != nil {
+		c.Log.Warnf("Refresh token reused, revoking session %s of user %s", session.ID, session.UserID)
+		session.RevokedAt = &now
+		if err := c.UserSessionRepository.Update(tx, session); err != nil {
+			c.Log.Warnf("Failed revoke session : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		if err := tx.Commit().Error; err != nil {
+			c.Log.Warnf("Failed commit transaction : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		return nil, fiber.ErrUnauthorized
+	}
+	if !now.Before(refreshToken.ExpiresAt) {
+		c.Log.Warnf("Refresh token expired at %s", refreshToken.ExpiresAt)
+		return nil, fiber.ErrUnauthorized
+	}
+
+	refreshToken.RevokedAt = &now
+	if err := c.RefreshTokenRepository.Update(tx, refreshToken); err != nil {
+		c.Log.Warnf("Failed revoke refresh token : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	session.LastSeenAt = now
+	if request.IPAddress != "" {
+		session.IPAddress = request.IPAddress
+	}
+	if err := c.UserSessionRepository.Update(tx, session); err != nil {
+		c.Log.Warnf("Failed update session : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	response, err := c.issueTokens(tx, &refreshToken.User, session, now)
+	if err != nil {
+		c.Log.Warnf("Failed issue tokens : %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
 
@@ -138,7 +432,37 @@ func (c *UserUseCase) Login(ctx context.Context, request *model.LoginUserRequest
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.UserToTokenResponse(user), nil
+	return response, nil
+}
+
+// issueTokens stores a new refresh token for the session and signs an access token bound to it.
+func (c *UserUseCase) issueTokens(tx *gorm.DB, user *entity.User, session *entity.UserSession, now time.Time) (*model.UserResponse, error) {
+	raw, hash, err := token.NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken := &entity.RefreshToken{
+		UserID:    user.ID,
+		SessionID: session.ID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(c.Tokens.RefreshTTL),
+	}
+	if err := c.RefreshTokenRepository.Create(tx, refreshToken); err != nil {
+		return nil, err
+	}
+
+	claims := &token.Claims{
+		Role:          user.Role,
+		SessionID:     session.ID.String(),
+		EmailVerified: user.EmailVerifiedAt != nil,
+		MFA:           session.MFAVerified,
+	}
+	claims.Subject = user.ID.String()
+	accessToken, expiresAt, err := c.Tokens.Sign(claims, now)
+	if err != nil {
+		return nil, err
+	}
+	return converter.UserToTokenResponse(accessToken, expiresAt, raw), nil
 }
 
 func (c *UserUseCase) Current(ctx context.Context, request *model.GetUserRequest) (*model.UserResponse, error) {
@@ -159,7 +483,7 @@ func (c *UserUseCase) Current(ctx context.Context, request *model.GetUserRequest
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.UserToResponse(user), nil
+	return c.render(ctx, user)
 
 }
 
@@ -180,7 +504,7 @@ func (c *UserUseCase) Get(ctx context.Context, request *model.GetUserRequest) (*
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.UserToResponse(user), nil
+	return c.render(ctx, user)
 }
 
 func (c *UserUseCase) Logout(ctx context.Context, request *model.LogoutUserRequest) (bool, error) {
@@ -191,26 +515,161 @@ func (c *UserUseCase) Logout(ctx context.Context, request *model.LogoutUserReque
 		return false, fiber.ErrBadRequest
 	}
 
-	user := new(entity.User)
-	if err := c.UserRepository.FindById(tx, user, request.ID); err != nil {
-		c.Log.Warnf("Failed find user by id : %+v", err)
+	session := new(entity.UserSession)
+	if err := c.UserSessionRepository.FindActiveByIdAndUserId(tx, session, request.SessionID, request.ID); err != nil {
+		c.Log.Warnf("Failed find session by id : %+v", err)
 		return false, fiber.ErrNotFound
 	}
-	user.Token = ""
+	now := time.Now()
+	session.RevokedAt = &now
+	if err := c.UserSessionRepository.Update(tx, session); err != nil {
+		c.Log.Warnf("Failed revoke session : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
 
-	if err := c.UserRepository.Update(tx, user); err != nil {
-		c.Log.Warnf("Failed update user : %+v", err)
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
 		return false, fiber.ErrInternalServerError
 	}
 
+	return true, nil
+}
+
+func (c *UserUseCase) Sessions(ctx context.Context, request *model.ListUserSessionRequest) ([]model.UserSessionResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+
+	sessions, err := c.UserSessionRepository.FindActiveByUserId(tx, request.UserID)
+	if err != nil {
+		c.Log.Warnf("Failed find sessions : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
 	if err := tx.Commit().Error; err != nil {
 		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	responses := make([]model.UserSessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = *converter.UserSessionToResponse(&session, request.SessionID)
+	}
+	return responses, nil
+}
+
+func (c *UserUseCase) RevokeSession(ctx context.Context, request *model.RevokeUserSessionRequest) (bool, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return false, fiber.ErrBadRequest
+	}
+
+	session := new(entity.UserSession)
+	if err := c.UserSessionRepository.FindActiveByIdAndUserId(tx, session, request.ID, request.UserID); err != nil {
+		c.Log.Warnf("Failed find session by id : %+v", err)
+		return false, fiber.ErrNotFound
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	if err := c.UserSessionRepository.Update(tx, session); err != nil {
+		c.Log.Warnf("Failed revoke session : %+v", err)
 		return false, fiber.ErrInternalServerError
 	}
 
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return false, fiber.ErrInternalServerError
+	}
 	return true, nil
 }
 
+// RevokeAllSessions signs the user out on every device and revokes their API keys.
+func (c *UserUseCase) RevokeAllSessions(ctx context.Context, request *model.RevokeAllUserSessionRequest) (int64, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return 0, fiber.ErrBadRequest
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.UserID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return 0, fiber.ErrNotFound
+	}
+	now := time.Now()
+	revoked, err := c.UserSessionRepository.RevokeByUserId(tx, request.UserID, now)
+	if err != nil {
+		c.Log.Warnf("Failed revoke sessions : %+v", err)
+		return 0, fiber.ErrInternalServerError
+	}
+	revokedKeys, err := c.APIKeyRepository.RevokeByUserId(tx, request.UserID, now)
+	if err != nil {
+		c.Log.Warnf("Failed revoke api keys : %+v", err)
+		return 0, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionRevokeSessions, audit.ResourceUser, user.ID.String(), nil, map[string]int64{"revoked_sessions": revoked, "revoked_api_keys": revokedKeys}); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return 0, fiber.ErrInternalServerError
+	}
+	return revoked, nil
+}
+
+// SetRole changes the role of a user and revokes their sessions and API keys, so tokens carrying
+// the old role and keys scoped under it stop working at once.
+func (c *UserUseCase) SetRole(ctx context.Context, request *model.UpdateUserRoleRequest) (*model.UserResponse, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	if err := c.Validate.Struct(request); err != nil {
+		c.Log.Warnf("Invalid request body : %+v", err)
+		return nil, fiber.ErrBadRequest
+	}
+	if !c.Policy.HasRole(request.Role) {
+		c.Log.Warnf("Unknown role %s", request.Role)
+		return nil, fiber.NewError(fiber.StatusBadRequest, "role is unknown")
+	}
+
+	user := new(entity.User)
+	if err := c.UserRepository.FindById(tx, user, request.ID); err != nil {
+		c.Log.Warnf("Failed find user by id : %+v", err)
+		return nil, fiber.ErrNotFound
+	}
+	before := audit.Snapshot(converter.UserToResponse(user))
+	if user.Role != request.Role {
+		user.Role = request.Role
+		if err := c.UserRepository.Update(tx, user); err != nil {
+			c.Log.Warnf("Failed update user : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		now := time.Now()
+		if _, err := c.UserSessionRepository.RevokeByUserId(tx, user.ID.String(), now); err != nil {
+			c.Log.Warnf("Failed revoke sessions : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		if _, err := c.APIKeyRepository.RevokeByUserId(tx, user.ID.String(), now); err != nil {
+			c.Log.Warnf("Failed revoke api keys : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+		if err := c.Auditor.Record(tx, request.Actor, audit.ActionSetRole, audit.ResourceUser, user.ID.String(), before, converter.UserToResponse(user)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.Log.Warnf("Failed commit transaction : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return c.render(ctx, user)
+}
+
 func (c *UserUseCase) Search(ctx context.Context, request *model.SearchUserRequest) ([]model.UserResponse, int64, error) {
 	tx := c.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
@@ -230,7 +689,11 @@ func (c *UserUseCase) Search(ctx context.Context, request *model.SearchUserReque
 
 	responses := make([]model.UserResponse, len(users))
 	for i, user := range users {
-		responses[i] = *converter.UserToResponse(&user)
+		response, err := c.render(ctx, &user)
+		if err != nil {
+			return nil, 0, err
+		}
+		responses[i] = *response
 	}
 	return responses, total, nil
 }
@@ -251,13 +714,16 @@ func (c *UserUseCase) Update(ctx context.Context, request *model.UpdateUserReque
 		c.Log.Warnf("Failed find user by id : %+v", err)
 		return nil, fiber.ErrNotFound
 	}
+	before := audit.Snapshot(converter.UserToResponse(user))
 
 	// Update user
 	if request.Username != "" {
 		user.Username = request.Username
 	}
-	if request.Email != "" {
+	emailChanged := request.Email != "" && request.Email != user.Email
+	if emailChanged {
 		user.Email = request.Email
+		user.EmailVerifiedAt = nil
 	}
 	if request.Password != "" {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
@@ -281,18 +747,40 @@ func (c *UserUseCase) Update(ctx context.Context, request *model.UpdateUserReque
 	if request.BirthDate != nil {
 		user.BirthDate = *request.BirthDate
 	}
+	previousAvatarUrl := user.AvatarUrl
+	if request.AvatarUrl != "" {
+		user.AvatarUrl = request.AvatarUrl
+	}
 
 	if err := c.UserRepository.Update(tx, user); err != nil {
 		c.Log.Warnf("Failed update user : %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
+	if user.AvatarUrl != previousAvatarUrl {
+		if err := c.MediaAssetRepository.RecountReferences(tx, []string{previousAvatarUrl, user.AvatarUrl}); err != nil {
+			c.Log.Warnf("Failed recount media asset references : %+v", err)
+			return nil, fiber.ErrInternalServerError
+		}
+	}
+	after := audit.Snapshot(converter.UserToResponse(user))
+	if request.Password != "" {
+		after = audit.Redact(after, "password")
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionUpdate, audit.ResourceUser, user.ID.String(), before, after); err != nil {
+		return nil, err
+	}
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		c.Log.Warnf("Failed to commit transaction : %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.UserToResponse(user), nil
+	if emailChanged {
+		if err := c.sendVerification(ctx, user); err != nil {
+			c.Log.Errorf("Failed to send verification mail to user %s: %v", user.ID, err)
+		}
+	}
+	return c.render(ctx, user)
 }
 
 func (c *UserUseCase) Delete(ctx context.Context, request *model.DeleteUserRequest) (*model.UserResponse, error) {
@@ -312,11 +800,23 @@ func (c *UserUseCase) Delete(ctx context.Context, request *model.DeleteUserReque
 		return nil, fiber.ErrNotFound
 	}
 
-	// Delete user
+	// Move user to the trash, signed out everywhere and with no reset token left to redeem
+	now := time.Now()
 	if err := c.UserRepository.Delete(tx, user); err != nil {
 		c.Log.Warnf("Failed delete user : %+v", err)
 		return nil, fiber.ErrInternalServerError
 	}
+	if _, err := c.UserSessionRepository.RevokeByUserId(tx, user.ID.String(), now); err != nil {
+		c.Log.Warnf("Failed revoke sessions : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.PasswordResetTokenRepository.InvalidateByUserId(tx, user.ID.String(), now); err != nil {
+		c.Log.Warnf("Failed invalidate reset tokens : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	if err := c.Auditor.Record(tx, request.Actor, audit.ActionDelete, audit.ResourceUser, user.ID.String(), converter.UserToResponse(user), nil); err != nil {
+		return nil, err
+	}
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
@@ -324,5 +824,26 @@ func (c *UserUseCase) Delete(ctx context.Context, request *model.DeleteUserReque
 		return nil, fiber.ErrInternalServerError
 	}
 
-	return converter.UserToResponse(user), nil
+	return c.render(ctx, user)
+}
+
+// render converts the user for a client, with the avatar URL rendered by renderUser.
+func (c *UserUseCase) render(ctx context.Context, user *entity.User) (*model.UserResponse, error) {
+	response := converter.UserToResponse(user)
+	if err := renderUser(ctx, c.FileStorage, response); err != nil {
+		c.Log.Warnf("Failed render user : %+v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+	return response, nil
+}
+
+// renderUser gives the avatar of a user a URL the client can fetch, a signed one when the storage
+// keeps the files private.
+func renderUser(ctx context.Context, fileStorage repository.FileStorage, response *model.UserResponse) error {
+	if response.AvatarUrl == "" {
+		return nil
+	}
+	var err error
+	response.AvatarUrl, err = fileStorage.SignURL(ctx, response.AvatarUrl)
+	return err
 }
@@ -0,0 +1,283 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fp-designpattern/internal/entity"
+	"fp-designpattern/internal/mailer"
+	"fp-designpattern/internal/model"
+	"fp-designpattern/internal/rbac"
+	"fp-designpattern/internal/totp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestRefreshRotation(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestUserUseCase(t, db)
+	ctx := context.Background()
+	user := newTestUser(t, db, rbac.RoleUser)
+
+	login, err := c.Login(ctx, &model.LoginUserRequest{Email: user.Email, Password: testPassword, IPAddress: "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := c.Refresh(ctx, &model.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.RefreshToken == login.RefreshToken || rotated.Token == "" {
+		t.Fatal("Refresh did not issue a new pair")
+	}
+	if _, err := c.Verify(ctx, &model.VerifyUserRequest{Token: rotated.Token}); err != nil {
+		t.Fatalf("new access token rejected: %v", err)
+	}
+
+	// Replaying the rotated token revokes the session, taking the new tokens with it
+	if _, err := c.Refresh(ctx, &model.RefreshTokenRequest{RefreshToken: login.RefreshToken}); !errors.Is(err, fiber.ErrUnauthorized) {
+		t.Fatalf("replayed refresh = %v, want 401", err)
+	}
+	if _, err := c.Refresh(ctx, &model.RefreshTokenRequest{RefreshToken: rotated.RefreshToken}); !errors.Is(err, fiber.ErrUnauthorized) {
+		t.Fatalf("refresh of a revoked session = %v, want 401", err)
+	}
+	if _, err := c.Verify(ctx, &model.VerifyUserRequest{Token: rotated.Token}); !errors.Is(err, fiber.ErrUnauthorized) {
+		t.Fatalf("access token of a revoked session = %v, want 401", err)
+	}
+	if _, err := c.Refresh(ctx, &model.RefreshTokenRequest{RefreshToken: "unknown"}); !errors.Is(err, fiber.ErrUnauthorized) {
+		t.Fatalf("unknown refresh token = %v, want 401", err)
+	}
+}
+
+func TestLoginTwoFactorChallengeIsSingleUse(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestUserUseCase(t, db)
+	ctx := context.Background()
+	user := newTestUser(t, db, rbac.RoleUser)
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(user).Updates(map[string]any{"totp_secret": secret, "totp_enabled_at": time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+	code := func(offset int64) string {
+		value, err := totp.Code(secret, totp.Step(time.Now())+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	login := func() string {
+		response, err := c.Login(ctx, &model.LoginUserRequest{Email: user.Email, Password: testPassword, IPAddress: "192.0.2.1"})
+		if err != nil || response.Challenge == "" {
+			t.Fatalf("Login = %+v, %v, want a challenge", response, err)
+		}
+		return response.Challenge
+	}
+
+	challenge := login()
+	if _, err := c.LoginTwoFactor(ctx, &model.LoginTwoFactorRequest{Challenge: challenge, Code: "000000x"}); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Fatalf("wrong code = %v, want invalid code", err)
+	}
+	if _, err := c.LoginTwoFactor(ctx, &model.LoginTwoFactorRequest{Challenge: challenge, Code: code(-1)}); err != nil {
+		t.Fatalf("right code = %v", err)
+	}
+	if _, err := c.LoginTwoFactor(ctx, &model.LoginTwoFactorRequest{Challenge: challenge, Code: code(1)}); !errors.Is(err, fiber.ErrUnauthorized) {
+		t.Fatalf("reused challenge = %v, want 401", err)
+	}
+
+	// The last attempt of a challenge uses it up, even the right code is refused after it
+	challenge = login()
+	if err := db.Model(user).Update("mfa_challenge_attempts", challengeAttempts-1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.LoginTwoFactor(ctx, &model.LoginTwoFactorRequest{Challenge: challenge, Code: "000000x"}); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Fatalf("wrong code = %v, want invalid code", err)
+	}
+	if _, err := c.LoginTwoFactor(ctx, &model.LoginTwoFactorRequest{Challenge: challenge, Code: code(1)}); !errors.Is(err, fiber.ErrUnauthorized) {
+		t.Fatalf("challenge out of attempts = %v, want 401", err)
+	}
+}
+
+func TestUserSessions(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestUserUseCase(t, db)
+	ctx := context.Background()
+	user := newTestUser(t, db, rbac.RoleUser)
+	other := newTestUser(t, db, rbac.RoleUser)
+	userID := user.ID.String()
+
+	// signIn logs the user in on the device and returns the access token and the session behind it
+	signIn := func(email string, device string) (string, string) {
+		t.Helper()
+		login, err := c.Login(ctx, &model.LoginUserRequest{Email: email, Password: testPassword, Device: device, IPAddress: "192.0.2.1", UserAgent: "test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		auth, err := c.Verify(ctx, &model.VerifyUserRequest{Token: login.Token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return login.Token, auth.SessionID
+	}
+	laptopToken, laptop := signIn(user.Email, "laptop")
+	phoneToken, phone := signIn(user.Email, "phone")
+	_, otherSession := signIn(other.Email, "laptop")
+
+	// Using a token moves the session to the top of the list
+	hourAgo := time.Now().Add(-time.Hour)
+	if err := db.Model(&entity.UserSession{}).Where("id IN ?", []string{laptop, phone}).Update("last_seen_at", hourAgo).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Verify(ctx, &model.VerifyUserRequest{Token: phoneToken}); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := c.Sessions(ctx, &model.ListUserSessionRequest{UserID: userID, SessionID: laptop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || sessions[0].ID.String() != phone || sessions[1].ID.String() != laptop {
+		t.Fatalf("sessions %+v, want the phone then the laptop", sessions)
+	}
+	if !sessions[0].LastSeenAt.After(hourAgo.Add(time.Minute)) || !sessions[1].LastSeenAt.Before(hourAgo.Add(time.Minute)) {
+		t.Errorf("last seen %v and %v, want only the phone seen just now", sessions[0].LastSeenAt, sessions[1].LastSeenAt)
+	}
+	if sessions[0].Current || !sessions[1].Current || sessions[0].Device != "phone" {
+		t.Errorf("sessions %+v, want the laptop flagged as current", sessions)
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		userID  string
+		want    int
+		revoked string
+	}{
+		{"session of another user", otherSession, userID, fiber.StatusNotFound, ""},
+		{"own session by another user", phone, other.ID.String(), fiber.StatusNotFound, ""},
+		{"unknown session", uuid.NewString(), userID, fiber.StatusNotFound, ""},
+		{"own session", phone, userID, 0, phoneToken},
+		{"revoked session", phone, userID, fiber.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		_, err := c.RevokeSession(ctx, &model.RevokeUserSessionRequest{ID: tt.id, UserID: tt.userID})
+		if got := statusOf(err); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+		if tt.revoked != "" {
+			if _, err := c.Verify(ctx, &model.VerifyUserRequest{Token: tt.revoked}); !errors.Is(err, fiber.ErrUnauthorized) {
+				t.Errorf("%s: token of the revoked session = %v, want 401", tt.name, err)
+			}
+		}
+	}
+	sessions, err = c.Sessions(ctx, &model.ListUserSessionRequest{UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID.String() != laptop {
+		t.Errorf("sessions %+v, want the laptop left", sessions)
+	}
+
+	// Signing out everywhere leaves the sessions of other users alone
+	revoked, err := c.RevokeAllSessions(ctx, &model.RevokeAllUserSessionRequest{UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked != 1 {
+		t.Errorf("revoked %d sessions, want 1", revoked)
+	}
+	if _, err := c.Verify(ctx, &model.VerifyUserRequest{Token: laptopToken}); !errors.Is(err, fiber.ErrUnauthorized) {
+		t.Errorf("token after signing out everywhere = %v, want 401", err)
+	}
+	sessions, err = c.Sessions(ctx, &model.ListUserSessionRequest{UserID: other.ID.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID.String() != otherSession {
+		t.Errorf("sessions of the other user %+v, want theirs untouched", sessions)
+	}
+}
+
+// memoryMailer keeps the messages it is asked to send.
+type memoryMailer struct {
+	messages []*mailer.Message
+}
+
+func (m *memoryMailer) Send(ctx context.Context, message *mailer.Message) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+func TestVerifyEmail(t *testing.T) {
+	db := newTestDB(t)
+	c := newTestUserUseCase(t, db)
+	mails := new(memoryMailer)
+	c.Mailer = mails
+	ctx := context.Background()
+	user := newTestUser(t, db, rbac.RoleUser)
+	userID := user.ID.String()
+
+	if _, err := c.ResendVerification(ctx, &model.ResendVerificationRequest{UserID: userID}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mails.messages) != 1 || mails.messages[0].To != user.Email {
+		t.Fatalf("sent %+v, want one mail to %s", mails.messages, user.Email)
+	}
+	_, link, _ := strings.Cut(mails.messages[0].Body, "verify your email address: ")
+	raw, _, _ := strings.Cut(link, "\n")
+	previousEmail, err := c.Tokens.SignVerification(userID, "previous@example.com", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"unknown token", "unknown", ErrVerificationTokenInvalid},
+		{"token sent to a previous email", previousEmail, ErrVerificationTokenInvalid},
+		{"mailed token", raw, nil},
+		// Opening the link again is harmless
+		{"mailed token again", raw, nil},
+	}
+	for _, tt := range tests {
+		if _, err := c.VerifyEmail(ctx, &model.VerifyEmailRequest{Token: tt.token}); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	verified, err := c.EmailVerified(ctx, &model.GetUserRequest{ID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verified {
+		t.Error("email is not verified after opening the mailed link")
+	}
+	// Tokens issued from now on carry the verified claim, the gate no longer reads the user
+	login, err := c.Login(ctx, &model.LoginUserRequest{Email: user.Email, Password: testPassword, IPAddress: "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := c.Verify(ctx, &model.VerifyUserRequest{Token: login.Token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !auth.EmailVerified {
+		t.Error("token issued after verifying lacks the verified claim")
+	}
+
+	if _, err := c.ResendVerification(ctx, &model.ResendVerificationRequest{UserID: userID}); statusOf(err) != fiber.StatusConflict {
+		t.Errorf("resending to a verified email = %v, want 409", err)
+	}
+	if _, err := c.ResendVerification(ctx, &model.ResendVerificationRequest{UserID: uuid.NewString()}); statusOf(err) != fiber.StatusNotFound {
+		t.Errorf("resending to an unknown user = %v, want 404", err)
+	}
+	if len(mails.messages) != 1 {
+		t.Errorf("sent %d mails, want only the first", len(mails.messages))
+	}
+}